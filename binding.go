@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Uniforms and vertex attributes can be declared as Go structs whose
+// fields are tagged with the GLSL name they stand for:
+//
+//	type uniforms struct {
+//		Model    glm.Mat4 `glsl:"model"`
+//		TexPuppy int32    `glsl:"texPuppy"`
+//	}
+//
+// Binding a struct to a program compares its fields with the active
+// uniforms or attributes of the program. A tagged name the program doesn't
+// use, an active name without a field and a field of the wrong type are
+// all errors, so a typo doesn't leave a uniform silently at zero.
+
+// uniformType describes a GLSL uniform type: the Go types a field for it
+// may have and how to upload count values of it.
+type uniformType struct {
+	name  string
+	types []reflect.Type
+	set   func(loc gl.UniformLocation, count int, v reflect.Value)
+}
+
+var (
+	float32Type = reflect.TypeOf(float32(0))
+	int32Type   = reflect.TypeOf(int32(0))
+	intType     = reflect.TypeOf(0)
+	boolType    = reflect.TypeOf(false)
+)
+
+func floatVector(name string, goType reflect.Type, set func(loc gl.UniformLocation, count int, v []float32)) uniformType {
+	types := []reflect.Type{goType}
+	if goType.Kind() == reflect.Array {
+		types = append(types, reflect.ArrayOf(goType.Len(), float32Type))
+	}
+	return uniformType{name, types, func(loc gl.UniformLocation, count int, v reflect.Value) {
+		set(loc, count, appendFloats(nil, v))
+	}}
+}
+
+func intVector(name string, n int, set func(loc gl.UniformLocation, count int, v []int32)) uniformType {
+	types := []reflect.Type{int32Type, intType, boolType}
+	if n > 1 {
+		types = []reflect.Type{reflect.ArrayOf(n, int32Type), reflect.ArrayOf(n, boolType)}
+	}
+	return uniformType{name, types, func(loc gl.UniformLocation, count int, v reflect.Value) {
+		set(loc, count, appendInts(nil, v))
+	}}
+}
+
+// sampler uniforms are set to the texture unit they read from.
+var samplerType = intVector("sampler", 1, gl.UniformLocation.Uniform1iv)
+
+var uniformTypes = map[gl.GLenum]uniformType{
+	gl.FLOAT:      floatVector("float", float32Type, gl.UniformLocation.Uniform1fv),
+	gl.FLOAT_VEC2: floatVector("vec2", reflect.TypeOf(glm.Vec2{}), gl.UniformLocation.Uniform2fv),
+	gl.FLOAT_VEC3: floatVector("vec3", reflect.TypeOf(glm.Vec3{}), gl.UniformLocation.Uniform3fv),
+	gl.FLOAT_VEC4: floatVector("vec4", reflect.TypeOf(glm.Vec4{}), gl.UniformLocation.Uniform4fv),
+	gl.INT:        intVector("int", 1, gl.UniformLocation.Uniform1iv),
+	gl.INT_VEC2:   intVector("ivec2", 2, gl.UniformLocation.Uniform2iv),
+	gl.INT_VEC3:   intVector("ivec3", 3, gl.UniformLocation.Uniform3iv),
+	gl.INT_VEC4:   intVector("ivec4", 4, gl.UniformLocation.Uniform4iv),
+	gl.BOOL:       intVector("bool", 1, gl.UniformLocation.Uniform1iv),
+	gl.BOOL_VEC2:  intVector("bvec2", 2, gl.UniformLocation.Uniform2iv),
+	gl.BOOL_VEC3:  intVector("bvec3", 3, gl.UniformLocation.Uniform3iv),
+	gl.BOOL_VEC4:  intVector("bvec4", 4, gl.UniformLocation.Uniform4iv),
+
+	gl.FLOAT_MAT2: floatVector("mat2", reflect.TypeOf(glm.Mat2{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][4]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*4:])
+		}
+		loc.UniformMatrix2fv(false, m...)
+	}),
+	gl.FLOAT_MAT3: floatVector("mat3", reflect.TypeOf(glm.Mat3{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][9]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*9:])
+		}
+		loc.UniformMatrix3fv(false, m...)
+	}),
+	gl.FLOAT_MAT4: floatVector("mat4", reflect.TypeOf(glm.Mat4{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][16]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*16:])
+		}
+		loc.UniformMatrix4fv(false, m...)
+	}),
+	// GLSL names matrices columns by rows, mathgl rows by columns
+	gl.FLOAT_MAT2x3: floatVector("mat2x3", reflect.TypeOf(glm.Mat3x2{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][6]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*6:])
+		}
+		loc.UniformMatrix2x3fv(false, m...)
+	}),
+	gl.FLOAT_MAT3x2: floatVector("mat3x2", reflect.TypeOf(glm.Mat2x3{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][6]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*6:])
+		}
+		loc.UniformMatrix3x2fv(false, m...)
+	}),
+	gl.FLOAT_MAT2x4: floatVector("mat2x4", reflect.TypeOf(glm.Mat4x2{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][8]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*8:])
+		}
+		loc.UniformMatrix2x4fv(false, m...)
+	}),
+	gl.FLOAT_MAT4x2: floatVector("mat4x2", reflect.TypeOf(glm.Mat2x4{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][8]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*8:])
+		}
+		loc.UniformMatrix4x2fv(false, m...)
+	}),
+	gl.FLOAT_MAT3x4: floatVector("mat3x4", reflect.TypeOf(glm.Mat4x3{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][12]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*12:])
+		}
+		loc.UniformMatrix3x4fv(false, m...)
+	}),
+	gl.FLOAT_MAT4x3: floatVector("mat4x3", reflect.TypeOf(glm.Mat3x4{}), func(loc gl.UniformLocation, count int, v []float32) {
+		m := make([][12]float32, count)
+		for i := range m {
+			copy(m[i][:], v[i*12:])
+		}
+		loc.UniformMatrix4x3fv(false, m...)
+	}),
+
+	gl.SAMPLER_1D:              samplerType,
+	gl.SAMPLER_2D:              samplerType,
+	gl.SAMPLER_3D:              samplerType,
+	gl.SAMPLER_CUBE:            samplerType,
+	gl.SAMPLER_1D_SHADOW:       samplerType,
+	gl.SAMPLER_2D_SHADOW:       samplerType,
+	gl.SAMPLER_2D_RECT:         samplerType,
+	gl.SAMPLER_2D_ARRAY:        samplerType,
+	gl.SAMPLER_CUBE_SHADOW:     samplerType,
+	gl.INT_SAMPLER_2D:          samplerType,
+	gl.UNSIGNED_INT_SAMPLER_2D: samplerType,
+}
+
+// appendFloats appends the float32 components of v, which may be a
+// float32 or an array or slice of them, to dst.
+func appendFloats(dst []float32, v reflect.Value) []float32 {
+	switch v.Kind() {
+	case reflect.Float32:
+		return append(dst, float32(v.Float()))
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			dst = appendFloats(dst, v.Index(i))
+		}
+	}
+	return dst
+}
+
+// appendInts is appendFloats for integers and bools.
+func appendInts(dst []int32, v reflect.Value) []int32 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int32:
+		return append(dst, int32(v.Int()))
+	case reflect.Bool:
+		if v.Bool() {
+			return append(dst, 1)
+		}
+		return append(dst, 0)
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			dst = appendInts(dst, v.Index(i))
+		}
+	}
+	return dst
+}
+
+// activeVar is an active uniform or attribute of a program.
+type activeVar struct {
+	size int
+	typ  gl.GLenum
+}
+
+// activeUniforms returns the uniforms program uses by name. Arrays are
+// listed under their name without [0].
+func activeUniforms(program gl.Program) map[string]activeVar {
+	vars := make(map[string]activeVar)
+	for i := 0; i < program.Get(gl.ACTIVE_UNIFORMS); i++ {
+		size, typ, name := program.GetActiveUniform(i)
+		if !strings.HasPrefix(name, "gl_") {
+			vars[strings.TrimSuffix(name, "[0]")] = activeVar{size, typ}
+		}
+	}
+	return vars
+}
+
+// taggedFields returns the indices of the fields of struct type t tagged
+// glsl:"name", by name.
+func taggedFields(t reflect.Type) (map[string]int, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not a struct", t)
+	}
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("glsl")
+		if name == "" {
+			continue
+		}
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("%v: more than one field is tagged %q", t, name)
+		}
+		fields[name] = i
+	}
+	return fields, nil
+}
+
+// bindingError lists everything wrong with binding a struct to a program.
+func bindingError(t reflect.Type, problems []string) error {
+	sort.Strings(problems)
+	return fmt.Errorf("cannot bind %v:\n\t%s", t, strings.Join(problems, "\n\t"))
+}
+
+// uniformBinding uploads the fields of a struct to the uniforms of a
+// program, see bindUniformStruct.
+type uniformBinding struct {
+	value    reflect.Value
+	uniforms []boundUniform
+}
+
+type boundUniform struct {
+	field    int
+	location gl.UniformLocation
+	count    int // for arrays, the number of elements the program uses
+	set      func(loc gl.UniformLocation, count int, v reflect.Value)
+}
+
+// bindUniformStruct looks up the uniforms named by the glsl tags of the
+// struct ptr points to and checks that they are all the active uniforms
+// of program and that the fields have matching types. Array uniforms take
+// a Go array or slice of the element type. Samplers take the texture unit
+// as an int32.
+//
+// The binding holds on to ptr: Upload sends the current values of all the
+// fields. Bind again after the program is relinked.
+func bindUniformStruct(program gl.Program, ptr interface{}) (*uniformBinding, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("bindUniformStruct needs a pointer to a struct, not %T", ptr)
+	}
+	v = v.Elem()
+	fields, err := taggedFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	b := &uniformBinding{value: v}
+	var problems []string
+	active := activeUniforms(program)
+	for name, u := range active {
+		field, ok := fields[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("uniform %s has no field", name))
+			continue
+		}
+		ut, ok := uniformTypes[u.typ]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("uniform %s has a type that can't be bound (%#x)", name, u.typ))
+			continue
+		}
+		f := v.Type().Field(field)
+		count, ok := uniformCount(f.Type, ut, u.size)
+		if !ok {
+			want := ut.name
+			if u.size > 1 {
+				want = fmt.Sprintf("%s[%d]", ut.name, u.size)
+			}
+			problems = append(problems, fmt.Sprintf("field %s is %v, which can't hold the %s uniform %s", f.Name, f.Type, want, name))
+			continue
+		}
+		b.uniforms = append(b.uniforms, boundUniform{field, program.GetUniformLocation(name), count, ut.set})
+	}
+	for name := range fields {
+		if _, ok := active[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is not an active uniform: it isn't declared or the shaders don't use it", name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, bindingError(v.Type(), problems)
+	}
+	return b, nil
+}
+
+// uniformCount reports whether a field of type t can hold a uniform of
+// type ut and size elements, and how many elements are uploaded. A Go
+// array must have the size of the uniform; slices may be shorter.
+func uniformCount(t reflect.Type, ut uniformType, size int) (int, bool) {
+	for _, u := range ut.types {
+		if t == u && size == 1 {
+			return 1, true
+		}
+	}
+	if t.Kind() != reflect.Array && t.Kind() != reflect.Slice {
+		return 0, false
+	}
+	for _, u := range ut.types {
+		switch {
+		case t.Elem() != u:
+		case t.Kind() == reflect.Slice:
+			return size, true
+		case t.Len() == size:
+			return size, true
+		}
+	}
+	return 0, false
+}
+
+// Upload sets every bound uniform to the value of its field. The program
+// must be current.
+func (b *uniformBinding) Upload() {
+	for _, u := range b.uniforms {
+		v := b.value.Field(u.field)
+		count := u.count
+		if v.Kind() == reflect.Slice && v.Len() < count {
+			count = v.Len()
+		}
+		if count > 0 {
+			u.set(u.location, count, v)
+		}
+	}
+}
+
+// attribTypes are the GLSL attribute types a struct field can supply,
+// with the number of floats they take.
+var attribTypes = map[gl.GLenum]struct {
+	size  uint
+	types []reflect.Type
+}{
+	gl.FLOAT:      {1, []reflect.Type{float32Type}},
+	gl.FLOAT_VEC2: {2, []reflect.Type{reflect.TypeOf(glm.Vec2{}), reflect.ArrayOf(2, float32Type)}},
+	gl.FLOAT_VEC3: {3, []reflect.Type{reflect.TypeOf(glm.Vec3{}), reflect.ArrayOf(3, float32Type)}},
+	gl.FLOAT_VEC4: {4, []reflect.Type{reflect.TypeOf(glm.Vec4{}), reflect.ArrayOf(4, float32Type)}},
+}
+
+// bindVertexStruct sets up the vertex attributes of program for vertices
+// laid out like the struct vertex, interleaved in the buffer bound to
+// ARRAY_BUFFER: the stride is the size of the struct and each attribute
+// starts at the offset of the field tagged with its name. The attributes
+// are enabled on the bound vertex array. As with bindUniformStruct, every
+// active attribute needs a field and every tagged field an active
+// attribute.
+func bindVertexStruct(program gl.Program, vertex interface{}) error {
+	t := reflect.TypeOf(vertex)
+	fields, err := taggedFields(t)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	active := make(map[string]bool)
+	for i := 0; i < program.Get(gl.ACTIVE_ATTRIBUTES); i++ {
+		_, typ, name := program.GetActiveAttrib(i)
+		if strings.HasPrefix(name, "gl_") {
+			continue
+		}
+		active[name] = true
+		field, ok := fields[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("attribute %s has no field", name))
+			continue
+		}
+		f := t.Field(field)
+		at, ok := attribTypes[typ]
+		match := false
+		for _, u := range at.types {
+			match = match || f.Type == u
+		}
+		if !ok || !match {
+			problems = append(problems, fmt.Sprintf("field %s is %v, which can't supply the attribute %s", f.Name, f.Type, name))
+			continue
+		}
+		loc := program.GetAttribLocation(name)
+		loc.EnableArray()
+		loc.AttribPointer(at.size, gl.FLOAT, false, int(t.Size()), f.Offset)
+		checkError(name + " attrib pointer")
+	}
+	for name := range fields {
+		if !active[name] {
+			problems = append(problems, fmt.Sprintf("%s is not an active attribute: it isn't declared or the shaders don't use it", name))
+		}
+	}
+	if len(problems) > 0 {
+		return bindingError(t, problems)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/go-gl/gl"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"reflect"
+	"testing"
+)
+
+func TestUniformFieldTypes(t *testing.T) {
+	tests := []struct {
+		field interface{}
+		typ   gl.GLenum
+		size  int
+		count int // 0 if the field doesn't fit
+	}{
+		{glm.Mat4{}, gl.FLOAT_MAT4, 1, 1},
+		{[16]float32{}, gl.FLOAT_MAT4, 1, 1},
+		{glm.Vec4{}, gl.FLOAT_MAT4, 1, 0},
+		{glm.Vec4{}, gl.FLOAT_MAT2, 1, 0},
+		{glm.Mat3x2{}, gl.FLOAT_MAT2x3, 1, 1},
+		{glm.Mat2x3{}, gl.FLOAT_MAT2x3, 1, 0},
+		{float32(0), gl.FLOAT, 1, 1},
+		{0.0, gl.FLOAT, 1, 0},
+		{int32(0), gl.SAMPLER_2D, 1, 1},
+		{true, gl.BOOL, 1, 1},
+		{[4]glm.Vec3{}, gl.FLOAT_VEC3, 4, 4},
+		{[3]glm.Vec3{}, gl.FLOAT_VEC3, 4, 0},
+		{[]glm.Vec3{}, gl.FLOAT_VEC3, 4, 4},
+		{glm.Vec3{}, gl.FLOAT_VEC3, 4, 0},
+	}
+	for _, test := range tests {
+		count, ok := uniformCount(reflect.TypeOf(test.field), uniformTypes[test.typ], test.size)
+		if !ok {
+			count = 0
+		}
+		if count != test.count {
+			t.Errorf("%T for %s[%d]: count %d, want %d", test.field, uniformTypes[test.typ].name, test.size, count, test.count)
+		}
+	}
+}
+
+func TestTaggedFields(t *testing.T) {
+	type vertex struct {
+		Position glm.Vec3 `glsl:"position"`
+		Color    glm.Vec3 `glsl:"color"`
+		padding  float32
+	}
+	fields, err := taggedFields(reflect.TypeOf(vertex{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"position": 0, "color": 1}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("taggedFields = %v, want %v", fields, want)
+	}
+
+	type twice struct {
+		A float32 `glsl:"a"`
+		B float32 `glsl:"a"`
+	}
+	if _, err := taggedFields(reflect.TypeOf(twice{})); err == nil {
+		t.Error("no error for two fields tagged a")
+	}
+}
+
+func TestAppendComponents(t *testing.T) {
+	floats := appendFloats(nil, reflect.ValueOf([]glm.Vec2{{1, 2}, {3, 4}}))
+	if want := []float32{1, 2, 3, 4}; !reflect.DeepEqual(floats, want) {
+		t.Errorf("appendFloats = %v, want %v", floats, want)
+	}
+	ints := appendInts(nil, reflect.ValueOf([3]bool{true, false, true}))
+	if want := []int32{1, 0, 1}; !reflect.DeepEqual(ints, want) {
+		t.Errorf("appendInts = %v, want %v", ints, want)
+	}
+}
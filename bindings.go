@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"os"
+	"strings"
+)
+
+// defaultBindings binds the actions of the launcher and the exercises. A
+// file passed with --bindings, in the same format, rebinds the actions it
+// lists; 'gltut bindings' prints these to start one from.
+const defaultBindings = `# action       bindings
+quit           escape
+fullscreen     f11
+screenshot     f12 ctrl+s
+pause          p
+slow-motion    m
+step           period
+
+# the camera of the 3D exercises
+turn           mouse1
+forward        w
+back           s
+left           a
+right          d
+up             e
+down           q
+reset-camera   r
+
+# exercises
+spin           space
+sampler        t
+exposure-up    equal
+exposure-down  minus
+tone-operator  o
+half-float     f
+`
+
+// bindingsPath is a file of bindings to load over the default ones, set
+// with the --bindings flag.
+var bindingsPath string
+
+// loadActions returns the actions with the default bindings, and those of
+// bindingsPath if it is set.
+func loadActions() (*input.Actions, error) {
+	actions := input.NewActions()
+	if err := actions.Load(strings.NewReader(defaultBindings)); err != nil {
+		panic(err)
+	}
+	if bindingsPath == "" {
+		return actions, nil
+	}
+
+	known := make(map[string]bool)
+	for _, name := range actions.Names() {
+		known[name] = true
+	}
+	if err := actions.LoadFile(bindingsPath); err != nil {
+		return nil, err
+	}
+	for _, name := range actions.Names() {
+		if !known[name] {
+			return nil, fmt.Errorf("%s: unknown action %q, see 'gltut bindings'", bindingsPath, name)
+		}
+	}
+	return actions, nil
+}
+
+func bindingsCommand(args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: gltut bindings")
+		os.Exit(2)
+	}
+	fmt.Print(defaultBindings)
+	return nil
+}
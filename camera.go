@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	glm "github.com/go-gl/mathgl/mgl32"
+)
+
+// cameraMode is the controller the 3D exercises move their view with, set
+// with the --camera flag: orbit, arcball or fly.
+var cameraMode = "orbit"
+
+// sceneCamera is the camera of the running exercise, if it made one. The
+// launcher feeds it the input of every frame before Draw.
+var sceneCamera camera.Controller
+
+// checkCameraMode reports whether cameraMode names a controller.
+func checkCameraMode() error {
+	switch cameraMode {
+	case "orbit", "arcball", "fly":
+		return nil
+	}
+	return fmt.Errorf("unknown camera %q, want orbit, arcball or fly", cameraMode)
+}
+
+// newCamera creates the controller cameraMode asks for, at eye looking at
+// target with up pointing up, and makes it the scene camera.
+func newCamera(eye, target, up glm.Vec3) camera.Controller {
+	switch cameraMode {
+	case "fly":
+		sceneCamera = camera.NewFly(eye, target)
+	case "arcball":
+		orbit := camera.NewOrbit(eye, target, up)
+		orbit.Arcball = true
+		sceneCamera = orbit
+	default:
+		sceneCamera = camera.NewOrbit(eye, target, up)
+	}
+	return sceneCamera
+}
+
+// cameraInput turns the actions of a frame into a camera.Input: the turn
+// action, the left button, drags, forward, back, left and right move, up
+// and down move up and down, and reset-camera resets.
+func cameraInput(actions *input.Actions) camera.Input {
+	return camera.Input{
+		Cursor:  actions.Cursor(),
+		Motion:  actions.Motion(),
+		Window:  actions.WindowSize(),
+		Scroll:  actions.Scroll(),
+		Drag:    actions.Held("turn"),
+		Forward: actions.Held("forward"),
+		Back:    actions.Held("back"),
+		Left:    actions.Held("left"),
+		Right:   actions.Held("right"),
+		Up:      actions.Held("up"),
+		Down:    actions.Held("down"),
+		Reset:   actions.Pressed("reset-camera"),
+	}
+}
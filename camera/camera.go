@@ -0,0 +1,268 @@
+// Package camera moves a viewpoint with the mouse and keyboard and turns
+// it into the view matrix the exercises upload every frame. Orbit circles
+// a point, turning like a turntable or an arcball; Fly moves freely with
+// WASD and mouse look. Both zoom with the mouse wheel and go back to where
+// they started on reset.
+//
+// Controllers don't read input devices themselves. They are fed an Input
+// for every frame, so tests and replays can drive them with made-up
+// events. Like the exercises, the world is Z up.
+package camera
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+// Input is what a controller sees of the mouse and keyboard in one frame.
+type Input struct {
+	// Cursor is the position of the cursor in window coordinates, from
+	// the top left, and Motion how far it moved since the last frame.
+	Cursor, Motion glm.Vec2
+
+	// Window is the size of the window, in the units of Cursor.
+	Window glm.Vec2
+
+	// Scroll is how far the wheel turned since the last frame, in clicks,
+	// positive away from the user.
+	Scroll float32
+
+	// Drag is whether the button that turns the camera is held.
+	Drag bool
+
+	// The movement keys held down.
+	Forward, Back, Left, Right, Up, Down bool
+
+	// Reset is whether the reset key was pressed this frame.
+	Reset bool
+}
+
+// Controller is a camera moved by input.
+type Controller interface {
+	// Update moves the camera by the input of a frame that lasted dt.
+	Update(in Input, dt time.Duration)
+
+	// View returns the view matrix.
+	View() glm.Mat4
+
+	// Eye returns the position of the camera in the world.
+	Eye() glm.Vec3
+
+	// Reset puts the camera back where it was created.
+	Reset()
+}
+
+// Orbit looks at Target from Distance away. Dragging turns the camera
+// around the target: about Up like a turntable, or freely like an arcball
+// if Arcball is set. The wheel moves it closer or further away.
+type Orbit struct {
+	Target   glm.Vec3
+	Distance float32
+
+	// Rotation turns the axes of the camera into the world. The camera
+	// looks down its -Z, so the eye is Distance along its +Z.
+	Rotation glm.Quat
+
+	Up      glm.Vec3
+	Arcball bool
+
+	Sensitivity float32 // turntable radians per pixel dragged
+	ZoomStep    float32 // distance factor per wheel click
+	MinDistance float32
+	MaxDistance float32
+
+	homeTarget   glm.Vec3
+	homeDistance float32
+	homeRotation glm.Quat
+}
+
+// NewOrbit returns a turntable at eye looking at target, with up pointing
+// up on the screen.
+func NewOrbit(eye, target, up glm.Vec3) *Orbit {
+	offset := eye.Sub(target)
+	o := &Orbit{
+		Target:      target,
+		Distance:    offset.Len(),
+		Rotation:    lookRotation(offset, up),
+		Up:          up.Normalize(),
+		Sensitivity: 0.01,
+		ZoomStep:    1.1,
+	}
+	o.MinDistance, o.MaxDistance = o.Distance/100, o.Distance*100
+	o.homeTarget, o.homeDistance, o.homeRotation = o.Target, o.Distance, o.Rotation
+	return o
+}
+
+// lookRotation returns the rotation that turns +Z into back and +Y as
+// close to up as it gets.
+func lookRotation(back, up glm.Vec3) glm.Quat {
+	z := back.Normalize()
+	x := up.Cross(z).Normalize()
+	y := z.Cross(x)
+	return glm.Mat4ToQuat(glm.Mat4{
+		x[0], x[1], x[2], 0,
+		y[0], y[1], y[2], 0,
+		z[0], z[1], z[2], 0,
+		0, 0, 0, 1,
+	}).Normalize()
+}
+
+func (o *Orbit) Update(in Input, dt time.Duration) {
+	if in.Reset {
+		o.Reset()
+	}
+	if in.Drag && in.Motion != (glm.Vec2{}) {
+		if o.Arcball {
+			o.arcball(in.Cursor.Sub(in.Motion), in.Cursor, in.Window)
+		} else {
+			o.turntable(in.Motion)
+		}
+	}
+	if in.Scroll != 0 {
+		o.Distance *= float32(math.Pow(float64(o.ZoomStep), float64(-in.Scroll)))
+		o.Distance = float32(math.Max(float64(o.MinDistance), math.Min(float64(o.MaxDistance), float64(o.Distance))))
+	}
+}
+
+// turntable turns the camera about Up for horizontal motion and tilts it
+// for vertical motion, so the scene follows the cursor. It stops tilting
+// short of looking straight down Up.
+func (o *Orbit) turntable(motion glm.Vec2) {
+	yaw := glm.QuatRotate(-motion[0]*o.Sensitivity, o.Up)
+	tilt := glm.QuatRotate(-motion[1]*o.Sensitivity, glm.Vec3{1, 0, 0})
+	rotation := yaw.Mul(o.Rotation).Mul(tilt).Normalize()
+	if rotation.Rotate(glm.Vec3{0, 1, 0}).Dot(o.Up) < 0.01 {
+		rotation = yaw.Mul(o.Rotation).Normalize()
+	}
+	o.Rotation = rotation
+}
+
+// arcball turns the camera as if the cursor dragged a ball filling the
+// window from from to to, turning the scene with it.
+func (o *Orbit) arcball(from, to, window glm.Vec2) {
+	p0, p1 := arcballPoint(from, window), arcballPoint(to, window)
+	o.Rotation = o.Rotation.Mul(glm.QuatBetweenVectors(p1, p0)).Normalize()
+}
+
+// arcballPoint returns the point of the ball under the cursor, in camera
+// coordinates. Outside the ball it is the nearest point on its rim.
+func arcballPoint(cursor, window glm.Vec2) glm.Vec3 {
+	radius := float32(math.Min(float64(window[0]), float64(window[1]))) / 2
+	if radius <= 0 {
+		radius = 1
+	}
+	p := glm.Vec3{(cursor[0] - window[0]/2) / radius, (window[1]/2 - cursor[1]) / radius, 0}
+	d := p[0]*p[0] + p[1]*p[1]
+	if d > 1 {
+		return p.Normalize()
+	}
+	p[2] = float32(math.Sqrt(float64(1 - d)))
+	return p
+}
+
+func (o *Orbit) View() glm.Mat4 {
+	eye := o.Eye()
+	return o.Rotation.Inverse().Mat4().Mul4(glm.Translate3D(-eye[0], -eye[1], -eye[2]))
+}
+
+func (o *Orbit) Eye() glm.Vec3 {
+	return o.Target.Add(o.Rotation.Rotate(glm.Vec3{0, 0, o.Distance}))
+}
+
+func (o *Orbit) Reset() {
+	o.Target, o.Distance, o.Rotation = o.homeTarget, o.homeDistance, o.homeRotation
+}
+
+// maxPitch keeps Fly from looking straight up or down, where its yaw
+// would be lost.
+const maxPitch = 0.49 * math.Pi
+
+// Fly is a first-person camera at Position. Dragging looks around, the
+// movement keys move it along where it looks at Speed, and the wheel
+// moves it forward or back ZoomStep per click.
+type Fly struct {
+	Position glm.Vec3
+
+	// Yaw is the heading in radians, counter-clockwise about Z from +X.
+	// Pitch is up from the horizon.
+	Yaw, Pitch float32
+
+	Speed       float32 // units per second
+	Sensitivity float32 // radians per pixel dragged
+	ZoomStep    float32
+
+	homePosition       glm.Vec3
+	homeYaw, homePitch float32
+}
+
+// NewFly returns a fly camera at eye looking at target.
+func NewFly(eye, target glm.Vec3) *Fly {
+	dir := target.Sub(eye).Normalize()
+	f := &Fly{
+		Position:    eye,
+		Yaw:         float32(math.Atan2(float64(dir[1]), float64(dir[0]))),
+		Pitch:       float32(math.Asin(float64(dir[2]))),
+		Speed:       1.5,
+		Sensitivity: 0.005,
+		ZoomStep:    0.25,
+	}
+	f.Pitch = float32(math.Max(-maxPitch, math.Min(maxPitch, float64(f.Pitch))))
+	f.homePosition, f.homeYaw, f.homePitch = f.Position, f.Yaw, f.Pitch
+	return f
+}
+
+// Forward returns the direction the camera looks in.
+func (f *Fly) Forward() glm.Vec3 {
+	sinYaw, cosYaw := math.Sincos(float64(f.Yaw))
+	sinPitch, cosPitch := math.Sincos(float64(f.Pitch))
+	return glm.Vec3{float32(cosPitch * cosYaw), float32(cosPitch * sinYaw), float32(sinPitch)}
+}
+
+func (f *Fly) Update(in Input, dt time.Duration) {
+	if in.Reset {
+		f.Reset()
+	}
+	if in.Drag {
+		f.Yaw -= in.Motion[0] * f.Sensitivity
+		f.Pitch -= in.Motion[1] * f.Sensitivity
+		f.Pitch = float32(math.Max(-maxPitch, math.Min(maxPitch, float64(f.Pitch))))
+	}
+
+	forward := f.Forward()
+	up := glm.Vec3{0, 0, 1}
+	right := forward.Cross(up).Normalize()
+	var move glm.Vec3
+	for _, key := range []struct {
+		held bool
+		dir  glm.Vec3
+	}{
+		{in.Forward, forward},
+		{in.Back, forward.Mul(-1)},
+		{in.Right, right},
+		{in.Left, right.Mul(-1)},
+		{in.Up, up},
+		{in.Down, up.Mul(-1)},
+	} {
+		if key.held {
+			move = move.Add(key.dir)
+		}
+	}
+	if move.Len() > 0 {
+		move = move.Normalize().Mul(f.Speed * float32(dt.Seconds()))
+	}
+	move = move.Add(forward.Mul(in.Scroll * f.ZoomStep))
+	f.Position = f.Position.Add(move)
+}
+
+func (f *Fly) View() glm.Mat4 {
+	return glm.LookAtV(f.Position, f.Position.Add(f.Forward()), glm.Vec3{0, 0, 1})
+}
+
+func (f *Fly) Eye() glm.Vec3 {
+	return f.Position
+}
+
+func (f *Fly) Reset() {
+	f.Position, f.Yaw, f.Pitch = f.homePosition, f.homeYaw, f.homePitch
+}
@@ -0,0 +1,139 @@
+package camera
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	eye    = glm.Vec3{2.2, 3.2, 2.2}
+	target = glm.Vec3{0, 0, 0}
+	up     = glm.Vec3{0, 0, 1}
+)
+
+// approxEqual compares matrices with an absolute tolerance, as entries
+// near 0 are never close in relative terms.
+func approxEqual(a, b glm.Mat4) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrbitView(t *testing.T) {
+	o := NewOrbit(eye, target, up)
+	want := glm.LookAtV(eye, target, up)
+	if !approxEqual(o.View(), want) {
+		t.Errorf("view\n%v\nwant\n%v", o.View(), want)
+	}
+	if !o.Eye().ApproxEqualThreshold(eye, 1e-5) {
+		t.Errorf("eye %v, want %v", o.Eye(), eye)
+	}
+}
+
+func TestOrbitTurntable(t *testing.T) {
+	o := NewOrbit(eye, target, up)
+	distance := o.Distance
+
+	// half a turn to the right puts the camera on the other side, at the
+	// same height
+	o.Update(Input{Drag: true, Motion: glm.Vec2{math.Pi / o.Sensitivity, 0}}, time.Second/60)
+	want := glm.Vec3{-eye[0], -eye[1], eye[2]}
+	if !o.Eye().ApproxEqualThreshold(want, 1e-4) {
+		t.Errorf("eye %v after half a turn, want %v", o.Eye(), want)
+	}
+
+	// tilting stops short of the pole
+	for i := 0; i < 100; i++ {
+		o.Update(Input{Drag: true, Motion: glm.Vec2{0, 50}}, time.Second/60)
+	}
+	if e := o.Eye(); e[2] >= distance || e[2] < 0.9*distance {
+		t.Errorf("eye %v after tilting up, want just below the pole", e)
+	}
+
+	// moving without the button held does nothing
+	before := o.Rotation
+	o.Update(Input{Motion: glm.Vec2{100, 100}}, time.Second/60)
+	if o.Rotation != before {
+		t.Error("camera turned without dragging")
+	}
+}
+
+func TestOrbitArcball(t *testing.T) {
+	o := NewOrbit(eye, target, up)
+	o.Arcball = true
+	window := glm.Vec2{800, 600}
+
+	// dragging from the center to the rim turns the camera a quarter turn,
+	// and the scene with the cursor
+	o.Update(Input{Drag: true, Cursor: glm.Vec2{700, 300}, Motion: glm.Vec2{300, 0}, Window: window}, time.Second/60)
+	back := eye.Normalize()
+	if d := o.Eye().Normalize().Dot(back); math.Abs(float64(d)) > 1e-4 {
+		t.Errorf("eye %v isn't a quarter turn from %v", o.Eye(), eye)
+	}
+	right := up.Cross(back).Normalize()
+	if d := o.Eye().Normalize().Dot(right); d > -0.999 {
+		t.Errorf("eye %v didn't turn to the left", o.Eye())
+	}
+	if math.Abs(float64(o.Eye().Len()-eye.Len())) > 1e-4 {
+		t.Errorf("distance changed to %g", o.Eye().Len())
+	}
+}
+
+func TestOrbitZoomAndReset(t *testing.T) {
+	o := NewOrbit(eye, target, up)
+	distance := o.Distance
+	o.Update(Input{Scroll: 2}, time.Second/60)
+	if want := distance / 1.21; math.Abs(float64(o.Distance-want)) > 1e-4 {
+		t.Errorf("distance %g after zooming in, want %g", o.Distance, want)
+	}
+	for i := 0; i < 100; i++ {
+		o.Update(Input{Scroll: -10}, time.Second/60)
+	}
+	if o.Distance != o.MaxDistance {
+		t.Errorf("distance %g, want it clamped at %g", o.Distance, o.MaxDistance)
+	}
+
+	o.Update(Input{Drag: true, Motion: glm.Vec2{120, 40}}, time.Second/60)
+	o.Update(Input{Reset: true}, time.Second/60)
+	if !approxEqual(o.View(), glm.LookAtV(eye, target, up)) {
+		t.Error("reset didn't restore the view")
+	}
+}
+
+func TestFly(t *testing.T) {
+	f := NewFly(eye, target)
+	if !approxEqual(f.View(), glm.LookAtV(eye, target, up)) {
+		t.Errorf("view\n%v\nwant\n%v", f.View(), glm.LookAtV(eye, target, up))
+	}
+
+	// a second of W moves Speed towards the target
+	forward := target.Sub(eye).Normalize()
+	f.Update(Input{Forward: true}, time.Second)
+	if want := eye.Add(forward.Mul(f.Speed)); !f.Position.ApproxEqualThreshold(want, 1e-5) {
+		t.Errorf("position %v, want %v", f.Position, want)
+	}
+
+	// strafing right keeps the height
+	z := f.Position[2]
+	f.Update(Input{Right: true}, time.Second)
+	if math.Abs(float64(f.Position[2]-z)) > 1e-5 {
+		t.Errorf("strafing changed the height to %g", f.Position[2])
+	}
+
+	// dragging right looks right, and pitch is clamped
+	yaw := f.Yaw
+	f.Update(Input{Drag: true, Motion: glm.Vec2{100, 100000}}, time.Second/60)
+	if f.Yaw >= yaw || f.Pitch != -maxPitch {
+		t.Errorf("yaw %g and pitch %g after dragging", f.Yaw, f.Pitch)
+	}
+
+	f.Update(Input{Reset: true}, time.Second/60)
+	if f.Position != eye || f.Yaw != yaw {
+		t.Error("reset didn't restore the camera")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"time"
+)
+
+// Clock supplies the time scenes animate with. Scenes read it instead of
+// the wall clock so that a given frame can be reproduced exactly.
+type Clock interface {
+	// Time returns the scene time of the current frame.
+	Time() time.Duration
+
+	// Delta returns the scene time that passed since the previous frame.
+	Delta() time.Duration
+
+	// FrameTime returns how long the previous frame took in real time,
+	// whether or not the scene is paused or slowed down. Cameras move by
+	// it, so the view can be moved around a paused scene.
+	FrameTime() time.Duration
+
+	// Advance moves the clock on to the next frame.
+	Advance()
+}
+
+// ClockStarter is implemented by clocks that follow the wall clock. Start
+// is called right before the first frame of a window, so the time spent
+// creating it and initializing the scene doesn't count as a frame.
+type ClockStarter interface {
+	Start()
+}
+
+// ClockControls are the hotkey actions available on every clock.
+type ClockControls interface {
+	TogglePause()
+	ToggleSlowMotion()
+	StepFrame()
+}
+
+const (
+	// defaultFrameStep is the frame duration used by fixed-step clocks and
+	// for single-frame steps while paused.
+	defaultFrameStep = time.Second / 60
+
+	slowMotionScale = 0.25
+
+	// simulationStep is the step Simulation scenes are updated in. It is
+	// a frame of the fixed-step clocks, so every frame they draw is one
+	// update further on.
+	simulationStep = defaultFrameStep
+)
+
+// timeControl implements pausing, slow motion and single-frame steps on
+// top of the time a clock would otherwise advance by.
+type timeControl struct {
+	paused   bool
+	slow     bool
+	stepping bool
+}
+
+func (c *timeControl) TogglePause() {
+	c.paused = !c.paused
+}
+
+func (c *timeControl) ToggleSlowMotion() {
+	c.slow = !c.slow
+}
+
+// StepFrame advances a paused clock by a single frame.
+func (c *timeControl) StepFrame() {
+	c.stepping = true
+}
+
+// apply returns how far the scene time moves when d passed in real time
+// and a frame lasts frame.
+func (c *timeControl) apply(d, frame time.Duration) time.Duration {
+	if c.paused {
+		if !c.stepping {
+			return 0
+		}
+		c.stepping = false
+		d = frame
+	}
+	if c.slow {
+		d = time.Duration(float64(d) * slowMotionScale)
+	}
+	return d
+}
+
+// RealClock follows the wall clock, so animation speed does not depend on
+// the frame rate, but two runs never render the same frames.
+type RealClock struct {
+	timeControl
+	last  time.Time
+	now   time.Duration
+	delta time.Duration
+	frame time.Duration
+}
+
+func NewRealClock() *RealClock {
+	return &RealClock{}
+}
+
+// Start measures the next frame from now.
+func (c *RealClock) Start() {
+	c.last = time.Now()
+}
+
+func (c *RealClock) Time() time.Duration      { return c.now }
+func (c *RealClock) Delta() time.Duration     { return c.delta }
+func (c *RealClock) FrameTime() time.Duration { return c.frame }
+
+// Advance moves the clock on by the time since the last frame. A clock
+// that wasn't started starts now, with no time passed.
+func (c *RealClock) Advance() {
+	current := time.Now()
+	if c.last.IsZero() {
+		c.last = current
+	}
+	c.frame = current.Sub(c.last)
+	c.delta = c.apply(c.frame, defaultFrameStep)
+	c.now += c.delta
+	c.last = current
+}
+
+// FixedClock advances by the same step every frame, so frame n is always
+// rendered at n times the step. Every frame takes a step of real time too,
+// so replayed camera moves come out the same.
+type FixedClock struct {
+	timeControl
+	step  time.Duration
+	now   time.Duration
+	delta time.Duration
+	frame time.Duration
+}
+
+func NewFixedClock(step time.Duration) *FixedClock {
+	return &FixedClock{step: step}
+}
+
+func (c *FixedClock) Time() time.Duration      { return c.now }
+func (c *FixedClock) Delta() time.Duration     { return c.delta }
+func (c *FixedClock) FrameTime() time.Duration { return c.frame }
+
+func (c *FixedClock) Advance() {
+	c.frame = c.step
+	c.delta = c.apply(c.step, c.step)
+	c.now += c.delta
+}
+
+// handleClockActions pauses, toggles slow motion and steps a single frame
+// while paused, on P, M and the period key by default.
+func handleClockActions(clock Clock, actions *input.Actions) {
+	controls, ok := clock.(ClockControls)
+	if !ok {
+		return
+	}
+
+	if actions.Pressed("pause") {
+		controls.TogglePause()
+	}
+	if actions.Pressed("slow-motion") {
+		controls.ToggleSlowMotion()
+	}
+	if actions.Pressed("step") {
+		controls.StepFrame()
+	}
+}
+
+// simTime is the state of simulations that are a function of time, like
+// a quad spinning at a constant rate. Scenes embed it to count the time
+// in Updates and read it interpolated while rendering.
+type simTime struct {
+	now, last time.Duration
+}
+
+func (t *simTime) Update(dt time.Duration) {
+	t.last = t.now
+	t.now += dt
+}
+
+// seconds returns the time alpha of the way from the last update to the
+// current one, in seconds.
+func (t *simTime) seconds(alpha float32) float64 {
+	return (t.last + time.Duration(float64(alpha)*float64(t.now-t.last))).Seconds()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	glfw "github.com/go-gl/glfw3"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+func TestFixedClock(t *testing.T) {
+	const step = 10 * time.Millisecond
+	c := NewFixedClock(step)
+
+	// each line is done to the clock before advancing it
+	tests := []struct {
+		do        func()
+		now       time.Duration
+		delta     time.Duration
+		operation string
+	}{
+		{nil, 10 * time.Millisecond, step, "run"},
+		{nil, 20 * time.Millisecond, step, "run"},
+		{c.TogglePause, 20 * time.Millisecond, 0, "pause"},
+		{nil, 20 * time.Millisecond, 0, "stay paused"},
+		{c.StepFrame, 30 * time.Millisecond, step, "step"},
+		{nil, 30 * time.Millisecond, 0, "stay paused after a step"},
+		{c.ToggleSlowMotion, 30 * time.Millisecond, 0, "slow motion while paused"},
+		{c.StepFrame, 32500 * time.Microsecond, step / 4, "step in slow motion"},
+		{c.TogglePause, 35 * time.Millisecond, step / 4, "resume in slow motion"},
+		{c.ToggleSlowMotion, 45 * time.Millisecond, step, "full speed"},
+	}
+	for _, test := range tests {
+		if test.do != nil {
+			test.do()
+		}
+		c.Advance()
+		if c.Time() != test.now || c.Delta() != test.delta {
+			t.Errorf("%s: time %v, delta %v, want %v, %v", test.operation, c.Time(), c.Delta(), test.now, test.delta)
+		}
+		if c.FrameTime() != step {
+			t.Errorf("%s: frame time %v, want %v", test.operation, c.FrameTime(), step)
+		}
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	// a clock counts from the first frame, not from when it was made
+	c := NewRealClock()
+	time.Sleep(20 * time.Millisecond)
+	c.Advance()
+	if c.Time() != 0 || c.Delta() != 0 {
+		t.Errorf("first frame at %v, delta %v, want 0", c.Time(), c.Delta())
+	}
+
+	c.Start()
+	time.Sleep(5 * time.Millisecond)
+	c.Advance()
+	if c.Delta() < 5*time.Millisecond || c.Time() != c.Delta() {
+		t.Errorf("after 5ms: time %v, delta %v", c.Time(), c.Delta())
+	}
+
+	// a paused clock stands still, but steps a frame at a time
+	before := c.Time()
+	c.TogglePause()
+	time.Sleep(time.Millisecond)
+	c.Advance()
+	if c.Time() != before || c.Delta() != 0 {
+		t.Errorf("paused: time %v, delta %v, want %v, 0", c.Time(), c.Delta(), before)
+	}
+	if c.FrameTime() < time.Millisecond {
+		t.Errorf("paused: frame time %v, want at least 1ms", c.FrameTime())
+	}
+	c.StepFrame()
+	c.Advance()
+	if c.Delta() != defaultFrameStep {
+		t.Errorf("stepped by %v, want %v", c.Delta(), defaultFrameStep)
+	}
+}
+
+func TestHandleClockActions(t *testing.T) {
+	actions, err := loadActions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	press := func(k glfw.Key) {
+		actions.Handle(input.Event{Kind: input.KeyEvent, Code: int(k), Action: glfw.Press})
+		actions.Handle(input.Event{Kind: input.KeyEvent, Code: int(k), Action: glfw.Release})
+	}
+
+	c := NewFixedClock(defaultFrameStep)
+	press(glfw.KeyP)
+	press(glfw.KeyM)
+	handleClockActions(c, actions)
+	actions.EndFrame()
+	if !c.paused || !c.slow {
+		t.Errorf("paused %v, slow motion %v after P and M", c.paused, c.slow)
+	}
+
+	press(glfw.KeyPeriod)
+	handleClockActions(c, actions)
+	c.Advance()
+	if c.Delta() != defaultFrameStep/4 {
+		t.Errorf("step in slow motion took %v", c.Delta())
+	}
+}
+
+func TestCameraMovesWhilePaused(t *testing.T) {
+	actions, err := loadActions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fly := camera.NewFly(glm.Vec3{0, 0, 5}, glm.Vec3{})
+	sceneCamera = fly
+	defer func() { sceneCamera = nil }()
+
+	c := NewFixedClock(defaultFrameStep)
+	c.TogglePause()
+	c.Advance()
+	actions.Handle(input.Event{Kind: input.KeyEvent, Code: int(glfw.KeyW), Action: glfw.Press})
+	before := fly.View()
+	updateScene(new(texture1), c, actions)
+	if fly.View() == before {
+		t.Errorf("the fly camera didn't move while the scene was paused")
+	}
+}
+
+func TestFixedStepNeedsSimulation(t *testing.T) {
+	// texture-1 draws the same frame however the clock moves
+	err := runExercise([]string{"--fixed-step=10ms", "texture-1"}, nil)
+	if want := "texture-1 is not animated, --fixed-step has no effect on it"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
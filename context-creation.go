@@ -3,68 +3,30 @@ package main
 import (
 	"fmt"
 	"github.com/go-gl/gl"
-	glfw "github.com/go-gl/glfw3"
 )
 
-func errorCallback(err glfw.ErrorCode, desc string) {
-	fmt.Printf("%v: %v\n", err, desc)
+func init() {
+	registerScene("context-creation", func() Scene { return new(contextCreation) })
 }
 
-func handleKey(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
-		return
-	}
-
-	if k == glfw.KeyEscape {
-		window.SetShouldClose(true)
-	}
+type contextCreation struct {
+	buffers []gl.Buffer
 }
 
-func main() {
-	var (
-		err     error
-		monitor *glfw.Monitor
-		window  *glfw.Window
-		buffers []gl.Buffer
-	)
-
-	glfw.SetErrorCallback(errorCallback)
-
-	if !glfw.Init() {
-		panic("Can't init glfw!")
-	}
-	defer glfw.Terminate()
-
-	// set opengl version
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
-	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
-
-	// turn off resizing
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-
-	monitor, err = glfw.GetPrimaryMonitor()
-	if err != nil {
-		panic(err)
-	}
-
-	window, err = glfw.CreateWindow(2560, 1600, "Testing", monitor, nil)
-	if err != nil {
-		panic(err)
-	}
+func (s *contextCreation) WindowConfig() WindowConfig {
+	return WindowConfig{Fullscreen: true}
+}
 
-	window.MakeContextCurrent()
-	window.SetKeyCallback(handleKey)
+func (s *contextCreation) Init() {
+	s.buffers = make([]gl.Buffer, 1)
+	gl.GenBuffers(s.buffers)
+	fmt.Println(s.buffers)
+}
 
-	gl.Init()
-	buffers = make([]gl.Buffer, 1)
-	gl.GenBuffers(buffers)
-	fmt.Println(buffers)
+func (s *contextCreation) Draw(clock Clock) {
+	//Do OpenGL stuff
+}
 
-	for !window.ShouldClose() {
-		//Do OpenGL stuff
-		window.SwapBuffers()
-		glfw.PollEvents()
-	}
+func (s *contextCreation) Delete() {
+	gl.DeleteBuffers(s.buffers)
 }
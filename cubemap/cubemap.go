@@ -0,0 +1,132 @@
+// Package cubemap converts between cube map faces and directions, and
+// resamples equirectangular panoramas into the six faces of a cube map on
+// the CPU.
+//
+// Faces follow the OpenGL conventions: they are numbered +X, -X, +Y, -Y,
+// +Z, -Z and the first row of each face image is the row OpenGL expects
+// first, so faces are uploaded without flipping. Y is up and the center of
+// a panorama looks down -Z.
+package cubemap
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Face identifies one side of a cube map.
+type Face int
+
+const (
+	PositiveX Face = iota
+	NegativeX
+	PositiveY
+	NegativeY
+	PositiveZ
+	NegativeZ
+)
+
+var faceNames = [...]string{"+X", "-X", "+Y", "-Y", "+Z", "-Z"}
+
+func (f Face) String() string {
+	if f < 0 || int(f) >= len(faceNames) {
+		return "invalid face"
+	}
+	return faceNames[f]
+}
+
+// Direction returns the direction from the center of the cube through the
+// point (s, t) of face f, both in [0, 1] with t = 0 on the first row. The
+// result is not normalized.
+func Direction(f Face, s, t float64) (x, y, z float64) {
+	sc, tc := 2*s-1, 2*t-1
+	switch f {
+	case PositiveX:
+		return 1, -tc, -sc
+	case NegativeX:
+		return -1, -tc, sc
+	case PositiveY:
+		return sc, 1, tc
+	case NegativeY:
+		return sc, -1, -tc
+	case PositiveZ:
+		return sc, -tc, 1
+	default:
+		return -sc, -tc, -1
+	}
+}
+
+// FromEquirectangular resamples a panorama covering 360 degrees
+// horizontally and 180 degrees vertically into six size x size faces,
+// filtering bilinearly. A size of 0 picks a quarter of the panorama width.
+func FromEquirectangular(pano image.Image, size int) [6]*image.NRGBA {
+	b := pano.Bounds()
+	src, ok := pano.(*image.NRGBA)
+	if !ok || b.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Rect, pano, b.Min, draw.Src)
+	}
+	if size <= 0 {
+		size = b.Dx() / 4
+		if size < 1 {
+			size = 1
+		}
+	}
+
+	var faces [6]*image.NRGBA
+	for f := range faces {
+		face := image.NewNRGBA(image.Rect(0, 0, size, size))
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				s := (float64(x) + 0.5) / float64(size)
+				t := (float64(y) + 0.5) / float64(size)
+				dx, dy, dz := Direction(Face(f), s, t)
+				u, v := equirectangular(dx, dy, dz)
+				face.SetNRGBA(x, y, sample(src, u, v))
+			}
+		}
+		faces[f] = face
+	}
+	return faces
+}
+
+// equirectangular maps a direction to panorama coordinates in [0, 1], v
+// counting from the top row.
+func equirectangular(x, y, z float64) (u, v float64) {
+	lon := math.Atan2(x, -z)
+	lat := math.Atan2(y, math.Hypot(x, z))
+	return lon/(2*math.Pi) + 0.5, 0.5 - lat/math.Pi
+}
+
+// sample filters img bilinearly at (u, v), wrapping horizontally and
+// clamping vertically.
+func sample(img *image.NRGBA, u, v float64) color.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	fx := u*float64(w) - 0.5
+	fy := v*float64(h) - 0.5
+	x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+	ax, ay := fx-float64(x0), fy-float64(y0)
+
+	texel := func(x, y int) [4]float64 {
+		x = ((x % w) + w) % w
+		if y < 0 {
+			y = 0
+		} else if y >= h {
+			y = h - 1
+		}
+		i := img.PixOffset(x, y)
+		p := img.Pix[i : i+4]
+		return [4]float64{float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])}
+	}
+	c00, c10 := texel(x0, y0), texel(x0+1, y0)
+	c01, c11 := texel(x0, y0+1), texel(x0+1, y0+1)
+
+	var out [4]uint8
+	for i := range out {
+		top := c00[i] + (c10[i]-c00[i])*ax
+		bottom := c01[i] + (c11[i]-c01[i])*ax
+		out[i] = uint8(math.Round(top + (bottom-top)*ay))
+	}
+	return color.NRGBA{out[0], out[1], out[2], out[3]}
+}
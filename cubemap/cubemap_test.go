@@ -0,0 +1,102 @@
+package cubemap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDirection(t *testing.T) {
+	centers := [6][3]float64{
+		{1, 0, 0}, {-1, 0, 0},
+		{0, 1, 0}, {0, -1, 0},
+		{0, 0, 1}, {0, 0, -1},
+	}
+	for f, want := range centers {
+		x, y, z := Direction(Face(f), 0.5, 0.5)
+		if [3]float64{x, y, z} != want {
+			t.Errorf("%v center = (%g, %g, %g), want %v", Face(f), x, y, z, want)
+		}
+	}
+
+	// the first texel of +X lies towards +Y and +Z, as in the OpenGL
+	// specification's face selection table
+	if x, y, z := Direction(PositiveX, 0, 0); x != 1 || y != 1 || z != 1 {
+		t.Errorf("+X corner = (%g, %g, %g), want (1, 1, 1)", x, y, z)
+	}
+	if x, y, z := Direction(PositiveY, 0, 0); x != -1 || y != 1 || z != -1 {
+		t.Errorf("+Y corner = (%g, %g, %g), want (-1, 1, -1)", x, y, z)
+	}
+}
+
+var faceColors = [6]color.NRGBA{
+	{0xFF, 0x00, 0x00, 0xFF},
+	{0x00, 0xFF, 0x00, 0xFF},
+	{0x00, 0x00, 0xFF, 0xFF},
+	{0xFF, 0xFF, 0x00, 0xFF},
+	{0x00, 0xFF, 0xFF, 0xFF},
+	{0xFF, 0x00, 0xFF, 0xFF},
+}
+
+// dominantFace returns the face a direction points at.
+func dominantFace(x, y, z float64) Face {
+	ax, ay, az := math.Abs(x), math.Abs(y), math.Abs(z)
+	switch {
+	case ax >= ay && ax >= az:
+		if x > 0 {
+			return PositiveX
+		}
+		return NegativeX
+	case ay >= az:
+		if y > 0 {
+			return PositiveY
+		}
+		return NegativeY
+	case z > 0:
+		return PositiveZ
+	}
+	return NegativeZ
+}
+
+func TestFromEquirectangular(t *testing.T) {
+	// color every panorama texel by the face its direction falls on
+	const w, h = 256, 128
+	pano := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			lon := ((float64(x)+0.5)/w - 0.5) * 2 * math.Pi
+			lat := (0.5 - (float64(y)+0.5)/h) * math.Pi
+			dx := math.Cos(lat) * math.Sin(lon)
+			dy := math.Sin(lat)
+			dz := -math.Cos(lat) * math.Cos(lon)
+			pano.SetNRGBA(x, y, faceColors[dominantFace(dx, dy, dz)])
+		}
+	}
+
+	faces := FromEquirectangular(pano, 0)
+	for f, face := range faces {
+		if face.Rect.Dx() != w/4 || face.Rect.Dy() != w/4 {
+			t.Fatalf("%v: size = %v, want %dx%d", Face(f), face.Rect.Size(), w/4, w/4)
+		}
+		// stay clear of the edges, where filtering blends neighbors
+		for _, p := range []image.Point{{32, 32}, {8, 8}, {55, 8}, {8, 55}, {55, 55}} {
+			if got := face.NRGBAAt(p.X, p.Y); got != faceColors[f] {
+				t.Errorf("%v at %v = %v, want %v", Face(f), p, got, faceColors[f])
+			}
+		}
+	}
+}
+
+func TestPanoramaCenterLooksDownNegativeZ(t *testing.T) {
+	pano := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	pano.SetNRGBA(31, 15, faceColors[0])
+	pano.SetNRGBA(32, 15, faceColors[0])
+	pano.SetNRGBA(31, 16, faceColors[0])
+	pano.SetNRGBA(32, 16, faceColors[0])
+
+	face := FromEquirectangular(pano, 16)[NegativeZ]
+	if got := face.NRGBAAt(8, 8); got.R == 0 {
+		t.Errorf("center of -Z = %v, want the panorama center", got)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/cubemap"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// cubemapSampler clamps so the seams between faces don't pick up texels
+// from the opposite edge.
+var cubemapSampler = SamplerOptions{
+	WrapS:     gl.CLAMP_TO_EDGE,
+	WrapT:     gl.CLAMP_TO_EDGE,
+	WrapR:     gl.CLAMP_TO_EDGE,
+	MinFilter: gl.LINEAR,
+	MagFilter: gl.LINEAR,
+}
+
+// cubemapFaceNames are the file name sets a directory of faces may use, in
+// the order +X, -X, +Y, -Y, +Z, -Z.
+var cubemapFaceNames = [][6]string{
+	{"posx", "negx", "posy", "negy", "posz", "negz"},
+	{"px", "nx", "py", "ny", "pz", "nz"},
+	{"right", "left", "top", "bottom", "front", "back"},
+}
+
+// createCubemap uploads six square faces, in the order +X, -X, +Y, -Y, +Z,
+// -Z, to a new cube map texture bound to the active texture unit.
+func createCubemap(faces [6]image.Image, opts SamplerOptions) (gl.Texture, error) {
+	size := faces[0].Bounds().Size()
+	if size.X == 0 || size.X != size.Y {
+		return gl.Texture(0), fmt.Errorf("cube map faces must be square, %v is %v", cubemap.Face(0), size)
+	}
+	for i, face := range faces {
+		if s := face.Bounds().Size(); s != size {
+			return gl.Texture(0), fmt.Errorf("cube map face %v is %v, the others are %v", cubemap.Face(i), s, size)
+		}
+	}
+
+	// all faces must share an internal format for the cube map to be complete
+	images := make([]*texImage, len(faces))
+	for i, face := range faces {
+		images[i] = newTexImage(face)
+		images[i].topFirst()
+		if images[i].internalFormat != images[0].internalFormat {
+			return gl.Texture(0), fmt.Errorf("cube map face %v has a different pixel format than %v", cubemap.Face(i), cubemap.Face(0))
+		}
+	}
+
+	textureId := gl.GenTexture()
+	textureId.Bind(gl.TEXTURE_CUBE_MAP)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for i, t := range images {
+		target := gl.TEXTURE_CUBE_MAP_POSITIVE_X + gl.GLenum(i)
+		gl.TexImage2D(target, 0, t.internalFormat, t.width, t.height, 0, t.format, t.typ, t.pixels)
+	}
+	checkError("cube map")
+
+	applySampler(gl.TEXTURE_CUBE_MAP, opts)
+	return textureId, nil
+}
+
+// loadCubemap loads a cube map from path, which is either a directory of
+// six face images named like one of cubemapFaceNames or a single
+// equirectangular panorama that is resampled on the CPU.
+func loadCubemap(path string, opts SamplerOptions) (gl.Texture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return gl.Texture(0), err
+	}
+
+	var faces [6]image.Image
+	if info.IsDir() {
+		faces, err = loadCubemapFaces(path)
+		if err != nil {
+			return gl.Texture(0), err
+		}
+	} else {
+		pano, err := texfmt.Open(path)
+		if err != nil {
+			return gl.Texture(0), err
+		}
+		for i, face := range cubemap.FromEquirectangular(pano, 0) {
+			faces[i] = face
+		}
+	}
+
+	texture, err := createCubemap(faces, opts)
+	if err != nil {
+		return gl.Texture(0), fmt.Errorf("%s: %v", path, err)
+	}
+	return texture, nil
+}
+
+// loadCubemapFaces reads the six faces in dir. The extension of each face
+// doesn't matter as long as texfmt can decode it.
+func loadCubemapFaces(dir string) ([6]image.Image, error) {
+	var faces [6]image.Image
+	for _, names := range cubemapFaceNames {
+		paths, ok := findFaces(dir, names)
+		if !ok {
+			continue
+		}
+		for i, path := range paths {
+			img, err := texfmt.Open(path)
+			if err != nil {
+				return faces, err
+			}
+			faces[i] = img
+		}
+		return faces, nil
+	}
+	return faces, errors.New(dir + ": no cube map faces found, expected posx/negx/..., px/nx/... or right/left/...")
+}
+
+func findFaces(dir string, names [6]string) ([6]string, bool) {
+	var paths [6]string
+	for i, name := range names {
+		matches, err := filepath.Glob(filepath.Join(dir, name+".*"))
+		if err != nil || len(matches) == 0 {
+			return paths, false
+		}
+		paths[i] = matches[0]
+	}
+	return paths, true
+}
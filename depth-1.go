@@ -1,343 +1,201 @@
 package main
 
 import (
-	"errors"
-	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/meshopt"
 	"github.com/go-gl/gl"
-	glfw "github.com/go-gl/glfw3"
 	"github.com/go-gl/glh"
-	"github.com/go-gl/glu"
 	glm "github.com/go-gl/mathgl/mgl32"
-	"image"
-	"image/png"
-	"io"
 	"math"
-	"os"
-	"time"
 )
 
-const vertexSource = `
-#version 150
-
-in vec3 position;
-in vec3 color;
-in vec2 texcoord;
-
-out vec3 Color;
-out vec2 Texcoord;
-
-uniform mat4 model;
-uniform mat4 view;
-uniform mat4 proj;
-
-void main()
-{
-	Texcoord = texcoord;
-	Color = color;
-	gl_Position = proj * view * model * vec4(position, 1.0);
-}
-`
-
-const fragmentSource = `
-#version 150
-
-in vec3 Color;
-in vec2 Texcoord;
-
-out vec4 outColor;
-
-uniform sampler2D texKitten;
-uniform sampler2D texPuppy;
-
-void main()
-{
-	vec4 colKitten = texture(texKitten, Texcoord);
-	vec4 colPuppy = texture(texPuppy, Texcoord);
-	outColor = mix(colKitten, colPuppy, 0.5);
+// cubeVertices is the cube of depth-1 as DrawArrays would draw it: two
+// triangles of three vertices for every face, 36 vertices in all.
+var cubeVertices = []float32{
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
 }
-`
 
-func errorCallback(err glfw.ErrorCode, desc string) {
-	fmt.Printf("%v: %v\n", err, desc)
+func init() {
+	registerScene("depth-1", func() Scene { return new(depth1) })
 }
 
-func handleKey(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
-		return
-	}
-
-	if k == glfw.KeyEscape {
-		window.SetShouldClose(true)
-	}
+type depth1 struct {
+	vbo               gl.Buffer
+	ebo               gl.Buffer
+	textures          []gl.Texture
+	vertices          []float32
+	elements          []uint32
+	program           *shaderProgram
+	texKittenLocation gl.UniformLocation
+	texPuppyLocation  gl.UniformLocation
+	modelLocation     gl.UniformLocation
+	viewLocation      gl.UniformLocation
+	projLocation      gl.UniformLocation
+	vao               gl.VertexArray
+	model             glm.Mat4
+	view              glm.Mat4
+	camera            camera.Controller
+	proj              glm.Mat4
+	sky               *skybox
+	simTime
 }
 
-func checkError(prefix string) {
-	if glError := gl.GetError(); glError != gl.NO_ERROR {
-		errorString, err := glu.ErrorString(glError)
-		if err != nil {
-			fmt.Printf("%s: unspecified error!\n", prefix)
-		} else {
-			fmt.Printf("%s error: %s\n", prefix, errorString)
-		}
-	}
-}
-
-// from github.com/go-gl/example/glfw3/gophercube
-func createTexture(r io.Reader) (gl.Texture, error) {
-	img, err := png.Decode(r)
-	if err != nil {
-		return gl.Texture(0), err
-	}
-
-	rgbaImg, ok := img.(*image.NRGBA)
-	if !ok {
-		return gl.Texture(0), errors.New("texture must be an NRGBA image")
-	}
-
-	textureId := gl.GenTexture()
-	textureId.Bind(gl.TEXTURE_2D)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-
-	// flip image: first pixel is lower left corner
-	imgWidth, imgHeight := img.Bounds().Dx(), img.Bounds().Dy()
-	data := make([]byte, imgWidth*imgHeight*4)
-	lineLen := imgWidth * 4
-	dest := len(data) - lineLen
-	for src := 0; src < len(rgbaImg.Pix); src += rgbaImg.Stride {
-		copy(data[dest:dest+lineLen], rgbaImg.Pix[src:src+rgbaImg.Stride])
-		dest -= lineLen
-	}
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, imgWidth, imgHeight, 0, gl.RGBA, gl.UNSIGNED_BYTE, data)
-
-	return textureId, nil
-}
-
-func main() {
-	var (
-		err               error
-		window            *glfw.Window
-		vbo               gl.Buffer
-		textures          []gl.Texture
-		vertices          []gl.GLfloat
-		vertexShader      gl.Shader
-		fragmentShader    gl.Shader
-		program           gl.Program
-		posAttrib         gl.AttribLocation
-		colAttrib         gl.AttribLocation
-		texAttrib         gl.AttribLocation
-		texKittenLocation gl.UniformLocation
-		texPuppyLocation  gl.UniformLocation
-		modelLocation     gl.UniformLocation
-		viewLocation      gl.UniformLocation
-		projLocation      gl.UniformLocation
-		vao               gl.VertexArray
-		model             glm.Mat4
-		view              glm.Mat4
-		proj              glm.Mat4
-		startTime         time.Time
-		diffTime          time.Duration
-	)
-
-	glfw.SetErrorCallback(errorCallback)
-
-	if !glfw.Init() {
-		panic("Can't init glfw!")
-	}
-	defer glfw.Terminate()
-
-	// set opengl version
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
-	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
-
-	// turn off resizing
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-
-	window, err = glfw.CreateWindow(800, 600, "Testing", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	defer window.Destroy()
-
-	window.MakeContextCurrent()
-	window.SetKeyCallback(handleKey)
+func (s *depth1) Init() {
+	var err error
 
-	gl.Init()
-	gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
 	gl.Enable(gl.DEPTH_TEST)
 
 	// create Vertex Array Object to save shader attributes
-	vao = gl.GenVertexArray()
-	defer vao.Delete()
-	vao.Bind()
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
 	checkError("vertex array object")
 
-	// setup vertex data
-	vertices = []gl.GLfloat{
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-
-		-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-
-		-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-
-		-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-		0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
-		-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
-		-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
-	}
-	vbo = gl.GenBuffer()
-	defer vbo.Delete()
-	vbo.Bind(gl.ARRAY_BUFFER)
-	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(vertices), vertices, gl.STATIC_DRAW)
+	// setup vertex data: the 36 vertices welded into 24 and element data
+	// ordered for the vertex cache
+	s.vertices, s.elements = meshopt.Weld(cubeVertices, 8)
+	s.elements = meshopt.OptimizeCache(s.elements, len(s.vertices)/8, 32)
+	s.vertices = meshopt.OptimizeFetch(s.vertices, 8, s.elements)
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(s.vertices), s.vertices, gl.STATIC_DRAW)
 	checkError("vertex data")
 
+	// setup element data
+	s.ebo = gl.GenBuffer()
+	s.ebo.Bind(gl.ELEMENT_ARRAY_BUFFER)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int(glh.Sizeof(gl.UNSIGNED_INT))*len(s.elements), s.elements, gl.STATIC_DRAW)
+	checkError("element data")
+
 	// setup texture data
-	textures = make([]gl.Texture, 2)
-	sample, err := os.Open("sample.png")
+	s.textures = make([]gl.Texture, 2)
+	gl.ActiveTexture(gl.TEXTURE0)
+	s.textures[0], err = loadTexture("sample.png", MipmapSampler)
 	if err != nil {
 		panic(err)
 	}
-	gl.ActiveTexture(gl.TEXTURE0)
-	textures[0], err = createTexture(sample)
+
+	gl.ActiveTexture(gl.TEXTURE1)
+	s.textures[1], err = loadTexture("sample2.png", MipmapSampler)
 	if err != nil {
 		panic(err)
 	}
-	defer textures[0].Delete()
-	sample.Close()
 
-	sample2, err := os.Open("sample2.png")
+	// create shader program
+	s.program, err = loadProgram("depth-1", nil)
 	if err != nil {
 		panic(err)
 	}
-	gl.ActiveTexture(gl.TEXTURE1)
-	textures[1], err = createTexture(sample2)
+
+	// tell vertex shader how to process vertex data
+	format := NewVertexFormat(Float3("position"), Float3("color"), Float2("texcoord"))
+	err = format.Enable(s.program.Program, s.vertices)
 	if err != nil {
 		panic(err)
 	}
-	defer textures[1].Delete()
-	sample2.Close()
 
-	// compile vertex shader
-	vertexShader = gl.CreateShader(gl.VERTEX_SHADER)
-	vertexShader.Source(vertexSource)
-	vertexShader.Compile()
-	if vertexShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("vertex shader compilation error: %s", vertexShader.GetInfoLog()))
-	}
-	checkError("vertex shader")
+	// look at the scene through a camera the mouse and keyboard move
+	s.camera = newCamera(
+		glm.Vec3{1.2, 1.2, 1.2},
+		glm.Vec3{0.0, 0.0, 0.0},
+		glm.Vec3{0.0, 0.0, 1.0})
 
-	// compile fragment shader
-	fragmentShader = gl.CreateShader(gl.FRAGMENT_SHADER)
-	fragmentShader.Source(fragmentSource)
-	fragmentShader.Compile()
-	if fragmentShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("fragment shader compilation error: %s", fragmentShader.GetInfoLog()))
-	}
-	checkError("fragment shader")
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
 
-	// create shader program
-	program = gl.CreateProgram()
-	program.AttachShader(vertexShader)
-	program.AttachShader(fragmentShader)
-	program.BindFragDataLocation(0, "outColor")
-	program.Link()
-	program.Use()
-	program.Validate()
-	if program.Get(gl.VALIDATE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("program error: %s", program.GetInfoLog()))
+	// draw a sky behind the scene if one was given
+	s.sky, err = loadSky()
+	if err != nil {
+		panic(err)
 	}
-	checkError("program")
-
-	// tell vertex shader how to process vertex data
-	posAttrib = program.GetAttribLocation("position")
-	posAttrib.EnableArray()
-	posAttrib.AttribPointer(3, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), nil)
-	checkError("position attrib pointer")
-
-	// color attribute
-	colAttrib = program.GetAttribLocation("color")
-	colAttrib.EnableArray()
-	colAttrib.AttribPointer(3, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), uintptr(3*int(glh.Sizeof(gl.FLOAT))))
-	checkError("color attrib pointer")
-
-	// texcoord attribute
-	texAttrib = program.GetAttribLocation("texcoord")
-	texAttrib.EnableArray()
-	texAttrib.AttribPointer(2, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), uintptr(6*int(glh.Sizeof(gl.FLOAT))))
-	checkError("texcoord attrib pointer")
+}
 
+// bindUniforms looks up the uniform locations and sets the uniforms that
+// stay the same from frame to frame.
+func (s *depth1) bindUniforms() {
 	// setup texture uniforms
-	texKittenLocation = program.GetUniformLocation("texKitten")
-	texKittenLocation.Uniform1i(0)
-	texPuppyLocation = program.GetUniformLocation("texPuppy")
-	texPuppyLocation.Uniform1i(1)
+	s.texKittenLocation = s.program.GetUniformLocation("texKitten")
+	s.texKittenLocation.Uniform1i(0)
+	s.texPuppyLocation = s.program.GetUniformLocation("texPuppy")
+	s.texPuppyLocation.Uniform1i(1)
 
 	// setup matrices
-	modelLocation = program.GetUniformLocation("model")
+	s.modelLocation = s.program.GetUniformLocation("model")
 
-	viewLocation = program.GetUniformLocation("view")
-	view = glm.LookAtV(
-		glm.Vec3{1.2, 1.2, 1.2},
-		glm.Vec3{0.0, 0.0, 0.0},
-		glm.Vec3{0.0, 0.0, 1.0})
-	viewLocation.UniformMatrix4fv(false, view)
+	s.viewLocation = s.program.GetUniformLocation("view")
 
-	projLocation = program.GetUniformLocation("proj")
-	proj = glm.Perspective(45.0, 800.0/600.0, 1.0, 10.0)
-	projLocation.UniformMatrix4fv(false, proj)
+	s.projLocation = s.program.GetUniformLocation("proj")
+}
 
-	startTime = time.Now()
-	for !window.ShouldClose() {
-		glfw.PollEvents()
+func (s *depth1) Render(alpha float32) {
+	// rotate
+	s.model = glm.HomogRotate3DZ(math.Pi * float32(s.seconds(alpha)))
+	s.modelLocation.UniformMatrix4fv(false, s.model)
 
-		// rotate
-		diffTime = time.Since(startTime)
-		model = glm.HomogRotate3DZ(math.Pi * float32(diffTime.Seconds()))
-		modelLocation.UniformMatrix4fv(false, model)
+	// move the camera and fit the projection to the window
+	s.view = s.camera.View()
+	s.viewLocation.UniformMatrix4fv(false, s.view)
+	s.proj = perspective(45.0, 1.0, 10.0)
+	s.projLocation.UniformMatrix4fv(false, s.proj)
 
-		// clear the screen to black
-		width, height := window.GetFramebufferSize()
-		gl.Viewport(0, 0, width, height)
-		gl.ClearColor(0.0, 0.0, 0.0, 1.0)
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	if s.sky != nil {
+		s.sky.Draw(s.view, s.proj)
+	}
 
-		// draw triangles
-		gl.DrawArrays(gl.TRIANGLES, 0, 36)
+	// draw triangles
+	gl.DrawElements(gl.TRIANGLES, len(s.elements), gl.UNSIGNED_INT, nil)
+}
 
-		checkError("main loop")
-		window.SwapBuffers()
+func (s *depth1) Delete() {
+	if s.sky != nil {
+		s.sky.Delete()
 	}
+	s.program.Delete()
+	gl.DeleteTextures(s.textures)
+	s.ebo.Delete()
+	s.vbo.Delete()
+	s.vao.Delete()
 }
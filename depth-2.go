@@ -1,183 +1,60 @@
 package main
 
 import (
-	"errors"
-	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/camera"
 	"github.com/go-gl/gl"
-	glfw "github.com/go-gl/glfw3"
 	"github.com/go-gl/glh"
-	"github.com/go-gl/glu"
 	glm "github.com/go-gl/mathgl/mgl32"
-	"image"
-	"image/png"
-	"io"
 	"math"
-	"os"
-	"time"
 )
 
-const vertexSource = `
-#version 150
-
-in vec3 position;
-in vec3 color;
-in vec2 texcoord;
-
-out vec3 Color;
-out vec2 Texcoord;
-
-uniform mat4 model;
-uniform mat4 view;
-uniform mat4 proj;
-uniform vec3 overrideColor;
-
-void main()
-{
-	Texcoord = texcoord;
-	Color = overrideColor * color;
-	gl_Position = proj * view * model * vec4(position, 1.0);
+func init() {
+	registerScene("depth-2", func() Scene { return new(depth2) })
 }
-`
-
-const fragmentSource = `
-#version 150
-
-in vec3 Color;
-in vec2 Texcoord;
-
-out vec4 outColor;
-
-uniform sampler2D texKitten;
-uniform sampler2D texPuppy;
 
-void main()
-{
-	vec4 colKitten = texture(texKitten, Texcoord);
-	vec4 colPuppy = texture(texPuppy, Texcoord);
-	outColor = vec4(Color, 1.0) * mix(colKitten, colPuppy, 0.5);
+// depth2Vertex is the layout of the vertex data: a position, a color and
+// texture coordinates.
+type depth2Vertex struct {
+	Position glm.Vec3 `glsl:"position"`
+	Color    glm.Vec3 `glsl:"color"`
+	Texcoord glm.Vec2 `glsl:"texcoord"`
 }
-`
 
-func errorCallback(err glfw.ErrorCode, desc string) {
-	fmt.Printf("%v: %v\n", err, desc)
+// depth2Uniforms are the uniforms of the depth-2 shaders.
+type depth2Uniforms struct {
+	Model         glm.Mat4 `glsl:"model"`
+	View          glm.Mat4 `glsl:"view"`
+	Proj          glm.Mat4 `glsl:"proj"`
+	OverrideColor glm.Vec3 `glsl:"overrideColor"`
+	TexKitten     int32    `glsl:"texKitten"`
+	TexPuppy      int32    `glsl:"texPuppy"`
 }
 
-func handleKey(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
-		return
-	}
-
-	if k == glfw.KeyEscape {
-		window.SetShouldClose(true)
-	}
+type depth2 struct {
+	vbo      gl.Buffer
+	textures []gl.Texture
+	vertices []gl.GLfloat
+	program  *shaderProgram
+	uniforms depth2Uniforms
+	binding  *uniformBinding
+	camera   camera.Controller
+	vao      gl.VertexArray
+	sky      *skybox
+	simTime
 }
 
-func checkError(prefix string) {
-	if glError := gl.GetError(); glError != gl.NO_ERROR {
-		errorString, err := glu.ErrorString(glError)
-		if err != nil {
-			fmt.Printf("%s: unspecified error!\n", prefix)
-		} else {
-			fmt.Printf("%s error: %s\n", prefix, errorString)
-		}
-	}
-}
-
-// from github.com/go-gl/example/glfw3/gophercube
-func createTexture(r io.Reader) (gl.Texture, error) {
-	img, err := png.Decode(r)
-	if err != nil {
-		return gl.Texture(0), err
-	}
-
-	rgbaImg, ok := img.(*image.NRGBA)
-	if !ok {
-		return gl.Texture(0), errors.New("texture must be an NRGBA image")
-	}
-
-	textureId := gl.GenTexture()
-	textureId.Bind(gl.TEXTURE_2D)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-
-	// flip image: first pixel is lower left corner
-	imgWidth, imgHeight := img.Bounds().Dx(), img.Bounds().Dy()
-	data := make([]byte, imgWidth*imgHeight*4)
-	lineLen := imgWidth * 4
-	dest := len(data) - lineLen
-	for src := 0; src < len(rgbaImg.Pix); src += rgbaImg.Stride {
-		copy(data[dest:dest+lineLen], rgbaImg.Pix[src:src+rgbaImg.Stride])
-		dest -= lineLen
-	}
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, imgWidth, imgHeight, 0, gl.RGBA, gl.UNSIGNED_BYTE, data)
-
-	return textureId, nil
-}
-
-func main() {
-	var (
-		err                   error
-		window                *glfw.Window
-		vbo                   gl.Buffer
-		textures              []gl.Texture
-		vertices              []gl.GLfloat
-		vertexShader          gl.Shader
-		fragmentShader        gl.Shader
-		program               gl.Program
-		posAttrib             gl.AttribLocation
-		colAttrib             gl.AttribLocation
-		texAttrib             gl.AttribLocation
-		texKittenLocation     gl.UniformLocation
-		texPuppyLocation      gl.UniformLocation
-		modelLocation         gl.UniformLocation
-		viewLocation          gl.UniformLocation
-		projLocation          gl.UniformLocation
-		overrideColorLocation gl.UniformLocation
-		vao                   gl.VertexArray
-		model                 glm.Mat4
-		view                  glm.Mat4
-		proj                  glm.Mat4
-		startTime             time.Time
-		diffTime              time.Duration
-	)
-
-	glfw.SetErrorCallback(errorCallback)
-
-	if !glfw.Init() {
-		panic("Can't init glfw!")
-	}
-	defer glfw.Terminate()
-
-	// set opengl version
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
-	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
-
-	// turn off resizing
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-
-	window, err = glfw.CreateWindow(800, 600, "Testing", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	defer window.Destroy()
-
-	window.MakeContextCurrent()
-	window.SetKeyCallback(handleKey)
+func (s *depth2) Init() {
+	var err error
 
-	gl.Init()
-	gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
 	gl.Enable(gl.DEPTH_TEST)
 
 	// create Vertex Array Object to save shader attributes
-	vao = gl.GenVertexArray()
-	defer vao.Delete()
-	vao.Bind()
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
 	checkError("vertex array object")
 
 	// setup vertex data
-	vertices = []gl.GLfloat{
+	s.vertices = []gl.GLfloat{
 		-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
 		0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
 		0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
@@ -227,155 +104,121 @@ func main() {
 		-1.0, 1.0, -0.5, 0.0, 0.0, 0.0, 0.0, 1.0,
 		-1.0, -1.0, -0.5, 0.0, 0.0, 0.0, 0.0, 0.0,
 	}
-	vbo = gl.GenBuffer()
-	defer vbo.Delete()
-	vbo.Bind(gl.ARRAY_BUFFER)
-	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(vertices), vertices, gl.STATIC_DRAW)
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(s.vertices), s.vertices, gl.STATIC_DRAW)
 	checkError("vertex data")
 
 	// setup texture data
-	textures = make([]gl.Texture, 2)
-	sample, err := os.Open("sample.png")
+	s.textures = make([]gl.Texture, 2)
+	gl.ActiveTexture(gl.TEXTURE0)
+	s.textures[0], err = loadTexture("sample.png", MipmapSampler)
 	if err != nil {
 		panic(err)
 	}
-	gl.ActiveTexture(gl.TEXTURE0)
-	textures[0], err = createTexture(sample)
+
+	gl.ActiveTexture(gl.TEXTURE1)
+	s.textures[1], err = loadTexture("sample2.png", MipmapSampler)
 	if err != nil {
 		panic(err)
 	}
-	defer textures[0].Delete()
-	sample.Close()
 
-	sample2, err := os.Open("sample2.png")
+	// create shader program
+	s.program, err = loadProgram("depth-2", nil)
 	if err != nil {
 		panic(err)
 	}
-	gl.ActiveTexture(gl.TEXTURE1)
-	textures[1], err = createTexture(sample2)
+
+	// tell vertex shader how to process vertex data
+	err = bindVertexStruct(s.program.Program, depth2Vertex{})
 	if err != nil {
 		panic(err)
 	}
-	defer textures[1].Delete()
-	sample2.Close()
-
-	// compile vertex shader
-	vertexShader = gl.CreateShader(gl.VERTEX_SHADER)
-	vertexShader.Source(vertexSource)
-	vertexShader.Compile()
-	if vertexShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("vertex shader compilation error: %s", vertexShader.GetInfoLog()))
+
+	// look at the scene through a camera the mouse and keyboard move
+	s.camera = newCamera(
+		glm.Vec3{2.2, 3.2, 2.2},
+		glm.Vec3{0.0, 0.0, 0.0},
+		glm.Vec3{0.0, 0.0, 0.5})
+
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.uniforms = depth2Uniforms{
+		View:          s.camera.View(),
+		OverrideColor: glm.Vec3{1.0, 1.0, 1.0},
+		TexKitten:     0,
+		TexPuppy:      1,
 	}
-	checkError("vertex shader")
-
-	// compile fragment shader
-	fragmentShader = gl.CreateShader(gl.FRAGMENT_SHADER)
-	fragmentShader.Source(fragmentSource)
-	fragmentShader.Compile()
-	if fragmentShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("fragment shader compilation error: %s", fragmentShader.GetInfoLog()))
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+
+	// draw a sky behind the scene if one was given
+	s.sky, err = loadSky()
+	if err != nil {
+		panic(err)
 	}
-	checkError("fragment shader")
+}
 
-	// create shader program
-	program = gl.CreateProgram()
-	program.AttachShader(vertexShader)
-	program.AttachShader(fragmentShader)
-	program.BindFragDataLocation(0, "outColor")
-	program.Link()
-	program.Use()
-	program.Validate()
-	if program.Get(gl.VALIDATE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("program error: %s", program.GetInfoLog()))
+// bindUniforms binds s.uniforms to the uniforms of the program and uploads
+// them.
+func (s *depth2) bindUniforms() {
+	var err error
+	s.binding, err = bindUniformStruct(s.program.Program, &s.uniforms)
+	if err != nil {
+		panic(err)
 	}
-	checkError("program")
+	s.binding.Upload()
+}
 
-	// tell vertex shader how to process vertex data
-	posAttrib = program.GetAttribLocation("position")
-	posAttrib.EnableArray()
-	posAttrib.AttribPointer(3, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), nil)
-	checkError("position attrib pointer")
-
-	// color attribute
-	colAttrib = program.GetAttribLocation("color")
-	colAttrib.EnableArray()
-	colAttrib.AttribPointer(3, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), uintptr(3*int(glh.Sizeof(gl.FLOAT))))
-	checkError("color attrib pointer")
-
-	// texcoord attribute
-	texAttrib = program.GetAttribLocation("texcoord")
-	texAttrib.EnableArray()
-	texAttrib.AttribPointer(2, gl.FLOAT, false, 8*int(glh.Sizeof(gl.FLOAT)), uintptr(6*int(glh.Sizeof(gl.FLOAT))))
-	checkError("texcoord attrib pointer")
-
-	// overrideColor uniform
-	overrideColorLocation = program.GetUniformLocation("overrideColor")
-	overrideColorLocation.Uniform3f(1.0, 1.0, 1.0)
-	checkError("overrideColor uniform pointer")
-
-	// setup texture uniforms
-	texKittenLocation = program.GetUniformLocation("texKitten")
-	texKittenLocation.Uniform1i(0)
-	texPuppyLocation = program.GetUniformLocation("texPuppy")
-	texPuppyLocation.Uniform1i(1)
-
-	// setup matrices
-	modelLocation = program.GetUniformLocation("model")
-
-	viewLocation = program.GetUniformLocation("view")
-	view = glm.LookAtV(
-		glm.Vec3{2.2, 3.2, 2.2},
-		glm.Vec3{0.0, 0.0, 0.0},
-		glm.Vec3{0.0, 0.0, 0.5})
-	viewLocation.UniformMatrix4fv(false, view)
-
-	projLocation = program.GetUniformLocation("proj")
-	proj = glm.Perspective(45.0, 800.0/600.0, 1.0, 10.0)
-	projLocation.UniformMatrix4fv(false, proj)
-
-	startTime = time.Now()
-	for !window.ShouldClose() {
-		glfw.PollEvents()
-
-		// clear the screen to black
-		width, height := window.GetFramebufferSize()
-		gl.Viewport(0, 0, width, height)
-		gl.ClearColor(1.0, 1.0, 1.0, 1.0)
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-
-		// rotate
-		diffTime = time.Since(startTime)
-		model = glm.HomogRotate3DZ(math.Pi * float32(diffTime.Seconds()))
-		modelLocation.UniformMatrix4fv(false, model)
-
-		// draw top box
-		gl.DrawArrays(gl.TRIANGLES, 0, 36)
-
-		// enable stencils
-		gl.Enable(gl.STENCIL_TEST)
-
-		// draw floor
-		gl.StencilFunc(gl.ALWAYS, 1, 0xFF)
-		gl.StencilOp(gl.KEEP, gl.KEEP, gl.REPLACE)
-		gl.StencilMask(0xFF)
-		gl.DepthMask(false)
-		gl.Clear(gl.STENCIL_BUFFER_BIT)
-		gl.DrawArrays(gl.TRIANGLES, 36, 6)
-
-		// draw reflection
-		gl.StencilFunc(gl.EQUAL, 1, 0xFF)
-		gl.StencilMask(0x00)
-		gl.DepthMask(true)
-		model = model.Mul4(glm.Translate3D(0.0, 0.0, -1.0)).Mul4(glm.Scale3D(1.0, 1.0, -1.0))
-		modelLocation.UniformMatrix4fv(false, model)
-		overrideColorLocation.Uniform3f(0.3, 0.3, 0.3)
-		gl.DrawArrays(gl.TRIANGLES, 0, 36)
-		overrideColorLocation.Uniform3f(1.0, 1.0, 1.0)
-
-		// disable stencils
-		gl.Disable(gl.STENCIL_TEST)
-
-		checkError("main loop")
-		window.SwapBuffers()
+func (s *depth2) Render(alpha float32) {
+	// move the camera and fit the projection to the window
+	s.uniforms.View = s.camera.View()
+	s.uniforms.Proj = perspective(45.0, 1.0, 10.0)
+
+	// clear the screen to white
+	gl.ClearColor(1.0, 1.0, 1.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	if s.sky != nil {
+		s.sky.Draw(s.uniforms.View, s.uniforms.Proj)
+	}
+
+	// rotate
+	s.uniforms.Model = glm.HomogRotate3DZ(math.Pi * float32(s.seconds(alpha)))
+	s.binding.Upload()
+
+	// draw top box
+	gl.DrawArrays(gl.TRIANGLES, 0, 36)
+
+	// enable stencils
+	gl.Enable(gl.STENCIL_TEST)
+
+	// draw floor
+	gl.StencilFunc(gl.ALWAYS, 1, 0xFF)
+	gl.StencilOp(gl.KEEP, gl.KEEP, gl.REPLACE)
+	gl.StencilMask(0xFF)
+	gl.DepthMask(false)
+	gl.Clear(gl.STENCIL_BUFFER_BIT)
+	gl.DrawArrays(gl.TRIANGLES, 36, 6)
+
+	// draw reflection
+	gl.StencilFunc(gl.EQUAL, 1, 0xFF)
+	gl.StencilMask(0x00)
+	gl.DepthMask(true)
+	s.uniforms.Model = s.uniforms.Model.Mul4(glm.Translate3D(0.0, 0.0, -1.0)).Mul4(glm.Scale3D(1.0, 1.0, -1.0))
+	s.uniforms.OverrideColor = glm.Vec3{0.3, 0.3, 0.3}
+	s.binding.Upload()
+	gl.DrawArrays(gl.TRIANGLES, 0, 36)
+	s.uniforms.OverrideColor = glm.Vec3{1.0, 1.0, 1.0}
+
+	// disable stencils
+	gl.Disable(gl.STENCIL_TEST)
+}
+
+func (s *depth2) Delete() {
+	if s.sky != nil {
+		s.sky.Delete()
 	}
+	s.program.Delete()
+	gl.DeleteTextures(s.textures)
+	s.vbo.Delete()
+	s.vao.Delete()
 }
@@ -1,167 +1,65 @@
 package main
 
 import (
-	"fmt"
 	"github.com/go-gl/gl"
-	glfw "github.com/go-gl/glfw3"
 	"github.com/go-gl/glh"
-	"github.com/go-gl/glu"
 )
 
-const vertexSource = `
-#version 150
-
-in vec2 position;
-
-void main()
-{
-	gl_Position = vec4(position, 0.0, 1.0);
-}
-`
-
-const fragmentSource = `
-#version 150
-
-out vec4 outColor;
-
-void main()
-{
-	outColor = vec4(1.0, 1.0, 1.0, 1.0);
+func init() {
+	registerScene("drawing-1", func() Scene { return new(drawing1) })
 }
-`
 
-func errorCallback(err glfw.ErrorCode, desc string) {
-	fmt.Printf("%v: %v\n", err, desc)
+type drawing1 struct {
+	vbo      gl.Buffer
+	vertices []float32
+	program  *shaderProgram
+	vao      gl.VertexArray
 }
 
-func handleKey(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
-		return
-	}
-
-	if k == glfw.KeyEscape {
-		window.SetShouldClose(true)
-	}
-}
-
-func checkError(prefix string) {
-	if glError := gl.GetError(); glError != gl.NO_ERROR {
-		errorString, err := glu.ErrorString(glError)
-		if err != nil {
-			fmt.Printf("%s: unspecified error!\n", prefix)
-		} else {
-			fmt.Printf("%s error: %s\n", prefix, errorString)
-		}
-	}
-}
-
-func main() {
-	var (
-		err            error
-		window         *glfw.Window
-		vbo            gl.Buffer
-		vertices       []float32
-		vertexShader   gl.Shader
-		fragmentShader gl.Shader
-		program        gl.Program
-		posAttrib      gl.AttribLocation
-		vao            gl.VertexArray
-	)
-
-	glfw.SetErrorCallback(errorCallback)
-
-	if !glfw.Init() {
-		panic("Can't init glfw!")
-	}
-	defer glfw.Terminate()
-
-	// set opengl version
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
-	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
-
-	// turn off resizing
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-
-	window, err = glfw.CreateWindow(800, 600, "Testing", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	defer window.Destroy()
-
-	window.MakeContextCurrent()
-	window.SetKeyCallback(handleKey)
-
-	gl.Init()
-	gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
+func (s *drawing1) Init() {
+	var err error
 
 	// create Vertex Array Object to save shader attributes
-	vao = gl.GenVertexArray()
-	vao.Bind()
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
 	checkError("vertex array object")
 
 	// setup vertex data
-	vertices = []float32{
+	s.vertices = []float32{
 		0.0, 0.5,
 		0.5, -0.5,
 		-0.5, -0.5,
 	}
-	vbo = gl.GenBuffer()
-	vbo.Bind(gl.ARRAY_BUFFER)
-	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(vertices), vertices, gl.STATIC_DRAW)
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(s.vertices), s.vertices, gl.STATIC_DRAW)
 	checkError("vertex data")
 
-	// compile vertex shader
-	vertexShader = gl.CreateShader(gl.VERTEX_SHADER)
-	vertexShader.Source(vertexSource)
-	vertexShader.Compile()
-	if vertexShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("vertex shader compilation error: %s", vertexShader.GetInfoLog()))
-	}
-	checkError("vertex shader")
-
-	// compile fragment shader
-	fragmentShader = gl.CreateShader(gl.FRAGMENT_SHADER)
-	fragmentShader.Source(fragmentSource)
-	fragmentShader.Compile()
-	if fragmentShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("fragment shader compilation error: %s", fragmentShader.GetInfoLog()))
-	}
-	checkError("fragment shader")
-
 	// create shader program
-	program = gl.CreateProgram()
-	program.AttachShader(vertexShader)
-	program.AttachShader(fragmentShader)
-	program.BindFragDataLocation(0, "outColor")
-	program.Link()
-	program.Use()
-	program.Validate()
-	if program.Get(gl.VALIDATE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("program error: %s", program.GetInfoLog()))
+	s.program, err = loadProgram("drawing-1", nil)
+	if err != nil {
+		panic(err)
 	}
-	checkError("program")
 
 	// tell vertex shader how to process vertex data
-	posAttrib = program.GetAttribLocation("position")
-	posAttrib.EnableArray()
-	posAttrib.AttribPointer(2, gl.FLOAT, false, 0, nil)
-	checkError("attrib pointer")
-
-	for !window.ShouldClose() {
-		glfw.PollEvents()
+	format := NewVertexFormat(Float2("position"))
+	err = format.Enable(s.program.Program, s.vertices)
+	if err != nil {
+		panic(err)
+	}
+}
 
-		// clear the screen to black
-		width, height := window.GetFramebufferSize()
-		gl.Viewport(0, 0, width, height)
-		gl.ClearColor(0.0, 0.0, 0.0, 1.0)
-		gl.Clear(gl.COLOR_BUFFER_BIT)
+func (s *drawing1) Draw(clock Clock) {
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
 
-		// draw triangles
-		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	// draw triangles
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+}
 
-		checkError("main loop")
-		window.SwapBuffers()
-	}
+func (s *drawing1) Delete() {
+	s.program.Delete()
+	s.vbo.Delete()
+	s.vao.Delete()
 }
@@ -1,190 +1,78 @@
 package main
 
 import (
-	"fmt"
 	"github.com/go-gl/gl"
-	glfw "github.com/go-gl/glfw3"
 	"github.com/go-gl/glh"
-	"github.com/go-gl/glu"
 )
 
-const vertexSource = `
-#version 150
-
-in vec2 position;
-in vec3 color;
-
-out vec3 Color;
-
-void main()
-{
-	Color = color;
-	gl_Position = vec4(position, 0.0, 1.0);
-}
-`
-
-const fragmentSource = `
-#version 150
-
-in vec3 Color;
-out vec4 outColor;
-
-void main()
-{
-	outColor = vec4(Color, 1.0);
-}
-`
-
-func errorCallback(err glfw.ErrorCode, desc string) {
-	fmt.Printf("%v: %v\n", err, desc)
-}
-
-func handleKey(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
-	if action != glfw.Press {
-		return
-	}
-
-	if k == glfw.KeyEscape {
-		window.SetShouldClose(true)
-	}
+func init() {
+	registerScene("drawing-5", func() Scene { return new(drawing5) })
 }
 
-func checkError(prefix string) {
-	if glError := gl.GetError(); glError != gl.NO_ERROR {
-		errorString, err := glu.ErrorString(glError)
-		if err != nil {
-			fmt.Printf("%s: unspecified error!\n", prefix)
-		} else {
-			fmt.Printf("%s error: %s\n", prefix, errorString)
-		}
-	}
+type drawing5 struct {
+	vbo, ebo gl.Buffer
+	vertices []gl.GLfloat
+	elements []gl.GLuint
+	program  *shaderProgram
+	vao      gl.VertexArray
 }
 
-func main() {
-	var (
-		err            error
-		window         *glfw.Window
-		vbo, ebo       gl.Buffer
-		vertices       []gl.GLfloat
-		elements       []gl.GLuint
-		vertexShader   gl.Shader
-		fragmentShader gl.Shader
-		program        gl.Program
-		posAttrib      gl.AttribLocation
-		colAttrib      gl.AttribLocation
-		vao            gl.VertexArray
-	)
-
-	glfw.SetErrorCallback(errorCallback)
-
-	if !glfw.Init() {
-		panic("Can't init glfw!")
-	}
-	defer glfw.Terminate()
-
-	// set opengl version
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
-	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
-
-	// turn off resizing
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-
-	window, err = glfw.CreateWindow(800, 600, "Testing", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	defer window.Destroy()
-
-	window.MakeContextCurrent()
-	window.SetKeyCallback(handleKey)
-
-	gl.Init()
-	gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
+func (s *drawing5) Init() {
+	var err error
 
 	// create Vertex Array Object to save shader attributes
-	vao = gl.GenVertexArray()
-	vao.Bind()
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
 	checkError("vertex array object")
 
 	// setup vertex data
-	vertices = []gl.GLfloat{
+	s.vertices = []gl.GLfloat{
 		-0.5, 0.5, 1.0, 0.0, 0.0, // top left
 		0.5, 0.5, 0.0, 1.0, 0.0, // top right
 		0.5, -0.5, 0.0, 0.0, 1.0, // bottom right
 		-0.5, -0.5, 1.0, 1.0, 1.0, // bottom left
 	}
-	vbo = gl.GenBuffer()
-	vbo.Bind(gl.ARRAY_BUFFER)
-	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(vertices), vertices, gl.STATIC_DRAW)
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(s.vertices), s.vertices, gl.STATIC_DRAW)
 	checkError("vertex data")
 
 	// setup element data
-	elements = []gl.GLuint{
+	s.elements = []gl.GLuint{
 		0, 1, 2,
 		2, 3, 0,
 	}
-	ebo = gl.GenBuffer()
-	ebo.Bind(gl.ELEMENT_ARRAY_BUFFER)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int(glh.Sizeof(gl.UNSIGNED_INT))*len(elements), elements, gl.STATIC_DRAW)
+	s.ebo = gl.GenBuffer()
+	s.ebo.Bind(gl.ELEMENT_ARRAY_BUFFER)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int(glh.Sizeof(gl.UNSIGNED_INT))*len(s.elements), s.elements, gl.STATIC_DRAW)
 	checkError("element data")
 
-	// compile vertex shader
-	vertexShader = gl.CreateShader(gl.VERTEX_SHADER)
-	vertexShader.Source(vertexSource)
-	vertexShader.Compile()
-	if vertexShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("vertex shader compilation error: %s", vertexShader.GetInfoLog()))
-	}
-	checkError("vertex shader")
-
-	// compile fragment shader
-	fragmentShader = gl.CreateShader(gl.FRAGMENT_SHADER)
-	fragmentShader.Source(fragmentSource)
-	fragmentShader.Compile()
-	if fragmentShader.Get(gl.COMPILE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("fragment shader compilation error: %s", fragmentShader.GetInfoLog()))
-	}
-	checkError("fragment shader")
-
 	// create shader program
-	program = gl.CreateProgram()
-	program.AttachShader(vertexShader)
-	program.AttachShader(fragmentShader)
-	program.BindFragDataLocation(0, "outColor")
-	program.Link()
-	program.Use()
-	program.Validate()
-	if program.Get(gl.VALIDATE_STATUS) != gl.TRUE {
-		panic(fmt.Errorf("program error: %s", program.GetInfoLog()))
+	s.program, err = loadProgram("drawing-5", nil)
+	if err != nil {
+		panic(err)
 	}
-	checkError("program")
 
 	// tell vertex shader how to process vertex data
-	posAttrib = program.GetAttribLocation("position")
-	posAttrib.EnableArray()
-	posAttrib.AttribPointer(2, gl.FLOAT, false, 5*int(glh.Sizeof(gl.FLOAT)), nil)
-	checkError("position attrib pointer")
-
-	colAttrib = program.GetAttribLocation("color")
-	colAttrib.EnableArray()
-	colAttrib.AttribPointer(3, gl.FLOAT, false, 5*int(glh.Sizeof(gl.FLOAT)), uintptr(2*int(glh.Sizeof(gl.FLOAT))))
-	checkError("color attrib pointer")
-
-	for !window.ShouldClose() {
-		glfw.PollEvents()
+	format := NewVertexFormat(Float2("position"), Float3("color"))
+	err = format.Enable(s.program.Program, s.vertices)
+	if err != nil {
+		panic(err)
+	}
+}
 
-		// clear the screen to black
-		width, height := window.GetFramebufferSize()
-		gl.Viewport(0, 0, width, height)
-		gl.ClearColor(0.0, 0.0, 0.0, 1.0)
-		gl.Clear(gl.COLOR_BUFFER_BIT)
+func (s *drawing5) Draw(clock Clock) {
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
 
-		// draw triangles
-		gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, nil)
+	// draw triangles
+	gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, nil)
+}
 
-		checkError("main loop")
-		window.SwapBuffers()
-	}
+func (s *drawing5) Delete() {
+	s.program.Delete()
+	s.ebo.Delete()
+	s.vbo.Delete()
+	s.vao.Delete()
 }
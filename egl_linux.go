@@ -0,0 +1,101 @@
+package main
+
+// #cgo LDFLAGS: -lEGL
+// #include <EGL/egl.h>
+// #include <EGL/eglext.h>
+// #include <string.h>
+//
+// // surfacelessDisplay returns Mesa's surfaceless platform if the driver
+// // has it, so no X server or Wayland compositor is opened, and the default
+// // display otherwise, which EGL drivers without a window system pick a
+// // device for.
+// static EGLDisplay surfacelessDisplay(void) {
+// 	const char *exts = eglQueryString(EGL_NO_DISPLAY, EGL_EXTENSIONS);
+// 	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+// 		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+// 	if (exts != NULL && strstr(exts, "EGL_MESA_platform_surfaceless") != NULL && getPlatformDisplay != NULL)
+// 		return getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+// 	return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+// }
+//
+// // createContext creates the same OpenGL 3.2 core, forward compatible
+// // context createWindow asks GLFW for, with a config that needn't support
+// // any kind of surface.
+// static EGLContext createContext(EGLDisplay display) {
+// 	static const EGLint configAttribs[] = {
+// 		EGL_SURFACE_TYPE, 0,
+// 		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+// 		EGL_NONE,
+// 	};
+// 	static const EGLint contextAttribs[] = {
+// 		EGL_CONTEXT_MAJOR_VERSION_KHR, 3,
+// 		EGL_CONTEXT_MINOR_VERSION_KHR, 2,
+// 		EGL_CONTEXT_OPENGL_PROFILE_MASK_KHR, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT_KHR,
+// 		EGL_CONTEXT_FLAGS_KHR, EGL_CONTEXT_OPENGL_FORWARD_COMPATIBLE_BIT_KHR,
+// 		EGL_NONE,
+// 	};
+// 	EGLConfig config;
+// 	EGLint n;
+// 	if (!eglChooseConfig(display, configAttribs, &config, 1, &n) || n == 0)
+// 		return EGL_NO_CONTEXT;
+// 	return eglCreateContext(display, config, EGL_NO_CONTEXT, contextAttribs);
+// }
+import "C"
+
+import (
+	"fmt"
+)
+
+// surfacelessContext is an OpenGL context made current without a window or
+// any other surface, which the scene draws into an offscreen target with.
+// It is created through EGL, so it doesn't need an X server: rendering
+// works on CI machines without a display, and with Mesa's llvmpipe, without
+// a GPU either.
+type surfacelessContext struct {
+	display C.EGLDisplay
+	context C.EGLContext
+}
+
+// eglError returns an error for the EGL call that failed last. It must be
+// called before any other EGL call resets the error.
+func eglError(call string) error {
+	return fmt.Errorf("%s failed: EGL error 0x%x", call, int(C.eglGetError()))
+}
+
+// newSurfacelessContext creates a context and makes it current on the
+// calling thread.
+func newSurfacelessContext() (*surfacelessContext, error) {
+	c := &surfacelessContext{display: C.surfacelessDisplay()}
+	if c.display == 0 {
+		return nil, eglError("eglGetDisplay")
+	}
+	if C.eglInitialize(c.display, nil, nil) == C.EGL_FALSE {
+		return nil, eglError("eglInitialize")
+	}
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		err := eglError("eglBindAPI")
+		C.eglTerminate(c.display)
+		return nil, err
+	}
+
+	c.context = C.createContext(c.display)
+	if c.context == nil {
+		err := eglError("eglCreateContext")
+		C.eglTerminate(c.display)
+		return nil, err
+	}
+	// drawing without a surface needs EGL_KHR_surfaceless_context
+	if C.eglMakeCurrent(c.display, nil, nil, c.context) == C.EGL_FALSE {
+		err := eglError("eglMakeCurrent")
+		c.Destroy()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *surfacelessContext) Destroy() {
+	C.eglMakeCurrent(c.display, nil, nil, nil)
+	C.eglDestroyContext(c.display, c.context)
+	C.eglTerminate(c.display)
+}
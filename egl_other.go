@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+// surfacelessContext is an OpenGL context without a window, which only
+// the EGL drivers on Linux provide.
+type surfacelessContext struct{}
+
+func newSurfacelessContext() (*surfacelessContext, error) {
+	return nil, errors.New("rendering without a window needs EGL, which is only supported on Linux")
+}
+
+func (c *surfacelessContext) Destroy() {}
@@ -0,0 +1,240 @@
+package glsl
+
+// The syntax tree of a shader. Types are kept as written; the checker
+// resolves them.
+
+type expr interface {
+	pos() Pos
+}
+
+type (
+	identExpr struct {
+		at   Pos
+		name string
+	}
+
+	literalExpr struct {
+		at   Pos
+		kind tokenKind // tokInt, tokUint, tokFloat or tokIdent for true and false
+		text string
+	}
+
+	unaryExpr struct {
+		at      Pos
+		op      string
+		x       expr
+		postfix bool
+	}
+
+	binaryExpr struct {
+		at   Pos
+		op   string
+		x, y expr
+	}
+
+	assignExpr struct {
+		at       Pos
+		op       string
+		lhs, rhs expr
+	}
+
+	condExpr struct {
+		at              Pos
+		cond, then, els expr
+	}
+
+	// callExpr is a function call, or a constructor if typ is set.
+	callExpr struct {
+		at   Pos
+		name string
+		typ  *typeSpec
+		args []expr
+	}
+
+	// lengthExpr is x.length().
+	lengthExpr struct {
+		at Pos
+		x  expr
+	}
+
+	indexExpr struct {
+		at       Pos
+		x, index expr
+	}
+
+	fieldExpr struct {
+		at   Pos
+		x    expr
+		name string
+	}
+
+	seqExpr struct {
+		at   Pos
+		list []expr
+	}
+)
+
+func (e *identExpr) pos() Pos   { return e.at }
+func (e *literalExpr) pos() Pos { return e.at }
+func (e *unaryExpr) pos() Pos   { return e.at }
+func (e *binaryExpr) pos() Pos  { return e.at }
+func (e *assignExpr) pos() Pos  { return e.at }
+func (e *condExpr) pos() Pos    { return e.at }
+func (e *callExpr) pos() Pos    { return e.at }
+func (e *lengthExpr) pos() Pos  { return e.at }
+func (e *indexExpr) pos() Pos   { return e.at }
+func (e *fieldExpr) pos() Pos   { return e.at }
+func (e *seqExpr) pos() Pos     { return e.at }
+
+// arraySpec is the [size] of an array type; size is nil for [].
+type arraySpec struct {
+	at   Pos
+	size expr
+}
+
+type typeSpec struct {
+	at    Pos
+	name  string
+	strct *structSpec
+	array *arraySpec
+}
+
+type structSpec struct {
+	at     Pos
+	name   string
+	fields []*varDecl
+}
+
+type qualifiers struct {
+	storage   string // const, in, out, inout, uniform, attribute or varying
+	interp    string // flat, smooth or noperspective
+	centroid  bool
+	invariant bool
+	layout    []layoutQualifier
+}
+
+type layoutQualifier struct {
+	at    Pos
+	name  string
+	value expr
+}
+
+type declarator struct {
+	at    Pos
+	name  string
+	array *arraySpec
+	init  expr
+}
+
+type varDecl struct {
+	at   Pos
+	qual qualifiers
+	typ  *typeSpec
+	vars []*declarator
+}
+
+// blockDecl is an interface block such as uniform Lights { ... } lights;
+type blockDecl struct {
+	at       Pos
+	qual     qualifiers
+	name     string
+	fields   []*varDecl
+	instance string
+	array    *arraySpec
+}
+
+// invariantDecl makes already declared outputs invariant.
+type invariantDecl struct {
+	at    Pos
+	names []string
+}
+
+type param struct {
+	at    Pos
+	qual  string // in, out, inout or const
+	typ   *typeSpec
+	name  string
+	array *arraySpec
+}
+
+type funcDecl struct {
+	at     Pos
+	ret    *typeSpec
+	name   string
+	params []*param
+	body   *blockStmt // nil for a prototype
+}
+
+type stmt interface{}
+
+type (
+	blockStmt struct {
+		at   Pos
+		list []stmt
+	}
+
+	declStmt struct {
+		decl *varDecl
+	}
+
+	exprStmt struct {
+		x expr
+	}
+
+	ifStmt struct {
+		at   Pos
+		cond expr
+		then stmt
+		els  stmt
+	}
+
+	forStmt struct {
+		at   Pos
+		init stmt
+		cond expr
+		post expr
+		body stmt
+	}
+
+	whileStmt struct {
+		at   Pos
+		cond expr
+		body stmt
+	}
+
+	doStmt struct {
+		at   Pos
+		body stmt
+		cond expr
+	}
+
+	returnStmt struct {
+		at Pos
+		x  expr
+	}
+
+	// branchStmt is break, continue or discard.
+	branchStmt struct {
+		at  Pos
+		tok string
+	}
+
+	switchStmt struct {
+		at   Pos
+		tag  expr
+		body *blockStmt
+	}
+
+	// caseStmt is a case label, or default if x is nil.
+	caseStmt struct {
+		at Pos
+		x  expr
+	}
+)
+
+// unit is a parsed shader: a list of *varDecl, *blockDecl,
+// *invariantDecl and *funcDecl.
+type unit struct {
+	decls []interface{}
+	end   Pos
+}
@@ -0,0 +1,443 @@
+package glsl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// function is a built-in or user function signature.
+type function struct {
+	name     string
+	ret      *Type
+	params   []*Type
+	quals    []string // in, out, inout or const per parameter
+	pos      Pos
+	defined  bool
+	builtin  bool
+	fragment bool // only available in fragment shaders
+}
+
+func (f *function) signature() string {
+	params := make([]string, len(f.params))
+	for i, p := range f.params {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("%s(%s)", f.name, strings.Join(params, ", "))
+}
+
+// builtinSpecs are the built-in functions of GLSL 1.50 except for a few
+// rarely used texture lookups. Generic types stand for several
+// signatures: genType is float or vec2 to vec4 and vec is vec2 to vec4,
+// both with i, u and b variants of the same size; mat is every matrix
+// type; gvec4 and gsampler are the float, int and unsigned variants of a
+// texture lookup. A leading "frag" marks functions only fragment shaders
+// have.
+var builtinSpecs = `
+genType radians(genType)
+genType degrees(genType)
+genType sin(genType)
+genType cos(genType)
+genType tan(genType)
+genType asin(genType)
+genType acos(genType)
+genType atan(genType, genType)
+genType atan(genType)
+genType sinh(genType)
+genType cosh(genType)
+genType tanh(genType)
+genType asinh(genType)
+genType acosh(genType)
+genType atanh(genType)
+
+genType pow(genType, genType)
+genType exp(genType)
+genType log(genType)
+genType exp2(genType)
+genType log2(genType)
+genType sqrt(genType)
+genType inversesqrt(genType)
+
+genType abs(genType)
+genIType abs(genIType)
+genType sign(genType)
+genIType sign(genIType)
+genType floor(genType)
+genType trunc(genType)
+genType round(genType)
+genType roundEven(genType)
+genType ceil(genType)
+genType fract(genType)
+genType mod(genType, float)
+genType mod(genType, genType)
+genType modf(genType, out genType)
+genType min(genType, genType)
+genType min(genType, float)
+genIType min(genIType, genIType)
+genIType min(genIType, int)
+genUType min(genUType, genUType)
+genUType min(genUType, uint)
+genType max(genType, genType)
+genType max(genType, float)
+genIType max(genIType, genIType)
+genIType max(genIType, int)
+genUType max(genUType, genUType)
+genUType max(genUType, uint)
+genType clamp(genType, genType, genType)
+genType clamp(genType, float, float)
+genIType clamp(genIType, genIType, genIType)
+genIType clamp(genIType, int, int)
+genUType clamp(genUType, genUType, genUType)
+genUType clamp(genUType, uint, uint)
+genType mix(genType, genType, genType)
+genType mix(genType, genType, float)
+genType mix(genType, genType, genBType)
+genType step(genType, genType)
+genType step(float, genType)
+genType smoothstep(genType, genType, genType)
+genType smoothstep(float, float, genType)
+genBType isnan(genType)
+genBType isinf(genType)
+
+float length(genType)
+float distance(genType, genType)
+float dot(genType, genType)
+vec3 cross(vec3, vec3)
+genType normalize(genType)
+genType faceforward(genType, genType, genType)
+genType reflect(genType, genType)
+genType refract(genType, genType, float)
+
+mat matrixCompMult(mat, mat)
+float determinant(mat2)
+float determinant(mat3)
+float determinant(mat4)
+mat2 inverse(mat2)
+mat3 inverse(mat3)
+mat4 inverse(mat4)
+
+bvec lessThan(vec, vec)
+bvec lessThan(ivec, ivec)
+bvec lessThan(uvec, uvec)
+bvec lessThanEqual(vec, vec)
+bvec lessThanEqual(ivec, ivec)
+bvec lessThanEqual(uvec, uvec)
+bvec greaterThan(vec, vec)
+bvec greaterThan(ivec, ivec)
+bvec greaterThan(uvec, uvec)
+bvec greaterThanEqual(vec, vec)
+bvec greaterThanEqual(ivec, ivec)
+bvec greaterThanEqual(uvec, uvec)
+bvec equal(vec, vec)
+bvec equal(ivec, ivec)
+bvec equal(uvec, uvec)
+bvec equal(bvec, bvec)
+bvec notEqual(vec, vec)
+bvec notEqual(ivec, ivec)
+bvec notEqual(uvec, uvec)
+bvec notEqual(bvec, bvec)
+bool any(bvec)
+bool all(bvec)
+bvec not(bvec)
+
+int textureSize(gsampler1D, int)
+ivec2 textureSize(gsampler2D, int)
+ivec3 textureSize(gsampler3D, int)
+ivec2 textureSize(gsamplerCube, int)
+int textureSize(sampler1DShadow, int)
+ivec2 textureSize(sampler2DShadow, int)
+ivec2 textureSize(samplerCubeShadow, int)
+ivec2 textureSize(gsampler2DRect)
+ivec2 textureSize(sampler2DRectShadow)
+ivec2 textureSize(gsampler1DArray, int)
+ivec3 textureSize(gsampler2DArray, int)
+ivec2 textureSize(sampler1DArrayShadow, int)
+ivec3 textureSize(sampler2DArrayShadow, int)
+int textureSize(gsamplerBuffer)
+ivec2 textureSize(gsampler2DMS)
+ivec3 textureSize(gsampler2DMSArray)
+
+gvec4 texture(gsampler1D, float)
+gvec4 texture(gsampler2D, vec2)
+gvec4 texture(gsampler3D, vec3)
+gvec4 texture(gsamplerCube, vec3)
+float texture(sampler1DShadow, vec3)
+float texture(sampler2DShadow, vec3)
+float texture(samplerCubeShadow, vec4)
+gvec4 texture(gsampler1DArray, vec2)
+gvec4 texture(gsampler2DArray, vec3)
+float texture(sampler1DArrayShadow, vec3)
+float texture(sampler2DArrayShadow, vec4)
+gvec4 texture(gsampler2DRect, vec2)
+float texture(sampler2DRectShadow, vec3)
+frag gvec4 texture(gsampler1D, float, float)
+frag gvec4 texture(gsampler2D, vec2, float)
+frag gvec4 texture(gsampler3D, vec3, float)
+frag gvec4 texture(gsamplerCube, vec3, float)
+frag float texture(sampler1DShadow, vec3, float)
+frag float texture(sampler2DShadow, vec3, float)
+frag float texture(samplerCubeShadow, vec4, float)
+frag gvec4 texture(gsampler1DArray, vec2, float)
+frag gvec4 texture(gsampler2DArray, vec3, float)
+frag float texture(sampler1DArrayShadow, vec3, float)
+
+gvec4 textureProj(gsampler1D, vec2)
+gvec4 textureProj(gsampler1D, vec4)
+gvec4 textureProj(gsampler2D, vec3)
+gvec4 textureProj(gsampler2D, vec4)
+gvec4 textureProj(gsampler3D, vec4)
+float textureProj(sampler1DShadow, vec4)
+float textureProj(sampler2DShadow, vec4)
+gvec4 textureProj(gsampler2DRect, vec3)
+gvec4 textureProj(gsampler2DRect, vec4)
+float textureProj(sampler2DRectShadow, vec4)
+frag gvec4 textureProj(gsampler1D, vec2, float)
+frag gvec4 textureProj(gsampler1D, vec4, float)
+frag gvec4 textureProj(gsampler2D, vec3, float)
+frag gvec4 textureProj(gsampler2D, vec4, float)
+frag gvec4 textureProj(gsampler3D, vec4, float)
+frag float textureProj(sampler1DShadow, vec4, float)
+frag float textureProj(sampler2DShadow, vec4, float)
+
+gvec4 textureLod(gsampler1D, float, float)
+gvec4 textureLod(gsampler2D, vec2, float)
+gvec4 textureLod(gsampler3D, vec3, float)
+gvec4 textureLod(gsamplerCube, vec3, float)
+float textureLod(sampler1DShadow, vec3, float)
+float textureLod(sampler2DShadow, vec3, float)
+gvec4 textureLod(gsampler1DArray, vec2, float)
+gvec4 textureLod(gsampler2DArray, vec3, float)
+float textureLod(sampler1DArrayShadow, vec3, float)
+
+gvec4 textureOffset(gsampler1D, float, int)
+gvec4 textureOffset(gsampler2D, vec2, ivec2)
+gvec4 textureOffset(gsampler3D, vec3, ivec3)
+gvec4 textureOffset(gsampler2DRect, vec2, ivec2)
+float textureOffset(sampler2DRectShadow, vec3, ivec2)
+float textureOffset(sampler1DShadow, vec3, int)
+float textureOffset(sampler2DShadow, vec3, ivec2)
+gvec4 textureOffset(gsampler1DArray, vec2, int)
+gvec4 textureOffset(gsampler2DArray, vec3, ivec2)
+float textureOffset(sampler1DArrayShadow, vec3, int)
+frag gvec4 textureOffset(gsampler1D, float, int, float)
+frag gvec4 textureOffset(gsampler2D, vec2, ivec2, float)
+frag gvec4 textureOffset(gsampler3D, vec3, ivec3, float)
+frag float textureOffset(sampler1DShadow, vec3, int, float)
+frag float textureOffset(sampler2DShadow, vec3, ivec2, float)
+frag gvec4 textureOffset(gsampler1DArray, vec2, int, float)
+frag gvec4 textureOffset(gsampler2DArray, vec3, ivec2, float)
+frag float textureOffset(sampler1DArrayShadow, vec3, int, float)
+
+gvec4 texelFetch(gsampler1D, int, int)
+gvec4 texelFetch(gsampler2D, ivec2, int)
+gvec4 texelFetch(gsampler3D, ivec3, int)
+gvec4 texelFetch(gsampler2DRect, ivec2)
+gvec4 texelFetch(gsampler1DArray, ivec2, int)
+gvec4 texelFetch(gsampler2DArray, ivec3, int)
+gvec4 texelFetch(gsamplerBuffer, int)
+gvec4 texelFetch(gsampler2DMS, ivec2, int)
+gvec4 texelFetch(gsampler2DMSArray, ivec3, int)
+gvec4 texelFetchOffset(gsampler1D, int, int, int)
+gvec4 texelFetchOffset(gsampler2D, ivec2, int, ivec2)
+gvec4 texelFetchOffset(gsampler3D, ivec3, int, ivec3)
+gvec4 texelFetchOffset(gsampler2DRect, ivec2, ivec2)
+gvec4 texelFetchOffset(gsampler1DArray, ivec2, int, int)
+gvec4 texelFetchOffset(gsampler2DArray, ivec3, int, ivec2)
+
+gvec4 textureGrad(gsampler1D, float, float, float)
+gvec4 textureGrad(gsampler2D, vec2, vec2, vec2)
+gvec4 textureGrad(gsampler3D, vec3, vec3, vec3)
+gvec4 textureGrad(gsamplerCube, vec3, vec3, vec3)
+gvec4 textureGrad(gsampler2DRect, vec2, vec2, vec2)
+float textureGrad(sampler2DRectShadow, vec3, vec2, vec2)
+float textureGrad(sampler1DShadow, vec3, float, float)
+float textureGrad(sampler2DShadow, vec3, vec2, vec2)
+float textureGrad(samplerCubeShadow, vec4, vec3, vec3)
+gvec4 textureGrad(gsampler1DArray, vec2, float, float)
+gvec4 textureGrad(gsampler2DArray, vec3, vec2, vec2)
+float textureGrad(sampler1DArrayShadow, vec3, float, float)
+float textureGrad(sampler2DArrayShadow, vec4, vec2, vec2)
+
+gvec4 textureLodOffset(gsampler1D, float, float, int)
+gvec4 textureLodOffset(gsampler2D, vec2, float, ivec2)
+gvec4 textureLodOffset(gsampler3D, vec3, float, ivec3)
+float textureLodOffset(sampler1DShadow, vec3, float, int)
+float textureLodOffset(sampler2DShadow, vec3, float, ivec2)
+gvec4 textureLodOffset(gsampler1DArray, vec2, float, int)
+gvec4 textureLodOffset(gsampler2DArray, vec3, float, ivec2)
+float textureLodOffset(sampler1DArrayShadow, vec3, float, int)
+
+gvec4 textureProjLod(gsampler1D, vec2, float)
+gvec4 textureProjLod(gsampler1D, vec4, float)
+gvec4 textureProjLod(gsampler2D, vec3, float)
+gvec4 textureProjLod(gsampler2D, vec4, float)
+gvec4 textureProjLod(gsampler3D, vec4, float)
+float textureProjLod(sampler1DShadow, vec4, float)
+float textureProjLod(sampler2DShadow, vec4, float)
+
+frag genType dFdx(genType)
+frag genType dFdy(genType)
+frag genType fwidth(genType)
+
+float noise1(genType)
+vec2 noise2(genType)
+vec3 noise3(genType)
+vec4 noise4(genType)
+`
+
+// builtinFuncs are the built-in functions by name.
+var builtinFuncs = make(map[string][]*function)
+
+var (
+	specPattern = regexp.MustCompile(`^(frag )?(\w+) (\w+)\((.*)\)$`)
+	genPattern  = regexp.MustCompile(`\bgen[IUB]?Type\b`)
+	vecPattern  = regexp.MustCompile(`\b[iub]?vec\b`)
+	gPattern    = regexp.MustCompile(`\bg(vec4|sampler\w+)\b`)
+	matPattern  = regexp.MustCompile(`\bmat\b`)
+)
+
+func init() {
+	for _, spec := range strings.Split(builtinSpecs, "\n") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			for _, s := range expandSpec(spec) {
+				addBuiltin(s)
+			}
+		}
+	}
+
+	// the matrix functions whose result has a different shape
+	for c := 2; c <= 4; c++ {
+		for r := 2; r <= 4; r++ {
+			addBuiltin(fmt.Sprintf("%v outerProduct(vec%d, vec%d)", matType(c, r), r, c))
+			addBuiltin(fmt.Sprintf("%v transpose(%v)", matType(r, c), matType(c, r)))
+		}
+	}
+}
+
+// expandSpec turns a generic signature into the signatures it stands for.
+func expandSpec(spec string) []string {
+	specs := []string{spec}
+	expand := func(pattern *regexp.Regexp, values []string, replace func(match, value string) string) {
+		var out []string
+		for _, s := range specs {
+			if !pattern.MatchString(s) {
+				out = append(out, s)
+				continue
+			}
+			for _, v := range values {
+				out = append(out, pattern.ReplaceAllStringFunc(s, func(m string) string { return replace(m, v) }))
+			}
+		}
+		specs = out
+	}
+
+	expand(genPattern, []string{"1", "2", "3", "4"}, func(m, n string) string {
+		prefix := map[string]string{"genType": "", "genIType": "i", "genUType": "u", "genBType": "b"}[m]
+		if n == "1" {
+			return map[string]string{"": "float", "i": "int", "u": "uint", "b": "bool"}[prefix]
+		}
+		return prefix + "vec" + n
+	})
+	expand(vecPattern, []string{"2", "3", "4"}, func(m, n string) string {
+		return m + n
+	})
+	expand(gPattern, []string{"", "i", "u"}, func(m, g string) string {
+		return g + m[1:]
+	})
+	var mats []string
+	for c := 2; c <= 4; c++ {
+		for r := 2; r <= 4; r++ {
+			mats = append(mats, matType(c, r).String())
+		}
+	}
+	expand(matPattern, mats, func(m, mat string) string {
+		return mat
+	})
+	return specs
+}
+
+func addBuiltin(spec string) {
+	m := specPattern.FindStringSubmatch(spec)
+	if m == nil {
+		panic("glsl: bad built-in " + spec)
+	}
+	f := &function{name: m[3], ret: typeNames[m[2]], builtin: true, fragment: m[1] != ""}
+	for _, p := range strings.Split(m[4], ",") {
+		fields := strings.Fields(p)
+		qual := "in"
+		if len(fields) == 2 {
+			qual = fields[0]
+		}
+		t := typeNames[fields[len(fields)-1]]
+		if t == nil || f.ret == nil {
+			panic("glsl: bad built-in " + spec)
+		}
+		f.params = append(f.params, t)
+		f.quals = append(f.quals, qual)
+	}
+	builtinFuncs[f.name] = append(builtinFuncs[f.name], f)
+}
+
+// builtinConstants are the implementation limits GLSL 1.50 declares, with
+// the minimum values the specification allows.
+var builtinConstants = map[string]int64{
+	"gl_MaxVertexAttribs":                 16,
+	"gl_MaxVertexUniformComponents":       1024,
+	"gl_MaxVaryingFloats":                 60,
+	"gl_MaxVaryingComponents":             60,
+	"gl_MaxVertexOutputComponents":        64,
+	"gl_MaxGeometryInputComponents":       64,
+	"gl_MaxGeometryOutputComponents":      128,
+	"gl_MaxFragmentInputComponents":       128,
+	"gl_MaxVertexTextureImageUnits":       16,
+	"gl_MaxCombinedTextureImageUnits":     48,
+	"gl_MaxTextureImageUnits":             16,
+	"gl_MaxFragmentUniformComponents":     1024,
+	"gl_MaxDrawBuffers":                   8,
+	"gl_MaxClipDistances":                 8,
+	"gl_MaxGeometryTextureImageUnits":     16,
+	"gl_MaxGeometryOutputVertices":        256,
+	"gl_MaxGeometryTotalOutputComponents": 1024,
+	"gl_MaxGeometryUniformComponents":     1024,
+	"gl_MaxGeometryVaryingComponents":     64,
+}
+
+// builtinVars returns fresh built-in variables for a shader of stage, so
+// their use can be tracked per shader.
+func builtinVars(stage Stage) []*Variable {
+	v := func(name string, t *Type, storage Storage) *Variable {
+		return &Variable{Name: name, Type: t, Storage: storage, Builtin: true}
+	}
+	var vars []*Variable
+	for name, value := range builtinConstants {
+		value := value
+		c := v(name, intType, Const)
+		c.value = &value
+		vars = append(vars, c)
+	}
+
+	depthRange := &Type{Base: Struct, Name: "gl_DepthRangeParameters", Fields: []*StructField{
+		{"near", floatType}, {"far", floatType}, {"diff", floatType},
+	}}
+	vars = append(vars, v("gl_DepthRange", depthRange, Uniform))
+
+	if stage == Vertex {
+		return append(vars,
+			v("gl_VertexID", intType, In),
+			v("gl_InstanceID", intType, In),
+			v("gl_Position", vecType(Float, 4), Out),
+			v("gl_PointSize", floatType, Out),
+			v("gl_ClipDistance", arrayOf(floatType, 0), Out),
+		)
+	}
+	return append(vars,
+		v("gl_FragCoord", vecType(Float, 4), In),
+		v("gl_FrontFacing", boolType, In),
+		v("gl_ClipDistance", arrayOf(floatType, 0), In),
+		v("gl_PointCoord", vecType(Float, 2), In),
+		v("gl_PrimitiveID", intType, In),
+		v("gl_FragColor", vecType(Float, 4), Out),
+		v("gl_FragData", arrayOf(vecType(Float, 4), 8), Out),
+		v("gl_FragDepth", floatType, Out),
+	)
+}
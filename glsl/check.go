@@ -0,0 +1,1255 @@
+package glsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type scope struct {
+	outer *scope
+	vars  map[string]*Variable
+	types map[string]*Type
+}
+
+func (s *scope) lookup(name string) *Variable {
+	for ; s != nil; s = s.outer {
+		if v := s.vars[name]; v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func (s *scope) lookupType(name string) *Type {
+	for ; s != nil; s = s.outer {
+		if t := s.types[name]; t != nil {
+			return t
+		}
+	}
+	return typeNames[name]
+}
+
+// access is how an expression is used: read, written or both.
+type access int
+
+const (
+	read access = 1 << iota
+	write
+)
+
+// operand is the result of checking an expression. typ is nil if the
+// expression has errors that were already reported.
+type operand struct {
+	typ      *Type
+	constant bool
+	value    *int64 // of constant integer expressions
+}
+
+type checker struct {
+	shader   *Shader
+	errs     []Error
+	scope    *scope
+	funcs    map[string][]*function
+	fn       *function
+	loops    int
+	switches int
+}
+
+func newChecker(sh *Shader) *checker {
+	c := &checker{shader: sh, funcs: make(map[string][]*function)}
+	universe := &scope{vars: make(map[string]*Variable), types: make(map[string]*Type)}
+	for _, v := range builtinVars(sh.Stage) {
+		universe.vars[v.Name] = v
+	}
+	c.scope = &scope{outer: universe, vars: make(map[string]*Variable), types: make(map[string]*Type)}
+	return c
+}
+
+func (c *checker) errorf(pos Pos, format string, args ...interface{}) {
+	c.errs = append(c.errs, Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) warnf(pos Pos, format string, args ...interface{}) {
+	c.errs = append(c.errs, Error{Pos: pos, Msg: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func (c *checker) push() {
+	c.scope = &scope{outer: c.scope, vars: make(map[string]*Variable), types: make(map[string]*Type)}
+}
+
+func (c *checker) pop() {
+	c.scope = c.scope.outer
+}
+
+func (c *checker) declare(v *Variable) {
+	if old := c.scope.vars[v.Name]; old != nil {
+		c.errorf(v.Pos, "%s redeclared", v.Name)
+		return
+	}
+	if c.scope.types[v.Name] != nil {
+		c.errorf(v.Pos, "%s redeclared; it is a struct", v.Name)
+		return
+	}
+	if strings.HasPrefix(v.Name, "gl_") {
+		c.errorf(v.Pos, "names starting with gl_ are reserved")
+	}
+	c.scope.vars[v.Name] = v
+}
+
+func (c *checker) unit(u *unit) {
+	if c.shader.Version == 0 {
+		c.warnf(Pos{Line: 1, Column: 1}, "no #version directive; GLSL 1.10 doesn't have in and out, use #version 150")
+	} else if c.shader.Version < 130 || c.shader.Version > 150 {
+		c.warnf(Pos{Line: 1, Column: 1}, "only GLSL 1.30 to 1.50 are checked, not %d", c.shader.Version)
+	}
+
+	for _, d := range u.decls {
+		switch d := d.(type) {
+		case *varDecl:
+			c.globalDecl(d)
+		case *blockDecl:
+			c.blockDecl(d)
+		case *invariantDecl:
+			for _, name := range d.names {
+				if v := c.scope.lookup(name); v == nil || v.Storage != Out {
+					c.errorf(d.at, "invariant needs an output, %s isn't one", name)
+				}
+			}
+		case *funcDecl:
+			c.funcDecl(d)
+		}
+	}
+
+	main := c.funcs["main"]
+	switch {
+	case len(main) == 0 || !main[0].defined:
+		c.errorf(u.end, "no main function")
+	case len(main) > 1 || len(main[0].params) > 0 || main[0].ret != voidType:
+		c.errorf(main[0].pos, "main must be declared as void main()")
+	}
+
+	if c.shader.Stage == Vertex {
+		if p := c.scope.lookup("gl_Position"); !p.Written {
+			c.warnf(u.end, "gl_Position is never written")
+		}
+	}
+}
+
+// resolveType returns the type spec stands for; structs it defines are
+// declared in the current scope.
+func (c *checker) resolveType(spec *typeSpec) *Type {
+	var t *Type
+	if spec.strct != nil {
+		t = c.structType(spec.strct)
+	} else {
+		t = c.scope.lookupType(spec.name)
+		if t == nil {
+			c.errorf(spec.at, "unknown type %s", spec.name)
+			return nil
+		}
+	}
+	return c.arrayType(t, spec.array)
+}
+
+func (c *checker) structType(s *structSpec) *Type {
+	t := &Type{Base: Struct, Name: s.name}
+	if t.Name == "" {
+		t.Name = "struct"
+	}
+	seen := make(map[string]bool)
+	for _, d := range s.fields {
+		if d.qual.storage != "" {
+			c.errorf(d.at, "struct fields can't be %s", d.qual.storage)
+		}
+		ft := c.resolveType(d.typ)
+		if ft == nil {
+			continue
+		}
+		for _, v := range d.vars {
+			vt := c.arrayType(ft, v.array)
+			if vt == nil {
+				continue
+			}
+			if vt.Base == Void || vt.opaque() {
+				c.errorf(v.at, "struct field %s can't be %v", v.name, vt)
+			}
+			if seen[v.name] {
+				c.errorf(v.at, "field %s redeclared", v.name)
+			}
+			seen[v.name] = true
+			t.Fields = append(t.Fields, &StructField{Name: v.name, Type: vt})
+		}
+	}
+	if s.name != "" {
+		if c.scope.types[s.name] != nil || c.scope.vars[s.name] != nil {
+			c.errorf(s.at, "%s redeclared", s.name)
+		} else {
+			c.scope.types[s.name] = t
+		}
+	}
+	return t
+}
+
+// arrayType applies an array size to t.
+func (c *checker) arrayType(t *Type, a *arraySpec) *Type {
+	if t == nil || a == nil {
+		return t
+	}
+	if t.Elem != nil {
+		c.errorf(a.at, "arrays of arrays are not supported")
+		return nil
+	}
+	if a.size == nil {
+		return arrayOf(t, 0)
+	}
+	n := c.expr(a.size, read)
+	if n.typ == nil {
+		return nil
+	}
+	if !n.typ.integer() || !n.typ.IsScalar() || n.value == nil {
+		c.errorf(a.at, "array size must be a constant integer")
+		return nil
+	}
+	if *n.value <= 0 {
+		c.errorf(a.at, "array size must be positive, not %d", *n.value)
+		return nil
+	}
+	return arrayOf(t, int(*n.value))
+}
+
+// storage maps a qualifier on a global to a Storage, reporting the ones
+// that can't be used.
+func (c *checker) storage(d *varDecl) (Storage, bool) {
+	stage := c.shader.Stage
+	switch d.qual.storage {
+	case "":
+		return Local, true
+	case "const":
+		return Const, true
+	case "uniform":
+		return Uniform, true
+	case "in":
+		return In, true
+	case "out":
+		return Out, true
+	case "attribute":
+		if stage != Vertex {
+			c.errorf(d.at, "attribute is only for vertex shaders")
+			return Local, false
+		}
+		c.warnf(d.at, "attribute is deprecated, use in")
+		return In, true
+	case "varying":
+		c.warnf(d.at, "varying is deprecated, use %s", map[Stage]string{Vertex: "out", Fragment: "in"}[stage])
+		if stage == Vertex {
+			return Out, true
+		}
+		return In, true
+	}
+	c.errorf(d.at, "%s is only for function parameters", d.qual.storage)
+	return Local, false
+}
+
+func (c *checker) globalDecl(d *varDecl) {
+	t := c.resolveType(d.typ)
+	if t == nil {
+		return
+	}
+	storage, ok := c.storage(d)
+	if !ok {
+		return
+	}
+	if len(d.vars) == 0 {
+		if t.Base != Struct || d.typ.strct == nil {
+			c.errorf(d.at, "declaration declares nothing")
+		}
+		return
+	}
+	for _, decl := range d.vars {
+		v := c.variable(d, decl, t, storage)
+		if v == nil {
+			continue
+		}
+		c.checkInterface(d, v)
+		c.declare(v)
+		switch storage {
+		case In:
+			c.shader.Inputs = append(c.shader.Inputs, v)
+		case Out:
+			c.shader.Outputs = append(c.shader.Outputs, v)
+		case Uniform:
+			c.shader.Uniforms = append(c.shader.Uniforms, v)
+		}
+	}
+}
+
+// variable checks a declarator of d and returns the variable it declares,
+// without declaring it.
+func (c *checker) variable(d *varDecl, decl *declarator, t *Type, storage Storage) *Variable {
+	t = c.arrayType(t, decl.array)
+	if t == nil {
+		return nil
+	}
+	v := &Variable{Name: decl.name, Type: t, Storage: storage, Pos: decl.at, Flat: d.qual.interp == "flat"}
+	if t.Base == Void {
+		c.errorf(decl.at, "%s can't be void", decl.name)
+		return nil
+	}
+	if t.opaque() && storage != Uniform {
+		c.errorf(decl.at, "%s is a %v and can only be a uniform or a parameter", decl.name, t)
+	}
+
+	if decl.init == nil {
+		if storage == Const {
+			c.errorf(decl.at, "constant %s needs a value", decl.name)
+		}
+		if t.Elem != nil && t.Len == 0 && storage != Uniform {
+			c.errorf(decl.at, "array %s needs a size", decl.name)
+		}
+		return v
+	}
+	switch storage {
+	case In, Out:
+		c.errorf(decl.at, "%s can't have an initializer", d.qual.storage)
+		return v
+	}
+	x := c.expr(decl.init, read)
+	if x.typ == nil {
+		return v
+	}
+	if t.Elem != nil && t.Len == 0 && x.typ.Elem != nil {
+		// float a[] = float[](...) takes the size of the initializer
+		t = arrayOf(t.Elem, x.typ.Len)
+		v.Type = t
+	}
+	if !convertible(x.typ, t) {
+		c.errorf(decl.init.pos(), "cannot initialize %s of type %v with %v", decl.name, t, x.typ)
+		return v
+	}
+	if storage == Const || storage == Uniform {
+		if !x.constant {
+			c.errorf(decl.init.pos(), "initializer of %s is not constant", decl.name)
+		}
+	}
+	if storage == Const {
+		v.value = x.value
+	}
+	if storage != Uniform {
+		v.Written = true
+	}
+	return v
+}
+
+// checkInterface checks the restrictions on inputs and outputs.
+func (c *checker) checkInterface(d *varDecl, v *Variable) {
+	t := v.Type
+	stage := c.shader.Stage
+	if d.qual.interp != "" && !(stage == Vertex && v.Storage == Out || stage == Fragment && v.Storage == In) {
+		c.errorf(v.Pos, "%s only applies to vertex outputs and fragment inputs", d.qual.interp)
+	}
+	switch {
+	case v.Storage == In && stage == Vertex:
+		if t.Base == Bool || t.Base == Struct || t.Elem != nil {
+			c.errorf(v.Pos, "vertex input %s can't be %v", v.Name, t)
+		}
+	case v.Storage == Out && stage == Vertex, v.Storage == In && stage == Fragment:
+		elem := t
+		if t.Elem != nil {
+			elem = t.Elem
+		}
+		if elem.Base == Bool || elem.Base == Struct {
+			c.errorf(v.Pos, "%v can't be passed between stages", t)
+		}
+		if stage == Fragment && elem.integer() && !v.Flat {
+			c.errorf(v.Pos, "integer input %s must be flat", v.Name)
+		}
+	case v.Storage == Out && stage == Fragment:
+		if t.Base == Bool || t.Base == Struct || t.IsMatrix() {
+			c.errorf(v.Pos, "fragment output %s can't be %v", v.Name, t)
+		}
+	}
+}
+
+func (c *checker) blockDecl(d *blockDecl) {
+	if d.qual.storage != "uniform" {
+		c.errorf(d.at, "only uniform blocks are supported")
+		return
+	}
+	t := &Type{Base: Struct, Name: d.name}
+	var members []*Variable
+	for _, fd := range d.fields {
+		ft := c.resolveType(fd.typ)
+		if ft == nil {
+			continue
+		}
+		for _, f := range fd.vars {
+			vt := c.arrayType(ft, f.array)
+			if vt == nil {
+				continue
+			}
+			if vt.opaque() {
+				c.errorf(f.at, "uniform block member %s can't be %v", f.name, vt)
+			}
+			t.Fields = append(t.Fields, &StructField{Name: f.name, Type: vt})
+			members = append(members, &Variable{Name: f.name, Type: vt, Storage: Uniform, Pos: f.at})
+		}
+	}
+	if d.instance == "" {
+		for _, v := range members {
+			c.declare(v)
+		}
+		return
+	}
+	it := c.arrayType(t, d.array)
+	if it != nil {
+		c.declare(&Variable{Name: d.instance, Type: it, Storage: Uniform, Pos: d.at})
+	}
+}
+
+func (c *checker) funcDecl(d *funcDecl) {
+	ret := c.resolveType(d.ret)
+	f := &function{name: d.name, ret: ret, pos: d.at}
+	ok := ret != nil
+	if ret != nil && ret.Elem != nil {
+		c.errorf(d.ret.at, "functions can't return arrays")
+		ok = false
+	}
+	for _, p := range d.params {
+		t := c.arrayType(c.resolveType(p.typ), p.array)
+		if t == nil {
+			ok = false
+			continue
+		}
+		if t.Base == Void {
+			c.errorf(p.at, "parameters can't be void")
+			ok = false
+		}
+		if t.opaque() && (p.qual == "out" || p.qual == "inout") {
+			c.errorf(p.at, "%v parameters can't be %s", t, p.qual)
+		}
+		qual := p.qual
+		if qual == "" {
+			qual = "in"
+		}
+		f.params = append(f.params, t)
+		f.quals = append(f.quals, qual)
+	}
+	if !ok {
+		return
+	}
+	if c.scope.lookup(d.name) != nil {
+		c.errorf(d.at, "%s redeclared as a function", d.name)
+		return
+	}
+
+	// match it with an earlier prototype
+	for _, g := range c.funcs[d.name] {
+		if !sameParams(f.params, g.params) {
+			continue
+		}
+		if !g.ret.Equal(ret) {
+			c.errorf(d.at, "%s redeclared with a different return type", d.name)
+			return
+		}
+		if d.body != nil && g.defined {
+			c.errorf(d.at, "%s redefined", f.signature())
+			return
+		}
+		f = g
+		f.quals = f.quals[:0]
+		for _, p := range d.params {
+			qual := p.qual
+			if qual == "" {
+				qual = "in"
+			}
+			f.quals = append(f.quals, qual)
+		}
+		break
+	}
+	if f.pos == d.at {
+		for _, g := range builtinFuncs[d.name] {
+			if sameParams(f.params, g.params) {
+				c.errorf(d.at, "%s redefines a built-in function", f.signature())
+				return
+			}
+		}
+		c.funcs[d.name] = append(c.funcs[d.name], f)
+	}
+	if d.body == nil {
+		return
+	}
+
+	f.defined = true
+	c.push()
+	for i, p := range d.params {
+		if p.name == "" {
+			continue
+		}
+		v := &Variable{Name: p.name, Type: f.params[i], Storage: Param, Pos: p.at, readonly: p.qual == "const"}
+		v.Read = f.quals[i] == "out" // an out parameter is written to be read by the caller
+		c.declare(v)
+	}
+	c.fn = f
+	for _, s := range d.body.list {
+		c.stmt(s)
+	}
+	c.fn = nil
+	c.pop()
+}
+
+func sameParams(a, b []*Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *checker) stmt(s stmt) {
+	switch s := s.(type) {
+	case *blockStmt:
+		c.push()
+		for _, s := range s.list {
+			c.stmt(s)
+		}
+		c.pop()
+	case *declStmt:
+		c.localDecl(s.decl)
+	case *exprStmt:
+		c.expr(s.x, read)
+	case *ifStmt:
+		c.condition(s.cond, "if")
+		c.subStmt(s.then)
+		if s.els != nil {
+			c.subStmt(s.els)
+		}
+	case *forStmt:
+		c.push()
+		if s.init != nil {
+			c.stmt(s.init)
+		}
+		if s.cond != nil {
+			c.condition(s.cond, "for")
+		}
+		if s.post != nil {
+			c.expr(s.post, read)
+		}
+		c.loops++
+		c.subStmt(s.body)
+		c.loops--
+		c.pop()
+	case *whileStmt:
+		c.condition(s.cond, "while")
+		c.loops++
+		c.subStmt(s.body)
+		c.loops--
+	case *doStmt:
+		c.loops++
+		c.subStmt(s.body)
+		c.loops--
+		c.condition(s.cond, "do-while")
+	case *returnStmt:
+		c.returnStmt(s)
+	case *branchStmt:
+		switch {
+		case s.tok == "break" && c.loops == 0 && c.switches == 0:
+			c.errorf(s.at, "break outside a loop or switch")
+		case s.tok == "continue" && c.loops == 0:
+			c.errorf(s.at, "continue outside a loop")
+		case s.tok == "discard" && c.shader.Stage != Fragment:
+			c.errorf(s.at, "discard is only for fragment shaders")
+		}
+	case *switchStmt:
+		tag := c.expr(s.tag, read)
+		if tag.typ != nil && !(tag.typ.integer() && tag.typ.IsScalar()) {
+			c.errorf(s.tag.pos(), "switch needs an integer, not %v", tag.typ)
+		}
+		c.switches++
+		c.push()
+		seen := make(map[int64]bool)
+		for _, s := range s.body.list {
+			cs, ok := s.(*caseStmt)
+			if !ok {
+				c.stmt(s)
+				continue
+			}
+			if cs.x == nil {
+				continue
+			}
+			x := c.expr(cs.x, read)
+			switch {
+			case x.typ == nil:
+			case x.value == nil:
+				c.errorf(cs.at, "case label must be a constant integer")
+			case seen[*x.value]:
+				c.errorf(cs.at, "duplicate case %d", *x.value)
+			default:
+				seen[*x.value] = true
+			}
+		}
+		c.pop()
+		c.switches--
+	case *caseStmt:
+		c.errorf(s.at, "case label outside a switch")
+	}
+}
+
+// subStmt checks the body of an if or loop, which has its own scope.
+func (c *checker) subStmt(s stmt) {
+	c.push()
+	c.stmt(s)
+	c.pop()
+}
+
+func (c *checker) condition(e expr, what string) {
+	x := c.expr(e, read)
+	if x.typ != nil && !x.typ.Equal(boolType) {
+		c.errorf(e.pos(), "%s condition must be a bool, not %v", what, x.typ)
+	}
+}
+
+func (c *checker) returnStmt(s *returnStmt) {
+	if c.fn == nil {
+		return
+	}
+	if s.x == nil {
+		if c.fn.ret != voidType {
+			c.errorf(s.at, "%s must return a %v", c.fn.name, c.fn.ret)
+		}
+		return
+	}
+	x := c.expr(s.x, read)
+	switch {
+	case x.typ == nil:
+	case c.fn.ret == voidType:
+		c.errorf(s.at, "void function %s returns a value", c.fn.name)
+	case !convertible(x.typ, c.fn.ret):
+		c.errorf(s.x.pos(), "cannot return %v from %s, which returns %v", x.typ, c.fn.name, c.fn.ret)
+	}
+}
+
+func (c *checker) localDecl(d *varDecl) {
+	t := c.resolveType(d.typ)
+	if t == nil {
+		return
+	}
+	storage := Local
+	switch d.qual.storage {
+	case "":
+	case "const":
+		storage = Const
+	default:
+		c.errorf(d.at, "%s variables must be global", d.qual.storage)
+		return
+	}
+	if d.qual.interp != "" || d.qual.centroid || d.qual.invariant || len(d.qual.layout) > 0 {
+		c.errorf(d.at, "local variables can't have interpolation or layout qualifiers")
+	}
+	for _, decl := range d.vars {
+		if v := c.variable(d, decl, t, storage); v != nil {
+			c.declare(v)
+		}
+	}
+}
+
+// expr checks e, used as acc says, and returns its type.
+func (c *checker) expr(e expr, acc access) operand {
+	if acc&write != 0 {
+		switch e.(type) {
+		case *identExpr, *indexExpr, *fieldExpr:
+		default:
+			c.errorf(e.pos(), "cannot assign to this expression")
+			acc = read
+		}
+	}
+
+	switch e := e.(type) {
+	case *identExpr:
+		return c.ident(e, acc)
+	case *literalExpr:
+		return c.literal(e)
+	case *unaryExpr:
+		return c.unary(e)
+	case *binaryExpr:
+		return c.binary(e)
+	case *assignExpr:
+		return c.assign(e)
+	case *condExpr:
+		cond := c.expr(e.cond, read)
+		if cond.typ != nil && !cond.typ.Equal(boolType) {
+			c.errorf(e.cond.pos(), "condition of ?: must be a bool, not %v", cond.typ)
+		}
+		x, y := c.expr(e.then, read), c.expr(e.els, read)
+		if x.typ == nil || y.typ == nil {
+			return operand{}
+		}
+		switch {
+		case convertible(y.typ, x.typ):
+			return operand{typ: x.typ, constant: cond.constant && x.constant && y.constant}
+		case convertible(x.typ, y.typ):
+			return operand{typ: y.typ, constant: cond.constant && x.constant && y.constant}
+		}
+		c.errorf(e.at, "branches of ?: have different types %v and %v", x.typ, y.typ)
+		return operand{}
+	case *callExpr:
+		return c.call(e)
+	case *lengthExpr:
+		x := c.expr(e.x, 0)
+		if x.typ == nil {
+			return operand{}
+		}
+		if x.typ.Elem == nil {
+			c.errorf(e.at, "length() is only for arrays in GLSL 1.50, not %v", x.typ)
+			return operand{}
+		}
+		if x.typ.Len == 0 {
+			c.errorf(e.at, "length() of an array without a size")
+			return operand{}
+		}
+		n := int64(x.typ.Len)
+		return operand{typ: intType, constant: true, value: &n}
+	case *indexExpr:
+		return c.index(e, acc)
+	case *fieldExpr:
+		return c.field(e, acc)
+	case *seqExpr:
+		var x operand
+		for _, item := range e.list {
+			x = c.expr(item, read)
+		}
+		return operand{typ: x.typ}
+	}
+	panic(fmt.Sprintf("glsl: unexpected expression %T", e))
+}
+
+func (c *checker) ident(e *identExpr, acc access) operand {
+	v := c.scope.lookup(e.name)
+	if v == nil {
+		if len(c.funcs[e.name]) > 0 || len(builtinFuncs[e.name]) > 0 {
+			c.errorf(e.at, "function %s used as a variable", e.name)
+		} else {
+			c.errorf(e.at, "undeclared identifier %s", e.name)
+		}
+		return operand{}
+	}
+	if acc&read != 0 {
+		v.Read = true
+	}
+	if acc&write != 0 {
+		switch {
+		case v.Storage == Const || v.readonly:
+			c.errorf(e.at, "cannot assign to constant %s", e.name)
+		case v.Storage == Uniform:
+			c.errorf(e.at, "cannot assign to uniform %s", e.name)
+		case v.Storage == In:
+			c.errorf(e.at, "cannot assign to input %s", e.name)
+		}
+		v.Written = true
+	}
+	return operand{typ: v.Type, constant: v.Storage == Const, value: v.value}
+}
+
+func (c *checker) literal(e *literalExpr) operand {
+	switch e.kind {
+	case tokInt, tokUint:
+		t := intType
+		text := e.text
+		if e.kind == tokUint {
+			t = uintType
+			text = text[:len(text)-1]
+		}
+		v, err := strconv.ParseUint(text, 0, 32)
+		if err != nil {
+			c.errorf(e.at, "integer %s out of range", e.text)
+			return operand{}
+		}
+		n := int64(v)
+		if t == intType {
+			n = int64(int32(uint32(v)))
+		}
+		return operand{typ: t, constant: true, value: &n}
+	case tokFloat:
+		return operand{typ: floatType, constant: true}
+	}
+	return operand{typ: boolType, constant: true}
+}
+
+func (c *checker) unary(e *unaryExpr) operand {
+	acc := read
+	if e.op == "++" || e.op == "--" {
+		acc |= write
+	}
+	x := c.expr(e.x, acc)
+	if x.typ == nil {
+		return operand{}
+	}
+	switch e.op {
+	case "+", "-", "++", "--":
+		if !x.typ.numeric() {
+			c.errorf(e.at, "operator %s needs a number, not %v", e.op, x.typ)
+			return operand{}
+		}
+		if e.op == "-" && x.value != nil {
+			n := -*x.value
+			return operand{typ: x.typ, constant: true, value: &n}
+		}
+		if e.op == "++" || e.op == "--" {
+			return operand{typ: x.typ}
+		}
+		return operand{typ: x.typ, constant: x.constant, value: x.value}
+	case "!":
+		if !x.typ.Equal(boolType) {
+			c.errorf(e.at, "operator ! needs a bool, not %v", x.typ)
+			return operand{}
+		}
+	case "~":
+		if !x.typ.integer() {
+			c.errorf(e.at, "operator ~ needs an integer, not %v", x.typ)
+			return operand{}
+		}
+		if x.value != nil {
+			n := ^*x.value
+			return operand{typ: x.typ, constant: true, value: &n}
+		}
+	}
+	return operand{typ: x.typ, constant: x.constant}
+}
+
+func (c *checker) binary(e *binaryExpr) operand {
+	x, y := c.expr(e.x, read), c.expr(e.y, read)
+	if x.typ == nil || y.typ == nil {
+		return operand{}
+	}
+	t := c.binaryType(e.at, e.op, x.typ, y.typ)
+	if t == nil {
+		return operand{}
+	}
+	r := operand{typ: t, constant: x.constant && y.constant}
+	if x.value != nil && y.value != nil && t.integer() && t.IsScalar() {
+		r.value = foldInt(e.op, *x.value, *y.value)
+	}
+	return r
+}
+
+// binaryType returns the type of x op y, or reports an error and returns
+// nil.
+func (c *checker) binaryType(pos Pos, op string, x, y *Type) *Type {
+	mismatch := func() *Type {
+		c.errorf(pos, "operator %s can't be applied to %v and %v", op, x, y)
+		return nil
+	}
+	if !x.basic() || !y.basic() {
+		if (op == "==" || op == "!=") && x.Equal(y) && !x.opaque() {
+			return boolType
+		}
+		return mismatch()
+	}
+
+	switch op {
+	case "&&", "||", "^^":
+		if !x.Equal(boolType) || !y.Equal(boolType) {
+			return mismatch()
+		}
+		return boolType
+	case "==", "!=":
+		if !convertible(x, y) && !convertible(y, x) {
+			return mismatch()
+		}
+		return boolType
+	case "<", ">", "<=", ">=":
+		if !x.IsScalar() || !y.IsScalar() || !x.numeric() || !y.numeric() || !convertible(x, y) && !convertible(y, x) {
+			return mismatch()
+		}
+		return boolType
+	case "<<", ">>":
+		if !x.integer() || !y.integer() || y.IsVector() && y.Size != x.Size || x.IsScalar() && y.IsVector() {
+			return mismatch()
+		}
+		return x
+	case "%", "&", "|", "^":
+		if !x.integer() || !y.integer() || x.Base != y.Base {
+			return mismatch()
+		}
+		return c.shape(x, y, mismatch)
+	}
+
+	// + - * /
+	if !x.numeric() || !y.numeric() {
+		return mismatch()
+	}
+	switch {
+	case x.Base == y.Base:
+	case convertible(x.withBase(y.Base), y.withBase(y.Base)) && y.Base == Float:
+		x = x.withBase(Float)
+	case x.Base == Float && (y.Base == Int || y.Base == Uint):
+		y = y.withBase(Float)
+	default:
+		return mismatch()
+	}
+	if op == "*" {
+		switch {
+		case x.IsMatrix() && y.IsMatrix():
+			if x.Cols != y.Size {
+				return mismatch()
+			}
+			return matType(y.Cols, x.Size)
+		case x.IsMatrix() && y.IsVector():
+			if x.Cols != y.Size {
+				return mismatch()
+			}
+			return vecType(Float, x.Size)
+		case x.IsVector() && y.IsMatrix():
+			if x.Size != y.Size {
+				return mismatch()
+			}
+			return vecType(Float, y.Cols)
+		}
+	}
+	return c.shape(x, y, mismatch)
+}
+
+// shape returns the type of a component-wise operation on x and y, which
+// have the same base.
+func (c *checker) shape(x, y *Type, mismatch func() *Type) *Type {
+	switch {
+	case x.Equal(y):
+		return x
+	case x.IsScalar():
+		return y
+	case y.IsScalar():
+		return x
+	}
+	return mismatch()
+}
+
+func foldInt(op string, x, y int64) *int64 {
+	var n int64
+	switch op {
+	case "+":
+		n = x + y
+	case "-":
+		n = x - y
+	case "*":
+		n = x * y
+	case "/", "%":
+		if y == 0 {
+			return nil
+		}
+		if op == "/" {
+			n = x / y
+		} else {
+			n = x % y
+		}
+	case "<<":
+		n = x << uint(y)
+	case ">>":
+		n = x >> uint(y)
+	case "&":
+		n = x & y
+	case "|":
+		n = x | y
+	case "^":
+		n = x ^ y
+	default:
+		return nil
+	}
+	return &n
+}
+
+func (c *checker) assign(e *assignExpr) operand {
+	acc := write
+	if e.op != "=" {
+		acc |= read
+	}
+	lhs := c.expr(e.lhs, acc)
+	rhs := c.expr(e.rhs, read)
+	if lhs.typ == nil || rhs.typ == nil {
+		return operand{}
+	}
+	if e.op == "=" {
+		if lhs.typ.opaque() {
+			c.errorf(e.at, "cannot assign to a %v", lhs.typ)
+			return operand{}
+		}
+		if !convertible(rhs.typ, lhs.typ) {
+			c.errorf(e.at, "cannot assign %v to %v", rhs.typ, lhs.typ)
+			return operand{}
+		}
+		return operand{typ: lhs.typ}
+	}
+	t := c.binaryType(e.at, e.op[:len(e.op)-1], lhs.typ, rhs.typ)
+	if t != nil && !t.Equal(lhs.typ) {
+		c.errorf(e.at, "result of %v %s %v is %v, which can't be stored in %v", lhs.typ, e.op[:len(e.op)-1], rhs.typ, t, lhs.typ)
+		return operand{}
+	}
+	return operand{typ: lhs.typ}
+}
+
+func (c *checker) index(e *indexExpr, acc access) operand {
+	x := c.expr(e.x, acc)
+	i := c.expr(e.index, read)
+	if x.typ == nil || i.typ == nil {
+		return operand{}
+	}
+	if !i.typ.integer() || !i.typ.IsScalar() {
+		c.errorf(e.index.pos(), "index must be an integer, not %v", i.typ)
+		return operand{}
+	}
+
+	var elem *Type
+	n := 0
+	switch t := x.typ; {
+	case t.Elem != nil:
+		elem, n = t.Elem, t.Len
+	case t.IsMatrix():
+		elem, n = vecType(Float, t.Size), t.Cols
+	case t.IsVector():
+		elem, n = vecType(t.Base, 1), t.Size
+	default:
+		c.errorf(e.at, "%v can't be indexed", t)
+		return operand{}
+	}
+	if i.value != nil && (*i.value < 0 || n > 0 && *i.value >= int64(n)) {
+		c.errorf(e.index.pos(), "index %d out of range for %v", *i.value, x.typ)
+		return operand{}
+	}
+	return operand{typ: elem, constant: x.constant && i.constant}
+}
+
+var swizzleSets = []string{"xyzw", "rgba", "stpq"}
+
+func (c *checker) field(e *fieldExpr, acc access) operand {
+	x := c.expr(e.x, acc)
+	if x.typ == nil {
+		return operand{}
+	}
+	t := x.typ
+	if t.Base == Struct && t.Elem == nil {
+		for _, f := range t.Fields {
+			if f.Name == e.name {
+				return operand{typ: f.Type, constant: x.constant}
+			}
+		}
+		c.errorf(e.at, "%v has no field %s", t, e.name)
+		return operand{}
+	}
+	if !t.IsVector() {
+		c.errorf(e.at, "%v has no field %s", t, e.name)
+		return operand{}
+	}
+
+	set := ""
+	for _, s := range swizzleSets {
+		if strings.IndexByte(s, e.name[0]) >= 0 {
+			set = s
+		}
+	}
+	if len(e.name) > 4 || set == "" {
+		c.errorf(e.at, "invalid swizzle .%s", e.name)
+		return operand{}
+	}
+	seen := make(map[rune]bool)
+	for _, r := range e.name {
+		i := strings.IndexRune(set, r)
+		switch {
+		case i < 0:
+			c.errorf(e.at, "swizzle .%s mixes component sets", e.name)
+			return operand{}
+		case i >= t.Size:
+			c.errorf(e.at, "swizzle .%s is out of range for %v", e.name, t)
+			return operand{}
+		case seen[r] && acc&write != 0:
+			c.errorf(e.at, "cannot assign to swizzle .%s, it repeats %c", e.name, r)
+			return operand{}
+		}
+		seen[r] = true
+	}
+	return operand{typ: vecType(t.Base, len(e.name)), constant: x.constant}
+}
+
+func (c *checker) call(e *callExpr) operand {
+	if e.typ != nil {
+		return c.constructor(e)
+	}
+
+	args := make([]*Type, len(e.args))
+	constant := true
+	for i, a := range e.args {
+		x := c.expr(a, read)
+		if x.typ == nil {
+			return operand{}
+		}
+		args[i] = x.typ
+		constant = constant && x.constant
+	}
+
+	if v := c.scope.lookup(e.name); v != nil {
+		c.errorf(e.at, "%s is a variable, not a function", e.name)
+		return operand{}
+	}
+	candidates := append(append([]*function(nil), c.funcs[e.name]...), builtinFuncs[e.name]...)
+	if len(candidates) == 0 {
+		c.errorf(e.at, "undeclared function %s", e.name)
+		return operand{}
+	}
+	f := resolve(candidates, args)
+	if f == nil {
+		names := make([]string, len(args))
+		for i, a := range args {
+			names[i] = a.String()
+		}
+		c.errorf(e.at, "no function %s(%s)", e.name, strings.Join(names, ", "))
+		return operand{}
+	}
+	if f.fragment && c.shader.Stage != Fragment {
+		c.errorf(e.at, "%s is only available in fragment shaders", f.signature())
+	}
+	for i, q := range f.quals {
+		if q == "out" || q == "inout" {
+			acc := write
+			if q == "inout" {
+				acc |= read
+			}
+			c.expr(e.args[i], acc)
+		}
+	}
+	if !f.builtin && c.fn == nil {
+		constant = false
+	}
+	return operand{typ: f.ret, constant: constant && f.builtin}
+}
+
+// resolve picks the overload of candidates that takes args, preferring an
+// exact match over one that needs conversions.
+func resolve(candidates []*function, args []*Type) *function {
+	for _, f := range candidates {
+		if sameParams(f.params, args) {
+			return f
+		}
+	}
+	for _, f := range candidates {
+		if len(f.params) != len(args) {
+			continue
+		}
+		ok := true
+		for i, p := range f.params {
+			if !convertible(args[i], p) || f.quals[i] != "in" && f.quals[i] != "const" && !p.Equal(args[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return f
+		}
+	}
+	return nil
+}
+
+func (c *checker) constructor(e *callExpr) operand {
+	t := c.resolveType(e.typ)
+	if t == nil {
+		return operand{}
+	}
+	args := make([]*Type, len(e.args))
+	constant := true
+	for i, a := range e.args {
+		x := c.expr(a, read)
+		if x.typ == nil {
+			return operand{}
+		}
+		args[i] = x.typ
+		constant = constant && x.constant
+	}
+
+	switch {
+	case t.Elem != nil:
+		if t.Len == 0 {
+			t = arrayOf(t.Elem, len(args))
+		}
+		if len(args) != t.Len {
+			c.errorf(e.at, "%v constructor needs %d values, not %d", t, t.Len, len(args))
+			return operand{}
+		}
+		for i, a := range args {
+			if !convertible(a, t.Elem) {
+				c.errorf(e.args[i].pos(), "cannot use %v as %v in %v constructor", a, t.Elem, t)
+				return operand{}
+			}
+		}
+	case t.Base == Struct:
+		if len(args) != len(t.Fields) {
+			c.errorf(e.at, "%v constructor needs %d values, not %d", t, len(t.Fields), len(args))
+			return operand{}
+		}
+		for i, a := range args {
+			if !convertible(a, t.Fields[i].Type) {
+				c.errorf(e.args[i].pos(), "cannot use %v as %v for field %s", a, t.Fields[i].Type, t.Fields[i].Name)
+				return operand{}
+			}
+		}
+	case !t.basic():
+		c.errorf(e.at, "%v values can't be constructed", t)
+		return operand{}
+	default:
+		if !c.basicConstructor(e, t, args) {
+			return operand{}
+		}
+	}
+
+	r := operand{typ: t, constant: constant}
+	if len(e.args) == 1 && t.integer() && t.IsScalar() && args[0].integer() && args[0].IsScalar() {
+		// int(2u) and such keep their value for array sizes
+		x := c.expr(e.args[0], 0)
+		r.value = x.value
+	}
+	return r
+}
+
+// basicConstructor checks the arguments of a scalar, vector or matrix
+// constructor.
+func (c *checker) basicConstructor(e *callExpr, t *Type, args []*Type) bool {
+	if len(args) == 0 {
+		c.errorf(e.at, "%v constructor needs arguments", t)
+		return false
+	}
+	for i, a := range args {
+		if !a.basic() {
+			c.errorf(e.args[i].pos(), "cannot construct %v from %v", t, a)
+			return false
+		}
+	}
+	if len(args) == 1 {
+		// conversion, splat, or matrix from matrix
+		return true
+	}
+	if t.IsScalar() {
+		c.errorf(e.at, "%v constructor takes one argument", t)
+		return false
+	}
+	for i, a := range args {
+		if t.IsMatrix() && a.IsMatrix() {
+			c.errorf(e.args[i].pos(), "a matrix can only be constructed from one matrix")
+			return false
+		}
+	}
+
+	need := t.components()
+	have := 0
+	for i, a := range args {
+		if have >= need {
+			c.errorf(e.args[i].pos(), "too many arguments for %v constructor", t)
+			return false
+		}
+		have += a.components()
+	}
+	if have < need {
+		c.errorf(e.at, "not enough values for %v constructor: %d of %d", t, have, need)
+		return false
+	}
+	return true
+}
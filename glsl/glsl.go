@@ -0,0 +1,214 @@
+// Package glsl is a front end for the GLSL 1.50 shaders the exercises use.
+// It preprocesses, parses and type checks a shader without a GL context,
+// so mistakes the driver would only report at run time can be caught by
+// tests and by the lint command:
+//
+//	sh, errs := glsl.Check(glsl.Fragment, src)
+//
+// Check reports syntax errors, undeclared identifiers and type mismatches
+// and records which of the shader's inputs, outputs and uniforms are read
+// and written. Link compares the interface of a vertex and a fragment
+// shader the way the linker does.
+//
+// Only what GLSL 1.50 core allows is accepted; geometry shaders, uniform
+// block layouts and the ## operator are not supported.
+package glsl
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Stage is the pipeline stage a shader is compiled for.
+type Stage int
+
+const (
+	Vertex Stage = iota
+	Fragment
+)
+
+func (s Stage) String() string {
+	if s == Vertex {
+		return "vertex"
+	}
+	return "fragment"
+}
+
+// Pos is a position in the shader source as the driver would report it:
+// Source is the source string number set by #line directives.
+type Pos struct {
+	Source int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d(%d)", p.Source, p.Line, p.Column)
+}
+
+func (p Pos) before(q Pos) bool {
+	if p.Source != q.Source {
+		return p.Source < q.Source
+	}
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.Column < q.Column
+}
+
+// Error is a problem found in a shader. Warnings are things a driver
+// accepts that are most likely mistakes anyway.
+type Error struct {
+	Stage   Stage
+	Pos     Pos
+	Msg     string
+	Warning bool
+}
+
+// Error formats e like Mesa formats its compile log.
+func (e Error) Error() string {
+	severity := "error"
+	if e.Warning {
+		severity = "warning"
+	}
+	return fmt.Sprintf("%v: %s: %s", e.Pos, severity, e.Msg)
+}
+
+func sortErrors(errs []Error) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Stage != errs[j].Stage {
+			return errs[i].Stage < errs[j].Stage
+		}
+		return errs[i].Pos.before(errs[j].Pos)
+	})
+}
+
+// Storage is how a variable gets its value.
+type Storage int
+
+const (
+	Local Storage = iota
+	Const
+	In
+	Out
+	Uniform
+	Param
+)
+
+// Variable is a variable declared by a shader or built into the language.
+// Read and Written record whether the shader uses it that way anywhere.
+type Variable struct {
+	Name    string
+	Type    *Type
+	Storage Storage
+	Pos     Pos
+	Flat    bool
+	Builtin bool
+	Read    bool
+	Written bool
+
+	// readonly is set for const parameters; value holds the value of
+	// integer constants.
+	readonly bool
+	value    *int64
+}
+
+// Shader is the interface of a checked shader.
+type Shader struct {
+	Stage   Stage
+	Version int
+
+	// Inputs, Outputs and Uniforms are the global variables the shader
+	// declares, in order. Built-in variables are not included.
+	Inputs   []*Variable
+	Outputs  []*Variable
+	Uniforms []*Variable
+}
+
+// Uniform returns the uniform called name, or nil.
+func (s *Shader) Uniform(name string) *Variable {
+	return lookupVar(s.Uniforms, name)
+}
+
+// Input returns the input called name, or nil.
+func (s *Shader) Input(name string) *Variable {
+	return lookupVar(s.Inputs, name)
+}
+
+// Output returns the output called name, or nil.
+func (s *Shader) Output(name string) *Variable {
+	return lookupVar(s.Outputs, name)
+}
+
+func lookupVar(vars []*Variable, name string) *Variable {
+	for _, v := range vars {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Check preprocesses, parses and type checks src as a shader of stage. The
+// Shader is returned even if there are errors; it then describes whatever
+// could be checked. Errors are sorted by position.
+func Check(stage Stage, src string) (*Shader, []Error) {
+	sh := &Shader{Stage: stage}
+	toks, version, errs := scan(src)
+	sh.Version = version
+	if len(errs) == 0 {
+		unit, err := parse(toks)
+		if err != nil {
+			errs = append(errs, *err)
+		} else {
+			c := newChecker(sh)
+			c.unit(unit)
+			errs = append(errs, c.errs...)
+		}
+	}
+	for i := range errs {
+		errs[i].Stage = stage
+	}
+	sortErrors(errs)
+	return sh, errs
+}
+
+// Link checks that the outputs of vertex match the inputs of fragment and
+// that uniforms both declare agree in type. Outputs nothing reads are
+// reported as warnings.
+func Link(vertex, fragment *Shader) []Error {
+	var errs []Error
+	report := func(stage Stage, pos Pos, warning bool, format string, args ...interface{}) {
+		errs = append(errs, Error{Stage: stage, Pos: pos, Msg: fmt.Sprintf(format, args...), Warning: warning})
+	}
+
+	for _, in := range fragment.Inputs {
+		out := vertex.Output(in.Name)
+		switch {
+		case out == nil:
+			report(Fragment, in.Pos, false, "input %s is not an output of the vertex shader", in.Name)
+		case !out.Type.Equal(in.Type):
+			report(Fragment, in.Pos, false, "input %s is %v here but %v in the vertex shader", in.Name, in.Type, out.Type)
+		case out.Flat != in.Flat:
+			report(Fragment, in.Pos, false, "input %s is interpolated differently in the vertex shader", in.Name)
+		case in.Read && !out.Written:
+			report(Vertex, out.Pos, false, "output %s is read by the fragment shader but never written", out.Name)
+		}
+	}
+	for _, out := range vertex.Outputs {
+		in := fragment.Input(out.Name)
+		switch {
+		case in == nil:
+			report(Vertex, out.Pos, true, "output %s is not an input of the fragment shader", out.Name)
+		case !in.Read && in.Type.Equal(out.Type):
+			report(Vertex, out.Pos, true, "output %s is not used by the fragment shader", out.Name)
+		}
+	}
+	for _, u := range fragment.Uniforms {
+		if v := vertex.Uniform(u.Name); v != nil && !v.Type.Equal(u.Type) {
+			report(Fragment, u.Pos, false, "uniform %s is %v here but %v in the vertex shader", u.Name, u.Type, v.Type)
+		}
+	}
+	sortErrors(errs)
+	return errs
+}
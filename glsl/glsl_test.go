@@ -0,0 +1,136 @@
+package glsl
+
+import (
+	"strings"
+	"testing"
+)
+
+const vertexShader = `#version 150
+
+in vec2 position;
+in vec3 color;
+
+out vec3 Color;
+out vec2 Texcoord;
+
+uniform mat4 model;
+
+void main()
+{
+	Color = color;
+	gl_Position = model * vec4(position, 0.0, 1.0);
+}
+`
+
+const fragmentShader = `#version 150
+
+in vec3 Color;
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+uniform sampler2D tex;
+
+void main()
+{
+	outColor = texture(tex, Texcoord) * vec4(Color, 1.0);
+}
+`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		stage Stage
+		src   string
+		err   string // substring of the first error, or "" for none
+	}{
+		{Vertex, vertexShader, ""},
+		{Fragment, fragmentShader, ""},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = vec4(1.0) }\n", "0:3(29): error: syntax error"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = colour; }\n", "undeclared identifier colour"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = vec3(1.0); }\n", "cannot assign vec3 to vec4"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = vec4(1.0, 2.0); }\n", "not enough values for vec4"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = vec4(vec3(vec2(1.0), vec2(1.0), 1.0), 1.0); }\n", "too many arguments"},
+		{Vertex, "#version 150\nuniform mat3 m;\nvoid main() { gl_Position = m * vec4(1.0); }\n", "operator * can't be applied to mat3 and vec4"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c.xx = vec2(1.0); }\n", "repeats x"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { c = vec4(1.0).xyq; }\n", "mixes component sets"},
+		{Fragment, "#version 150\nin vec4 v;\nout vec4 c;\nvoid main() { v = c; }\n", "cannot assign to input v"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { if (1) c = vec4(0.0); }\n", "if condition must be a bool"},
+		{Fragment, "#version 150\nout vec4 c;\nvoid main() { float a[2]; c = vec4(a[2]); }\n", "index 2 out of range"},
+		{Fragment, "#version 150\nflat in int i;\nout vec4 c;\nvoid main() { c = vec4(i % 2.0); }\n", "operator % can't be applied"},
+		{Fragment, "#version 150\nin int i;\nout vec4 c;\nvoid main() { c = vec4(i); }\n", "integer input i must be flat"},
+		{Vertex, "#version 150\nvoid main() { discard; }\n", "discard is only for fragment shaders"},
+		{Vertex, "#version 150\nuniform sampler2D t;\nvoid main() { gl_Position = texture(t, vec2(0.0), 1.0); }\n", "only available in fragment shaders"},
+		{Fragment, "#version 150\nout vec4 c;\nfloat f(float x) { return vec2(x); }\nvoid main() { c = vec4(f(1)); }\n", "cannot return vec2"},
+		{Fragment, "#version 150\nout vec4 c;\n", "no main function"},
+		{Fragment, "#version 150\n#define N 3\nuniform float w[N];\nout vec4 c;\nvoid main() { c = vec4(w[N - 1]); }\n", ""},
+		{Fragment, "#version 150\n#if 0\nsyntax error\n#endif\nout vec4 c;\nvoid main() { c = vec4(1.0); }\n", ""},
+		{Fragment, "#version 150\n#ifdef MISSING\n#error MISSING is set\n#endif\n#ifndef MISSING\n#error MISSING is not set\n#endif\n", "MISSING is not set"},
+	}
+	for _, test := range tests {
+		_, errs := Check(test.stage, test.src)
+		var first string
+		for _, e := range errs {
+			if !e.Warning {
+				first = e.Error()
+				break
+			}
+		}
+		switch {
+		case test.err == "" && first != "":
+			t.Errorf("Check(%q): unexpected error %s", test.src, first)
+		case test.err != "" && !strings.Contains(first, test.err):
+			t.Errorf("Check(%q) = %q, want %q", test.src, first, test.err)
+		}
+	}
+}
+
+func TestCheckRecordsUse(t *testing.T) {
+	sh, errs := Check(Fragment, fragmentShader)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if in := sh.Input("Color"); in == nil || !in.Read || in.Type.String() != "vec3" {
+		t.Errorf("Color = %+v, want a vec3 that is read", in)
+	}
+	if out := sh.Output("outColor"); out == nil || !out.Written {
+		t.Errorf("outColor = %+v, want an output that is written", out)
+	}
+	if u := sh.Uniform("tex"); u == nil || u.Type.String() != "sampler2D" {
+		t.Errorf("tex = %+v, want a sampler2D uniform", u)
+	}
+}
+
+func TestLink(t *testing.T) {
+	vertex, _ := Check(Vertex, vertexShader)
+	fragment, _ := Check(Fragment, fragmentShader)
+	errs := Link(vertex, fragment)
+	if len(errs) != 1 || errs[0].Warning || !strings.Contains(errs[0].Msg, "Texcoord is read by the fragment shader but never written") {
+		t.Errorf("Link = %v, want an error for Texcoord", errs)
+	}
+
+	unused := strings.Replace(fragmentShader, "* vec4(Color, 1.0)", "", 1)
+	fragment, _ = Check(Fragment, unused)
+	errs = Link(vertex, fragment)
+	found := false
+	for _, e := range errs {
+		if e.Warning && e.Stage == Vertex && strings.Contains(e.Msg, "output Color is not used") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Link = %v, want a warning for Color", errs)
+	}
+
+	missing := strings.Replace(fragmentShader, "in vec2 Texcoord;", "in vec2 Texcoord;\nin float Fog;", 1)
+	fragment, _ = Check(Fragment, missing)
+	errs = Link(vertex, fragment)
+	found = false
+	for _, e := range errs {
+		if !e.Warning && e.Stage == Fragment && strings.Contains(e.Msg, "input Fog is not an output of the vertex shader") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Link = %v, want an error for Fog", errs)
+	}
+}
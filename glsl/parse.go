@@ -0,0 +1,643 @@
+package glsl
+
+import "fmt"
+
+// keywords can't be used as names.
+var keywords = map[string]bool{
+	"attribute": true, "const": true, "uniform": true, "varying": true,
+	"layout": true, "centroid": true, "flat": true, "smooth": true,
+	"noperspective": true, "break": true, "continue": true, "do": true,
+	"for": true, "while": true, "switch": true, "case": true,
+	"default": true, "if": true, "else": true, "in": true, "out": true,
+	"inout": true, "true": true, "false": true, "invariant": true,
+	"discard": true, "return": true, "struct": true, "precision": true,
+	"lowp": true, "mediump": true, "highp": true,
+}
+
+var storageQualifiers = map[string]bool{
+	"const": true, "in": true, "out": true, "inout": true,
+	"uniform": true, "attribute": true, "varying": true,
+}
+
+var interpolationQualifiers = map[string]bool{
+	"flat": true, "smooth": true, "noperspective": true,
+}
+
+var precisionQualifiers = map[string]bool{
+	"lowp": true, "mediump": true, "highp": true,
+}
+
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"<<=": true, ">>=": true, "&=": true, "^=": true, "|=": true,
+}
+
+var binaryPrecedence = map[string]int{
+	"||": 1, "^^": 2, "&&": 3, "|": 4, "^": 5, "&": 6,
+	"==": 7, "!=": 7, "<": 8, ">": 8, "<=": 8, ">=": 8,
+	"<<": 9, ">>": 9, "+": 10, "-": 10, "*": 11, "/": 11, "%": 11,
+}
+
+type parser struct {
+	toks    []token
+	i       int
+	structs map[string]bool
+	err     *Error
+}
+
+// bailout is panicked with to stop at the first syntax error.
+type bailout struct{}
+
+func parse(toks []token) (u *unit, err *Error) {
+	p := &parser{toks: toks, structs: make(map[string]bool)}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			u, err = nil, p.err
+		}
+	}()
+
+	u = new(unit)
+	for p.peek().kind != tokEOF {
+		if d := p.externalDecl(); d != nil {
+			u.decls = append(u.decls, d)
+		}
+	}
+	u.end = p.peek().pos
+	return u, nil
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) {
+	p.err = &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	panic(bailout{})
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.i+n >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.i+n]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return t.text == text && (t.kind == tokPunct || t.kind == tokIdent)
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	if !p.is(text) {
+		p.errorf(p.peek().pos, "syntax error: expected %q, found %v", text, p.peek())
+	}
+	return p.next()
+}
+
+func (p *parser) ident() token {
+	t := p.peek()
+	if t.kind != tokIdent || keywords[t.text] {
+		p.errorf(t.pos, "syntax error: expected a name, found %v", t)
+	}
+	if p.isTypeName(t) {
+		p.errorf(t.pos, "syntax error: %s is a type name", t.text)
+	}
+	return p.next()
+}
+
+func (p *parser) isTypeName(t token) bool {
+	return t.kind == tokIdent && (typeNames[t.text] != nil || p.structs[t.text])
+}
+
+func (p *parser) isQualifier(t token) bool {
+	return t.kind == tokIdent && (storageQualifiers[t.text] || interpolationQualifiers[t.text] ||
+		precisionQualifiers[t.text] || t.text == "layout" || t.text == "centroid" || t.text == "invariant")
+}
+
+// isDecl reports whether a declaration starts at the current token.
+func (p *parser) isDecl() bool {
+	t := p.peek()
+	if p.isQualifier(t) || t.text == "struct" && t.kind == tokIdent {
+		return true
+	}
+	if !p.isTypeName(t) {
+		return false
+	}
+	n := 1
+	if p.peekAt(1).text == "[" {
+		for depth := 0; ; n++ {
+			switch next := p.peekAt(n); {
+			case next.kind == tokEOF:
+				return false
+			case next.text == "[":
+				depth++
+			case next.text == "]":
+				depth--
+			}
+			if depth == 0 {
+				n++
+				break
+			}
+		}
+	}
+	return p.peekAt(n).kind == tokIdent
+}
+
+func (p *parser) externalDecl() interface{} {
+	if p.accept(";") {
+		return nil
+	}
+	if p.is("precision") {
+		for !p.accept(";") {
+			if p.peek().kind == tokEOF {
+				p.errorf(p.peek().pos, "syntax error: unterminated precision statement")
+			}
+			p.next()
+		}
+		return nil
+	}
+
+	at := p.peek().pos
+	qual := p.qualifiers()
+	t := p.peek()
+	if qual.invariant && qual.storage == "" && t.kind == tokIdent && !p.isTypeName(t) {
+		d := &invariantDecl{at: at}
+		for {
+			d.names = append(d.names, p.ident().text)
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.expect(";")
+		return d
+	}
+	if qual.storage != "" && t.kind == tokIdent && !p.isTypeName(t) && p.peekAt(1).text == "{" {
+		return p.blockDecl(at, qual)
+	}
+
+	typ := p.typeSpec()
+	if p.accept(";") {
+		return &varDecl{at: at, qual: qual, typ: typ}
+	}
+	name := p.ident()
+	if p.is("(") {
+		if qual.storage != "" || qual.interp != "" || qual.centroid || qual.invariant {
+			p.errorf(at, "syntax error: qualifiers on a function")
+		}
+		return p.funcDecl(typ, name)
+	}
+	return p.varDeclRest(at, qual, typ, name)
+}
+
+func (p *parser) qualifiers() qualifiers {
+	var q qualifiers
+	for {
+		t := p.peek()
+		if t.kind != tokIdent {
+			return q
+		}
+		switch {
+		case t.text == "layout":
+			p.next()
+			p.expect("(")
+			for {
+				lq := layoutQualifier{at: p.peek().pos, name: p.ident().text}
+				if p.accept("=") {
+					lq.value = p.condExpr()
+				}
+				q.layout = append(q.layout, lq)
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(")")
+			continue
+		case storageQualifiers[t.text]:
+			if q.storage != "" {
+				p.errorf(t.pos, "syntax error: %s after %s", t.text, q.storage)
+			}
+			q.storage = t.text
+		case interpolationQualifiers[t.text]:
+			q.interp = t.text
+		case t.text == "centroid":
+			q.centroid = true
+		case t.text == "invariant":
+			q.invariant = true
+		case precisionQualifiers[t.text]:
+		default:
+			return q
+		}
+		p.next()
+	}
+}
+
+func (p *parser) typeSpec() *typeSpec {
+	t := p.peek()
+	spec := &typeSpec{at: t.pos}
+	switch {
+	case t.text == "struct" && t.kind == tokIdent:
+		spec.strct = p.structSpec()
+		spec.name = spec.strct.name
+	case p.isTypeName(t):
+		spec.name = p.next().text
+	default:
+		p.errorf(t.pos, "syntax error: expected a type, found %v", t)
+	}
+	spec.array = p.arraySpec()
+	return spec
+}
+
+func (p *parser) arraySpec() *arraySpec {
+	if !p.is("[") {
+		return nil
+	}
+	a := &arraySpec{at: p.next().pos}
+	if !p.is("]") {
+		a.size = p.condExpr()
+	}
+	p.expect("]")
+	return a
+}
+
+func (p *parser) structSpec() *structSpec {
+	s := &structSpec{at: p.expect("struct").pos}
+	if !p.is("{") {
+		s.name = p.ident().text
+		p.structs[s.name] = true
+	}
+	p.expect("{")
+	for !p.accept("}") {
+		s.fields = append(s.fields, p.memberDecl())
+	}
+	if len(s.fields) == 0 {
+		p.errorf(s.at, "syntax error: empty struct")
+	}
+	return s
+}
+
+// memberDecl parses a field of a struct or interface block.
+func (p *parser) memberDecl() *varDecl {
+	at := p.peek().pos
+	d := &varDecl{at: at, qual: p.qualifiers(), typ: p.typeSpec()}
+	for {
+		t := p.ident()
+		d.vars = append(d.vars, &declarator{at: t.pos, name: t.text, array: p.arraySpec()})
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(";")
+	return d
+}
+
+func (p *parser) blockDecl(at Pos, qual qualifiers) *blockDecl {
+	b := &blockDecl{at: at, qual: qual, name: p.ident().text}
+	p.expect("{")
+	for !p.accept("}") {
+		b.fields = append(b.fields, p.memberDecl())
+	}
+	if !p.is(";") {
+		b.instance = p.ident().text
+		b.array = p.arraySpec()
+	}
+	p.expect(";")
+	return b
+}
+
+// varDeclRest parses the declarators of a declaration whose first name
+// has been read.
+func (p *parser) varDeclRest(at Pos, qual qualifiers, typ *typeSpec, name token) *varDecl {
+	d := &varDecl{at: at, qual: qual, typ: typ}
+	for {
+		v := &declarator{at: name.pos, name: name.text, array: p.arraySpec()}
+		if p.accept("=") {
+			v.init = p.assignExpr()
+		}
+		d.vars = append(d.vars, v)
+		if !p.accept(",") {
+			break
+		}
+		name = p.ident()
+	}
+	p.expect(";")
+	return d
+}
+
+func (p *parser) funcDecl(ret *typeSpec, name token) *funcDecl {
+	f := &funcDecl{at: name.pos, ret: ret, name: name.text}
+	p.expect("(")
+	if p.is("void") && p.peekAt(1).text == ")" {
+		p.next()
+	}
+	for !p.is(")") {
+		if len(f.params) > 0 {
+			p.expect(",")
+		}
+		prm := &param{at: p.peek().pos}
+		q := p.qualifiers()
+		prm.qual = q.storage
+		switch prm.qual {
+		case "", "in", "out", "inout", "const":
+		default:
+			p.errorf(prm.at, "syntax error: %s parameter", prm.qual)
+		}
+		prm.typ = p.typeSpec()
+		if p.peek().kind == tokIdent && !keywords[p.peek().text] {
+			t := p.ident()
+			prm.at, prm.name = t.pos, t.text
+			prm.array = p.arraySpec()
+		}
+		f.params = append(f.params, prm)
+	}
+	p.expect(")")
+	if !p.accept(";") {
+		f.body = p.block()
+	}
+	return f
+}
+
+func (p *parser) block() *blockStmt {
+	b := &blockStmt{at: p.expect("{").pos}
+	for !p.accept("}") {
+		if p.peek().kind == tokEOF {
+			p.errorf(p.peek().pos, "syntax error: missing }")
+		}
+		b.list = append(b.list, p.statement())
+	}
+	return b
+}
+
+func (p *parser) statement() stmt {
+	t := p.peek()
+	if t.kind == tokIdent || t.kind == tokPunct {
+		switch t.text {
+		case "{":
+			return p.block()
+		case ";":
+			p.next()
+			return &blockStmt{at: t.pos}
+		case "if":
+			p.next()
+			s := &ifStmt{at: t.pos}
+			p.expect("(")
+			s.cond = p.expr()
+			p.expect(")")
+			s.then = p.statement()
+			if p.accept("else") {
+				s.els = p.statement()
+			}
+			return s
+		case "for":
+			p.next()
+			s := &forStmt{at: t.pos}
+			p.expect("(")
+			switch {
+			case p.accept(";"):
+			case p.isDecl():
+				s.init = &declStmt{p.localDecl()}
+			default:
+				s.init = &exprStmt{p.expr()}
+				p.expect(";")
+			}
+			if !p.is(";") {
+				s.cond = p.expr()
+			}
+			p.expect(";")
+			if !p.is(")") {
+				s.post = p.expr()
+			}
+			p.expect(")")
+			s.body = p.statement()
+			return s
+		case "while":
+			p.next()
+			s := &whileStmt{at: t.pos}
+			p.expect("(")
+			s.cond = p.expr()
+			p.expect(")")
+			s.body = p.statement()
+			return s
+		case "do":
+			p.next()
+			s := &doStmt{at: t.pos, body: p.statement()}
+			p.expect("while")
+			p.expect("(")
+			s.cond = p.expr()
+			p.expect(")")
+			p.expect(";")
+			return s
+		case "return":
+			p.next()
+			s := &returnStmt{at: t.pos}
+			if !p.is(";") {
+				s.x = p.expr()
+			}
+			p.expect(";")
+			return s
+		case "break", "continue", "discard":
+			p.next()
+			p.expect(";")
+			return &branchStmt{at: t.pos, tok: t.text}
+		case "switch":
+			p.next()
+			s := &switchStmt{at: t.pos}
+			p.expect("(")
+			s.tag = p.expr()
+			p.expect(")")
+			s.body = p.block()
+			return s
+		case "case":
+			p.next()
+			s := &caseStmt{at: t.pos, x: p.expr()}
+			p.expect(":")
+			return s
+		case "default":
+			p.next()
+			p.expect(":")
+			return &caseStmt{at: t.pos}
+		case "else":
+			p.errorf(t.pos, "syntax error: else without if")
+		}
+	}
+	if p.isDecl() {
+		return &declStmt{p.localDecl()}
+	}
+	s := &exprStmt{p.expr()}
+	p.expect(";")
+	return s
+}
+
+func (p *parser) localDecl() *varDecl {
+	at := p.peek().pos
+	qual := p.qualifiers()
+	typ := p.typeSpec()
+	if p.accept(";") {
+		return &varDecl{at: at, qual: qual, typ: typ}
+	}
+	return p.varDeclRest(at, qual, typ, p.ident())
+}
+
+// expr parses an expression, including the comma operator.
+func (p *parser) expr() expr {
+	x := p.assignExpr()
+	if !p.is(",") {
+		return x
+	}
+	seq := &seqExpr{at: x.pos(), list: []expr{x}}
+	for p.accept(",") {
+		seq.list = append(seq.list, p.assignExpr())
+	}
+	return seq
+}
+
+func (p *parser) assignExpr() expr {
+	x := p.condExpr()
+	if t := p.peek(); t.kind == tokPunct && assignOps[t.text] {
+		p.next()
+		return &assignExpr{at: t.pos, op: t.text, lhs: x, rhs: p.assignExpr()}
+	}
+	return x
+}
+
+func (p *parser) condExpr() expr {
+	x := p.binaryExpr(0)
+	if t := p.peek(); t.text == "?" && t.kind == tokPunct {
+		p.next()
+		c := &condExpr{at: t.pos, cond: x, then: p.expr()}
+		p.expect(":")
+		c.els = p.assignExpr()
+		return c
+	}
+	return x
+}
+
+func (p *parser) binaryExpr(min int) expr {
+	x := p.unaryExpr()
+	for {
+		t := p.peek()
+		prec, ok := binaryPrecedence[t.text]
+		if t.kind != tokPunct || !ok || prec <= min {
+			return x
+		}
+		p.next()
+		x = &binaryExpr{at: t.pos, op: t.text, x: x, y: p.binaryExpr(prec)}
+	}
+}
+
+func (p *parser) unaryExpr() expr {
+	t := p.peek()
+	if t.kind == tokPunct {
+		switch t.text {
+		case "+", "-", "!", "~", "++", "--":
+			p.next()
+			return &unaryExpr{at: t.pos, op: t.text, x: p.unaryExpr()}
+		}
+	}
+	return p.postfixExpr()
+}
+
+func (p *parser) postfixExpr() expr {
+	x := p.primaryExpr()
+	for {
+		t := p.peek()
+		if t.kind != tokPunct {
+			return x
+		}
+		switch t.text {
+		case "[":
+			p.next()
+			x = &indexExpr{at: t.pos, x: x, index: p.expr()}
+			p.expect("]")
+		case ".":
+			p.next()
+			name := p.peek()
+			if name.kind != tokIdent {
+				p.errorf(name.pos, "syntax error: expected a field name, found %v", name)
+			}
+			p.next()
+			if name.text == "length" && p.is("(") {
+				p.next()
+				p.expect(")")
+				x = &lengthExpr{at: name.pos, x: x}
+				continue
+			}
+			x = &fieldExpr{at: name.pos, x: x, name: name.text}
+		case "++", "--":
+			p.next()
+			x = &unaryExpr{at: t.pos, op: t.text, x: x, postfix: true}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) primaryExpr() expr {
+	t := p.peek()
+	switch t.kind {
+	case tokInt, tokUint, tokFloat:
+		p.next()
+		return &literalExpr{at: t.pos, kind: t.kind, text: t.text}
+	case tokPunct:
+		if t.text == "(" {
+			p.next()
+			x := p.expr()
+			p.expect(")")
+			return x
+		}
+	case tokIdent:
+		switch {
+		case t.text == "true" || t.text == "false":
+			p.next()
+			return &literalExpr{at: t.pos, kind: tokIdent, text: t.text}
+		case p.isTypeName(t) || t.text == "struct":
+			typ := p.typeSpec()
+			if typ.strct != nil {
+				p.errorf(t.pos, "syntax error: struct declared in an expression")
+			}
+			return &callExpr{at: t.pos, name: typ.name, typ: typ, args: p.args()}
+		case keywords[t.text]:
+			p.errorf(t.pos, "syntax error: unexpected %s", t.text)
+		}
+		p.next()
+		if p.is("(") {
+			return &callExpr{at: t.pos, name: t.text, args: p.args()}
+		}
+		return &identExpr{at: t.pos, name: t.text}
+	}
+	p.errorf(t.pos, "syntax error: unexpected %v", t)
+	return nil
+}
+
+func (p *parser) args() []expr {
+	p.expect("(")
+	var args []expr
+	if p.is("void") && p.peekAt(1).text == ")" {
+		p.next()
+	}
+	for !p.accept(")") {
+		if len(args) > 0 {
+			p.expect(",")
+		}
+		args = append(args, p.assignExpr())
+	}
+	return args
+}
@@ -0,0 +1,661 @@
+package glsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokUint
+	tokFloat
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of file"
+	}
+	return strconv.Quote(t.text)
+}
+
+// punctuators are the operators and separators, longest first.
+var punctuators = []string{
+	"<<=", ">>=",
+	"++", "--", "<=", ">=", "==", "!=", "&&", "||", "^^", "<<", ">>",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "&", "|", "^",
+	"?", ":", ";", ",", ".", "(", ")", "{", "}", "[", "]",
+}
+
+type macro struct {
+	function bool
+	params   []string
+	body     []token
+}
+
+// cond is an #if, #ifdef or #ifndef being processed.
+type cond struct {
+	active  bool // lines are compiled
+	taken   bool // a branch of this conditional was active
+	outer   bool // the enclosing conditional was active
+	sawElse bool
+}
+
+// scanner preprocesses a shader and splits it into tokens.
+type scanner struct {
+	tokens  []token
+	errs    []Error
+	macros  map[string]*macro
+	conds   []cond
+	version int
+
+	// the line after physical line lineBase-1 is line lineNum of source
+	// string source, as set by #line
+	lineBase int
+	lineNum  int
+	source   int
+}
+
+// scan preprocesses src and returns its tokens, ending with a tokEOF, and
+// the #version it declares.
+func scan(src string) ([]token, int, []Error) {
+	s := &scanner{macros: make(map[string]*macro), lineNum: 1}
+	s.macros["GL_core_profile"] = &macro{body: []token{{kind: tokInt, text: "1"}}}
+	s.macros["__VERSION__"] = &macro{body: []token{{kind: tokInt, text: "150"}}}
+
+	text, ok := stripComments(src)
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		pos := s.pos(i, 1)
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSuffix(lines[i], "\r")
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "#") {
+			pos.Column += len(line) - len(trimmed)
+			s.directive(trimmed[1:], pos, i)
+			continue
+		}
+		if !s.active() {
+			continue
+		}
+		s.tokens = append(s.tokens, s.expand(s.tokenize(line, pos), nil)...)
+	}
+
+	end := s.pos(len(lines)-1, len(lines[len(lines)-1])+1)
+	if !ok {
+		s.errorf(end, "unterminated comment")
+	}
+	if len(s.conds) > 0 {
+		s.errorf(end, "missing #endif")
+	}
+	s.tokens = append(s.tokens, token{kind: tokEOF, pos: end})
+	return s.tokens, s.version, s.errs
+}
+
+func (s *scanner) errorf(pos Pos, format string, args ...interface{}) {
+	s.errs = append(s.errs, Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// pos returns the position of column col of physical line i.
+func (s *scanner) pos(i, col int) Pos {
+	return Pos{Source: s.source, Line: i - s.lineBase + s.lineNum, Column: col}
+}
+
+func (s *scanner) active() bool {
+	return len(s.conds) == 0 || s.conds[len(s.conds)-1].active
+}
+
+// stripComments replaces comments with spaces, keeping line breaks so
+// positions stay the same. It reports false if a comment isn't closed.
+func stripComments(src string) (string, bool) {
+	b := []byte(src)
+	for i := 0; i < len(b); i++ {
+		if b[i] != '/' || i+1 == len(b) {
+			continue
+		}
+		switch b[i+1] {
+		case '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case '*':
+			b[i], b[i+1] = ' ', ' '
+			for i += 2; ; i++ {
+				if i+1 >= len(b) {
+					for ; i < len(b); i++ {
+						b[i] = ' '
+					}
+					return string(b), false
+				}
+				if b[i] == '*' && b[i+1] == '/' {
+					b[i], b[i+1] = ' ', ' '
+					i++
+					break
+				}
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+		}
+	}
+	return string(b), true
+}
+
+// tokenize splits one line into tokens; pos is where the line starts.
+func (s *scanner) tokenize(line string, pos Pos) []token {
+	var toks []token
+	for i := 0; i < len(line); {
+		c := line[i]
+		at := pos
+		at.Column += i
+		switch {
+		case c == ' ' || c == '\t' || c == '\f' || c == '\v' || c == '\r':
+			i++
+		case isLetter(c):
+			j := i + 1
+			for j < len(line) && (isLetter(line[j]) || isDigit(line[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: line[i:j], pos: at})
+			i = j
+		case isDigit(c) || c == '.' && i+1 < len(line) && isDigit(line[i+1]):
+			kind, n := scanNumber(line[i:])
+			if n < len(line)-i && (isLetter(line[i+n]) || isDigit(line[i+n])) {
+				s.errorf(at, "invalid number %s", line[i:i+n+1])
+				return toks
+			}
+			toks = append(toks, token{kind: kind, text: line[i : i+n], pos: at})
+			i += n
+		default:
+			p := ""
+			for _, punct := range punctuators {
+				if strings.HasPrefix(line[i:], punct) {
+					p = punct
+					break
+				}
+			}
+			if p == "" {
+				s.errorf(at, "unexpected character %q", c)
+				return toks
+			}
+			toks = append(toks, token{kind: tokPunct, text: p, pos: at})
+			i += len(p)
+		}
+	}
+	return toks
+}
+
+// scanNumber returns the kind and length of the number literal s starts
+// with.
+func scanNumber(s string) (tokenKind, int) {
+	i := 0
+	digits := func(ok func(byte) bool) {
+		for i < len(s) && ok(s[i]) {
+			i++
+		}
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		i = 2
+		digits(isHex)
+	} else {
+		digits(isDigit)
+		float := false
+		if i < len(s) && s[i] == '.' {
+			float = true
+			i++
+			digits(isDigit)
+		}
+		if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+			j := i + 1
+			if j < len(s) && (s[j] == '+' || s[j] == '-') {
+				j++
+			}
+			if j < len(s) && isDigit(s[j]) {
+				float = true
+				i = j
+				digits(isDigit)
+			}
+		}
+		if float {
+			if i < len(s) && (s[i] == 'f' || s[i] == 'F') {
+				i++
+			}
+			return tokFloat, i
+		}
+	}
+	if i < len(s) && (s[i] == 'u' || s[i] == 'U') {
+		return tokUint, i + 1
+	}
+	return tokInt, i
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// directive handles a preprocessor line, without the #, at physical
+// line i.
+func (s *scanner) directive(line string, pos Pos, i int) {
+	name := line
+	rest := ""
+	if j := strings.IndexAny(line, " \t("); j >= 0 {
+		name, rest = line[:j], line[j:]
+	}
+	name = strings.TrimSpace(name)
+	restPos := pos
+	restPos.Column += 1 + len(name)
+
+	switch name {
+	case "if", "ifdef", "ifndef":
+		outer := s.active()
+		c := cond{outer: outer}
+		if outer {
+			c.active = s.condition(name, rest, restPos)
+			c.taken = c.active
+		}
+		s.conds = append(s.conds, c)
+		return
+	case "elif", "else", "endif":
+		if len(s.conds) == 0 {
+			s.errorf(pos, "#%s without #if", name)
+			return
+		}
+		c := &s.conds[len(s.conds)-1]
+		switch {
+		case name == "endif":
+			s.conds = s.conds[:len(s.conds)-1]
+		case c.sawElse:
+			s.errorf(pos, "#%s after #else", name)
+		case name == "else":
+			c.sawElse = true
+			c.active = c.outer && !c.taken
+			c.taken = c.taken || c.active
+		default:
+			c.active = c.outer && !c.taken && s.condition("if", rest, restPos)
+			c.taken = c.taken || c.active
+		}
+		return
+	}
+	if !s.active() {
+		return
+	}
+
+	switch name {
+	case "":
+	case "version":
+		fields := strings.Fields(rest)
+		v, err := strconv.Atoi(firstOr(fields, ""))
+		if err != nil {
+			s.errorf(pos, "invalid #version")
+			return
+		}
+		if len(fields) > 1 && fields[1] != "core" {
+			s.errorf(pos, "profile %s is not supported, only core", fields[1])
+		}
+		s.version = v
+	case "define":
+		s.define(rest, restPos)
+	case "undef":
+		delete(s.macros, strings.TrimSpace(rest))
+	case "line":
+		toks := s.expand(s.tokenize(rest, restPos), nil)
+		var nums []int
+		for _, t := range toks {
+			n, err := strconv.Atoi(t.text)
+			if t.kind != tokInt || err != nil {
+				s.errorf(t.pos, "#line expects numbers")
+				return
+			}
+			nums = append(nums, n)
+		}
+		if len(nums) < 1 || len(nums) > 2 {
+			s.errorf(pos, "#line expects a line and optionally a source string number")
+			return
+		}
+		s.lineBase, s.lineNum = i+1, nums[0]
+		if len(nums) == 2 {
+			s.source = nums[1]
+		}
+	case "error":
+		s.errorf(pos, "#error%s", rest)
+	case "extension", "pragma":
+	default:
+		s.errorf(pos, "unknown directive #%s", name)
+	}
+}
+
+func firstOr(fields []string, def string) string {
+	if len(fields) == 0 {
+		return def
+	}
+	return fields[0]
+}
+
+func (s *scanner) define(rest string, pos Pos) {
+	trimmed := strings.TrimLeft(rest, " \t")
+	pos.Column += len(rest) - len(trimmed)
+	n := 0
+	for n < len(trimmed) && (isLetter(trimmed[n]) || n > 0 && isDigit(trimmed[n])) {
+		n++
+	}
+	if n == 0 {
+		s.errorf(pos, "#define expects a name")
+		return
+	}
+	name := trimmed[:n]
+	if strings.HasPrefix(name, "GL_") || strings.Contains(name, "__") {
+		s.errorf(pos, "macro names starting with GL_ or containing __ are reserved")
+		return
+	}
+	m := new(macro)
+	body := trimmed[n:]
+	bodyPos := pos
+	bodyPos.Column += n
+	if strings.HasPrefix(body, "(") {
+		end := strings.Index(body, ")")
+		if end < 0 {
+			s.errorf(pos, "missing ) in parameter list of %s", name)
+			return
+		}
+		m.function = true
+		for _, p := range strings.Split(body[1:end], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				m.params = append(m.params, p)
+			}
+		}
+		body = body[end+1:]
+		bodyPos.Column += end + 1
+	}
+	m.body = s.tokenize(body, bodyPos)
+	s.macros[name] = m
+}
+
+// expand replaces the macros in toks. hide holds the macros being
+// expanded, which don't expand again.
+func (s *scanner) expand(toks []token, hide map[string]bool) []token {
+	var out []token
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		m := s.macros[t.text]
+		if t.kind != tokIdent || m == nil || hide[t.text] {
+			out = append(out, t)
+			continue
+		}
+
+		body := m.body
+		if m.function {
+			if i+1 == len(toks) || toks[i+1].text != "(" {
+				out = append(out, t)
+				continue
+			}
+			args, end := macroArgs(toks, i+1)
+			if end < 0 {
+				s.errorf(t.pos, "unterminated call of macro %s", t.text)
+				return out
+			}
+			i = end
+			if len(args) == 1 && len(args[0]) == 0 && len(m.params) == 0 {
+				args = nil
+			}
+			if len(args) != len(m.params) {
+				s.errorf(t.pos, "macro %s takes %d arguments, not %d", t.text, len(m.params), len(args))
+				continue
+			}
+			body = nil
+			for _, b := range m.body {
+				if j := indexOf(m.params, b.text); b.kind == tokIdent && j >= 0 {
+					body = append(body, s.expand(args[j], hide)...)
+				} else {
+					body = append(body, b)
+				}
+			}
+		}
+
+		inner := map[string]bool{t.text: true}
+		for name := range hide {
+			inner[name] = true
+		}
+		for _, b := range s.expand(body, inner) {
+			// errors in expansions are reported where the macro is used
+			b.pos = t.pos
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// macroArgs splits the arguments of a macro call whose ( is toks[open].
+// It returns the index of the closing ), or -1.
+func macroArgs(toks []token, open int) ([][]token, int) {
+	var args [][]token
+	var arg []token
+	depth := 0
+	for i := open + 1; i < len(toks); i++ {
+		switch t := toks[i]; {
+		case t.text == "(":
+			depth++
+		case t.text == ")" && depth == 0:
+			return append(args, arg), i
+		case t.text == ")":
+			depth--
+		case t.text == "," && depth == 0:
+			args = append(args, arg)
+			arg = nil
+			continue
+		}
+		arg = append(arg, toks[i])
+	}
+	return nil, -1
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// condition evaluates the condition of an #if, #ifdef or #ifndef.
+func (s *scanner) condition(directive, rest string, pos Pos) bool {
+	if directive != "if" {
+		name := strings.TrimSpace(rest)
+		if name == "" {
+			s.errorf(pos, "#%s expects a name", directive)
+			return false
+		}
+		_, defined := s.macros[name]
+		return defined == (directive == "ifdef")
+	}
+
+	// replace defined X and defined(X) before expanding macros
+	raw := s.tokenize(rest, pos)
+	var toks []token
+	for i := 0; i < len(raw); i++ {
+		if raw[i].text != "defined" {
+			toks = append(toks, raw[i])
+			continue
+		}
+		j := i + 1
+		paren := j < len(raw) && raw[j].text == "("
+		if paren {
+			j++
+		}
+		if j == len(raw) || raw[j].kind != tokIdent || paren && (j+1 == len(raw) || raw[j+1].text != ")") {
+			s.errorf(raw[i].pos, "defined expects a name")
+			return false
+		}
+		value := "0"
+		if _, ok := s.macros[raw[j].text]; ok {
+			value = "1"
+		}
+		toks = append(toks, token{kind: tokInt, text: value, pos: raw[i].pos})
+		if paren {
+			j++
+		}
+		i = j
+	}
+
+	e := &condEval{toks: s.expand(toks, nil), end: pos}
+	v := e.expr(0)
+	if e.err == nil && e.i < len(e.toks) {
+		e.fail(e.toks[e.i].pos, "unexpected %v in #if", e.toks[e.i])
+	}
+	if e.err != nil {
+		s.errs = append(s.errs, *e.err)
+		return false
+	}
+	return v != 0
+}
+
+// condEval evaluates the integer expression of an #if.
+type condEval struct {
+	toks []token
+	i    int
+	end  Pos
+	err  *Error
+}
+
+var condPrecedence = map[string]int{
+	"||": 1, "&&": 2, "|": 3, "^": 4, "&": 5,
+	"==": 6, "!=": 6, "<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8, "+": 9, "-": 9, "*": 10, "/": 10, "%": 10,
+}
+
+func (e *condEval) fail(pos Pos, format string, args ...interface{}) {
+	if e.err == nil {
+		e.err = &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+	}
+}
+
+func (e *condEval) expr(min int) int64 {
+	x := e.unary()
+	for e.err == nil && e.i < len(e.toks) {
+		op := e.toks[e.i]
+		prec, ok := condPrecedence[op.text]
+		if !ok || prec <= min {
+			break
+		}
+		e.i++
+		y := e.expr(prec)
+		x = e.binary(op, x, y)
+	}
+	return x
+}
+
+func (e *condEval) binary(op token, x, y int64) int64 {
+	b := func(v bool) int64 {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	switch op.text {
+	case "||":
+		return b(x != 0 || y != 0)
+	case "&&":
+		return b(x != 0 && y != 0)
+	case "|":
+		return x | y
+	case "^":
+		return x ^ y
+	case "&":
+		return x & y
+	case "==":
+		return b(x == y)
+	case "!=":
+		return b(x != y)
+	case "<":
+		return b(x < y)
+	case ">":
+		return b(x > y)
+	case "<=":
+		return b(x <= y)
+	case ">=":
+		return b(x >= y)
+	case "<<":
+		return x << uint(y)
+	case ">>":
+		return x >> uint(y)
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	}
+	if y == 0 {
+		e.fail(op.pos, "division by zero in #if")
+		return 0
+	}
+	if op.text == "/" {
+		return x / y
+	}
+	return x % y
+}
+
+func (e *condEval) unary() int64 {
+	if e.i == len(e.toks) {
+		e.fail(e.end, "#if expression ends unexpectedly")
+		return 0
+	}
+	t := e.toks[e.i]
+	e.i++
+	switch {
+	case t.text == "(":
+		v := e.expr(0)
+		if e.i == len(e.toks) || e.toks[e.i].text != ")" {
+			e.fail(t.pos, "missing ) in #if")
+			return 0
+		}
+		e.i++
+		return v
+	case t.text == "-":
+		return -e.unary()
+	case t.text == "+":
+		return e.unary()
+	case t.text == "!":
+		if e.unary() == 0 {
+			return 1
+		}
+		return 0
+	case t.text == "~":
+		return ^e.unary()
+	case t.kind == tokInt || t.kind == tokUint:
+		v, err := strconv.ParseInt(strings.TrimRight(t.text, "uU"), 0, 64)
+		if err != nil {
+			e.fail(t.pos, "invalid number %s", t.text)
+		}
+		return v
+	case t.kind == tokIdent:
+		e.fail(t.pos, "undefined macro %s in #if", t.text)
+		return 0
+	}
+	e.fail(t.pos, "unexpected %v in #if", t)
+	return 0
+}
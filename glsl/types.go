@@ -0,0 +1,213 @@
+package glsl
+
+import "fmt"
+
+// Base is the kind of a type, or of the components of a vector or matrix.
+type Base int
+
+const (
+	Void Base = iota
+	Bool
+	Int
+	Uint
+	Float
+	Sampler
+	Struct
+)
+
+// Type is a GLSL type. Scalars have Size 1, vectors Size components and
+// matrices Cols columns of Size rows. Arrays have an Elem type and Len
+// elements, or Len 0 if the size isn't known yet.
+type Type struct {
+	Base   Base
+	Size   int
+	Cols   int
+	Name   string // of samplers and structs
+	Fields []*StructField
+	Elem   *Type
+	Len    int
+}
+
+type StructField struct {
+	Name string
+	Type *Type
+}
+
+var (
+	voidType  = &Type{Base: Void}
+	boolType  = &Type{Base: Bool, Size: 1}
+	intType   = &Type{Base: Int, Size: 1}
+	uintType  = &Type{Base: Uint, Size: 1}
+	floatType = &Type{Base: Float, Size: 1}
+)
+
+// vecType returns the scalar or vector type with n components of base.
+func vecType(base Base, n int) *Type {
+	if n == 1 {
+		switch base {
+		case Bool:
+			return boolType
+		case Int:
+			return intType
+		case Uint:
+			return uintType
+		case Float:
+			return floatType
+		}
+	}
+	return &Type{Base: base, Size: n}
+}
+
+func matType(cols, rows int) *Type {
+	return &Type{Base: Float, Size: rows, Cols: cols}
+}
+
+func arrayOf(elem *Type, n int) *Type {
+	return &Type{Base: elem.Base, Elem: elem, Len: n}
+}
+
+// samplerTypes are the sampler types of GLSL 1.50, without the i and u
+// prefixes of the integer samplers.
+var samplerTypes = []string{
+	"sampler1D", "sampler2D", "sampler3D", "samplerCube", "sampler2DRect",
+	"sampler1DArray", "sampler2DArray", "samplerBuffer", "sampler2DMS",
+	"sampler2DMSArray",
+}
+
+var shadowSamplerTypes = []string{
+	"sampler1DShadow", "sampler2DShadow", "samplerCubeShadow",
+	"sampler2DRectShadow", "sampler1DArrayShadow", "sampler2DArrayShadow",
+}
+
+// typeNames are the built-in types by name.
+var typeNames = builtinTypes()
+
+func builtinTypes() map[string]*Type {
+	types := map[string]*Type{
+		"void":  voidType,
+		"bool":  boolType,
+		"int":   intType,
+		"uint":  uintType,
+		"float": floatType,
+	}
+	for n := 2; n <= 4; n++ {
+		types[fmt.Sprintf("vec%d", n)] = vecType(Float, n)
+		types[fmt.Sprintf("bvec%d", n)] = vecType(Bool, n)
+		types[fmt.Sprintf("ivec%d", n)] = vecType(Int, n)
+		types[fmt.Sprintf("uvec%d", n)] = vecType(Uint, n)
+		types[fmt.Sprintf("mat%d", n)] = matType(n, n)
+		for m := 2; m <= 4; m++ {
+			types[fmt.Sprintf("mat%dx%d", n, m)] = matType(n, m)
+		}
+	}
+	for _, name := range samplerTypes {
+		for _, prefix := range []string{"", "i", "u"} {
+			types[prefix+name] = &Type{Base: Sampler, Name: prefix + name}
+		}
+	}
+	for _, name := range shadowSamplerTypes {
+		types[name] = &Type{Base: Sampler, Name: name}
+	}
+	return types
+}
+
+func (t *Type) String() string {
+	switch {
+	case t.Elem != nil:
+		if t.Len == 0 {
+			return t.Elem.String() + "[]"
+		}
+		return fmt.Sprintf("%v[%d]", t.Elem, t.Len)
+	case t.Base == Sampler || t.Base == Struct:
+		return t.Name
+	case t.Base == Void:
+		return "void"
+	case t.Cols > 0:
+		if t.Cols == t.Size {
+			return fmt.Sprintf("mat%d", t.Cols)
+		}
+		return fmt.Sprintf("mat%dx%d", t.Cols, t.Size)
+	case t.Size == 1:
+		return [...]string{Bool: "bool", Int: "int", Uint: "uint", Float: "float"}[t.Base]
+	}
+	return fmt.Sprintf("%svec%d", [...]string{Bool: "b", Int: "i", Uint: "u", Float: ""}[t.Base], t.Size)
+}
+
+// Equal reports whether t and u are the same type.
+func (t *Type) Equal(u *Type) bool {
+	switch {
+	case t == u:
+		return true
+	case t.Elem != nil || u.Elem != nil:
+		return t.Elem != nil && u.Elem != nil && t.Len == u.Len && t.Elem.Equal(u.Elem)
+	case t.Base == Struct:
+		return false
+	}
+	return t.Base == u.Base && t.Size == u.Size && t.Cols == u.Cols && t.Name == u.Name
+}
+
+func (t *Type) IsScalar() bool {
+	return t.Elem == nil && t.Base >= Bool && t.Base <= Float && t.Size == 1 && t.Cols == 0
+}
+
+func (t *Type) IsVector() bool {
+	return t.Elem == nil && t.Base >= Bool && t.Base <= Float && t.Size > 1 && t.Cols == 0
+}
+
+func (t *Type) IsMatrix() bool {
+	return t.Elem == nil && t.Cols > 0
+}
+
+// basic reports whether t is a scalar, vector or matrix.
+func (t *Type) basic() bool {
+	return t.Elem == nil && t.Base >= Bool && t.Base <= Float
+}
+
+// numeric reports whether t is a basic type of numbers.
+func (t *Type) numeric() bool {
+	return t.basic() && t.Base != Bool
+}
+
+// integer reports whether t is an integer scalar or vector.
+func (t *Type) integer() bool {
+	return t.basic() && (t.Base == Int || t.Base == Uint)
+}
+
+// components is the number of scalars in a basic type.
+func (t *Type) components() int {
+	if t.Cols > 0 {
+		return t.Cols * t.Size
+	}
+	return t.Size
+}
+
+// withBase returns the type of the same shape as t with base components.
+func (t *Type) withBase(base Base) *Type {
+	if t.Cols > 0 {
+		return t
+	}
+	return vecType(base, t.Size)
+}
+
+// opaque reports whether t is or contains a sampler.
+func (t *Type) opaque() bool {
+	if t.Elem != nil {
+		return t.Elem.opaque()
+	}
+	for _, f := range t.Fields {
+		if f.Type.opaque() {
+			return true
+		}
+	}
+	return t.Base == Sampler
+}
+
+// convertible reports whether a value of type from can be used where to
+// is expected: GLSL 1.50 converts int and uint to float implicitly.
+func convertible(from, to *Type) bool {
+	if from.Equal(to) {
+		return true
+	}
+	return to.Base == Float && (from.Base == Int || from.Base == Uint) &&
+		from.Elem == nil && to.Elem == nil && from.Cols == 0 && to.Cols == 0 && from.Size == to.Size
+}
@@ -0,0 +1,301 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// component types
+const (
+	typeByte          = 5120
+	typeUnsignedByte  = 5121
+	typeShort         = 5122
+	typeUnsignedShort = 5123
+	typeUnsignedInt   = 5125
+	typeFloat         = 5126
+)
+
+var componentSizes = map[int]int{
+	typeByte:          1,
+	typeUnsignedByte:  1,
+	typeShort:         2,
+	typeUnsignedShort: 2,
+	typeUnsignedInt:   4,
+	typeFloat:         4,
+}
+
+// maxBytes limits the size of decoded accessors, in bytes of float64
+// values, so a broken file can't make the loader allocate without bounds:
+// a sparse accessor without a buffer view is allocated from its count
+// alone.
+const maxBytes = 1 << 26
+
+// elementTypes maps accessor types to the number of components and, for
+// matrices, of rows in a column.
+var elementTypes = map[string]struct{ components, rows int }{
+	"SCALAR": {1, 0},
+	"VEC2":   {2, 0},
+	"VEC3":   {3, 0},
+	"VEC4":   {4, 0},
+	"MAT2":   {4, 2},
+	"MAT3":   {9, 3},
+	"MAT4":   {16, 4},
+}
+
+// readURI returns the data of a buffer or image URI: a data URI, or a file
+// opened relative to the glTF file.
+func (d *decoder) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("only base64 data URIs are supported")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	if d.open == nil {
+		return nil, fmt.Errorf("no way to open %s", uri)
+	}
+	f, err := d.open(unescapeURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// unescapeURI undoes the percent-encoding of a relative URI, leaving
+// malformed escapes alone.
+func unescapeURI(uri string) string {
+	var b strings.Builder
+	for i := 0; i < len(uri); i++ {
+		if uri[i] == '%' && i+2 < len(uri) {
+			var c byte
+			if _, err := fmt.Sscanf(uri[i+1:i+3], "%02x", &c); err == nil {
+				b.WriteByte(c)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(uri[i])
+	}
+	return b.String()
+}
+
+// buffer returns the data of buffer i, loading it the first time.
+func (d *decoder) buffer(i int) ([]byte, error) {
+	if i < 0 || i >= len(d.doc.Buffers) {
+		return nil, fmt.Errorf("buffer %d doesn't exist", i)
+	}
+	if d.buffers[i] != nil {
+		return d.buffers[i], nil
+	}
+	b := d.doc.Buffers[i]
+	var data []byte
+	if b.URI == "" {
+		// the binary chunk of a GLB file
+		if i != 0 || d.bin == nil {
+			return nil, fmt.Errorf("buffer %d has no uri", i)
+		}
+		data = d.bin
+	} else {
+		var err error
+		data, err = d.readURI(b.URI)
+		if err != nil {
+			return nil, fmt.Errorf("buffer %d: %v", i, err)
+		}
+	}
+	if len(data) < b.ByteLength {
+		return nil, fmt.Errorf("buffer %d has %d bytes, want %d", i, len(data), b.ByteLength)
+	}
+	d.buffers[i] = data[:b.ByteLength]
+	return d.buffers[i], nil
+}
+
+// bufferView returns the bytes of buffer view i and its stride.
+func (d *decoder) bufferView(i int) ([]byte, int, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d doesn't exist", i)
+	}
+	v := d.doc.BufferViews[i]
+	buf, err := d.buffer(v.Buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset > len(buf) || v.ByteLength > len(buf)-v.ByteOffset {
+		return nil, 0, fmt.Errorf("buffer view %d: %d bytes at offset %d don't fit buffer %d", i, v.ByteLength, v.ByteOffset, v.Buffer)
+	}
+	return buf[v.ByteOffset : v.ByteOffset+v.ByteLength], v.ByteStride, nil
+}
+
+// elementSize returns the size of an element of n components of ctype in
+// a buffer. Matrix columns start at multiples of four bytes.
+func elementSize(ctype, components, rows int) int {
+	size := componentSizes[ctype]
+	if rows == 0 {
+		return components * size
+	}
+	return (components / rows) * ((rows*size + 3) &^ 3)
+}
+
+// readElements decodes count elements of components values of ctype from
+// data, stride bytes apart or packed if stride is 0.
+func readElements(data []byte, stride, count, ctype, components, rows int, normalized bool) ([]float64, error) {
+	size := componentSizes[ctype]
+	if size == 0 {
+		return nil, fmt.Errorf("unknown component type %d", ctype)
+	}
+	elem := elementSize(ctype, components, rows)
+	if stride == 0 {
+		stride = elem
+	}
+	if stride < elem || stride > 252 {
+		return nil, fmt.Errorf("stride %d doesn't fit the %d byte elements", stride, elem)
+	}
+	if count > 0 && (count-1)*stride+elem > len(data) {
+		return nil, fmt.Errorf("%d elements of %d bytes, %d apart, don't fit in %d bytes", count, elem, stride, len(data))
+	}
+
+	out := make([]float64, 0, count*components)
+	for i := 0; i < count; i++ {
+		e := data[i*stride:]
+		for c := 0; c < components; c++ {
+			offset := c * size
+			if rows > 0 {
+				// skip the padding at the end of the columns
+				col := (rows*size + 3) &^ 3
+				offset = c/rows*col + c%rows*size
+			}
+			out = append(out, component(e[offset:], ctype, normalized))
+		}
+	}
+	return out, nil
+}
+
+// component decodes one value, mapping normalized integers to [0, 1] or
+// [-1, 1] as the glTF specification says.
+func component(b []byte, ctype int, normalized bool) float64 {
+	var x, max float64
+	switch ctype {
+	case typeFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case typeByte:
+		x, max = float64(int8(b[0])), 127
+	case typeUnsignedByte:
+		x, max = float64(b[0]), 255
+	case typeShort:
+		x, max = float64(int16(binary.LittleEndian.Uint16(b))), 32767
+	case typeUnsignedShort:
+		x, max = float64(binary.LittleEndian.Uint16(b)), 65535
+	case typeUnsignedInt:
+		x, max = float64(binary.LittleEndian.Uint32(b)), 4294967295
+	}
+	if !normalized {
+		return x
+	}
+	return math.Max(x/max, -1)
+}
+
+// accessor decodes accessor i, checking that its elements have one of the
+// allowed numbers of components. Sparse values are applied on top of the
+// buffer view, or of zeros if there is none. An accessor with neither is
+// an error rather than a block of zeros.
+func (d *decoder) accessor(i int, allowed ...int) (values []float64, components int, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("accessor %d: %v", i, err)
+		}
+	}()
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, 0, fmt.Errorf("doesn't exist")
+	}
+	a := d.doc.Accessors[i]
+	t, ok := elementTypes[a.Type]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown type %q", a.Type)
+	}
+	if componentSizes[a.ComponentType] == 0 {
+		return nil, 0, fmt.Errorf("unknown component type %d", a.ComponentType)
+	}
+	if a.Count < 0 || a.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("negative count or offset")
+	}
+	if !contains(allowed, t.components) {
+		return nil, 0, fmt.Errorf("type %s is not allowed here", a.Type)
+	}
+	if a.Count > maxBytes/(8*t.components) {
+		return nil, 0, fmt.Errorf("%d elements of type %s are too many", a.Count, a.Type)
+	}
+
+	if a.BufferView == nil {
+		if a.Sparse == nil {
+			return nil, 0, fmt.Errorf("has neither a buffer view nor sparse values")
+		}
+		values = make([]float64, a.Count*t.components)
+	} else {
+		data, stride, err := d.bufferView(*a.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		if a.ByteOffset > len(data) {
+			return nil, 0, fmt.Errorf("offset %d is past the end of buffer view %d", a.ByteOffset, *a.BufferView)
+		}
+		values, err = readElements(data[a.ByteOffset:], stride, a.Count, a.ComponentType, t.components, t.rows, a.Normalized)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if s := a.Sparse; s != nil {
+		if s.Count < 0 || s.Count > a.Count {
+			return nil, 0, fmt.Errorf("%d sparse values for %d elements", s.Count, a.Count)
+		}
+		data, _, err := d.bufferView(s.Indices.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		if s.Indices.ByteOffset < 0 || s.Indices.ByteOffset > len(data) {
+			return nil, 0, fmt.Errorf("sparse indices offset %d is out of range", s.Indices.ByteOffset)
+		}
+		switch s.Indices.ComponentType {
+		case typeUnsignedByte, typeUnsignedShort, typeUnsignedInt:
+		default:
+			return nil, 0, fmt.Errorf("sparse indices have component type %d", s.Indices.ComponentType)
+		}
+		indices, err := readElements(data[s.Indices.ByteOffset:], 0, s.Count, s.Indices.ComponentType, 1, 0, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("sparse indices: %v", err)
+		}
+		data, _, err = d.bufferView(s.Values.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		if s.Values.ByteOffset < 0 || s.Values.ByteOffset > len(data) {
+			return nil, 0, fmt.Errorf("sparse values offset %d is out of range", s.Values.ByteOffset)
+		}
+		sparse, err := readElements(data[s.Values.ByteOffset:], 0, s.Count, a.ComponentType, t.components, t.rows, a.Normalized)
+		if err != nil {
+			return nil, 0, fmt.Errorf("sparse values: %v", err)
+		}
+		for k, index := range indices {
+			e := int(index)
+			if e >= a.Count {
+				return nil, 0, fmt.Errorf("sparse index %d out of range", e)
+			}
+			copy(values[e*t.components:(e+1)*t.components], sparse[k*t.components:])
+		}
+	}
+	return values, t.components, nil
+}
+
+func contains(list []int, x int) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
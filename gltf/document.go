@@ -0,0 +1,160 @@
+package gltf
+
+import (
+	"encoding/json"
+)
+
+// The JSON of a glTF file. Only what the loader uses is declared; indices
+// into the top-level arrays are pointers where the property is optional.
+
+type document struct {
+	Asset struct {
+		Version    string `json:"version"`
+		MinVersion string `json:"minVersion"`
+	} `json:"asset"`
+	ExtensionsRequired []string `json:"extensionsRequired"`
+
+	Scene       *int            `json:"scene"`
+	Scenes      []docScene      `json:"scenes"`
+	Nodes       []docNode       `json:"nodes"`
+	Meshes      []docMesh       `json:"meshes"`
+	Accessors   []docAccessor   `json:"accessors"`
+	BufferViews []docBufferView `json:"bufferViews"`
+	Buffers     []docBuffer     `json:"buffers"`
+	Materials   []docMaterial   `json:"materials"`
+	Textures    []docTexture    `json:"textures"`
+	Images      []docImage      `json:"images"`
+	Samplers    []docSampler    `json:"samplers"`
+	Cameras     []docCamera     `json:"cameras"`
+}
+
+type docScene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type docNode struct {
+	Name        string       `json:"name"`
+	Children    []int        `json:"children"`
+	Mesh        *int         `json:"mesh"`
+	Camera      *int         `json:"camera"`
+	Matrix      *[16]float32 `json:"matrix"`
+	Translation *[3]float32  `json:"translation"`
+	Rotation    *[4]float32  `json:"rotation"`
+	Scale       *[3]float32  `json:"scale"`
+}
+
+type docMesh struct {
+	Name       string         `json:"name"`
+	Primitives []docPrimitive `json:"primitives"`
+}
+
+type docPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type docAccessor struct {
+	BufferView    *int   `json:"bufferView"`
+	ByteOffset    int    `json:"byteOffset"`
+	ComponentType int    `json:"componentType"`
+	Normalized    bool   `json:"normalized"`
+	Count         int    `json:"count"`
+	Type          string `json:"type"`
+	Sparse        *struct {
+		Count   int `json:"count"`
+		Indices struct {
+			BufferView    int `json:"bufferView"`
+			ByteOffset    int `json:"byteOffset"`
+			ComponentType int `json:"componentType"`
+		} `json:"indices"`
+		Values struct {
+			BufferView int `json:"bufferView"`
+			ByteOffset int `json:"byteOffset"`
+		} `json:"values"`
+	} `json:"sparse"`
+}
+
+type docBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type docBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type docTextureInfo struct {
+	Index    int      `json:"index"`
+	TexCoord int      `json:"texCoord"`
+	Scale    *float32 `json:"scale"`    // normal textures
+	Strength *float32 `json:"strength"` // occlusion textures
+}
+
+type docMaterial struct {
+	Name                 string `json:"name"`
+	PBRMetallicRoughness *struct {
+		BaseColorFactor          *[4]float32     `json:"baseColorFactor"`
+		BaseColorTexture         *docTextureInfo `json:"baseColorTexture"`
+		MetallicFactor           *float32        `json:"metallicFactor"`
+		RoughnessFactor          *float32        `json:"roughnessFactor"`
+		MetallicRoughnessTexture *docTextureInfo `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture    *docTextureInfo `json:"normalTexture"`
+	OcclusionTexture *docTextureInfo `json:"occlusionTexture"`
+	EmissiveTexture  *docTextureInfo `json:"emissiveTexture"`
+	EmissiveFactor   [3]float32      `json:"emissiveFactor"`
+	AlphaMode        string          `json:"alphaMode"`
+	AlphaCutoff      *float32        `json:"alphaCutoff"`
+	DoubleSided      bool            `json:"doubleSided"`
+}
+
+type docTexture struct {
+	Name    string `json:"name"`
+	Sampler *int   `json:"sampler"`
+	Source  *int   `json:"source"`
+}
+
+type docImage struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type docSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type docCamera struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		AspectRatio float32  `json:"aspectRatio"`
+		YFov        float32  `json:"yfov"`
+		ZFar        *float32 `json:"zfar"`
+		ZNear       float32  `json:"znear"`
+	} `json:"perspective"`
+	Orthographic *struct {
+		XMag  float32 `json:"xmag"`
+		YMag  float32 `json:"ymag"`
+		ZFar  float32 `json:"zfar"`
+		ZNear float32 `json:"znear"`
+	} `json:"orthographic"`
+}
+
+func parseDocument(data []byte) (*document, error) {
+	doc := new(document)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
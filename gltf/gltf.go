@@ -0,0 +1,690 @@
+// Package gltf loads glTF 2.0 scenes from .gltf files, with their buffers
+// in separate files or data URIs, and from binary .glb files.
+//
+// The loader resolves the node hierarchy into Nodes with TRS or matrix
+// transforms, the mesh primitives into indexed mesh.Meshes, and reads PBR
+// metallic-roughness materials with their textures decoded to images, and
+// cameras. Accessors may be sparse or normalized.
+//
+// glTF is Y up; the loader keeps the coordinates as they are. Texture
+// coordinates are flipped to put v = 0 at the bottom of the image, like
+// the rest of the repository. Animations, skins, morph targets and
+// extensions are not supported, and primitives that draw points or lines
+// are skipped.
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Scene is the scene of a glTF file: the root nodes of the default scene
+// and everything they use.
+type Scene struct {
+	Name      string
+	Nodes     []*Node
+	Meshes    []*Mesh
+	Materials []*Material
+	Textures  []*Texture
+	Cameras   []*Camera
+}
+
+// Node is a node of the hierarchy. Its transform is relative to its
+// parent; Transform combines Translation, Rotation and Scale, or is the
+// matrix given in the file.
+type Node struct {
+	Name        string
+	Transform   glm.Mat4
+	Translation glm.Vec3
+	Rotation    glm.Quat
+	Scale       glm.Vec3
+	Mesh        *Mesh
+	Camera      *Camera
+	Children    []*Node
+}
+
+// Mesh is a glTF mesh: one or more primitives, each with a material.
+type Mesh struct {
+	Name       string
+	Primitives []Primitive
+}
+
+// Primitive is a triangle mesh drawn with one material. Material is nil
+// for the default material of the specification, plain white.
+type Primitive struct {
+	Mesh     *mesh.Mesh
+	Material *Material
+}
+
+// Material is a PBR metallic-roughness material. Textures are nil when
+// the material has none; the factors multiply what the textures hold.
+type Material struct {
+	Name                     string
+	BaseColor                glm.Vec4
+	BaseColorTexture         *TextureRef
+	Metallic                 float32
+	Roughness                float32
+	MetallicRoughnessTexture *TextureRef // roughness in G, metallic in B
+	NormalTexture            *TextureRef
+	NormalScale              float32
+	OcclusionTexture         *TextureRef // in R
+	OcclusionStrength        float32
+	Emissive                 glm.Vec3
+	EmissiveTexture          *TextureRef
+	AlphaMode                string // OPAQUE, MASK or BLEND
+	AlphaCutoff              float32
+	DoubleSided              bool
+}
+
+// Texture is an image and how to sample it. The filters and wrap modes
+// are OpenGL enums, zero where the file leaves the choice open.
+type Texture struct {
+	Name                 string
+	Image                image.Image
+	MagFilter, MinFilter int
+	WrapS, WrapT         int
+}
+
+// TextureRef is a use of a texture by a material. TexCoord is the index
+// of the texture coordinate set; only set 0 is loaded.
+type TextureRef struct {
+	*Texture
+	TexCoord int
+}
+
+// Camera is a perspective or orthographic camera. It looks down -Z of the
+// node it is attached to, with Y up. ZFar is 0 for an infinite perspective
+// projection; AspectRatio is 0 if the file leaves it to the viewport.
+type Camera struct {
+	Name         string
+	Orthographic bool
+	YFov         float32 // radians
+	AspectRatio  float32
+	XMag, YMag   float32
+	ZNear, ZFar  float32
+}
+
+// Projection returns the projection matrix of the camera for a viewport
+// with the given aspect ratio, which the camera's own one overrides.
+func (c *Camera) Projection(aspect float32) glm.Mat4 {
+	if c.Orthographic {
+		return glm.Ortho(-c.XMag, c.XMag, -c.YMag, c.YMag, c.ZNear, c.ZFar)
+	}
+	if c.AspectRatio > 0 {
+		aspect = c.AspectRatio
+	}
+	if c.ZFar > 0 {
+		return glm.Perspective(c.YFov, aspect, c.ZNear, c.ZFar)
+	}
+	// the infinite projection of the specification
+	f := float32(1 / math.Tan(float64(c.YFov)/2))
+	return glm.Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, -1, -1,
+		0, 0, -2 * c.ZNear, 0,
+	}
+}
+
+// Walk calls fn for every node of the scene, parents before children,
+// with the transform from the node to the scene.
+func (s *Scene) Walk(fn func(n *Node, world glm.Mat4)) {
+	var walk func(n *Node, parent glm.Mat4)
+	walk = func(n *Node, parent glm.Mat4) {
+		world := parent.Mul4(n.Transform)
+		fn(n, world)
+		for _, c := range n.Children {
+			walk(c, world)
+		}
+	}
+	for _, n := range s.Nodes {
+		walk(n, glm.Ident4())
+	}
+}
+
+// Load reads the .gltf or .glb file name. Buffers and images in other
+// files are looked up relative to its directory.
+func Load(name string) (*Scene, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(name)
+	s, err := Decode(data, func(uri string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(uri)))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return s, nil
+}
+
+// Decode reads a glTF file, JSON or binary, from data. open is called with
+// the relative URIs of buffers and images in other files and may be nil if
+// there are none.
+func Decode(data []byte, open func(uri string) (io.ReadCloser, error)) (*Scene, error) {
+	d := &decoder{open: open}
+	if bytes.HasPrefix(data, []byte("glTF")) {
+		var err error
+		data, d.bin, err = splitGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	d.doc, err = parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(d.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("glTF version %q is not supported", d.doc.Asset.Version)
+	}
+	if len(d.doc.ExtensionsRequired) > 0 {
+		return nil, fmt.Errorf("required extensions are not supported: %s", strings.Join(d.doc.ExtensionsRequired, ", "))
+	}
+	d.buffers = make([][]byte, len(d.doc.Buffers))
+	return d.scene()
+}
+
+// splitGLB returns the JSON and binary chunks of a GLB file.
+func splitGLB(data []byte) (json, bin []byte, err error) {
+	if len(data) < 12 {
+		return nil, nil, errors.New("GLB header is truncated")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+		return nil, nil, fmt.Errorf("GLB version %d is not supported", v)
+	}
+	n := binary.LittleEndian.Uint32(data[8:])
+	if n < 12 || uint64(n) > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("GLB length %d doesn't match the %d bytes of the file", n, len(data))
+	}
+	data = data[:n]
+	for rest := data[12:]; len(rest) > 0; {
+		if len(rest) < 8 {
+			return nil, nil, errors.New("GLB chunk header is truncated")
+		}
+		n, typ := binary.LittleEndian.Uint32(rest), binary.LittleEndian.Uint32(rest[4:])
+		if uint64(n) > uint64(len(rest)-8) {
+			return nil, nil, errors.New("GLB chunk is truncated")
+		}
+		chunk := rest[8 : 8+n]
+		rest = rest[8+n:]
+		switch {
+		case typ == 0x4E4F534A && json == nil: // JSON
+			json = chunk
+		case typ == 0x004E4942 && bin == nil: // BIN
+			bin = chunk
+		}
+	}
+	if json == nil {
+		return nil, nil, errors.New("GLB file has no JSON chunk")
+	}
+	return json, bin, nil
+}
+
+type decoder struct {
+	doc     *document
+	open    func(string) (io.ReadCloser, error)
+	bin     []byte
+	buffers [][]byte
+
+	out       *Scene
+	nodes     []*Node
+	meshes    []*Mesh
+	materials []*Material
+	textures  []*Texture
+	cameras   []*Camera
+}
+
+// scene builds the default scene, or the first one, or, if there are no
+// scenes, one of all the nodes that aren't children of another node.
+func (d *decoder) scene() (*Scene, error) {
+	s := new(Scene)
+	d.out = s
+	d.nodes = make([]*Node, len(d.doc.Nodes))
+	d.meshes = make([]*Mesh, len(d.doc.Meshes))
+	d.materials = make([]*Material, len(d.doc.Materials))
+	d.textures = make([]*Texture, len(d.doc.Textures))
+	d.cameras = make([]*Camera, len(d.doc.Cameras))
+
+	var roots []int
+	switch {
+	case d.doc.Scene != nil:
+		if *d.doc.Scene < 0 || *d.doc.Scene >= len(d.doc.Scenes) {
+			return nil, fmt.Errorf("scene %d doesn't exist", *d.doc.Scene)
+		}
+		s.Name, roots = d.doc.Scenes[*d.doc.Scene].Name, d.doc.Scenes[*d.doc.Scene].Nodes
+	case len(d.doc.Scenes) > 0:
+		s.Name, roots = d.doc.Scenes[0].Name, d.doc.Scenes[0].Nodes
+	default:
+		child := make(map[int]bool)
+		for _, n := range d.doc.Nodes {
+			for _, c := range n.Children {
+				child[c] = true
+			}
+		}
+		for i := range d.doc.Nodes {
+			if !child[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	for _, i := range roots {
+		n, err := d.node(i, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.Nodes = append(s.Nodes, n)
+	}
+	return s, nil
+}
+
+// node builds node i and its children. path holds the nodes above it, to
+// catch cycles.
+func (d *decoder) node(i int, path []int) (*Node, error) {
+	if i < 0 || i >= len(d.doc.Nodes) {
+		return nil, fmt.Errorf("node %d doesn't exist", i)
+	}
+	if contains(path, i) {
+		return nil, fmt.Errorf("node %d is its own ancestor", i)
+	}
+	if d.nodes[i] != nil {
+		return nil, fmt.Errorf("node %d has two parents", i)
+	}
+	dn := d.doc.Nodes[i]
+	n := &Node{
+		Name:     dn.Name,
+		Rotation: glm.QuatIdent(),
+		Scale:    glm.Vec3{1, 1, 1},
+	}
+	d.nodes[i] = n
+
+	if dn.Matrix != nil {
+		n.Transform = glm.Mat4(*dn.Matrix)
+	} else {
+		if dn.Translation != nil {
+			n.Translation = glm.Vec3(*dn.Translation)
+		}
+		if r := dn.Rotation; r != nil {
+			n.Rotation = glm.Quat{W: r[3], V: glm.Vec3{r[0], r[1], r[2]}}.Normalize()
+		}
+		if dn.Scale != nil {
+			n.Scale = glm.Vec3(*dn.Scale)
+		}
+		n.Transform = glm.Translate3D(n.Translation[0], n.Translation[1], n.Translation[2]).
+			Mul4(n.Rotation.Mat4()).
+			Mul4(glm.Scale3D(n.Scale[0], n.Scale[1], n.Scale[2]))
+	}
+
+	var err error
+	if dn.Mesh != nil {
+		if n.Mesh, err = d.mesh(*dn.Mesh); err != nil {
+			return nil, err
+		}
+	}
+	if dn.Camera != nil {
+		if n.Camera, err = d.camera(*dn.Camera); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range dn.Children {
+		child, err := d.node(c, append(path, i))
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, child)
+	}
+	return n, nil
+}
+
+func (d *decoder) camera(i int) (*Camera, error) {
+	if i < 0 || i >= len(d.cameras) {
+		return nil, fmt.Errorf("camera %d doesn't exist", i)
+	}
+	if d.cameras[i] != nil {
+		return d.cameras[i], nil
+	}
+	dc := d.doc.Cameras[i]
+	c := &Camera{Name: dc.Name}
+	switch {
+	case dc.Type == "perspective" && dc.Perspective != nil:
+		p := dc.Perspective
+		c.YFov, c.AspectRatio, c.ZNear = p.YFov, p.AspectRatio, p.ZNear
+		if p.ZFar != nil {
+			c.ZFar = *p.ZFar
+		}
+	case dc.Type == "orthographic" && dc.Orthographic != nil:
+		o := dc.Orthographic
+		c.Orthographic = true
+		c.XMag, c.YMag, c.ZNear, c.ZFar = o.XMag, o.YMag, o.ZNear, o.ZFar
+	default:
+		return nil, fmt.Errorf("camera %d: type %q without its properties", i, dc.Type)
+	}
+	d.cameras[i] = c
+	d.out.Cameras = append(d.out.Cameras, c)
+	return c, nil
+}
+
+func (d *decoder) mesh(i int) (*Mesh, error) {
+	if i < 0 || i >= len(d.meshes) {
+		return nil, fmt.Errorf("mesh %d doesn't exist", i)
+	}
+	if d.meshes[i] != nil {
+		return d.meshes[i], nil
+	}
+	dm := d.doc.Meshes[i]
+	m := &Mesh{Name: dm.Name}
+	for k, p := range dm.Primitives {
+		prim, err := d.primitive(p)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %d primitive %d: %v", i, k, err)
+		}
+		if prim.Mesh != nil {
+			m.Primitives = append(m.Primitives, prim)
+		}
+	}
+	d.meshes[i] = m
+	d.out.Meshes = append(d.out.Meshes, m)
+	return m, nil
+}
+
+// primitive modes
+const (
+	modeTriangles     = 4
+	modeTriangleStrip = 5
+	modeTriangleFan   = 6
+)
+
+// primitive loads a primitive into a mesh.Mesh, or returns a Primitive
+// without a mesh for points and lines.
+func (d *decoder) primitive(p docPrimitive) (Primitive, error) {
+	mode := modeTriangles
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	if mode != modeTriangles && mode != modeTriangleStrip && mode != modeTriangleFan {
+		return Primitive{}, nil
+	}
+
+	pos, ok := p.Attributes["POSITION"]
+	if !ok {
+		return Primitive{}, errors.New("no POSITION attribute")
+	}
+	values, _, err := d.accessor(pos, 3)
+	if err != nil {
+		return Primitive{}, err
+	}
+	m := new(mesh.Mesh)
+	n := len(values) / 3
+	m.Positions = make([]glm.Vec3, n)
+	for i := range m.Positions {
+		m.Positions[i] = glm.Vec3{float32(values[3*i]), float32(values[3*i+1]), float32(values[3*i+2])}
+	}
+
+	// the other attributes must have as many elements
+	attrib := func(name string, components int) ([]float64, error) {
+		i, ok := p.Attributes[name]
+		if !ok {
+			return nil, nil
+		}
+		values, _, err := d.accessor(i, components)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != n*components {
+			return nil, fmt.Errorf("%s has %d elements for %d positions", name, len(values)/components, n)
+		}
+		return values, nil
+	}
+	normals, err := attrib("NORMAL", 3)
+	if err != nil {
+		return Primitive{}, err
+	}
+	texcoords, err := attrib("TEXCOORD_0", 2)
+	if err != nil {
+		return Primitive{}, err
+	}
+	tangents, err := attrib("TANGENT", 4)
+	if err != nil {
+		return Primitive{}, err
+	}
+	m.Texcoords = make([]glm.Vec2, n)
+	for i := range m.Texcoords {
+		if texcoords != nil {
+			m.Texcoords[i] = glm.Vec2{float32(texcoords[2*i]), 1 - float32(texcoords[2*i+1])}
+		}
+	}
+
+	var indices []uint32
+	if p.Indices != nil {
+		values, _, err := d.accessor(*p.Indices, 1)
+		if err != nil {
+			return Primitive{}, err
+		}
+		indices = make([]uint32, len(values))
+		for k, v := range values {
+			if v < 0 || v >= float64(n) || v != math.Trunc(v) {
+				return Primitive{}, fmt.Errorf("index %g out of range", v)
+			}
+			indices[k] = uint32(v)
+		}
+	} else {
+		indices = make([]uint32, n)
+		for k := range indices {
+			indices[k] = uint32(k)
+		}
+	}
+	m.Indices = triangles(indices, mode)
+
+	if normals == nil {
+		// the specification asks for flat normals
+		unweld(m)
+		m.Normals = make([]glm.Vec3, len(m.Positions))
+		for t := 0; t+2 < len(m.Indices); t += 3 {
+			a, b, c := m.Indices[t], m.Indices[t+1], m.Indices[t+2]
+			face := m.Positions[b].Sub(m.Positions[a]).Cross(m.Positions[c].Sub(m.Positions[a]))
+			if face.Len() > 0 {
+				face = face.Normalize()
+			}
+			m.Normals[a], m.Normals[b], m.Normals[c] = face, face, face
+		}
+		tangents = nil
+	} else {
+		m.Normals = make([]glm.Vec3, n)
+		for i := range m.Normals {
+			m.Normals[i] = glm.Vec3{float32(normals[3*i]), float32(normals[3*i+1]), float32(normals[3*i+2])}
+		}
+	}
+	if tangents != nil {
+		// flipping v turns the bitangent around
+		m.Tangents = make([]glm.Vec4, n)
+		for i := range m.Tangents {
+			m.Tangents[i] = glm.Vec4{float32(tangents[4*i]), float32(tangents[4*i+1]), float32(tangents[4*i+2]), -float32(tangents[4*i+3])}
+		}
+	} else {
+		m.ComputeTangents()
+	}
+
+	prim := Primitive{Mesh: m}
+	if p.Material != nil {
+		if prim.Material, err = d.material(*p.Material); err != nil {
+			return Primitive{}, err
+		}
+	}
+	return prim, nil
+}
+
+// triangles converts strips and fans to a triangle list.
+func triangles(indices []uint32, mode int) []uint32 {
+	var tris []uint32
+	switch mode {
+	case modeTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				tris = append(tris, indices[i], indices[i+1], indices[i+2])
+			} else {
+				tris = append(tris, indices[i+1], indices[i], indices[i+2])
+			}
+		}
+	case modeTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			tris = append(tris, indices[0], indices[i], indices[i+1])
+		}
+	default:
+		tris = indices[:len(indices)/3*3]
+	}
+	return tris
+}
+
+// unweld gives every triangle its own vertices, so they can have
+// different normals.
+func unweld(m *mesh.Mesh) {
+	positions := make([]glm.Vec3, len(m.Indices))
+	texcoords := make([]glm.Vec2, len(m.Indices))
+	for k, i := range m.Indices {
+		positions[k], texcoords[k] = m.Positions[i], m.Texcoords[i]
+		m.Indices[k] = uint32(k)
+	}
+	m.Positions, m.Texcoords = positions, texcoords
+}
+
+func (d *decoder) material(i int) (*Material, error) {
+	if i < 0 || i >= len(d.materials) {
+		return nil, fmt.Errorf("material %d doesn't exist", i)
+	}
+	if d.materials[i] != nil {
+		return d.materials[i], nil
+	}
+	dm := d.doc.Materials[i]
+	m := &Material{
+		Name:              dm.Name,
+		BaseColor:         glm.Vec4{1, 1, 1, 1},
+		Metallic:          1,
+		Roughness:         1,
+		NormalScale:       1,
+		OcclusionStrength: 1,
+		Emissive:          glm.Vec3(dm.EmissiveFactor),
+		AlphaMode:         "OPAQUE",
+		AlphaCutoff:       0.5,
+		DoubleSided:       dm.DoubleSided,
+	}
+	if dm.AlphaMode != "" {
+		m.AlphaMode = dm.AlphaMode
+	}
+	if dm.AlphaCutoff != nil {
+		m.AlphaCutoff = *dm.AlphaCutoff
+	}
+
+	var err error
+	ref := func(info *docTextureInfo) *TextureRef {
+		if info == nil || err != nil {
+			return nil
+		}
+		var t *Texture
+		if t, err = d.texture(info.Index); err != nil {
+			err = fmt.Errorf("material %d: %v", i, err)
+			return nil
+		}
+		return &TextureRef{Texture: t, TexCoord: info.TexCoord}
+	}
+	if pbr := dm.PBRMetallicRoughness; pbr != nil {
+		if pbr.BaseColorFactor != nil {
+			m.BaseColor = glm.Vec4(*pbr.BaseColorFactor)
+		}
+		if pbr.MetallicFactor != nil {
+			m.Metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			m.Roughness = *pbr.RoughnessFactor
+		}
+		m.BaseColorTexture = ref(pbr.BaseColorTexture)
+		m.MetallicRoughnessTexture = ref(pbr.MetallicRoughnessTexture)
+	}
+	m.NormalTexture = ref(dm.NormalTexture)
+	if dm.NormalTexture != nil && dm.NormalTexture.Scale != nil {
+		m.NormalScale = *dm.NormalTexture.Scale
+	}
+	m.OcclusionTexture = ref(dm.OcclusionTexture)
+	if dm.OcclusionTexture != nil && dm.OcclusionTexture.Strength != nil {
+		m.OcclusionStrength = *dm.OcclusionTexture.Strength
+	}
+	m.EmissiveTexture = ref(dm.EmissiveTexture)
+	if err != nil {
+		return nil, err
+	}
+
+	d.materials[i] = m
+	d.out.Materials = append(d.out.Materials, m)
+	return m, nil
+}
+
+func (d *decoder) texture(i int) (*Texture, error) {
+	if i < 0 || i >= len(d.textures) {
+		return nil, fmt.Errorf("texture %d doesn't exist", i)
+	}
+	if d.textures[i] != nil {
+		return d.textures[i], nil
+	}
+	dt := d.doc.Textures[i]
+	t := &Texture{Name: dt.Name}
+	if dt.Sampler != nil {
+		if *dt.Sampler < 0 || *dt.Sampler >= len(d.doc.Samplers) {
+			return nil, fmt.Errorf("texture %d: sampler %d doesn't exist", i, *dt.Sampler)
+		}
+		s := d.doc.Samplers[*dt.Sampler]
+		t.MagFilter, t.MinFilter, t.WrapS, t.WrapT = s.MagFilter, s.MinFilter, s.WrapS, s.WrapT
+	}
+	if dt.Source == nil {
+		return nil, fmt.Errorf("texture %d has no image", i)
+	}
+	var err error
+	if t.Image, err = d.image(*dt.Source); err != nil {
+		return nil, fmt.Errorf("texture %d: %v", i, err)
+	}
+	d.textures[i] = t
+	d.out.Textures = append(d.out.Textures, t)
+	return t, nil
+}
+
+// image decodes image i from its file, data URI or buffer view.
+func (d *decoder) image(i int) (image.Image, error) {
+	if i < 0 || i >= len(d.doc.Images) {
+		return nil, fmt.Errorf("image %d doesn't exist", i)
+	}
+	di := d.doc.Images[i]
+	var (
+		data []byte
+		name string
+		err  error
+	)
+	switch {
+	case di.BufferView != nil:
+		data, _, err = d.bufferView(*di.BufferView)
+		name = "image." + strings.TrimPrefix(di.MimeType, "image/")
+	case di.URI != "":
+		data, err = d.readURI(di.URI)
+		name = di.URI
+	default:
+		err = errors.New("no uri or buffer view")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %v", i, err)
+	}
+	img, _, err := texfmt.Decode(bytes.NewReader(data), name)
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %v", i, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,245 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testBuffer is the binary data of testJSON:
+//
+//	0: 4 positions, VEC3 float
+//	48: 4 texture coordinates, VEC2 normalized unsigned bytes
+//	56: 6 indices, unsigned bytes
+//	64: 1 sparse index, unsigned short, and 2 bytes padding
+//	68: 1 sparse position, VEC3 float
+func testBuffer() []byte {
+	var b bytes.Buffer
+	for _, f := range []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0} {
+		binary.Write(&b, binary.LittleEndian, f)
+	}
+	b.Write([]byte{0, 0, 255, 0, 255, 255, 0, 255})
+	b.Write([]byte{0, 1, 2, 0, 2, 3, 0, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(3))
+	b.Write([]byte{0, 0})
+	for _, f := range []float32{0, 2, 0} {
+		binary.Write(&b, binary.LittleEndian, f)
+	}
+	return b.Bytes()
+}
+
+func testPNG() string {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0xFF, 0, 0, 0xFF})
+	img.SetNRGBA(1, 0, color.NRGBA{0, 0, 0xFF, 0xFF})
+	var b bytes.Buffer
+	png.Encode(&b, img)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+// testJSON is a quad under a node hierarchy, with a textured material and
+// a camera. bufferURI is the "uri" property of the buffer, if any.
+func testJSON(bufferURI string) string {
+	return `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"name": "test", "nodes": [0, 3]}],
+	"nodes": [
+		{"name": "parent", "translation": [1, 2, 3], "children": [1]},
+		{"name": "child", "rotation": [0, 0, 0.7071068, 0.7071068], "scale": [2, 2, 2], "mesh": 0, "children": [2]},
+		{"name": "leaf", "matrix": [1,0,0,0, 0,1,0,0, 0,0,1,0, 5,0,0,1]},
+		{"name": "eye", "translation": [0, 0, 5], "camera": 0}
+	],
+	"meshes": [{"name": "quad", "primitives": [{
+		"attributes": {"POSITION": 0, "TEXCOORD_0": 1},
+		"indices": 2,
+		"material": 0
+	}]}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3",
+		 "sparse": {"count": 1,
+			"indices": {"bufferView": 3, "componentType": 5123},
+			"values": {"bufferView": 4}}},
+		{"bufferView": 1, "componentType": 5121, "normalized": true, "count": 4, "type": "VEC2"},
+		{"bufferView": 2, "componentType": 5121, "count": 6, "type": "SCALAR"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteOffset": 0, "byteLength": 48},
+		{"buffer": 0, "byteOffset": 48, "byteLength": 8},
+		{"buffer": 0, "byteOffset": 56, "byteLength": 6},
+		{"buffer": 0, "byteOffset": 64, "byteLength": 2},
+		{"buffer": 0, "byteOffset": 68, "byteLength": 12}
+	],
+	"buffers": [{` + bufferURI + `"byteLength": 80}],
+	"materials": [{
+		"name": "red",
+		"pbrMetallicRoughness": {
+			"baseColorFactor": [1, 0.5, 0.5, 1],
+			"baseColorTexture": {"index": 0},
+			"metallicFactor": 0.25
+		},
+		"doubleSided": true
+	}],
+	"textures": [{"sampler": 0, "source": 0}],
+	"samplers": [{"magFilter": 9728, "wrapS": 33071}],
+	"images": [{"uri": "` + testPNG() + `"}],
+	"cameras": [{"type": "perspective", "perspective": {"yfov": 0.8, "znear": 0.1}}]
+}`
+}
+
+func checkScene(t *testing.T, s *Scene) {
+	if s.Name != "test" || len(s.Nodes) != 2 {
+		t.Fatalf("scene %q with %d root nodes", s.Name, len(s.Nodes))
+	}
+
+	world := make(map[string]glm.Mat4)
+	s.Walk(func(n *Node, m glm.Mat4) {
+		world[n.Name] = m
+	})
+	// the leaf is 5 along X of the child, which is rotated a quarter turn
+	// about Z and scaled by 2
+	leaf := world["leaf"].Mul4x1(glm.Vec4{0, 0, 0, 1})
+	if !leaf.ApproxEqualThreshold(glm.Vec4{1, 12, 3, 1}, 1e-5) {
+		t.Errorf("leaf is at %v, want (1, 12, 3)", leaf)
+	}
+
+	child := s.Nodes[0].Children[0]
+	if child.Mesh == nil || len(child.Mesh.Primitives) != 1 {
+		t.Fatalf("child mesh %+v", child.Mesh)
+	}
+	p := child.Mesh.Primitives[0]
+	m := p.Mesh
+	// without normals in the file every triangle gets its own vertices
+	if len(m.Positions) != 6 || len(m.Indices) != 6 {
+		t.Fatalf("%d positions and %d indices", len(m.Positions), len(m.Indices))
+	}
+	if m.Positions[5] != (glm.Vec3{0, 2, 0}) {
+		t.Errorf("sparse position is %v, want (0, 2, 0)", m.Positions[5])
+	}
+	if m.Texcoords[2] != (glm.Vec2{1, 0}) || m.Texcoords[0] != (glm.Vec2{0, 1}) {
+		t.Errorf("texture coordinates %v aren't normalized and flipped", m.Texcoords)
+	}
+	// flat normals for a quad facing +Z
+	for i, n := range m.Normals {
+		if n != (glm.Vec3{0, 0, 1}) {
+			t.Errorf("normal %d is %v", i, n)
+		}
+	}
+
+	mat := p.Material
+	if mat == nil || mat.Name != "red" {
+		t.Fatalf("material %+v", mat)
+	}
+	if mat.BaseColor != (glm.Vec4{1, 0.5, 0.5, 1}) || mat.Metallic != 0.25 || mat.Roughness != 1 || !mat.DoubleSided || mat.AlphaMode != "OPAQUE" {
+		t.Errorf("material %+v", mat)
+	}
+	tex := mat.BaseColorTexture
+	if tex == nil || tex.Image.Bounds().Dx() != 2 || tex.MagFilter != 9728 || tex.WrapS != 33071 || tex.WrapT != 0 {
+		t.Fatalf("base color texture %+v", tex)
+	}
+
+	eye := s.Nodes[1]
+	if eye.Camera == nil || len(s.Cameras) != 1 {
+		t.Fatalf("camera %+v", eye.Camera)
+	}
+	proj := eye.Camera.Projection(2)
+	// a point at the near plane maps to depth -1
+	clip := proj.Mul4x1(glm.Vec4{0, 0, -0.1, 1})
+	if z := clip[2] / clip[3]; math.Abs(float64(z+1)) > 1e-5 {
+		t.Errorf("near plane maps to depth %g", z)
+	}
+	if proj[0]*2 != proj[5] {
+		t.Errorf("projection %v doesn't use the aspect ratio", proj)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	uri := `"uri": "data:application/octet-stream;base64,` + base64.StdEncoding.EncodeToString(testBuffer()) + `", `
+	s, err := Decode([]byte(testJSON(uri)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScene(t, s)
+}
+
+func TestDecodeGLB(t *testing.T) {
+	chunk := func(typ uint32, data []byte, pad byte) []byte {
+		for len(data)%4 != 0 {
+			data = append(data, pad)
+		}
+		var b bytes.Buffer
+		binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+		binary.Write(&b, binary.LittleEndian, typ)
+		b.Write(data)
+		return b.Bytes()
+	}
+	body := append(chunk(0x4E4F534A, []byte(testJSON("")), ' '), chunk(0x004E4942, testBuffer(), 0)...)
+	var glb bytes.Buffer
+	glb.WriteString("glTF")
+	binary.Write(&glb, binary.LittleEndian, uint32(2))
+	binary.Write(&glb, binary.LittleEndian, uint32(12+len(body)))
+	glb.Write(body)
+
+	s, err := Decode(glb.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScene(t, s)
+
+	if _, err := Decode(glb.Bytes()[:glb.Len()-4], nil); err == nil {
+		t.Error("truncated GLB decoded without an error")
+	}
+}
+
+func TestReadElements(t *testing.T) {
+	// a MAT2 of signed bytes has every column padded to four bytes
+	data := []byte{127, 0x81, 0, 0, 0, 127, 0, 0}
+	got, err := readElements(data, 0, 1, typeByte, 4, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[1 -1 0 1]" {
+		t.Errorf("got %v, want [1 -1 0 1]", got)
+	}
+	if _, err := readElements(data, 0, 2, typeByte, 4, 2, true); err == nil {
+		t.Error("read past the end of the data")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		json, err string
+	}{
+		{`{"asset": {"version": "1.0"}}`, `glTF version "1.0" is not supported`},
+		{`{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`, "required extensions are not supported: KHR_draco_mesh_compression"},
+		{`{"asset": {"version": "2.0"}, "scenes": [{"nodes": [0]}], "nodes": [{"children": [0]}]}`, "node 0 is its own ancestor"},
+		{`{"asset": {"version": "2.0"}, "scenes": [{"nodes": [0]}], "nodes": [{"mesh": 1}]}`, "mesh 1 doesn't exist"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}], "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+			"accessors": [{"bufferView": 0, "componentType": 5126, "count": 2, "type": "VEC3"}],
+			"bufferViews": [{"buffer": 0, "byteLength": 12}], "buffers": [{"uri": "data:;base64,AAAAAAAAAAAAAAAA", "byteLength": 12}]}`,
+			"mesh 0 primitive 0: accessor 0: 2 elements of 12 bytes, 12 apart, don't fit in 12 bytes"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}], "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+			"accessors": [{"componentType": 5126, "count": 1, "type": "VEC2"}]}`,
+			"mesh 0 primitive 0: accessor 0: type VEC2 is not allowed here"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}], "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+			"accessors": [{"componentType": 5126, "count": 22000000, "type": "VEC3"}]}`,
+			"mesh 0 primitive 0: accessor 0: 22000000 elements of type VEC3 are too many"},
+		{`{"asset": {"version": "2.0"}, "nodes": [{"mesh": 0}], "meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+			"accessors": [{"componentType": 5126, "count": 3, "type": "VEC3"}]}`,
+			"mesh 0 primitive 0: accessor 0: has neither a buffer view nor sparse values"},
+	}
+	for _, test := range tests {
+		_, err := Decode([]byte(test.json), nil)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("got error %v, want %s", err, test.err)
+		}
+	}
+}
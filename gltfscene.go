@@ -0,0 +1,264 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/gltf"
+	"github.com/go-gl/gl"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"image/color"
+	"math"
+)
+
+// gltfPath is the glTF file the gltf scene draws, set with the --gltf
+// flag.
+var gltfPath = "models/boxes.gltf"
+
+func init() {
+	registerScene("gltf", func() Scene { return new(gltfScene) })
+}
+
+// gltfUniforms are the uniforms of the gltf shaders.
+type gltfUniforms struct {
+	Model                glm.Mat4 `glsl:"model"`
+	View                 glm.Mat4 `glsl:"view"`
+	Proj                 glm.Mat4 `glsl:"proj"`
+	NormalMatrix         glm.Mat3 `glsl:"normalMatrix"`
+	BaseColorFactor      glm.Vec4 `glsl:"baseColorFactor"`
+	BaseColorMap         int32    `glsl:"baseColorMap"`
+	MetallicFactor       float32  `glsl:"metallicFactor"`
+	RoughnessFactor      float32  `glsl:"roughnessFactor"`
+	MetallicRoughnessMap int32    `glsl:"metallicRoughnessMap"`
+	EmissiveFactor       glm.Vec3 `glsl:"emissiveFactor"`
+	AlphaCutoff          float32  `glsl:"alphaCutoff"`
+	CameraPos            glm.Vec3 `glsl:"cameraPos"`
+	LightDir             glm.Vec3 `glsl:"lightDir"`
+}
+
+// gltfDraw is a primitive of the scene with the transform of its node.
+type gltfDraw struct {
+	mesh     *glMesh
+	world    glm.Mat4
+	material *gltf.Material
+}
+
+// gltfScene draws a glTF scene with its PBR materials, lit by a single
+// directional light, from the first camera in the file or from the view
+// the 3D exercises use.
+type gltfScene struct {
+	draws    []gltfDraw
+	textures map[*gltf.Texture]gl.Texture
+	white    gl.Texture
+	root     glm.Mat4
+	program  *shaderProgram
+	uniforms gltfUniforms
+	binding  *uniformBinding
+	camera   camera.Controller
+	lens     *gltf.Camera
+	simTime
+}
+
+func (s *gltfScene) Init() {
+	var err error
+
+	gl.Enable(gl.DEPTH_TEST)
+
+	scene, err := gltf.Load(gltfPath)
+	if err != nil {
+		panic(err)
+	}
+
+	// create shader program
+	s.program, err = loadProgram("gltf", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup vertex data, one vertex array per primitive, and textures
+	s.textures = make(map[*gltf.Texture]gl.Texture)
+	white := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	white.SetNRGBA(0, 0, color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF})
+	s.white, err = createTexture(white, DefaultSampler)
+	if err != nil {
+		panic(err)
+	}
+	var positions []glm.Vec3
+	meshes := make(map[*gltf.Mesh][]*glMesh)
+	scene.Walk(func(n *gltf.Node, world glm.Mat4) {
+		if n.Mesh == nil || err != nil {
+			return
+		}
+		if meshes[n.Mesh] == nil {
+			for _, p := range n.Mesh.Primitives {
+				var m *glMesh
+				if m, err = uploadMesh(p.Mesh, s.program.Program); err != nil {
+					return
+				}
+				meshes[n.Mesh] = append(meshes[n.Mesh], m)
+			}
+		}
+		for i, p := range n.Mesh.Primitives {
+			s.draws = append(s.draws, gltfDraw{meshes[n.Mesh][i], world, p.Material})
+			for _, pos := range p.Mesh.Positions {
+				positions = append(positions, world.Mul4x1(pos.Vec4(1)).Vec3())
+			}
+			if p.Material != nil {
+				for _, t := range []*gltf.TextureRef{p.Material.BaseColorTexture, p.Material.MetallicRoughnessTexture} {
+					if t != nil && err == nil {
+						err = s.uploadTexture(t.Texture)
+					}
+				}
+			}
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// scale the scene into a unit cube around the origin, Z up
+	s.root = fitUnitCube(positions)
+
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.uniforms = gltfUniforms{
+		BaseColorMap:         0,
+		MetallicRoughnessMap: 1,
+		LightDir:             glm.Vec3{0.3, 0.5, 1.0}.Normalize(),
+	}
+
+	// look at the scene through a camera the mouse and keyboard move,
+	// starting where the first camera of the file is, if it has one
+	eye, target, up := glm.Vec3{1.1, 1.6, 1.1}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 0.5}
+	scene.Walk(func(n *gltf.Node, world glm.Mat4) {
+		if n.Camera != nil && len(scene.Cameras) > 0 && n.Camera == scene.Cameras[0] {
+			m := s.root.Mul4(world)
+			eye, up = m.Col(3).Vec3(), m.Col(1).Vec3()
+			distance := eye.Len()
+			if distance == 0 {
+				distance = 1
+			}
+			target = eye.Sub(m.Col(2).Vec3().Normalize().Mul(distance))
+			s.lens = n.Camera
+		}
+	})
+	s.camera = newCamera(eye, target, up)
+	s.uniforms.View = s.camera.View()
+	s.uniforms.CameraPos = s.camera.Eye()
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+}
+
+// uploadTexture creates the texture for t unless it exists already,
+// sampling it as the file asks.
+func (s *gltfScene) uploadTexture(t *gltf.Texture) error {
+	if _, ok := s.textures[t]; ok {
+		return nil
+	}
+	opts := DefaultSampler
+	if t.MagFilter != 0 {
+		opts.MagFilter = gl.GLenum(t.MagFilter)
+	}
+	if t.MinFilter != 0 {
+		opts.MinFilter = gl.GLenum(t.MinFilter)
+	}
+	if t.WrapS != 0 {
+		opts.WrapS = gl.GLenum(t.WrapS)
+	}
+	if t.WrapT != 0 {
+		opts.WrapT = gl.GLenum(t.WrapT)
+	}
+	texture, err := createTexture(t.Image, opts)
+	if err != nil {
+		return err
+	}
+	s.textures[t] = texture
+	return nil
+}
+
+// bindUniforms binds s.uniforms to the uniforms of the program and uploads
+// them.
+func (s *gltfScene) bindUniforms() {
+	var err error
+	s.binding, err = bindUniformStruct(s.program.Program, &s.uniforms)
+	if err != nil {
+		panic(err)
+	}
+	s.binding.Upload()
+}
+
+// bindTexture binds the texture of ref, or white if there is none, to
+// texture unit.
+func (s *gltfScene) bindTexture(unit gl.GLenum, ref *gltf.TextureRef) {
+	gl.ActiveTexture(unit)
+	if ref == nil {
+		s.white.Bind(gl.TEXTURE_2D)
+		return
+	}
+	s.textures[ref.Texture].Bind(gl.TEXTURE_2D)
+}
+
+func (s *gltfScene) Render(alpha float32) {
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// move the camera and fit the projection to the window
+	s.uniforms.View = s.camera.View()
+	s.uniforms.CameraPos = s.camera.Eye()
+	s.uniforms.Proj = perspective(45.0, 0.1, 10.0)
+	if s.lens != nil {
+		s.uniforms.Proj = s.lens.Projection(framebufferAspect)
+	}
+
+	// rotate
+	spin := glm.HomogRotate3DZ(0.25 * math.Pi * float32(s.seconds(alpha))).Mul4(s.root)
+
+	for _, d := range s.draws {
+		s.uniforms.Model = spin.Mul4(d.world)
+		s.uniforms.NormalMatrix = s.uniforms.Model.Mat3().Inv().Transpose()
+
+		// the default material is white, fully metallic and rough
+		m := d.material
+		if m == nil {
+			m = &gltf.Material{BaseColor: glm.Vec4{1, 1, 1, 1}, Metallic: 1, Roughness: 1, AlphaMode: "OPAQUE"}
+		}
+		s.uniforms.BaseColorFactor = m.BaseColor
+		s.uniforms.MetallicFactor = m.Metallic
+		s.uniforms.RoughnessFactor = m.Roughness
+		s.uniforms.EmissiveFactor = m.Emissive
+		s.uniforms.AlphaCutoff = 0
+		if m.AlphaMode == "MASK" {
+			s.uniforms.AlphaCutoff = m.AlphaCutoff
+		}
+		s.binding.Upload()
+		s.bindTexture(gl.TEXTURE0, m.BaseColorTexture)
+		s.bindTexture(gl.TEXTURE1, m.MetallicRoughnessTexture)
+
+		if m.DoubleSided {
+			gl.Disable(gl.CULL_FACE)
+		} else {
+			gl.Enable(gl.CULL_FACE)
+		}
+		if m.AlphaMode == "BLEND" {
+			gl.Enable(gl.BLEND)
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		}
+		d.mesh.Draw()
+		gl.Disable(gl.BLEND)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func (s *gltfScene) Delete() {
+	done := make(map[*glMesh]bool)
+	for _, d := range s.draws {
+		if !done[d.mesh] {
+			d.mesh.Delete()
+			done[d.mesh] = true
+		}
+	}
+	for _, t := range s.textures {
+		t.Delete()
+	}
+	s.white.Delete()
+	s.program.Delete()
+}
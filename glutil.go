@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/shader"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	"github.com/go-gl/glu"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+func checkError(prefix string) {
+	if glError := gl.GetError(); glError != gl.NO_ERROR {
+		errorString, err := glu.ErrorString(glError)
+		if err != nil {
+			fmt.Printf("%s: unspecified error!\n", prefix)
+		} else {
+			fmt.Printf("%s error: %s\n", prefix, errorString)
+		}
+	}
+}
+
+// createTexture uploads img to a new texture bound to the active texture
+// unit and samples it with opts. The internal format follows the image:
+// opaque images leave out the alpha channel, 16-bit images keep their
+// precision and texfmt.FloatImages become RGBA32F.
+func createTexture(img image.Image, opts SamplerOptions) (gl.Texture, error) {
+	if img.Bounds().Empty() {
+		return gl.Texture(0), errors.New("texture image is empty")
+	}
+	return uploadTexture(newTexImage(img), opts), nil
+}
+
+// createFloatTexture uploads img as a floating-point RGBA texture, RGBA16F
+// if half is set and RGBA32F otherwise. Images that aren't already a
+// texfmt.FloatImage are assumed to be sRGB encoded and are linearized.
+func createFloatTexture(img image.Image, half bool, opts SamplerOptions) (gl.Texture, error) {
+	if img.Bounds().Empty() {
+		return gl.Texture(0), errors.New("texture image is empty")
+	}
+	t := newTexImage(texfmt.ToFloat(img, true))
+	if half {
+		// OpenGL converts the float data on upload
+		t.internalFormat = gl.RGBA16F
+	}
+	return uploadTexture(t, opts), nil
+}
+
+func uploadTexture(t *texImage, opts SamplerOptions) gl.Texture {
+	textureId := gl.GenTexture()
+	textureId.Bind(gl.TEXTURE_2D)
+
+	// rows of three channel images aren't 4-byte aligned
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, t.internalFormat, t.width, t.height, 0, t.format, t.typ, t.pixels)
+	checkError("texture")
+
+	// after the upload, mipmaps are generated from the base level
+	applySampler(gl.TEXTURE_2D, opts)
+	return textureId
+}
+
+// loadTexture decodes the image file at path in any format texfmt knows
+// and uploads it to the texture unit that is currently active.
+func loadTexture(path string, opts SamplerOptions) (gl.Texture, error) {
+	img, err := texfmt.Open(path)
+	if err != nil {
+		return gl.Texture(0), err
+	}
+
+	texture, err := createTexture(img, opts)
+	if err != nil {
+		return gl.Texture(0), fmt.Errorf("%s: %v", path, err)
+	}
+	return texture, nil
+}
+
+// loadFloatTexture is loadTexture for createFloatTexture, for previewing
+// HDR images and 16-bit PNGs.
+func loadFloatTexture(path string, half bool, opts SamplerOptions) (gl.Texture, error) {
+	img, err := texfmt.Open(path)
+	if err != nil {
+		return gl.Texture(0), err
+	}
+
+	texture, err := createFloatTexture(img, half, opts)
+	if err != nil {
+		return gl.Texture(0), fmt.Errorf("%s: %v", path, err)
+	}
+	return texture, nil
+}
+
+// compileShader compiles src. The compile log of a failed compile is
+// returned with the source lines it points at.
+func compileShader(typ gl.GLenum, name string, src *shader.Source) (gl.Shader, error) {
+	s := gl.CreateShader(typ)
+	s.Source(src.Code)
+	s.Compile()
+	if s.Get(gl.COMPILE_STATUS) != gl.TRUE {
+		defer s.Delete()
+		return gl.Shader(0), fmt.Errorf("%s shader compilation error in %s:\n%s", name, shaderDir, src.Annotate(src.Errors(s.GetInfoLog())))
+	}
+	checkError(name + " shader")
+	return s, nil
+}
+
+// shaderDir is the directory loadProgram reads shader files from.
+var shaderDir = "shaders"
+
+// loadProgram compiles name.vert.glsl and name.frag.glsl from shaderDir,
+// preprocessed with defines, links them with outColor bound to the first
+// draw buffer and makes the result the current program. Compiler messages
+// point at the lines of the original files, shown with some context. The
+// program is rebuilt when its files change, see reloadPrograms.
+func loadProgram(name string, defines map[string]string) (*shaderProgram, error) {
+	program, files, err := buildProgram(name, defines, nil)
+	if err != nil {
+		return nil, err
+	}
+	p := &shaderProgram{
+		Program: program,
+		name:    name,
+		defines: defines,
+		files:   modTimes(files),
+	}
+	livePrograms = append(livePrograms, p)
+	return p, nil
+}
+
+// buildProgram does the work of loadProgram. Attributes named in attribs
+// are bound to the given locations before linking. It returns the paths of
+// the files the program was read from.
+func buildProgram(name string, defines map[string]string, attribs map[string]gl.AttribLocation) (gl.Program, []string, error) {
+	fsys := os.DirFS(shaderDir)
+	vertexSource, err := shader.Load(fsys, name+".vert.glsl", defines)
+	if err != nil {
+		return gl.Program(0), nil, err
+	}
+	fragmentSource, err := shader.Load(fsys, name+".frag.glsl", defines)
+	if err != nil {
+		return gl.Program(0), nil, err
+	}
+
+	var files []string
+	for _, file := range append(vertexSource.Files, fragmentSource.Files...) {
+		files = append(files, filepath.Join(shaderDir, file))
+	}
+
+	vertexShader, err := compileShader(gl.VERTEX_SHADER, "vertex", vertexSource)
+	if err != nil {
+		return gl.Program(0), files, err
+	}
+	defer vertexShader.Delete()
+
+	fragmentShader, err := compileShader(gl.FRAGMENT_SHADER, "fragment", fragmentSource)
+	if err != nil {
+		return gl.Program(0), files, err
+	}
+	defer fragmentShader.Delete()
+
+	program, err := linkProgram(vertexShader, fragmentShader, attribs)
+	if err != nil {
+		return gl.Program(0), files, fmt.Errorf("%s: %v", name, err)
+	}
+	return program, files, nil
+}
+
+func linkProgram(vertexShader, fragmentShader gl.Shader, attribs map[string]gl.AttribLocation) (gl.Program, error) {
+	program := gl.CreateProgram()
+	program.AttachShader(vertexShader)
+	program.AttachShader(fragmentShader)
+	for name, location := range attribs {
+		program.BindAttribLocation(location, name)
+	}
+	program.BindFragDataLocation(0, "outColor")
+	program.Link()
+	program.Use()
+	program.Validate()
+	if program.Get(gl.VALIDATE_STATUS) != gl.TRUE {
+		defer program.Delete()
+		return gl.Program(0), fmt.Errorf("program error: %s", program.GetInfoLog())
+	}
+	checkError("program")
+
+	return program, nil
+}
@@ -0,0 +1,247 @@
+// Package golden compares rendered frames against reference images.
+//
+// Two metrics are computed: a per-pixel check where a pixel differs when
+// any channel is off by more than a tolerance, and the mean structural
+// similarity (SSIM) of the luminance, which tolerates noise a viewer would
+// not notice but catches blurring, shifts and contrast changes.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+)
+
+// Options control when two images are considered equal.
+type Options struct {
+	// Tolerance is the largest per-channel difference (0-255) that does
+	// not count as a differing pixel.
+	Tolerance uint8
+
+	// MaxDiffRatio is the fraction of pixels that may differ.
+	MaxDiffRatio float64
+
+	// MinSSIM is the lowest acceptable mean structural similarity, 1
+	// meaning identical.
+	MinSSIM float64
+}
+
+// DefaultOptions allow for rounding differences between GL drivers.
+var DefaultOptions = Options{
+	Tolerance:    2,
+	MaxDiffRatio: 0.001,
+	MinSSIM:      0.99,
+}
+
+// Result describes how much two images differ.
+type Result struct {
+	Pixels     int     // number of compared pixels
+	DiffPixels int     // pixels with a channel difference above the tolerance
+	MaxDelta   uint8   // largest channel difference
+	SSIM       float64 // mean structural similarity of the luminance
+
+	// Heatmap shows the reference dimmed to gray with differing pixels
+	// highlighted from yellow (small) to red (large).
+	Heatmap *image.NRGBA
+}
+
+// DiffRatio returns the fraction of pixels that differ.
+func (r *Result) DiffRatio() float64 {
+	if r.Pixels == 0 {
+		return 0
+	}
+	return float64(r.DiffPixels) / float64(r.Pixels)
+}
+
+// Passed reports whether the result lies within the limits of opts.
+func (r *Result) Passed(opts Options) bool {
+	return r.DiffRatio() <= opts.MaxDiffRatio && r.SSIM >= opts.MinSSIM
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%d of %d pixels differ (%.3f%%), max delta %d, SSIM %.4f",
+		r.DiffPixels, r.Pixels, 100*r.DiffRatio(), r.MaxDelta, r.SSIM)
+}
+
+// Compare compares got against the reference want. Both images must have
+// the same size.
+func Compare(want, got image.Image, opts Options) (*Result, error) {
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Dx() != gb.Dx() || wb.Dy() != gb.Dy() {
+		return nil, fmt.Errorf("size mismatch: want %dx%d, got %dx%d", wb.Dx(), wb.Dy(), gb.Dx(), gb.Dy())
+	}
+
+	w, g := toNRGBA(want), toNRGBA(got)
+	width, height := wb.Dx(), wb.Dy()
+	r := &Result{
+		Pixels:  width * height,
+		Heatmap: image.NewNRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*w.Stride + x*4
+			var delta uint8
+			for c := 0; c < 4; c++ {
+				if d := absDiff(w.Pix[i+c], g.Pix[i+c]); d > delta {
+					delta = d
+				}
+			}
+			if delta > r.MaxDelta {
+				r.MaxDelta = delta
+			}
+
+			var hc color.NRGBA
+			if delta > opts.Tolerance {
+				r.DiffPixels++
+				hc = heat(float64(delta) / 255)
+			} else {
+				l := uint8(luma(w.Pix[i:i+4]) / 4)
+				hc = color.NRGBA{l, l, l, 0xff}
+			}
+			r.Heatmap.SetNRGBA(x, y, hc)
+		}
+	}
+
+	r.SSIM = ssim(lumaPlane(w), lumaPlane(g), width, height)
+	return r, nil
+}
+
+// ReadPNG decodes the PNG file at path.
+func ReadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// WritePNG encodes img to the PNG file at path.
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	if n, ok := img.(*image.NRGBA); ok && b.Min == (image.Point{}) && n.Stride == 4*b.Dx() {
+		return n
+	}
+
+	n := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			n.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return n
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// luma returns the Rec. 601 luminance of a non-premultiplied RGBA pixel
+// composited over black.
+func luma(p []uint8) float64 {
+	a := float64(p[3]) / 255
+	return a * (0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2]))
+}
+
+func lumaPlane(img *image.NRGBA) []float64 {
+	b := img.Bounds()
+	plane := make([]float64, 0, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			i := y*img.Stride + x*4
+			plane = append(plane, luma(img.Pix[i:i+4]))
+		}
+	}
+	return plane
+}
+
+// heat maps t in [0, 1] from yellow to red.
+func heat(t float64) color.NRGBA {
+	t = math.Max(0, math.Min(1, t))
+	// small differences would be invisible on a linear ramp
+	t = math.Sqrt(t)
+	return color.NRGBA{0xff, uint8(0xff * (1 - t)), 0, 0xff}
+}
+
+const (
+	ssimWindow = 8
+	ssimC1     = (0.01 * 255) * (0.01 * 255)
+	ssimC2     = (0.03 * 255) * (0.03 * 255)
+)
+
+// ssim computes the mean structural similarity over windows of 8x8 pixels
+// that overlap by half. Images smaller than a window are compared as a
+// single window.
+func ssim(a, b []float64, width, height int) float64 {
+	win := ssimWindow
+	if width < win || height < win {
+		return ssimBlock(a, b, width, 0, 0, width, height)
+	}
+
+	step := win / 2
+	var (
+		sum float64
+		n   int
+	)
+	for y := 0; y+win <= height; y += step {
+		for x := 0; x+win <= width; x += step {
+			sum += ssimBlock(a, b, width, x, y, win, win)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+func ssimBlock(a, b []float64, stride, x0, y0, w, h int) float64 {
+	if w == 0 || h == 0 {
+		return 1
+	}
+
+	var meanA, meanB float64
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			meanA += a[y*stride+x]
+			meanB += b[y*stride+x]
+		}
+	}
+	n := float64(w * h)
+	meanA /= n
+	meanB /= n
+
+	var varA, varB, cov float64
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			da, db := a[y*stride+x]-meanA, b[y*stride+x]-meanB
+			varA += da * da
+			varB += db * db
+			cov += da * db
+		}
+	}
+	varA /= n
+	varB /= n
+	cov /= n
+
+	return ((2*meanA*meanB + ssimC1) * (2*cov + ssimC2)) /
+		((meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2))
+}
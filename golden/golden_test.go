@@ -0,0 +1,142 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// pattern returns a width x height image of diagonal stripes, with enough
+// structure for SSIM to notice changes.
+func pattern(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x + y) * 16)
+			img.SetNRGBA(x, y, color.NRGBA{v, 255 - v, v / 2, 0xff})
+		}
+	}
+	return img
+}
+
+func TestCompareIdentical(t *testing.T) {
+	img := pattern(32, 24)
+	r, err := Compare(img, img, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pixels != 32*24 || r.DiffPixels != 0 || r.MaxDelta != 0 || math.Abs(r.SSIM-1) > 1e-9 {
+		t.Errorf("identical images: %v", r)
+	}
+	if !r.Passed(DefaultOptions) {
+		t.Errorf("identical images fail")
+	}
+
+	// the heatmap shows the reference dimmed to gray
+	c := r.Heatmap.NRGBAAt(5, 7)
+	if c.R != c.G || c.G != c.B || c.A != 0xff {
+		t.Errorf("heatmap of a matching pixel is %v, want gray", c)
+	}
+}
+
+func TestCompareTolerance(t *testing.T) {
+	want := pattern(32, 24)
+	got := pattern(32, 24)
+	got.Pix[0] += 2                     // within the tolerance
+	got.SetNRGBA(10, 20, color.NRGBA{}) // far off, alpha included
+
+	opts := Options{Tolerance: 2, MaxDiffRatio: 1, MinSSIM: 0}
+	r, err := Compare(want, got, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.DiffPixels != 1 || r.MaxDelta != 0xff {
+		t.Errorf("%d differing pixels, max delta %d, want 1, 255", r.DiffPixels, r.MaxDelta)
+	}
+	if got := r.DiffRatio(); got != 1.0/(32*24) {
+		t.Errorf("diff ratio %g", got)
+	}
+	if c := r.Heatmap.NRGBAAt(10, 20); c != (color.NRGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("heatmap of the largest difference is %v, want red", c)
+	}
+	if c := r.Heatmap.NRGBAAt(0, 0); c.R != c.B {
+		t.Errorf("heatmap of a difference within the tolerance is %v, want gray", c)
+	}
+
+	opts.MaxDiffRatio = 0
+	if r.Passed(opts) {
+		t.Errorf("passed with a differing pixel and MaxDiffRatio 0")
+	}
+}
+
+func TestCompareSize(t *testing.T) {
+	if _, err := Compare(pattern(32, 24), pattern(24, 32), DefaultOptions); err == nil {
+		t.Errorf("compared images of different sizes")
+	}
+
+	// only the size of the bounds matters, not where they start
+	img := pattern(40, 30)
+	sub := img.SubImage(image.Rect(8, 6, 40, 30))
+	shifted := image.NewNRGBA(image.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			shifted.SetNRGBA(x, y, img.NRGBAAt(x+8, y+6))
+		}
+	}
+	r, err := Compare(shifted, sub, DefaultOptions)
+	if err != nil || r.DiffPixels != 0 {
+		t.Errorf("sub-image: %v, %v", r, err)
+	}
+}
+
+func TestSSIM(t *testing.T) {
+	want := pattern(32, 32)
+
+	// a shift moves every edge, which SSIM catches
+	shifted := image.NewNRGBA(want.Bounds())
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			shifted.SetNRGBA(x, y, want.NRGBAAt((x+3)%32, y))
+		}
+	}
+	// slight noise keeps the structure
+	noisy := pattern(32, 32)
+	for i := 0; i < len(noisy.Pix); i += 4 * 7 {
+		noisy.Pix[i]++
+	}
+
+	opts := Options{Tolerance: 0, MaxDiffRatio: 1, MinSSIM: 0}
+	rShifted, _ := Compare(want, shifted, opts)
+	rNoisy, _ := Compare(want, noisy, opts)
+	if rNoisy.SSIM < 0.99 {
+		t.Errorf("noisy SSIM %.4f, want at least 0.99", rNoisy.SSIM)
+	}
+	if rShifted.SSIM >= rNoisy.SSIM || rShifted.SSIM > 0.9 {
+		t.Errorf("shifted SSIM %.4f, noisy %.4f", rShifted.SSIM, rNoisy.SSIM)
+	}
+
+	// images smaller than a window are compared as a whole
+	small, black := pattern(4, 4), image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	if r, _ := Compare(small, black, opts); r.SSIM > 0.5 {
+		t.Errorf("SSIM of a pattern and black %.4f", r.SSIM)
+	}
+}
+
+func TestHeat(t *testing.T) {
+	tests := []struct {
+		t    float64
+		want color.NRGBA
+	}{
+		{-1, color.NRGBA{0xff, 0xff, 0, 0xff}},
+		{0, color.NRGBA{0xff, 0xff, 0, 0xff}},
+		{0.25, color.NRGBA{0xff, 0x7f, 0, 0xff}},
+		{1, color.NRGBA{0xff, 0, 0, 0xff}},
+		{2, color.NRGBA{0xff, 0, 0, 0xff}},
+	}
+	for _, test := range tests {
+		if got := heat(test.t); got != test.want {
+			t.Errorf("heat(%g) = %v, want %v", test.t, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"github.com/Happy-Ferret/gl-tutorial/golden"
+	"github.com/Happy-Ferret/gl-tutorial/softscenes"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var (
+	renderGL     = flag.Bool("golden.gl", false, "render the exercises with the OpenGL driver and compare the frames")
+	update       = flag.Bool("update", false, "write the rendered frames as new reference images")
+	goldenFrame  = flag.Int("golden.frame", 0, "index of the frame compared against the reference")
+	tolerance    = flag.Int("golden.tolerance", int(golden.DefaultOptions.Tolerance), "largest per-channel difference that still matches")
+	maxDiffRatio = flag.Float64("golden.maxdiff", golden.DefaultOptions.MaxDiffRatio, "fraction of pixels that may differ")
+	minSSIM      = flag.Float64("golden.minssim", golden.DefaultOptions.MinSSIM, "lowest acceptable structural similarity")
+	failureDir   = flag.String("golden.failures", filepath.Join("testdata", "failures"), "directory for frames and heatmaps of failed comparisons")
+)
+
+// scenes that draw nothing worth comparing
+var goldenSkip = map[string]bool{
+	"context-creation": true,
+}
+
+// The software rasterizer only has to agree with the driver up to
+// rounding and sampling differences along edges.
+var softwareOptions = golden.Options{
+	Tolerance:    8,
+	MaxDiffRatio: 0.01,
+	MinSSIM:      0.95,
+}
+
+// scenes whose GL output is undefined
+var softwareSkip = map[string]bool{
+	"texture-6": true, // draws 12 elements from a 6 element buffer
+}
+
+// requireGL skips a test that renders with the driver unless -golden.gl is
+// set. With it, a scene that can't be rendered fails the test rather than
+// skipping it, so a broken driver setup doesn't pass without comparing.
+// TestGolden still skips scenes that have no reference image yet.
+func requireGL(t *testing.T) {
+	if !*renderGL {
+		t.Skip("rendering with OpenGL needs -golden.gl")
+	}
+}
+
+func TestGolden(t *testing.T) {
+	requireGL(t)
+	opts := golden.Options{
+		Tolerance:    uint8(*tolerance),
+		MaxDiffRatio: *maxDiffRatio,
+		MinSSIM:      *minSSIM,
+	}
+
+	for _, name := range sceneNames() {
+		if goldenSkip[name] {
+			continue
+		}
+		name := name
+		t.Run(name, func(t *testing.T) {
+			refPath := filepath.Join("testdata", "golden", name+".png")
+
+			if *update {
+				got := renderGolden(t, name)
+				if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := golden.WritePNG(refPath, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			// the references depend on the driver, so they are recorded
+			// on the machine that compares against them
+			want, err := golden.ReadPNG(refPath)
+			if os.IsNotExist(err) {
+				t.Skipf("no reference image %s, record the references with go test -golden.gl -update first", refPath)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			got := renderGolden(t, name)
+			result, err := golden.Compare(want, got, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed(opts) {
+				return
+			}
+
+			t.Errorf("frame %d does not match %s: %v", *goldenFrame, refPath, result)
+			writeFailure(t, name, got, result.Heatmap)
+		})
+	}
+}
+
+// TestSoftwareMatchesGL cross-checks the software versions of the
+// exercises against what the driver renders.
+func TestSoftwareMatchesGL(t *testing.T) {
+	requireGL(t)
+	for _, name := range softscenes.Names() {
+		if softwareSkip[name] {
+			continue
+		}
+		name := name
+		t.Run(name, func(t *testing.T) {
+			want := renderGolden(t, name)
+
+			var got *image.NRGBA
+			b := want.Bounds()
+			err := softscenes.Render(name, ".", b.Dx(), b.Dy(), *goldenFrame+1, defaultFrameStep, func(i int, img *image.NRGBA) error {
+				got = img
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := golden.Compare(want, got, softwareOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed(softwareOptions) {
+				return
+			}
+
+			t.Errorf("software frame %d does not match GL: %v", *goldenFrame, result)
+			writeFailure(t, name+"-software", got, result.Heatmap)
+		})
+	}
+}
+
+// writeFailure saves a frame that failed a comparison and its heatmap as
+// <prefix>-got.png and <prefix>-diff.png.
+func writeFailure(t *testing.T, prefix string, got, heatmap *image.NRGBA) {
+	if err := os.MkdirAll(*failureDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gotPath := filepath.Join(*failureDir, prefix+"-got.png")
+	diffPath := filepath.Join(*failureDir, prefix+"-diff.png")
+	if err := golden.WritePNG(gotPath, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := golden.WritePNG(diffPath, heatmap); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("wrote %s and %s", gotPath, diffPath)
+}
+
+// renderGolden draws the named scene offscreen and returns the frame
+// selected with -golden.frame.
+func renderGolden(t *testing.T, name string) *image.NRGBA {
+	// the GL context is bound to the thread that created it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	scene, err := lookupScene(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var frame *image.NRGBA
+	err = renderOffscreen(scene, NewFixedClock(defaultFrameStep), nil, *goldenFrame+1, func(i int, img *image.NRGBA) error {
+		frame = img
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot render offscreen: %v", err)
+	}
+	return frame
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	"github.com/go-gl/glh"
+	"image"
+)
+
+// hdrImagePath is the image the hdr scene shows, set with the --image flag.
+var hdrImagePath = "sample.png"
+
+func init() {
+	registerScene("hdr", func() Scene { return new(hdr) })
+}
+
+// hdr previews an HDR image, or any other image linearized from sRGB, in
+// a floating-point texture through the tone-mapping pass.
+type hdr struct {
+	vbo, ebo      gl.Buffer
+	texture       gl.Texture
+	vertices      []gl.GLfloat
+	elements      []gl.GLuint
+	program       *shaderProgram
+	scaleLocation gl.UniformLocation
+	vao           gl.VertexArray
+	toneMap       *toneMapper
+	img           image.Image
+	half          bool
+}
+
+func (s *hdr) Init() {
+	var err error
+
+	// create Vertex Array Object to save shader attributes
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
+	checkError("vertex array object")
+
+	// setup vertex data
+	s.vertices = []gl.GLfloat{
+		-1.0, 1.0, 0.0, 1.0, // top left
+		1.0, 1.0, 1.0, 1.0, // top right
+		1.0, -1.0, 1.0, 0.0, // bottom right
+		-1.0, -1.0, 0.0, 0.0, // bottom left
+	}
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(s.vertices), s.vertices, gl.STATIC_DRAW)
+	checkError("vertex data")
+
+	// setup element data
+	s.elements = []gl.GLuint{
+		0, 1, 2,
+		2, 3, 0,
+	}
+	s.ebo = gl.GenBuffer()
+	s.ebo.Bind(gl.ELEMENT_ARRAY_BUFFER)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int(glh.Sizeof(gl.UNSIGNED_INT))*len(s.elements), s.elements, gl.STATIC_DRAW)
+	checkError("element data")
+
+	// setup texture data, RGBA32F until F switches to RGBA16F
+	s.img, err = texfmt.Open(hdrImagePath)
+	if err != nil {
+		panic(err)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	s.texture, err = createFloatTexture(s.img, s.half, DefaultSampler)
+	if err != nil {
+		panic(fmt.Errorf("%s: %v", hdrImagePath, err))
+	}
+
+	// create shader program
+	s.program, err = loadProgram("hdr", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// tell vertex shader how to process vertex data
+	format := NewVertexFormat(Float2("position"), Float2("texcoord"))
+	err = format.Enable(s.program.Program, s.vertices)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+
+	// setup tone mapping
+	s.toneMap, err = newToneMapper()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// bindUniforms looks up the uniform locations and sets the uniforms that
+// stay the same from frame to frame.
+func (s *hdr) bindUniforms() {
+	s.program.GetUniformLocation("tex").Uniform1i(0)
+	s.scaleLocation = s.program.GetUniformLocation("scale")
+}
+
+func (s *hdr) HandleActions(actions *input.Actions) {
+	switch {
+	case actions.Pressed("exposure-up") || actions.Repeat("exposure-up"):
+		s.toneMap.Exposure += 0.5
+	case actions.Pressed("exposure-down") || actions.Repeat("exposure-down"):
+		s.toneMap.Exposure -= 0.5
+	case actions.Pressed("tone-operator"):
+		s.toneMap.Operator = (s.toneMap.Operator + 1) % toneOperator(len(toneOperatorNames))
+	case actions.Pressed("half-float"):
+		s.half = !s.half
+		texture, err := createFloatTexture(s.img, s.half, DefaultSampler)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		s.texture.Delete()
+		s.texture = texture
+	default:
+		return
+	}
+
+	format := "RGBA32F"
+	if s.half {
+		format = "RGBA16F"
+	}
+	fmt.Printf("%s, exposure %+.1f, %v\n", format, s.toneMap.Exposure, s.toneMap.Operator)
+}
+
+func (s *hdr) Draw(clock Clock) {
+	if ok, err := s.toneMap.Begin(); err != nil {
+		panic(err)
+	} else if !ok {
+		return
+	}
+
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	// fit the image into the viewport without stretching it
+	viewport := make([]int32, 4)
+	gl.GetIntegerv(gl.VIEWPORT, viewport)
+	b := s.img.Bounds()
+	aspect := float32(b.Dx()) / float32(b.Dy()) * float32(viewport[3]) / float32(viewport[2])
+	if aspect > 1 {
+		s.scaleLocation.Uniform2f(1.0, 1.0/aspect)
+	} else {
+		s.scaleLocation.Uniform2f(aspect, 1.0)
+	}
+
+	// draw triangles
+	gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, nil)
+
+	s.toneMap.End()
+}
+
+func (s *hdr) Delete() {
+	s.toneMap.Delete()
+	s.program.Delete()
+	s.texture.Delete()
+	s.ebo.Delete()
+	s.vbo.Delete()
+	s.vao.Delete()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"github.com/Happy-Ferret/gl-tutorial/loop"
+	"github.com/go-gl/gl"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// offscreenTarget is a framebuffer object with a color and a combined
+// depth/stencil renderbuffer that scenes can be drawn into without a
+// visible window.
+type offscreenTarget struct {
+	fbo          gl.Framebuffer
+	color        gl.Renderbuffer
+	depthStencil gl.Renderbuffer
+	width        int
+	height       int
+}
+
+func newOffscreenTarget(width, height int) (*offscreenTarget, error) {
+	t := &offscreenTarget{width: width, height: height}
+
+	t.fbo = gl.GenFramebuffer()
+	t.fbo.Bind()
+
+	t.color = gl.GenRenderbuffer()
+	t.color.Bind()
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, t.color)
+
+	t.depthStencil = gl.GenRenderbuffer()
+	t.depthStencil.Bind()
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, t.depthStencil)
+	checkError("offscreen framebuffer")
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		t.Delete()
+		return nil, fmt.Errorf("offscreen framebuffer incomplete: 0x%x", status)
+	}
+
+	return t, nil
+}
+
+// ReadImage reads back the color attachment.
+func (t *offscreenTarget) ReadImage() *image.NRGBA {
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	return readPixels(t.width, t.height)
+}
+
+// readPixels reads back width by height pixels of the read buffer. OpenGL
+// stores the lower left corner first, so the rows are flipped to undo what
+// createTexture does on upload.
+func readPixels(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, img.Pix)
+	checkError("read pixels")
+
+	flipRows(img.Pix, img.Stride)
+	return img
+}
+
+func (t *offscreenTarget) Delete() {
+	t.fbo.Unbind()
+	t.depthStencil.Delete()
+	t.color.Delete()
+	t.fbo.Delete()
+}
+
+// flipRows mirrors a tightly packed image vertically in place.
+func flipRows(pix []byte, stride int) {
+	line := make([]byte, stride)
+	for top, bottom := 0, len(pix)-stride; top < bottom; top, bottom = top+stride, bottom-stride {
+		copy(line, pix[top:top+stride])
+		copy(pix[top:top+stride], pix[bottom:bottom+stride])
+		copy(pix[bottom:bottom+stride], line)
+	}
+}
+
+// renderOffscreen draws frames frames of scene into an offscreen target
+// and hands every frame to fn. The GL context is surfaceless, so no window
+// is opened and no display is needed. The scene runs on a fixed-step clock,
+// so frame n always shows the same image, and takes its input from player,
+// if set. With Mesa, setting LIBGL_ALWAYS_SOFTWARE=1 selects the software
+// rasterizer so no GPU is needed either.
+func renderOffscreen(scene Scene, clock *FixedClock, player *input.Player, frames int, fn func(frame int, img *image.NRGBA) error) error {
+	var actions *input.Actions
+	if player != nil {
+		var err error
+		actions, err = loadActions()
+		if err != nil {
+			return err
+		}
+	}
+
+	config := defaultWindowConfig
+	if c, ok := scene.(WindowConfigurer); ok {
+		config = c.WindowConfig()
+	}
+	config = config.windowed()
+
+	context, err := newSurfacelessContext()
+	if err != nil {
+		return err
+	}
+	defer context.Destroy()
+
+	gl.Init()     // GLEW reports it found no GLX display, after loading the functions
+	gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
+
+	target, err := newOffscreenTarget(config.Width, config.Height)
+	if err != nil {
+		return err
+	}
+	defer target.Delete()
+
+	scene.Init()
+	defer scene.Delete()
+
+	sim := loop.New(simulationStep)
+	for i := 0; i < frames; i++ {
+		if player != nil {
+			player.Frame(actions.Handle)
+			updateScene(scene, clock, actions)
+			actions.EndFrame()
+		}
+		setFramebufferSize(target.width, target.height)
+		drawFrame(scene, clock, sim)
+		checkError("main loop")
+
+		if err := fn(i, target.ReadImage()); err != nil {
+			return err
+		}
+		clock.Advance()
+	}
+
+	return nil
+}
+
+// runHeadless renders frames frames of the named scene and writes them to
+// outDir as <name>-0000.png, <name>-0001.png, ...
+func runHeadless(name string, scene Scene, clock *FixedClock, player *input.Player, frames int, outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	return renderOffscreen(scene, clock, player, frames, func(frame int, img *image.NRGBA) error {
+		return writePNG(filepath.Join(outDir, fmt.Sprintf("%s-%04d.png", name, frame)), img)
+	})
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// saveScreenshot writes the back buffer, width by height pixels, to a PNG
+// file in the current directory named after the time.
+func saveScreenshot(width, height int) error {
+	gl.ReadBuffer(gl.BACK)
+	path := time.Now().Format("gltut-20060102-150405.000.png")
+	if err := writePNG(path, readPixels(width, height)); err != nil {
+		return err
+	}
+	fmt.Printf("saved %s\n", path)
+	return nil
+}
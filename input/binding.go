@@ -0,0 +1,160 @@
+package input
+
+import (
+	"fmt"
+	glfw "github.com/go-gl/glfw3"
+	"strconv"
+	"strings"
+)
+
+// Binding is a key or a mouse button, pressed with exactly the modifiers
+// in Mods held.
+type Binding struct {
+	Mouse bool // Code is a glfw.MouseButton rather than a glfw.Key
+	Code  int
+	Mods  glfw.ModifierKey
+}
+
+// Key returns a binding of k pressed with mods.
+func Key(k glfw.Key, mods glfw.ModifierKey) Binding {
+	return Binding{Code: int(k), Mods: mods}
+}
+
+// Button returns a binding of button b pressed with mods.
+func Button(b glfw.MouseButton, mods glfw.ModifierKey) Binding {
+	return Binding{Mouse: true, Code: int(b), Mods: mods}
+}
+
+var modNames = []struct {
+	name string
+	mod  glfw.ModifierKey
+}{
+	{"ctrl", glfw.ModControl},
+	{"shift", glfw.ModShift},
+	{"alt", glfw.ModAlt},
+	{"super", glfw.ModSuper},
+}
+
+var keyNames = map[string]glfw.Key{
+	"space":        glfw.KeySpace,
+	"apostrophe":   glfw.KeyApostrophe,
+	"comma":        glfw.KeyComma,
+	"minus":        glfw.KeyMinus,
+	"period":       glfw.KeyPeriod,
+	"slash":        glfw.KeySlash,
+	"semicolon":    glfw.KeySemicolon,
+	"equal":        glfw.KeyEqual,
+	"leftbracket":  glfw.KeyLeftBracket,
+	"backslash":    glfw.KeyBackslash,
+	"rightbracket": glfw.KeyRightBracket,
+	"grave":        glfw.KeyGraveAccent,
+	"escape":       glfw.KeyEscape,
+	"enter":        glfw.KeyEnter,
+	"tab":          glfw.KeyTab,
+	"backspace":    glfw.KeyBackspace,
+	"insert":       glfw.KeyInsert,
+	"delete":       glfw.KeyDelete,
+	"right":        glfw.KeyRight,
+	"left":         glfw.KeyLeft,
+	"down":         glfw.KeyDown,
+	"up":           glfw.KeyUp,
+	"pageup":       glfw.KeyPageUp,
+	"pagedown":     glfw.KeyPageDown,
+	"home":         glfw.KeyHome,
+	"end":          glfw.KeyEnd,
+	"pause":        glfw.KeyPause,
+	"lshift":       glfw.KeyLeftShift,
+	"lctrl":        glfw.KeyLeftControl,
+	"lalt":         glfw.KeyLeftAlt,
+	"lsuper":       glfw.KeyLeftSuper,
+	"rshift":       glfw.KeyRightShift,
+	"rctrl":        glfw.KeyRightControl,
+	"ralt":         glfw.KeyRightAlt,
+	"rsuper":       glfw.KeyRightSuper,
+}
+
+func init() {
+	for k := glfw.KeyA; k <= glfw.KeyZ; k++ {
+		keyNames[string(rune('a'+k-glfw.KeyA))] = k
+	}
+	for k := glfw.Key0; k <= glfw.Key9; k++ {
+		keyNames[string(rune('0'+k-glfw.Key0))] = k
+	}
+	for k := glfw.KeyF1; k <= glfw.KeyF12; k++ {
+		keyNames[fmt.Sprintf("f%d", k-glfw.KeyF1+1)] = k
+	}
+}
+
+// ParseBinding parses a binding such as "space", "ctrl+s" or
+// "shift+mouse1": the modifiers ctrl, shift, alt and super, then a key or
+// mouse1 to mouse8, the left, right and middle buttons first. Keys are
+// named by what they show, or by name for the keys that don't show a
+// letter or digit, like "escape", "f11", "minus" or "lshift". Keys without
+// a name are given by their GLFW code, like "key320" for keypad 0.
+func ParseBinding(s string) (Binding, error) {
+	var b Binding
+	parts := strings.Split(strings.ToLower(s), "+")
+	for _, part := range parts[:len(parts)-1] {
+		found := false
+		for _, m := range modNames {
+			if part == m.name {
+				b.Mods |= m.mod
+				found = true
+			}
+		}
+		if !found {
+			return Binding{}, fmt.Errorf("unknown modifier %q in %q", part, s)
+		}
+	}
+
+	name := parts[len(parts)-1]
+	if k, ok := keyNames[name]; ok {
+		b.Code = int(k)
+		return b, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "mouse")); strings.HasPrefix(name, "mouse") && err == nil && n >= 1 && n <= 8 {
+		b.Mouse = true
+		b.Code = int(glfw.MouseButton1) + n - 1
+		return b, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "key")); strings.HasPrefix(name, "key") && err == nil && n >= int(glfw.KeySpace) && n <= int(glfw.KeyLast) {
+		b.Code = n
+		return b, nil
+	}
+	return Binding{}, fmt.Errorf("unknown key %q in %q", name, s)
+}
+
+// String returns the binding the way ParseBinding reads it.
+func (b Binding) String() string {
+	var s string
+	for _, m := range modNames {
+		if b.Mods&m.mod != 0 {
+			s += m.name + "+"
+		}
+	}
+	if b.Mouse {
+		return fmt.Sprintf("%smouse%d", s, b.Code-int(glfw.MouseButton1)+1)
+	}
+	for name, k := range keyNames {
+		if int(k) == b.Code {
+			return s + name
+		}
+	}
+	return fmt.Sprintf("%skey%d", s, b.Code)
+}
+
+// keyMods returns the modifier that k is one of the keys of, so pressing
+// a modifier key matches a binding whatever the platform reports it held.
+func keyMods(k glfw.Key) glfw.ModifierKey {
+	switch k {
+	case glfw.KeyLeftShift, glfw.KeyRightShift:
+		return glfw.ModShift
+	case glfw.KeyLeftControl, glfw.KeyRightControl:
+		return glfw.ModControl
+	case glfw.KeyLeftAlt, glfw.KeyRightAlt:
+		return glfw.ModAlt
+	case glfw.KeyLeftSuper, glfw.KeyRightSuper:
+		return glfw.ModSuper
+	}
+	return 0
+}
@@ -0,0 +1,282 @@
+// Package input maps the keys, mouse buttons and modifier combinations of
+// a window to named actions, like "quit" or "spin", so exercises ask
+// whether an action was pressed this frame instead of handling GLFW key
+// callbacks. Bindings are read from a text file with one action per line,
+// followed by its bindings:
+//
+//	# action     bindings
+//	quit         escape
+//	screenshot   f12 ctrl+s
+//	turn         mouse1
+//
+// The window's events are turned into Events, which Actions are fed one
+// at a time. A Recorder writes them to a file with the frame they arrived
+// in, and a Player feeds them back the same way, frame by frame.
+package input
+
+import (
+	"bufio"
+	"fmt"
+	glfw "github.com/go-gl/glfw3"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EventKind tells what an Event reports.
+type EventKind int
+
+const (
+	KeyEvent    EventKind = iota // a glfw.Key pressed, repeated or released
+	ButtonEvent                  // a glfw.MouseButton pressed or released
+	CursorEvent                  // the cursor moved to X, Y
+	ScrollEvent                  // the wheel turned by X, Y
+	SizeEvent                    // the window was resized to X by Y
+)
+
+// Event is a single event of a window.
+type Event struct {
+	Kind   EventKind
+	Code   int // the key or mouse button
+	Action glfw.Action
+	Mods   glfw.ModifierKey
+	X, Y   float64
+}
+
+// Attach sets the key, mouse button, cursor and scroll callbacks of window
+// to pass its events to handle.
+func Attach(window *glfw.Window, handle func(Event)) {
+	window.SetKeyCallback(func(window *glfw.Window, k glfw.Key, s int, action glfw.Action, mods glfw.ModifierKey) {
+		handle(Event{Kind: KeyEvent, Code: int(k), Action: action, Mods: mods})
+	})
+	window.SetMouseButtonCallback(func(window *glfw.Window, b glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		handle(Event{Kind: ButtonEvent, Code: int(b), Action: action, Mods: mods})
+	})
+	window.SetCursorPositionCallback(func(window *glfw.Window, x, y float64) {
+		handle(Event{Kind: CursorEvent, X: x, Y: y})
+	})
+	window.SetScrollCallback(func(window *glfw.Window, x, y float64) {
+		handle(Event{Kind: ScrollEvent, X: x, Y: y})
+	})
+	window.SetSizeCallback(func(window *glfw.Window, width, height int) {
+		handle(Event{Kind: SizeEvent, X: float64(width), Y: float64(height)})
+	})
+}
+
+// binding is a Binding of an action, and whether it is held down.
+type binding struct {
+	Binding
+	action string
+	down   bool
+}
+
+// state is what happened to an action this frame.
+type state struct {
+	held                        int // bindings held down
+	pressed, released, repeated bool
+}
+
+// Actions tracks the state of named actions from the events of a window,
+// frame by frame. The events of a frame are passed to Handle, the state
+// is read while drawing it, and EndFrame starts the next one.
+type Actions struct {
+	bindings []*binding
+	states   map[string]*state
+
+	cursor, last glm.Vec2
+	moved        bool
+	scroll       float32
+	size         glm.Vec2
+}
+
+func NewActions() *Actions {
+	return &Actions{states: make(map[string]*state)}
+}
+
+// Bind replaces the bindings of action. Without bindings, the action
+// exists but can't be triggered.
+func (a *Actions) Bind(action string, bindings ...Binding) {
+	kept := a.bindings[:0]
+	for _, b := range a.bindings {
+		if b.action != action {
+			kept = append(kept, b)
+		}
+	}
+	a.bindings = kept
+	for _, b := range bindings {
+		a.bindings = append(a.bindings, &binding{Binding: b, action: action})
+	}
+	a.states[action] = new(state)
+}
+
+// Bindings returns the bindings of action.
+func (a *Actions) Bindings(action string) []Binding {
+	var bindings []Binding
+	for _, b := range a.bindings {
+		if b.action == action {
+			bindings = append(bindings, b.Binding)
+		}
+	}
+	return bindings
+}
+
+// Names returns the names of all actions in sorted order.
+func (a *Actions) Names() []string {
+	names := make([]string, 0, len(a.states))
+	for name := range a.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads bindings from r, replacing those of every action it lists.
+func (a *Actions) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		bindings := make([]Binding, len(fields)-1)
+		for i, field := range fields[1:] {
+			b, err := ParseBinding(field)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			bindings[i] = b
+		}
+		a.Bind(fields[0], bindings...)
+	}
+	return scanner.Err()
+}
+
+// LoadFile reads bindings from the file at path.
+func (a *Actions) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := a.Load(f); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Handle updates the actions with an event of the window.
+func (a *Actions) Handle(e Event) {
+	switch e.Kind {
+	case KeyEvent, ButtonEvent:
+		mouse := e.Kind == ButtonEvent
+		mods := e.Mods
+		if !mouse {
+			mods &^= keyMods(glfw.Key(e.Code))
+		}
+		for _, b := range a.bindings {
+			if b.Mouse != mouse || b.Code != e.Code {
+				continue
+			}
+			s := a.states[b.action]
+			switch {
+			case e.Action == glfw.Press && !b.down && b.Mods == mods:
+				b.down = true
+				if s.held++; s.held == 1 {
+					s.pressed = true
+				}
+			case e.Action == glfw.Repeat && b.down:
+				s.repeated = true
+			case e.Action == glfw.Release && b.down:
+				b.down = false
+				if s.held--; s.held == 0 {
+					s.released = true
+				}
+			}
+		}
+	case CursorEvent:
+		a.cursor = glm.Vec2{float32(e.X), float32(e.Y)}
+		if !a.moved {
+			a.last, a.moved = a.cursor, true
+		}
+	case ScrollEvent:
+		a.scroll += float32(e.Y)
+	case SizeEvent:
+		a.size = glm.Vec2{float32(e.X), float32(e.Y)}
+	}
+}
+
+// EndFrame forgets what happened in the frame, keeping what is held.
+func (a *Actions) EndFrame() {
+	for _, s := range a.states {
+		s.pressed, s.released, s.repeated = false, false, false
+	}
+	a.last, a.scroll = a.cursor, 0
+}
+
+// Clear forgets the bindings held down and the cursor, without reporting
+// anything released, for a new window that won't see them let go.
+func (a *Actions) Clear() {
+	for _, b := range a.bindings {
+		b.down = false
+	}
+	for _, s := range a.states {
+		*s = state{}
+	}
+	a.cursor, a.last, a.moved, a.scroll = glm.Vec2{}, glm.Vec2{}, false, 0
+}
+
+// Pressed reports whether action started being held this frame.
+func (a *Actions) Pressed(action string) bool {
+	s, ok := a.states[action]
+	return ok && s.pressed
+}
+
+// Held reports whether any binding of action is held down.
+func (a *Actions) Held(action string) bool {
+	s, ok := a.states[action]
+	return ok && s.held > 0
+}
+
+// Released reports whether the last binding of action held was let go
+// this frame. A quick tap can be both pressed and released in one frame.
+func (a *Actions) Released(action string) bool {
+	s, ok := a.states[action]
+	return ok && s.released
+}
+
+// Repeat reports whether a key of action held down repeated this frame,
+// as keys do when held for a while.
+func (a *Actions) Repeat(action string) bool {
+	s, ok := a.states[action]
+	return ok && s.repeated
+}
+
+// Cursor returns the position of the cursor, in screen coordinates from
+// the top left of the window.
+func (a *Actions) Cursor() glm.Vec2 {
+	return a.cursor
+}
+
+// Motion returns how far the cursor moved this frame.
+func (a *Actions) Motion() glm.Vec2 {
+	return a.cursor.Sub(a.last)
+}
+
+// WindowSize returns the size of the window, in the screen coordinates of
+// the cursor.
+func (a *Actions) WindowSize() glm.Vec2 {
+	return a.size
+}
+
+// Scroll returns how far the wheel turned this frame, in clicks, positive
+// away from the user.
+func (a *Actions) Scroll() float32 {
+	return a.scroll
+}
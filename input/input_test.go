@@ -0,0 +1,221 @@
+package input
+
+import (
+	glfw "github.com/go-gl/glfw3"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"strings"
+	"testing"
+)
+
+func TestParseBinding(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Binding
+	}{
+		{"space", Key(glfw.KeySpace, 0)},
+		{"Ctrl+S", Key(glfw.KeyS, glfw.ModControl)},
+		{"shift+alt+f11", Key(glfw.KeyF11, glfw.ModShift|glfw.ModAlt)},
+		{"7", Key(glfw.Key7, 0)},
+		{"mouse1", Button(glfw.MouseButtonLeft, 0)},
+		{"super+mouse3", Button(glfw.MouseButtonMiddle, glfw.ModSuper)},
+	}
+	for _, test := range tests {
+		b, err := ParseBinding(test.s)
+		if err != nil || b != test.want {
+			t.Errorf("%q: got %+v, %v, want %+v", test.s, b, err, test.want)
+		}
+		if again, err := ParseBinding(b.String()); err != nil || again != b {
+			t.Errorf("%q: %q doesn't parse back", test.s, b.String())
+		}
+	}
+
+	for _, s := range []string{"", "hyper+a", "mouse9", "mouse", "ctrl+", "key", "key-1", "key+1"} {
+		if b, err := ParseBinding(s); err == nil {
+			t.Errorf("%q parsed as %v", s, b)
+		}
+	}
+}
+
+// TestBindingRoundTrip checks that every key and button, named or not,
+// prints as a binding that parses back, so the output of 'gltut bindings'
+// can be pasted into a bindings file.
+func TestBindingRoundTrip(t *testing.T) {
+	var bindings []Binding
+	for k := glfw.KeySpace; k <= glfw.KeyLast; k++ {
+		bindings = append(bindings, Key(k, 0), Key(k, glfw.ModControl|glfw.ModShift))
+	}
+	for b := glfw.MouseButton1; b <= glfw.MouseButtonLast; b++ {
+		bindings = append(bindings, Button(b, glfw.ModAlt))
+	}
+	for _, b := range bindings {
+		if again, err := ParseBinding(b.String()); err != nil || again != b {
+			t.Errorf("%+v prints as %q, which parses as %+v, %v", b, b.String(), again, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	a := NewActions()
+	a.Bind("quit", Key(glfw.KeyQ, 0))
+	err := a.Load(strings.NewReader(`
+# comment
+screenshot  f12 ctrl+s
+quit        escape   # replaces q
+spin
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Names(); strings.Join(got, " ") != "quit screenshot spin" {
+		t.Errorf("actions %v", got)
+	}
+	if got := a.Bindings("screenshot"); len(got) != 2 || got[1] != Key(glfw.KeyS, glfw.ModControl) {
+		t.Errorf("screenshot bound to %v", got)
+	}
+	if got := a.Bindings("quit"); len(got) != 1 || got[0] != Key(glfw.KeyEscape, 0) {
+		t.Errorf("quit bound to %v", got)
+	}
+
+	err = a.Load(strings.NewReader("spin space\nquit ctrl+esc\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("error %v, want one on line 2", err)
+	}
+}
+
+func TestActions(t *testing.T) {
+	a := NewActions()
+	a.Bind("spin", Key(glfw.KeySpace, 0))
+	a.Bind("save", Key(glfw.KeyS, glfw.ModControl))
+	a.Bind("back", Key(glfw.KeyS, 0))
+	a.Bind("run", Key(glfw.KeyLeftShift, 0), Key(glfw.KeyRightShift, 0))
+	a.Bind("turn", Button(glfw.MouseButtonLeft, 0))
+	key := func(k glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
+		a.Handle(Event{Kind: KeyEvent, Code: int(k), Action: action, Mods: mods})
+	}
+	check := func(action string, pressed, held, released, repeat bool) {
+		t.Helper()
+		if a.Pressed(action) != pressed || a.Held(action) != held || a.Released(action) != released || a.Repeat(action) != repeat {
+			t.Errorf("%s: pressed %v, held %v, released %v, repeat %v", action, a.Pressed(action), a.Held(action), a.Released(action), a.Repeat(action))
+		}
+	}
+
+	// a press lasts one frame, holding until released
+	key(glfw.KeySpace, glfw.Press, 0)
+	check("spin", true, true, false, false)
+	a.EndFrame()
+	key(glfw.KeySpace, glfw.Repeat, 0)
+	check("spin", false, true, false, true)
+	a.EndFrame()
+	key(glfw.KeySpace, glfw.Release, 0)
+	check("spin", false, false, true, false)
+	a.EndFrame()
+
+	// a tap within a frame is pressed and released
+	key(glfw.KeySpace, glfw.Press, 0)
+	key(glfw.KeySpace, glfw.Release, 0)
+	check("spin", true, false, true, false)
+	a.EndFrame()
+
+	// modifiers must match exactly, and letting go of ctrl first still
+	// releases the combination
+	key(glfw.KeyS, glfw.Press, glfw.ModControl)
+	check("save", true, true, false, false)
+	check("back", false, false, false, false)
+	a.EndFrame()
+	key(glfw.KeyS, glfw.Release, 0)
+	check("save", false, false, true, false)
+
+	// either shift key runs, and the action is held until both are up;
+	// the key of a modifier matches whatever mods the platform reports
+	key(glfw.KeyLeftShift, glfw.Press, glfw.ModShift)
+	key(glfw.KeyRightShift, glfw.Press, glfw.ModShift)
+	key(glfw.KeyLeftShift, glfw.Release, glfw.ModShift)
+	check("run", true, true, false, false)
+	a.EndFrame()
+	key(glfw.KeyRightShift, glfw.Release, 0)
+	check("run", false, false, true, false)
+
+	// buttons and the cursor
+	a.Handle(Event{Kind: CursorEvent, X: 10, Y: 20})
+	a.Handle(Event{Kind: ButtonEvent, Code: int(glfw.MouseButtonLeft), Action: glfw.Press})
+	a.EndFrame()
+	a.Handle(Event{Kind: CursorEvent, X: 15, Y: 18})
+	a.Handle(Event{Kind: ScrollEvent, Y: 1})
+	a.Handle(Event{Kind: ScrollEvent, Y: 2})
+	check("turn", false, true, false, false)
+	if a.Cursor() != (glm.Vec2{15, 18}) || a.Motion() != (glm.Vec2{5, -2}) || a.Scroll() != 3 {
+		t.Errorf("cursor %v, motion %v, scroll %g", a.Cursor(), a.Motion(), a.Scroll())
+	}
+	a.EndFrame()
+	if a.Motion() != (glm.Vec2{}) || a.Scroll() != 0 {
+		t.Errorf("motion %v and scroll %g left over", a.Motion(), a.Scroll())
+	}
+}
+
+func TestRecording(t *testing.T) {
+	events := []Record{
+		{0, Event{Kind: SizeEvent, X: 800, Y: 600}},
+		{2, Event{Kind: KeyEvent, Code: int(glfw.KeySpace), Action: glfw.Press}},
+		{2, Event{Kind: CursorEvent, X: 400.5, Y: 300.25}},
+		{3, Event{Kind: KeyEvent, Code: int(glfw.KeySpace), Action: glfw.Release, Mods: glfw.ModShift}},
+		{5, Event{Kind: ButtonEvent, Code: int(glfw.MouseButtonRight), Action: glfw.Press}},
+		{5, Event{Kind: ScrollEvent, Y: -1}},
+	}
+	args := []string{"--sky=my sky.jpg", `--model="quoted"`, "transform-5"}
+
+	var buf strings.Builder
+	r := NewRecorder(&buf, args)
+	next := 0
+	for frame := 0; frame < 7; frame++ {
+		for ; next < len(events) && events[next].Frame == frame; next++ {
+			r.Handle(events[next].Event)
+		}
+		r.EndFrame()
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := ReadRecording(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, buf.String())
+	}
+	if strings.Join(rec.Args, "|") != strings.Join(args, "|") || rec.Frames != 7 || len(rec.Records) != len(events) {
+		t.Fatalf("read back %+v from\n%s", rec, buf.String())
+	}
+	for i := range events {
+		if rec.Records[i] != events[i] {
+			t.Errorf("record %d is %+v, want %+v", i, rec.Records[i], events[i])
+		}
+	}
+
+	// playing it back presses spin on frame 2 and lets go on frame 3
+	a := NewActions()
+	a.Bind("spin", Key(glfw.KeySpace, 0))
+	p := NewPlayer(rec)
+	var pressed []int
+	for frame := 0; !p.Done(); frame++ {
+		p.Frame(a.Handle)
+		if a.Pressed("spin") {
+			pressed = append(pressed, frame)
+		}
+		if frame == 0 && a.WindowSize() != (glm.Vec2{800, 600}) {
+			t.Errorf("window size %v", a.WindowSize())
+		}
+		a.EndFrame()
+	}
+	if len(pressed) != 1 || pressed[0] != 2 {
+		t.Errorf("spin pressed on frames %v, want [2]", pressed)
+	}
+
+	// a recording cut short ends after its last event
+	cut := buf.String()[:strings.LastIndex(buf.String(), "end")]
+	if rec, err := ReadRecording(strings.NewReader(cut)); err != nil || rec.Frames != 6 {
+		t.Errorf("cut recording has %v frames, %v", rec, err)
+	}
+	for _, bad := range []string{"", "gltut-recording 1\n3 key 32 hold 0\n", "gltut-recording 1\n3 cursor 1 2\n2 cursor 1 2\n"} {
+		if _, err := ReadRecording(strings.NewReader(bad)); err == nil {
+			t.Errorf("read %q", bad)
+		}
+	}
+}
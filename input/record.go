@@ -0,0 +1,241 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	glfw "github.com/go-gl/glfw3"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A recording is a text file that starts with the command line arguments
+// it was made with and lists every event with the frame it arrived in,
+// counting from 0:
+//
+//	gltut-recording 1
+//	args "--fixed-step=16.666666ms" "transform-5"
+//	0 size 800 600
+//	12 key 32 press 0
+//	13 cursor 400.5 300
+//	13 scroll 0 1
+//	14 button 0 release 0
+//	end 245
+//
+// Keys and buttons are GLFW codes, followed by the action and the
+// modifiers. The end line gives the number of frames; a recording cut
+// short by a crash lacks it and ends after its last event.
+const recordingMagic = "gltut-recording 1"
+
+var kindNames = map[EventKind]string{
+	KeyEvent:    "key",
+	ButtonEvent: "button",
+	CursorEvent: "cursor",
+	ScrollEvent: "scroll",
+	SizeEvent:   "size",
+}
+
+var actionNames = map[glfw.Action]string{
+	glfw.Press:   "press",
+	glfw.Release: "release",
+	glfw.Repeat:  "repeat",
+}
+
+// Record is an event and the frame it arrived in.
+type Record struct {
+	Frame int
+	Event
+}
+
+// Recording is a recorded run of an exercise.
+type Recording struct {
+	Args    []string // the arguments of the run
+	Frames  int      // the number of frames drawn
+	Records []Record
+}
+
+// Recorder writes the events passed to Handle to a recording as they
+// arrive, so a crash loses at most the frame it happened in.
+type Recorder struct {
+	w     *bufio.Writer
+	frame int
+	err   error
+}
+
+// NewRecorder starts a recording of a run with args on w.
+func NewRecorder(w io.Writer, args []string) *Recorder {
+	r := &Recorder{w: bufio.NewWriter(w)}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = strconv.Quote(arg)
+	}
+	r.printf("%s\nargs %s\n", recordingMagic, strings.Join(quoted, " "))
+	return r
+}
+
+func (r *Recorder) printf(format string, a ...interface{}) {
+	if r.err == nil {
+		_, r.err = fmt.Fprintf(r.w, format, a...)
+	}
+}
+
+// Handle records an event in the current frame.
+func (r *Recorder) Handle(e Event) {
+	switch e.Kind {
+	case KeyEvent, ButtonEvent:
+		r.printf("%d %s %d %s %d\n", r.frame, kindNames[e.Kind], e.Code, actionNames[e.Action], e.Mods)
+	default:
+		r.printf("%d %s %s %s\n", r.frame, kindNames[e.Kind],
+			strconv.FormatFloat(e.X, 'g', -1, 64), strconv.FormatFloat(e.Y, 'g', -1, 64))
+	}
+}
+
+// EndFrame moves on to the next frame, writing out the events so far.
+func (r *Recorder) EndFrame() {
+	r.frame++
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+}
+
+// Close ends the recording, returning the first error writing it. It
+// doesn't close the underlying writer.
+func (r *Recorder) Close() error {
+	r.printf("end %d\n", r.frame)
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+// ReadRecording reads a recording written by a Recorder.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || scanner.Text() != recordingMagic {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("not a recording")
+	}
+
+	rec := new(Recording)
+	ended := false
+	for line := 2; scanner.Scan(); line++ {
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch {
+		case fields[0] == "args":
+			rec.Args, err = unquoteArgs(strings.TrimSpace(text)[len("args"):])
+		case fields[0] == "end" && len(fields) == 2:
+			rec.Frames, err = strconv.Atoi(fields[1])
+			ended = true
+		default:
+			var record Record
+			record, err = parseRecord(fields)
+			if err == nil && len(rec.Records) > 0 && record.Frame < rec.Records[len(rec.Records)-1].Frame {
+				err = fmt.Errorf("frame %d after frame %d", record.Frame, rec.Records[len(rec.Records)-1].Frame)
+			}
+			rec.Records = append(rec.Records, record)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !ended && len(rec.Records) > 0 {
+		rec.Frames = rec.Records[len(rec.Records)-1].Frame + 1
+	}
+	return rec, nil
+}
+
+// unquoteArgs undoes the quoting of the args line.
+func unquoteArgs(line string) ([]string, error) {
+	var args []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("bad arguments %s", line)
+		}
+		arg, _ := strconv.Unquote(quoted)
+		args = append(args, arg)
+		line = line[len(quoted):]
+	}
+	return args, nil
+}
+
+func parseRecord(fields []string) (Record, error) {
+	var r Record
+	var err error
+	if len(fields) < 2 {
+		return r, fmt.Errorf("bad event %q", strings.Join(fields, " "))
+	}
+	if r.Frame, err = strconv.Atoi(fields[0]); err != nil || r.Frame < 0 {
+		return r, fmt.Errorf("bad frame %q", fields[0])
+	}
+	r.Kind = -1
+	for kind, name := range kindNames {
+		if fields[1] == name {
+			r.Kind = kind
+		}
+	}
+
+	switch r.Kind {
+	case KeyEvent, ButtonEvent:
+		if len(fields) != 5 {
+			break
+		}
+		r.Action = -1
+		for action, name := range actionNames {
+			if fields[3] == name {
+				r.Action = action
+			}
+		}
+		code, err1 := strconv.Atoi(fields[2])
+		mods, err2 := strconv.Atoi(fields[4])
+		if err1 == nil && err2 == nil && r.Action >= 0 {
+			r.Code, r.Mods = code, glfw.ModifierKey(mods)
+			return r, nil
+		}
+	case CursorEvent, ScrollEvent, SizeEvent:
+		if len(fields) != 4 {
+			break
+		}
+		x, err1 := strconv.ParseFloat(fields[2], 64)
+		y, err2 := strconv.ParseFloat(fields[3], 64)
+		if err1 == nil && err2 == nil {
+			r.X, r.Y = x, y
+			return r, nil
+		}
+	}
+	return r, fmt.Errorf("bad event %q", strings.Join(fields, " "))
+}
+
+// Player hands the events of a recording back frame by frame.
+type Player struct {
+	rec   *Recording
+	frame int
+	next  int
+}
+
+func NewPlayer(rec *Recording) *Player {
+	return &Player{rec: rec}
+}
+
+// Frame passes the events of the next frame to handle.
+func (p *Player) Frame(handle func(Event)) {
+	for ; p.next < len(p.rec.Records) && p.rec.Records[p.next].Frame <= p.frame; p.next++ {
+		handle(p.rec.Records[p.next].Event)
+	}
+	p.frame++
+}
+
+// Done reports whether all the frames of the recording were played.
+func (p *Player) Done() bool {
+	return p.frame >= p.rec.Frames
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/shader"
+	"os"
+)
+
+func lintCommand(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	goDir := fs.String("go", ".", "directory of the Go code whose uniform and attribute names are checked")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gltut lint [--go dir] [shader dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dir := shaderDir
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	errors, err := shader.Lint(dir, *goDir, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if errors > 0 {
+		return fmt.Errorf("%d errors in %s", errors, dir)
+	}
+	return nil
+}
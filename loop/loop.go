@@ -0,0 +1,78 @@
+// Package loop runs a simulation in fixed steps however fast frames are
+// drawn, so what it computes doesn't depend on the refresh rate of the
+// monitor. Every frame the time that passed is added to an accumulator,
+// the simulation is updated once for every whole step in it, and the
+// frame is rendered between the last two states by the part of a step
+// that is left over.
+//
+// After a stall, like a breakpoint or a dragged window, catching up could
+// take longer than the time it makes up, so the loop falls further behind
+// every frame: the spiral of death. A Loop runs at most MaxSteps updates
+// in a frame and lets the rest of the time go.
+package loop
+
+import (
+	"time"
+)
+
+// DefaultMaxSteps is the number of updates a Loop runs in a frame at most,
+// unless told otherwise.
+const DefaultMaxSteps = 8
+
+// Loop is a fixed-step accumulator. It runs one step ahead of the time it
+// was given, so that the state it interpolates at Alpha 0 is the state at
+// that time rather than a step before it: with frames as long as the step,
+// frame n shows the simulation after n steps, exactly.
+type Loop struct {
+	Step     time.Duration
+	MaxSteps int
+
+	acc     time.Duration
+	steps   int
+	dropped time.Duration
+}
+
+// New returns a loop that updates every step.
+func New(step time.Duration) *Loop {
+	if step <= 0 {
+		panic("loop: step must be positive")
+	}
+	return &Loop{Step: step, MaxSteps: DefaultMaxSteps, acc: step}
+}
+
+// Advance adds elapsed to the accumulator and calls update for every whole
+// step in it, returning how many it ran.
+func (l *Loop) Advance(elapsed time.Duration, update func(dt time.Duration)) int {
+	l.acc += elapsed
+	n := 0
+	for l.acc >= l.Step {
+		if l.MaxSteps > 0 && n == l.MaxSteps {
+			// give up on catching up, keeping the part of a step
+			dropped := l.acc / l.Step * l.Step
+			l.acc -= dropped
+			l.dropped += dropped
+			break
+		}
+		update(l.Step)
+		l.acc -= l.Step
+		n++
+	}
+	l.steps += n
+	return n
+}
+
+// Alpha returns how far the frame is from the state before the last
+// update, 0, to the state after it, 1.
+func (l *Loop) Alpha() float32 {
+	return float32(float64(l.acc) / float64(l.Step))
+}
+
+// Steps returns the number of updates run so far.
+func (l *Loop) Steps() int {
+	return l.steps
+}
+
+// Dropped returns the time let go so far to keep up.
+func (l *Loop) Dropped() time.Duration {
+	return l.dropped
+}
@@ -0,0 +1,61 @@
+package loop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvance(t *testing.T) {
+	step := time.Second / 60
+	l := New(step)
+	var now time.Duration
+	update := func(dt time.Duration) {
+		if dt != step {
+			t.Errorf("update by %v, want %v", dt, step)
+		}
+		now += dt
+	}
+
+	// frames as long as the step run one update each, a step ahead
+	for frame := 0; frame < 5; frame++ {
+		elapsed := step
+		if frame == 0 {
+			elapsed = 0
+		}
+		if n := l.Advance(elapsed, update); n != 1 {
+			t.Errorf("frame %d ran %d updates", frame, n)
+		}
+		if l.Alpha() != 0 {
+			t.Errorf("frame %d has alpha %g", frame, l.Alpha())
+		}
+		if want := time.Duration(frame+1) * step; now != want {
+			t.Errorf("frame %d simulated %v, want %v", frame, now, want)
+		}
+	}
+
+	// faster frames run an update every other frame, halfway in between
+	if n := l.Advance(step/2, update); n != 0 || l.Alpha() != 0.5 {
+		t.Errorf("half a step ran %d updates with alpha %g", n, l.Alpha())
+	}
+	if n := l.Advance(step/2, update); n != 1 || l.Alpha() != 0 {
+		t.Errorf("another half ran %d updates with alpha %g", n, l.Alpha())
+	}
+
+	// a stall runs MaxSteps updates and drops the rest of whole steps,
+	// keeping the part of a step
+	before := l.Steps()
+	if n := l.Advance(100*step+step/4, update); n != DefaultMaxSteps {
+		t.Errorf("stall ran %d updates", n)
+	}
+	if l.Steps() != before+DefaultMaxSteps || l.Dropped() != (100-DefaultMaxSteps)*step {
+		t.Errorf("%d steps, dropped %v", l.Steps(), l.Dropped())
+	}
+	if a := l.Alpha(); a < 0.249 || a > 0.251 {
+		t.Errorf("alpha %g after the stall, want 0.25", a)
+	}
+
+	// paused, nothing moves
+	if n := l.Advance(0, update); n != 0 {
+		t.Errorf("pause ran %d updates", n)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"os"
+	"runtime"
+)
+
+func init() {
+	// GLFW event handling must run on the main OS thread
+	runtime.LockOSThread()
+}
+
+const usage = `usage: gltut <command> [arguments]
+
+commands:
+	list            print the names of all exercises
+	run <exercise>  open a window and run the named exercise
+	lint [dir]      check the shaders in dir (default shaders) without a GL
+	                context: syntax, types, the varyings passed between stages
+	                and the uniform and attribute names the Go code uses
+	meshopt [model ...]
+	                weld and reorder the triangles of the built-in meshes, or
+	                of OBJ and glTF models, for the post-transform vertex
+	                cache, and report the ACMR (vertices transformed per
+	                triangle) before and after; --cache N sets the cache size
+	modes           print the video modes of every monitor, the current one
+	                marked with *
+	bindings        print the default key and mouse bindings, in the format
+	                --bindings reads
+	replay [--headless [--frames N] [--out dir]] <recording>
+	                run the exercise of a recording made with run --record,
+	                with the same flags, feeding it the recorded input frame
+	                by frame; --headless renders the frames to PNG files,
+	                all of them unless --frames is given
+
+run flags:
+	--headless      render offscreen and write the frames as PNG files
+	--frames N      number of frames to render in headless mode (default 1)
+	--out dir       directory the headless frames are written to (default .)
+	--fixed-step d  advance the animation by d every frame instead of following
+	                the wall clock; headless runs use 1/60s unless given. Only
+	                the animated exercises accept it
+	--record path   record the input of every frame to path, on a fixed-step
+	                clock, for gltut replay
+	--sky path      draw a skybox behind the 3D exercises (transform-3 to
+	                depth-2); path is an equirectangular panorama or a
+	                directory of six faces named posx, negx, ... or px, nx, ...
+	--image path    image the hdr exercise shows (default sample.png)
+	--model path    Wavefront OBJ file the model exercise draws (default
+	                models/crate.obj)
+	--gltf path     glTF 2.0 file, .gltf or .glb, the gltf exercise draws
+	                (default models/boxes.gltf)
+	--camera mode   how the mouse and keyboard move the view of the 3D
+	                exercises: orbit (default), arcball or fly
+	--bindings path file of key and mouse bindings that replace the default
+	                ones of the actions it lists, see 'gltut bindings'
+
+keys while running, by default:
+	Esc             quit
+	F12, Ctrl+S     save a screenshot
+	P               pause or resume the animation
+	M               toggle slow motion
+	.               step a single frame while paused
+	Space           spin the square (transform-5)
+	T               cycle the texture sampler presets (texture exercises)
+	- =             decrease or increase the exposure (hdr)
+	O               cycle the tone-mapping operator (hdr)
+	F               switch between RGBA32F and RGBA16F textures (hdr)
+	left drag       turn the camera (3D exercises)
+	wheel           zoom
+	W A S D E Q     move forward, left, back, right, up and down (fly camera)
+	R               reset the camera
+	F11             switch between a window and fullscreen
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "list":
+		err = listCommand(args)
+	case "run":
+		err = runCommand(args)
+	case "lint":
+		err = lintCommand(args)
+	case "meshopt":
+		err = meshoptCommand(args)
+	case "modes":
+		err = modesCommand(args)
+	case "bindings":
+		err = bindingsCommand(args)
+	case "replay":
+		err = replayCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "gltut: unknown command %q\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gltut: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func listCommand(args []string) error {
+	for _, name := range sceneNames() {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func runCommand(args []string) error {
+	return runExercise(args, nil)
+}
+
+// runExercise runs an exercise as the run command does with args. With a
+// recording, the exercise takes its input from the recording instead of
+// the window.
+func runExercise(args []string, replay *input.Recording) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	headless := fs.Bool("headless", false, "render offscreen and write the frames as PNG files")
+	frames := fs.Int("frames", 1, "number of frames to render in headless mode")
+	out := fs.String("out", ".", "directory the headless frames are written to")
+	fixedStep := fs.Duration("fixed-step", 0, "advance the animation by this much every frame instead of following the wall clock")
+	record := fs.String("record", "", "record the input of every frame to this file, on a fixed-step clock")
+	fs.StringVar(&hdrImagePath, "image", hdrImagePath, "image the hdr exercise shows: Radiance .hdr, 16-bit PNG or any other texture")
+	fs.StringVar(&modelPath, "model", modelPath, "Wavefront OBJ file the model exercise draws")
+	fs.StringVar(&gltfPath, "gltf", gltfPath, "glTF 2.0 file, .gltf or .glb, the gltf exercise draws")
+	fs.StringVar(&cameraMode, "camera", cameraMode, "how the mouse and keyboard move the view of the 3D exercises: orbit, arcball or fly")
+	fs.StringVar(&skyPath, "sky", "", "cube map to draw behind the 3D exercises: a panorama or a directory of six faces")
+	fs.StringVar(&bindingsPath, "bindings", "", "file of key and mouse bindings to use over the default ones")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gltut run [--headless [--frames N] [--out dir]] [--fixed-step d] [--record path] [--sky path] [--image path] [--model path] [--gltf path] [--camera mode] [--bindings path] <exercise>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *frames < 1 || (*record != "" && *headless) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := checkCameraMode(); err != nil {
+		return err
+	}
+
+	name := fs.Arg(0)
+	scene, err := lookupScene(name)
+	if err != nil {
+		return err
+	}
+	// only Simulations move on with the clock; recordings of the other
+	// exercises still carry the step they were made with
+	if _, ok := scene.(Simulation); !ok && *fixedStep > 0 && replay == nil {
+		return fmt.Errorf("%s is not animated, --fixed-step has no effect on it", name)
+	}
+
+	// a recording replays on the clock it was made with
+	if *record != "" && *fixedStep <= 0 {
+		*fixedStep = defaultFrameStep
+	}
+	var player *input.Player
+	if replay != nil {
+		player = input.NewPlayer(replay)
+	}
+
+	if *headless {
+		step := defaultFrameStep
+		if *fixedStep > 0 {
+			step = *fixedStep
+		}
+		return runHeadless(name, scene, NewFixedClock(step), player, *frames, *out)
+	}
+
+	var clock Clock = NewRealClock()
+	if *fixedStep > 0 {
+		clock = NewFixedClock(*fixedStep)
+	}
+	if *record == "" {
+		return runScene(scene, clock, player, nil)
+	}
+
+	// record the flags that change what is drawn, and the step
+	var recorded []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "headless", "frames", "out", "record", "fixed-step":
+		default:
+			recorded = append(recorded, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	recorded = append(recorded, "--fixed-step="+fixedStep.String(), name)
+
+	f, err := os.Create(*record)
+	if err != nil {
+		return err
+	}
+	recorder := input.NewRecorder(f, recorded)
+	err = runScene(scene, clock, nil, recorder)
+	if rerr := recorder.Close(); err == nil {
+		err = rerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func replayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	headless := fs.Bool("headless", false, "render offscreen and write the frames as PNG files")
+	frames := fs.Int("frames", 0, "number of frames to render in headless mode (default all of the recording)")
+	out := fs.String("out", ".", "directory the headless frames are written to")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gltut replay [--headless [--frames N] [--out dir]] <recording>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *frames < 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	rec, err := input.ReadRecording(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(0), err)
+	}
+
+	var run []string
+	if *headless {
+		if *frames == 0 {
+			*frames = rec.Frames
+		}
+		if *frames == 0 {
+			*frames = 1
+		}
+		run = append(run, "--headless", fmt.Sprintf("--frames=%d", *frames), "--out="+*out)
+	}
+	return runExercise(append(run, rec.Args...), rec)
+}
@@ -0,0 +1,181 @@
+// Package mesh generates indexed triangle meshes of basic shapes: cubes,
+// planes, grids, spheres, cylinders, cones and tori. Every vertex has a
+// position, a normal, a tangent and texture coordinates.
+//
+// Shapes are centered on the origin with Z up, like the scenes of the
+// exercises. Triangles wind counter-clockwise seen from outside. Texture
+// coordinates follow OpenGL, with v = 0 at the bottom of the image, and
+// textures appear upright on the sides of the shapes.
+package mesh
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Mesh is an indexed triangle list. The vertex attributes are parallel
+// slices. Tangents point along increasing u; their w is 1 or -1 and
+// cross(normal, tangent.Vec3()) * w points along increasing v.
+type Mesh struct {
+	Positions []glm.Vec3
+	Normals   []glm.Vec3
+	Tangents  []glm.Vec4
+	Texcoords []glm.Vec2
+	Indices   []uint32
+}
+
+// VertexSize is the number of floats per vertex in Vertices.
+const VertexSize = 3 + 3 + 4 + 2
+
+// Vertices returns the vertices interleaved: position, normal, tangent and
+// texture coordinates, VertexSize floats in all.
+func (m *Mesh) Vertices() []float32 {
+	v := make([]float32, 0, len(m.Positions)*VertexSize)
+	for i := range m.Positions {
+		v = append(v, m.Positions[i][:]...)
+		v = append(v, m.Normals[i][:]...)
+		v = append(v, m.Tangents[i][:]...)
+		v = append(v, m.Texcoords[i][:]...)
+	}
+	return v
+}
+
+func (m *Mesh) vertex(p, n glm.Vec3, uv glm.Vec2) uint32 {
+	m.Positions = append(m.Positions, p)
+	m.Normals = append(m.Normals, n)
+	m.Texcoords = append(m.Texcoords, uv)
+	return uint32(len(m.Positions) - 1)
+}
+
+// triangle adds the triangle a, b, c unless it has no area, as happens at
+// the poles of a sphere.
+func (m *Mesh) triangle(a, b, c uint32) {
+	pa, pb, pc := m.Positions[a], m.Positions[b], m.Positions[c]
+	if pb.Sub(pa).Cross(pc.Sub(pa)).Len() < 1e-12 {
+		return
+	}
+	m.Indices = append(m.Indices, a, b, c)
+}
+
+// quad adds the quad with the corners top left, top right, bottom right
+// and bottom left, seen from the front.
+func (m *Mesh) quad(tl, tr, br, bl uint32) {
+	m.triangle(tl, bl, br)
+	m.triangle(tl, br, tr)
+}
+
+// surface adds a grid of cols x rows quads whose vertices f returns for u
+// and v from 0 to 1. Seen from the front, u runs to the right and v down;
+// the texture coordinates are (u, 1 - v).
+func (m *Mesh) surface(cols, rows int, f func(u, v float32) (p, n glm.Vec3)) {
+	first := uint32(len(m.Positions))
+	for j := 0; j <= rows; j++ {
+		for i := 0; i <= cols; i++ {
+			u, v := float32(i)/float32(cols), float32(j)/float32(rows)
+			p, n := f(u, v)
+			m.vertex(p, n, glm.Vec2{u, 1 - v})
+		}
+	}
+	at := func(i, j int) uint32 { return first + uint32(j*(cols+1)+i) }
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			m.quad(at(i, j), at(i+1, j), at(i+1, j+1), at(i, j+1))
+		}
+	}
+}
+
+// rect adds a grid of cols x rows quads centered on center. right and
+// down are half the width and height of the rectangle as seen from the
+// front; the front faces right x -down.
+func (m *Mesh) rect(center, right, down glm.Vec3, cols, rows int) {
+	n := right.Cross(down.Mul(-1)).Normalize()
+	m.surface(cols, rows, func(u, v float32) (glm.Vec3, glm.Vec3) {
+		return center.Add(right.Mul(2*u - 1)).Add(down.Mul(2*v - 1)), n
+	})
+}
+
+// disk adds a disk of radius at height z facing up or down, as a fan of
+// segments triangles. Texture coordinates map the disk into the unit
+// square the way it looks from the front, with +X to the right.
+func (m *Mesh) disk(radius, z float32, up bool, segments int) {
+	n, flip := glm.Vec3{0, 0, 1}, float32(1)
+	if !up {
+		n, flip = glm.Vec3{0, 0, -1}, -1
+	}
+	center := m.vertex(glm.Vec3{0, 0, z}, n, glm.Vec2{0.5, 0.5})
+	first := uint32(len(m.Positions))
+	for i := 0; i <= segments; i++ {
+		s, c := sincos(2 * math.Pi * float64(i) / float64(segments))
+		m.vertex(glm.Vec3{radius * c, radius * s, z}, n, glm.Vec2{0.5 + c/2, 0.5 + flip*s/2})
+	}
+	for i := uint32(0); i < uint32(segments); i++ {
+		if up {
+			m.triangle(center, first+i, first+i+1)
+		} else {
+			m.triangle(center, first+i+1, first+i)
+		}
+	}
+}
+
+// sincos takes a float64 angle so that multiples of pi are exact enough
+// for the poles of a sphere to coincide.
+func sincos(a float64) (float32, float32) {
+	s, c := math.Sincos(a)
+	return float32(s), float32(c)
+}
+
+// ComputeTangents sets the tangents from the positions, normals and
+// texture coordinates, averaging over the triangles that share a vertex.
+// The generators call it; loaders of meshes without tangents can too.
+func (m *Mesh) ComputeTangents() {
+	tan := make([]glm.Vec3, len(m.Positions))
+	bitan := make([]glm.Vec3, len(m.Positions))
+	for t := 0; t+2 < len(m.Indices); t += 3 {
+		a, b, c := m.Indices[t], m.Indices[t+1], m.Indices[t+2]
+		e1, e2 := m.Positions[b].Sub(m.Positions[a]), m.Positions[c].Sub(m.Positions[a])
+		d1, d2 := m.Texcoords[b].Sub(m.Texcoords[a]), m.Texcoords[c].Sub(m.Texcoords[a])
+		det := d1[0]*d2[1] - d2[0]*d1[1]
+		if det == 0 {
+			continue
+		}
+		r := 1 / det
+		// dP/du and dP/dv over the triangle
+		sdir := e1.Mul(d2[1]).Sub(e2.Mul(d1[1])).Mul(r)
+		tdir := e2.Mul(d1[0]).Sub(e1.Mul(d2[0])).Mul(r)
+		for _, i := range []uint32{a, b, c} {
+			tan[i] = tan[i].Add(sdir)
+			bitan[i] = bitan[i].Add(tdir)
+		}
+	}
+
+	m.Tangents = make([]glm.Vec4, len(m.Positions))
+	for i, n := range m.Normals {
+		// make the tangent perpendicular to the normal
+		t := tan[i].Sub(n.Mul(n.Dot(tan[i])))
+		if t.Len() < 1e-6 {
+			t = perpendicular(n)
+		}
+		t = t.Normalize()
+		w := float32(1)
+		if n.Cross(t).Dot(bitan[i]) < 0 {
+			w = -1
+		}
+		m.Tangents[i] = t.Vec4(w)
+	}
+}
+
+// perpendicular returns some unit vector perpendicular to n.
+func perpendicular(n glm.Vec3) glm.Vec3 {
+	axis := glm.Vec3{1, 0, 0}
+	if abs(n[0]) > 0.9 {
+		axis = glm.Vec3{0, 1, 0}
+	}
+	return axis.Sub(n.Mul(n.Dot(axis))).Normalize()
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
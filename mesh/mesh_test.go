@@ -0,0 +1,108 @@
+package mesh
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"testing"
+)
+
+var shapes = map[string]*Mesh{
+	"cube":      Cube(1),
+	"plane":     Plane(2, 1),
+	"grid":      Grid(2, 2, 4, 3),
+	"uvsphere":  UVSphere(1, 16, 8),
+	"icosphere": Icosphere(1, 2),
+	"cylinder":  Cylinder(0.5, 1, 12),
+	"cone":      Cone(0.5, 1, 12),
+	"torus":     Torus(1, 0.25, 16, 8),
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestShapes(t *testing.T) {
+	for name, m := range shapes {
+		n := len(m.Positions)
+		if n == 0 || len(m.Indices) == 0 || len(m.Indices)%3 != 0 {
+			t.Errorf("%s: %d vertices, %d indices", name, n, len(m.Indices))
+			continue
+		}
+		if len(m.Normals) != n || len(m.Tangents) != n || len(m.Texcoords) != n {
+			t.Errorf("%s: attribute lengths %d, %d, %d for %d vertices", name, len(m.Normals), len(m.Tangents), len(m.Texcoords), n)
+			continue
+		}
+		if got := len(m.Vertices()); got != n*VertexSize {
+			t.Errorf("%s: %d interleaved floats, want %d", name, got, n*VertexSize)
+		}
+		for _, i := range m.Indices {
+			if int(i) >= n {
+				t.Fatalf("%s: index %d out of range", name, i)
+			}
+		}
+		for i := 0; i < n; i++ {
+			nrm, tan := m.Normals[i], m.Tangents[i]
+			if !near(nrm.Len(), 1) || !near(tan.Vec3().Len(), 1) {
+				t.Errorf("%s: vertex %d: normal %v or tangent %v is not a unit vector", name, i, nrm, tan)
+				break
+			}
+			if !near(nrm.Dot(tan.Vec3()), 0) || !near(tan[3]*tan[3], 1) {
+				t.Errorf("%s: vertex %d: tangent %v doesn't fit normal %v", name, i, tan, nrm)
+				break
+			}
+		}
+		// the triangles face the way their vertex normals point
+		for k := 0; k < len(m.Indices); k += 3 {
+			a, b, c := m.Indices[k], m.Indices[k+1], m.Indices[k+2]
+			face := m.Positions[b].Sub(m.Positions[a]).Cross(m.Positions[c].Sub(m.Positions[a]))
+			if face.Dot(m.Normals[a].Add(m.Normals[b]).Add(m.Normals[c])) <= 0 {
+				t.Errorf("%s: triangle %d winds the wrong way", name, k/3)
+				break
+			}
+		}
+	}
+}
+
+func TestCube(t *testing.T) {
+	m := Cube(2)
+	if len(m.Positions) != 24 || len(m.Indices) != 36 {
+		t.Fatalf("%d vertices and %d indices, want 24 and 36", len(m.Positions), len(m.Indices))
+	}
+	for i, p := range m.Positions {
+		for _, c := range p {
+			if !near(abs(c), 1) {
+				t.Fatalf("vertex %d at %v is not a corner", i, p)
+			}
+		}
+	}
+	// the sides show the texture upright: v = 0 is the bottom row
+	for i, n := range m.Normals {
+		if n[2] == 0 && m.Texcoords[i][1] == 0 && m.Positions[i][2] != -1 {
+			t.Errorf("vertex %d at %v has v = 0 but isn't at the bottom", i, m.Positions[i])
+		}
+	}
+}
+
+func TestTangents(t *testing.T) {
+	// on the plane u runs along +X and v along +Y
+	m := Plane(2, 2)
+	for i, tan := range m.Tangents {
+		if tan != (glm.Vec4{1, 0, 0, 1}) {
+			t.Errorf("tangent %d is %v, want (1, 0, 0, 1)", i, tan)
+		}
+	}
+}
+
+func TestIcosphereSeam(t *testing.T) {
+	m := Icosphere(1, 1)
+	for k := 0; k < len(m.Indices); k += 3 {
+		lo, hi := float32(2), float32(-1)
+		for _, i := range m.Indices[k : k+3] {
+			u := m.Texcoords[i][0]
+			lo, hi = float32(math.Min(float64(lo), float64(u))), float32(math.Max(float64(hi), float64(u)))
+		}
+		if hi-lo > 0.5 {
+			t.Errorf("triangle %d spans u from %g to %g", k/3, lo, hi)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package mesh
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Cube returns a cube with edges of length size. Each face has its own
+// four vertices and the whole texture; the side faces show it upright.
+func Cube(size float32) *Mesh {
+	h := size / 2
+	x, y, z := glm.Vec3{h, 0, 0}, glm.Vec3{0, h, 0}, glm.Vec3{0, 0, h}
+	m := new(Mesh)
+	m.rect(x, y, z.Mul(-1), 1, 1)                 // +X
+	m.rect(x.Mul(-1), y.Mul(-1), z.Mul(-1), 1, 1) // -X
+	m.rect(y, x.Mul(-1), z.Mul(-1), 1, 1)         // +Y
+	m.rect(y.Mul(-1), x, z.Mul(-1), 1, 1)         // -Y
+	m.rect(z, x, y.Mul(-1), 1, 1)                 // top
+	m.rect(z.Mul(-1), x, y, 1, 1)                 // bottom
+	m.ComputeTangents()
+	return m
+}
+
+// Plane returns a width x depth rectangle in the XY plane facing up.
+func Plane(width, depth float32) *Mesh {
+	return Grid(width, depth, 1, 1)
+}
+
+// Grid returns a width x depth rectangle in the XY plane facing up,
+// divided into cols x rows quads. The texture covers it once.
+func Grid(width, depth float32, cols, rows int) *Mesh {
+	m := new(Mesh)
+	m.rect(glm.Vec3{}, glm.Vec3{width / 2, 0, 0}, glm.Vec3{0, -depth / 2, 0}, max(cols, 1), max(rows, 1))
+	m.ComputeTangents()
+	return m
+}
+
+// UVSphere returns a sphere made of segments slices around the Z axis and
+// rings stacks from pole to pole. The texture wraps around it as an
+// equirectangular map, u starting at +X and v at the south pole.
+func UVSphere(radius float32, segments, rings int) *Mesh {
+	m := new(Mesh)
+	m.surface(max(segments, 3), max(rings, 2), func(u, v float32) (glm.Vec3, glm.Vec3) {
+		n := spherical(2*math.Pi*float64(u), math.Pi*float64(v))
+		return n.Mul(radius), n
+	})
+	m.ComputeTangents()
+	return m
+}
+
+// spherical returns the unit vector at longitude phi from +X and polar
+// angle theta from +Z.
+func spherical(phi, theta float64) glm.Vec3 {
+	sp, cp := sincos(phi)
+	st, ct := sincos(theta)
+	return glm.Vec3{st * cp, st * sp, ct}
+}
+
+// Icosphere returns a sphere made by splitting the faces of an icosahedron
+// into four subdivisions times, which spreads the triangles more evenly
+// than UVSphere does. It is textured like UVSphere; vertices on the
+// texture seam are doubled.
+func Icosphere(radius float32, subdivisions int) *Mesh {
+	const t = 1.618034 // the golden ratio
+	dirs := []glm.Vec3{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}
+	for i := range dirs {
+		dirs[i] = dirs[i].Normalize()
+	}
+	faces := [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+
+	for ; subdivisions > 0; subdivisions-- {
+		midpoints := make(map[[2]int]int)
+		midpoint := func(a, b int) int {
+			key := [2]int{a, b}
+			if a > b {
+				key = [2]int{b, a}
+			}
+			if i, ok := midpoints[key]; ok {
+				return i
+			}
+			dirs = append(dirs, dirs[a].Add(dirs[b]).Normalize())
+			midpoints[key] = len(dirs) - 1
+			return len(dirs) - 1
+		}
+		split := make([][3]int, 0, 4*len(faces))
+		for _, f := range faces {
+			ab, bc, ca := midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])
+			split = append(split, [3]int{f[0], ab, ca}, [3]int{f[1], bc, ab}, [3]int{f[2], ca, bc}, [3]int{ab, bc, ca})
+		}
+		faces = split
+	}
+
+	m := new(Mesh)
+	for _, d := range dirs {
+		m.vertex(d.Mul(radius), d, sphereUV(d))
+	}
+	// a face that crosses the seam would stretch over the whole texture;
+	// give it copies of its vertices on the u < 0.5 side with u + 1
+	wrapped := make(map[int]uint32)
+	for _, f := range faces {
+		var idx [3]uint32
+		lo, hi := float32(1), float32(0)
+		pole := -1
+		for k, i := range f {
+			idx[k] = uint32(i)
+			if atPole(dirs[i]) {
+				pole = k
+				continue
+			}
+			u := m.Texcoords[i][0]
+			if u < lo {
+				lo = u
+			}
+			if u > hi {
+				hi = u
+			}
+		}
+		if hi-lo > 0.5 {
+			for k, i := range f {
+				if k == pole || m.Texcoords[i][0] >= 0.5 {
+					continue
+				}
+				w, ok := wrapped[i]
+				if !ok {
+					uv := m.Texcoords[i]
+					w = m.vertex(m.Positions[i], m.Normals[i], glm.Vec2{uv[0] + 1, uv[1]})
+					wrapped[i] = w
+				}
+				idx[k] = w
+			}
+		}
+		// u is arbitrary at a pole; every face gets its own pole vertex in
+		// the middle of the other two
+		if pole >= 0 {
+			a, b := idx[(pole+1)%3], idx[(pole+2)%3]
+			u := (m.Texcoords[a][0] + m.Texcoords[b][0]) / 2
+			i := f[pole]
+			idx[pole] = m.vertex(m.Positions[i], m.Normals[i], glm.Vec2{u, m.Texcoords[i][1]})
+		}
+		m.triangle(idx[0], idx[1], idx[2])
+	}
+	m.ComputeTangents()
+	return m
+}
+
+// sphereUV returns the texture coordinates of the unit vector d, matching
+// UVSphere.
+func sphereUV(d glm.Vec3) glm.Vec2 {
+	u := float32(math.Atan2(float64(d[1]), float64(d[0])) / (2 * math.Pi))
+	if u < 0 {
+		u++
+	}
+	z := float64(d[2])
+	v := 1 - float32(math.Acos(math.Max(-1, math.Min(1, z)))/math.Pi)
+	return glm.Vec2{u, v}
+}
+
+func atPole(d glm.Vec3) bool {
+	return abs(d[0]) < 1e-6 && abs(d[1]) < 1e-6
+}
+
+// Cylinder returns a closed cylinder around the Z axis with segments
+// sides. The texture wraps once around the side; the caps map it onto a
+// disk.
+func Cylinder(radius, height float32, segments int) *Mesh {
+	segments = max(segments, 3)
+	h := height / 2
+	m := new(Mesh)
+	m.surface(segments, 1, func(u, v float32) (glm.Vec3, glm.Vec3) {
+		s, c := sincos(2 * math.Pi * float64(u))
+		return glm.Vec3{radius * c, radius * s, h - v*height}, glm.Vec3{c, s, 0}
+	})
+	m.disk(radius, h, true, segments)
+	m.disk(radius, -h, false, segments)
+	m.ComputeTangents()
+	return m
+}
+
+// Cone returns a cone around the Z axis with its apex at the top and a
+// closed base. The side is smooth shaded.
+func Cone(radius, height float32, segments int) *Mesh {
+	segments = max(segments, 3)
+	h := height / 2
+	// the side normals lean up by the slope of the side
+	slope := glm.Vec2{height, radius}.Normalize()
+	m := new(Mesh)
+	m.surface(segments, 1, func(u, v float32) (glm.Vec3, glm.Vec3) {
+		s, c := sincos(2 * math.Pi * float64(u))
+		return glm.Vec3{v * radius * c, v * radius * s, h - v*height}, glm.Vec3{slope[0] * c, slope[0] * s, slope[1]}
+	})
+	m.disk(radius, -h, false, segments)
+	m.ComputeTangents()
+	return m
+}
+
+// Torus returns a torus around the Z axis: a tube of radius minor whose
+// center is a circle of radius major, with segments steps around the Z
+// axis and sides steps around the tube.
+func Torus(major, minor float32, segments, sides int) *Mesh {
+	m := new(Mesh)
+	m.surface(max(segments, 3), max(sides, 3), func(u, v float32) (glm.Vec3, glm.Vec3) {
+		s, c := sincos(2 * math.Pi * float64(u))
+		// start at the top of the tube and go outwards and down
+		st, ct := sincos(math.Pi/2 - 2*math.Pi*float64(v))
+		n := glm.Vec3{ct * c, ct * s, st}
+		center := glm.Vec3{major * c, major * s, 0}
+		return center.Add(n.Mul(minor)), n
+	})
+	m.ComputeTangents()
+	return m
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	"github.com/go-gl/gl"
+	"github.com/go-gl/glh"
+)
+
+// meshFormat is the layout of mesh.Mesh.Vertices.
+var meshFormat = NewVertexFormat(Float3("position"), Float3("normal"), Float4("tangent"), Float2("texcoord"))
+
+// glMesh is a mesh uploaded into a vertex array with its own vertex and
+// element buffers.
+type glMesh struct {
+	vao      gl.VertexArray
+	vbo, ebo gl.Buffer
+	count    int
+}
+
+// uploadMesh copies m into new buffers and points the attributes of
+// program at them. Attributes the program doesn't declare are left out,
+// so a shader can take just the positions and normals. The vertex array
+// stays bound.
+func uploadMesh(m *mesh.Mesh, program gl.Program) (*glMesh, error) {
+	g := &glMesh{count: len(m.Indices)}
+
+	// create Vertex Array Object to save shader attributes
+	g.vao = gl.GenVertexArray()
+	g.vao.Bind()
+	checkError("vertex array object")
+
+	// setup vertex data
+	vertices := m.Vertices()
+	g.vbo = gl.GenBuffer()
+	g.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(vertices), vertices, gl.STATIC_DRAW)
+	checkError("vertex data")
+
+	// setup element data
+	g.ebo = gl.GenBuffer()
+	g.ebo.Bind(gl.ELEMENT_ARRAY_BUFFER)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int(glh.Sizeof(gl.UNSIGNED_INT))*len(m.Indices), m.Indices, gl.STATIC_DRAW)
+	checkError("element data")
+
+	// tell vertex shader how to process vertex data
+	if err := meshFormat.Enable(program, vertices); err != nil {
+		g.Delete()
+		return nil, err
+	}
+	return g, nil
+}
+
+// Draw draws the triangles of the mesh with the current program.
+func (g *glMesh) Draw() {
+	g.vao.Bind()
+	gl.DrawElements(gl.TRIANGLES, g.count, gl.UNSIGNED_INT, nil)
+}
+
+// DrawRange draws count indices starting at first, e.g. one part of a
+// model.
+func (g *glMesh) DrawRange(first, count int) {
+	g.vao.Bind()
+	gl.DrawElements(gl.TRIANGLES, count, gl.UNSIGNED_INT, uintptr(first*int(glh.Sizeof(gl.UNSIGNED_INT))))
+}
+
+func (g *glMesh) Delete() {
+	g.ebo.Delete()
+	g.vbo.Delete()
+	g.vao.Delete()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	"github.com/go-gl/gl"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+func init() {
+	registerScene("meshes", func() Scene { return new(meshes) })
+}
+
+// meshesUniforms are the uniforms of the meshes shaders.
+type meshesUniforms struct {
+	Model    glm.Mat4 `glsl:"model"`
+	View     glm.Mat4 `glsl:"view"`
+	Proj     glm.Mat4 `glsl:"proj"`
+	LightDir glm.Vec3 `glsl:"lightDir"`
+	Tex      int32    `glsl:"tex"`
+}
+
+// meshes draws every shape of the mesh package, textured and lit from
+// above, in two rows of four.
+type meshes struct {
+	shapes   []*glMesh
+	texture  gl.Texture
+	program  *shaderProgram
+	uniforms meshesUniforms
+	binding  *uniformBinding
+	camera   camera.Controller
+	simTime
+}
+
+func (s *meshes) Init() {
+	var err error
+
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.CULL_FACE)
+
+	// setup texture data
+	gl.ActiveTexture(gl.TEXTURE0)
+	s.texture, err = loadTexture("sample.png", DefaultSampler)
+	if err != nil {
+		panic(err)
+	}
+
+	// create shader program
+	s.program, err = loadProgram("meshes", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup vertex data, one vertex array per shape
+	for _, m := range []*mesh.Mesh{
+		mesh.Cube(1.0),
+		mesh.UVSphere(0.6, 32, 16),
+		mesh.Icosphere(0.6, 3),
+		mesh.Torus(0.5, 0.2, 32, 16),
+		mesh.Plane(1.2, 1.2),
+		mesh.Grid(1.2, 1.2, 8, 8),
+		mesh.Cylinder(0.5, 1.0, 32),
+		mesh.Cone(0.5, 1.0, 32),
+	} {
+		shape, err := uploadMesh(m, s.program.Program)
+		if err != nil {
+			panic(err)
+		}
+		s.shapes = append(s.shapes, shape)
+	}
+
+	// look at the scene through a camera the mouse and keyboard move
+	s.camera = newCamera(
+		glm.Vec3{0.0, -7.0, 4.5},
+		glm.Vec3{0.0, 0.0, 0.0},
+		glm.Vec3{0.0, 0.0, 1.0})
+
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.uniforms = meshesUniforms{
+		View:     s.camera.View(),
+		LightDir: glm.Vec3{0.3, -0.5, 1.0}.Normalize(),
+		Tex:      0,
+	}
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+}
+
+// bindUniforms binds s.uniforms to the uniforms of the program and uploads
+// them.
+func (s *meshes) bindUniforms() {
+	var err error
+	s.binding, err = bindUniformStruct(s.program.Program, &s.uniforms)
+	if err != nil {
+		panic(err)
+	}
+	s.binding.Upload()
+}
+
+func (s *meshes) Render(alpha float32) {
+	// move the camera and fit the projection to the window
+	s.uniforms.View = s.camera.View()
+	s.uniforms.Proj = perspective(45.0, 1.0, 20.0)
+
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// spin every shape in place, the first row at the back
+	spin := glm.HomogRotate3DZ(0.25 * math.Pi * float32(s.seconds(alpha)))
+	for i, shape := range s.shapes {
+		x, y := float32(i%4)*2.0-3.0, 1.2-float32(i/4)*2.4
+		s.uniforms.Model = glm.Translate3D(x, y, 0.0).Mul4(spin)
+		s.binding.Upload()
+		shape.Draw()
+	}
+}
+
+func (s *meshes) Delete() {
+	for _, shape := range s.shapes {
+		shape.Delete()
+	}
+	s.program.Delete()
+	s.texture.Delete()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/gltf"
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	"github.com/Happy-Ferret/gl-tutorial/meshopt"
+	"github.com/Happy-Ferret/gl-tutorial/obj"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+func meshoptCommand(args []string) error {
+	fs := flag.NewFlagSet("meshopt", flag.ExitOnError)
+	cacheSize := fs.Int("cache", 32, "number of vertices in the simulated FIFO post-transform cache")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gltut meshopt [--cache N] [model ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *cacheSize < 4 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "mesh\ttriangles\tvertices\twelded\tACMR\tForsyth\tTipsify\t")
+	if fs.NArg() == 0 {
+		// the cube of depth-1 and the shapes of the meshes exercise
+		indices := make([]uint32, len(cubeVertices)/8)
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+		reportMesh(w, "depth-1 cube", cubeVertices, 8, indices, *cacheSize)
+		for _, shape := range []struct {
+			name string
+			mesh *mesh.Mesh
+		}{
+			{"cube", mesh.Cube(1.0)},
+			{"uv sphere", mesh.UVSphere(0.6, 32, 16)},
+			{"icosphere", mesh.Icosphere(0.6, 3)},
+			{"torus", mesh.Torus(0.5, 0.2, 32, 16)},
+			{"plane", mesh.Plane(1.2, 1.2)},
+			{"grid", mesh.Grid(1.2, 1.2, 8, 8)},
+			{"cylinder", mesh.Cylinder(0.5, 1.0, 32)},
+			{"cone", mesh.Cone(0.5, 1.0, 32)},
+		} {
+			reportMesh(w, shape.name, shape.mesh.Vertices(), mesh.VertexSize, shape.mesh.Indices, *cacheSize)
+		}
+	}
+	for _, name := range fs.Args() {
+		if err := reportModel(w, name, *cacheSize); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// reportModel reports every part of an OBJ model, or every primitive of a
+// glTF scene, as they are drawn separately.
+func reportModel(w io.Writer, name string, cacheSize int) error {
+	base := filepath.Base(name)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".obj":
+		model, err := obj.Load(name)
+		if err != nil {
+			return err
+		}
+		vertices := model.Mesh.Vertices()
+		for _, p := range model.Parts {
+			part := strings.Join(strings.Fields(p.Object+" "+p.Group+" "+p.Material), " ")
+			reportMesh(w, base+" "+part, vertices, mesh.VertexSize, model.Mesh.Indices[p.First:p.First+p.Count], cacheSize)
+		}
+	case ".gltf", ".glb":
+		scene, err := gltf.Load(name)
+		if err != nil {
+			return err
+		}
+		done := make(map[*gltf.Mesh]bool)
+		scene.Walk(func(n *gltf.Node, world glm.Mat4) {
+			if n.Mesh == nil || done[n.Mesh] {
+				return
+			}
+			done[n.Mesh] = true
+			for i, p := range n.Mesh.Primitives {
+				reportMesh(w, fmt.Sprintf("%s %s %d", base, n.Mesh.Name, i), p.Mesh.Vertices(), mesh.VertexSize, p.Mesh.Indices, cacheSize)
+			}
+		})
+	default:
+		return fmt.Errorf("%s: only .obj, .gltf and .glb models are supported", name)
+	}
+	return nil
+}
+
+// reportMesh writes a row of the report: the vertices indices use and the
+// ACMR as they are, and after welding the vertices and reordering the
+// triangles with each of the optimizers.
+func reportMesh(w io.Writer, name string, vertices []float32, stride int, indices []uint32, cacheSize int) {
+	before := meshopt.Analyze(indices, len(vertices)/stride, cacheSize)
+	used := make(map[uint32]bool)
+	for _, i := range indices {
+		used[i] = true
+	}
+
+	corners := make([]float32, 0, len(indices)*stride)
+	for _, i := range indices {
+		corners = append(corners, vertices[int(i)*stride:int(i+1)*stride]...)
+	}
+	welded, weldedIndices := meshopt.Weld(corners, stride)
+	n := len(welded) / stride
+	forsyth := meshopt.Analyze(meshopt.OptimizeCache(weldedIndices, n, cacheSize), n, cacheSize)
+	tipsify := meshopt.Analyze(meshopt.Tipsify(weldedIndices, n, cacheSize), n, cacheSize)
+
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\t\n", name, before.Triangles, len(used), n, before.ACMR(), forsyth.ACMR(), tipsify.ACMR())
+}
@@ -0,0 +1,224 @@
+package meshopt
+
+import (
+	"math"
+)
+
+// The tuning of Tom Forsyth's "Linear-Speed Vertex Cache Optimisation".
+const (
+	cacheDecayPower   = 1.5
+	lastTriangleScore = 0.75
+	valenceBoostScale = 2.0
+	valenceBoostPower = 0.5
+)
+
+// vertexScore rates a vertex at position in the LRU cache, or -1 outside
+// it, with remaining triangles still to draw. Vertices of the last
+// triangle score a little lower than the rest of the front of the cache,
+// and vertices with few triangles left score higher, to finish them off.
+func vertexScore(position, remaining, cacheSize int) float64 {
+	if remaining == 0 {
+		return -1
+	}
+	score := 0.0
+	switch {
+	case position < 0:
+	case position < 3:
+		score = lastTriangleScore
+	default:
+		score = math.Pow(1-float64(position-3)/float64(cacheSize-3), cacheDecayPower)
+	}
+	return score + valenceBoostScale*math.Pow(float64(remaining), -valenceBoostPower)
+}
+
+// OptimizeCache reorders the triangles of indices with Forsyth's algorithm
+// for a cache of cacheSize vertices: it greedily draws the triangle whose
+// vertices score best, modelling the cache as LRU. vertexCount is the
+// number of vertices indices refer to. The corners of each triangle keep
+// their order, so the winding is unchanged.
+func OptimizeCache(indices []uint32, vertexCount, cacheSize int) []uint32 {
+	if cacheSize < 4 {
+		cacheSize = 4
+	}
+	indices = indices[:len(indices)/3*3]
+	triangles := len(indices) / 3
+	offsets, around := adjacency(indices, vertexCount)
+
+	// remaining[v] triangles of v are still to draw, kept at the front of
+	// its adjacency list
+	remaining := make([]int, vertexCount)
+	position := make([]int, vertexCount)
+	score := make([]float64, vertexCount)
+	for v := range remaining {
+		remaining[v] = offsets[v+1] - offsets[v]
+		position[v] = -1
+		score[v] = vertexScore(-1, remaining[v], cacheSize)
+	}
+	triangleScore := make([]float64, triangles)
+	for t := range triangleScore {
+		triangleScore[t] = score[indices[3*t]] + score[indices[3*t+1]] + score[indices[3*t+2]]
+	}
+	drawn := make([]bool, triangles)
+
+	out := make([]uint32, 0, len(indices))
+	cache := make([]uint32, 0, cacheSize+3)
+	next := make([]uint32, 0, cacheSize+3)
+	best, cursor := -1, 0
+	for len(out) < len(indices) {
+		if best < 0 {
+			// nothing in the cache has triangles left: take the best
+			// of the rest
+			for drawn[cursor] {
+				cursor++
+			}
+			best = cursor
+			for t := cursor + 1; t < triangles; t++ {
+				if !drawn[t] && triangleScore[t] > triangleScore[best] {
+					best = t
+				}
+			}
+		}
+
+		tri := indices[3*best : 3*best+3]
+		out = append(out, tri...)
+		drawn[best] = true
+		for _, v := range tri {
+			list := around[offsets[v] : offsets[v]+remaining[v]]
+			for i, t := range list {
+				if t == best {
+					list[i] = list[len(list)-1]
+					break
+				}
+			}
+			remaining[v]--
+		}
+
+		// move the corners to the front of the cache
+		next = next[:0]
+		for _, v := range tri {
+			if !containsVertex(next, v) {
+				next = append(next, v)
+			}
+		}
+		for _, v := range cache {
+			if !containsVertex(tri, v) {
+				next = append(next, v)
+			}
+		}
+		cache, next = next, cache
+
+		// rescore the vertices in the cache and those that fell out of it,
+		// and their triangles
+		best = -1
+		for i, v := range cache {
+			if i < cacheSize {
+				position[v] = i
+			} else {
+				position[v] = -1
+			}
+			score[v] = vertexScore(position[v], remaining[v], cacheSize)
+		}
+		for _, v := range cache {
+			for _, t := range around[offsets[v] : offsets[v]+remaining[v]] {
+				triangleScore[t] = score[indices[3*t]] + score[indices[3*t+1]] + score[indices[3*t+2]]
+				if best < 0 || triangleScore[t] > triangleScore[best] {
+					best = t
+				}
+			}
+		}
+		if len(cache) > cacheSize {
+			cache = cache[:cacheSize]
+		}
+	}
+	return out
+}
+
+func containsVertex(list []uint32, v uint32) bool {
+	for _, w := range list {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Tipsify reorders the triangles of indices with the algorithm of Sander,
+// Nehab and Barczak's "Fast Triangle Reordering for Vertex Locality and
+// Reduced Overdraw", for a FIFO cache of cacheSize vertices. It draws
+// fans of triangles around one vertex at a time, choosing the next one
+// among the vertices just drawn that will still be in the cache. It runs
+// in linear time, and as it models the cache the way Analyze does, it
+// usually measures a little better there than OptimizeCache.
+func Tipsify(indices []uint32, vertexCount, cacheSize int) []uint32 {
+	indices = indices[:len(indices)/3*3]
+	offsets, around := adjacency(indices, vertexCount)
+
+	// live[v] triangles of v are still to draw; stamp[v] is the time v
+	// entered the cache
+	live := make([]int, vertexCount)
+	stamp := make([]int, vertexCount)
+	for v := range live {
+		live[v] = offsets[v+1] - offsets[v]
+	}
+	drawn := make([]bool, len(indices)/3)
+	now := cacheSize + 1
+
+	out := make([]uint32, 0, len(indices))
+	var deadEnd, candidates []uint32
+	fan, cursor := 0, 0
+	if vertexCount == 0 {
+		fan = -1
+	}
+	for fan >= 0 {
+		candidates = candidates[:0]
+		for _, t := range around[offsets[fan]:offsets[fan+1]] {
+			if drawn[t] {
+				continue
+			}
+			drawn[t] = true
+			for _, v := range indices[3*t : 3*t+3] {
+				out = append(out, v)
+				deadEnd = append(deadEnd, v)
+				candidates = append(candidates, v)
+				live[v]--
+				if now-stamp[v] > cacheSize {
+					stamp[v] = now
+					now++
+				}
+			}
+		}
+
+		// continue with the candidate that has been in the cache longest
+		// and will still be there after drawing its triangles
+		fan = -1
+		priority := -1
+		for _, v := range candidates {
+			if live[v] == 0 {
+				continue
+			}
+			p := 0
+			if now-stamp[v]+2*live[v] <= cacheSize {
+				p = now - stamp[v]
+			}
+			if p > priority {
+				fan, priority = int(v), p
+			}
+		}
+
+		// or with a recently drawn vertex that has triangles left, or any
+		// vertex that has
+		for fan < 0 && len(deadEnd) > 0 {
+			v := deadEnd[len(deadEnd)-1]
+			deadEnd = deadEnd[:len(deadEnd)-1]
+			if live[v] > 0 {
+				fan = int(v)
+			}
+		}
+		for ; fan < 0 && cursor < vertexCount; cursor++ {
+			if live[cursor] > 0 {
+				fan = cursor
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+// Package meshopt prepares triangle lists for drawing with DrawElements.
+// Weld turns a list drawn with DrawArrays into an index buffer over its
+// distinct vertices. OptimizeCache and Tipsify reorder the triangles so
+// the GPU's post-transform cache reuses more vertices, and OptimizeFetch
+// then reorders the vertices into the order they are first used, so the
+// vertex fetches walk through memory. Analyze measures the result.
+//
+// Vertices are interleaved float32s, stride floats each, as the exercises
+// upload them. Indices are uint32 triangle lists.
+package meshopt
+
+import (
+	"fmt"
+	"math"
+)
+
+// Weld returns the distinct vertices of vertices, in the order they first
+// appear, and the indices that draw the original list from them. Vertices
+// are the same if all their floats are equal; 0 and -0 count as equal.
+func Weld(vertices []float32, stride int) ([]float32, []uint32) {
+	count := vertexCount(vertices, stride)
+	seen := make(map[string]uint32, count)
+	welded := make([]float32, 0, len(vertices))
+	indices := make([]uint32, count)
+	key := make([]byte, 4*stride)
+	for i := range indices {
+		v := vertices[i*stride : (i+1)*stride]
+		for j, f := range v {
+			if f == 0 {
+				f = 0
+			}
+			b := math.Float32bits(f)
+			key[4*j], key[4*j+1], key[4*j+2], key[4*j+3] = byte(b), byte(b>>8), byte(b>>16), byte(b>>24)
+		}
+		index, ok := seen[string(key)]
+		if !ok {
+			index = uint32(len(welded) / stride)
+			seen[string(key)] = index
+			welded = append(welded, v...)
+		}
+		indices[i] = index
+	}
+	return welded, indices
+}
+
+// OptimizeFetch reorders vertices into the order indices first use them and
+// rewrites indices to match. Vertices no triangle uses are dropped.
+func OptimizeFetch(vertices []float32, stride int, indices []uint32) []float32 {
+	remap, used := fetchRemap(indices, vertexCount(vertices, stride))
+	out := make([]float32, used*stride)
+	for v, i := range remap {
+		if i >= 0 {
+			copy(out[i*stride:(i+1)*stride], vertices[v*stride:])
+		}
+	}
+	for k, v := range indices {
+		indices[k] = uint32(remap[v])
+	}
+	return out
+}
+
+// fetchRemap returns the new index of every vertex when they are numbered
+// in the order indices first use them, or -1 for unused ones, and how many
+// are used.
+func fetchRemap(indices []uint32, count int) ([]int, int) {
+	remap := make([]int, count)
+	for v := range remap {
+		remap[v] = -1
+	}
+	used := 0
+	for _, v := range indices {
+		if remap[v] < 0 {
+			remap[v] = used
+			used++
+		}
+	}
+	return remap, used
+}
+
+// Stats describe how a triangle list uses a FIFO post-transform cache.
+type Stats struct {
+	Triangles   int // triangles in the list
+	Vertices    int // vertices in the buffer
+	Transformed int // vertices the vertex shader runs for
+}
+
+// ACMR is the average cache miss ratio: vertices transformed per
+// triangle. It is 3 without any reuse and approaches 0.5 for large
+// regular meshes.
+func (s Stats) ACMR() float64 {
+	if s.Triangles == 0 {
+		return 0
+	}
+	return float64(s.Transformed) / float64(s.Triangles)
+}
+
+// ATVR is the average transform to vertex ratio: how often each vertex is
+// transformed. 1 is the best possible, whatever the mesh.
+func (s Stats) ATVR() float64 {
+	if s.Vertices == 0 {
+		return 0
+	}
+	return float64(s.Transformed) / float64(s.Vertices)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d triangles, %d vertices, ACMR %.3f, ATVR %.3f", s.Triangles, s.Vertices, s.ACMR(), s.ATVR())
+}
+
+// Analyze simulates drawing indices through a FIFO cache of cacheSize
+// vertices, the model most hardware is closest to.
+func Analyze(indices []uint32, vertexCount, cacheSize int) Stats {
+	s := Stats{Triangles: len(indices) / 3, Vertices: vertexCount}
+	// entered is the miss count when each vertex last entered the cache
+	entered := make([]int, vertexCount)
+	for v := range entered {
+		entered[v] = -cacheSize - 1
+	}
+	for _, v := range indices[:s.Triangles*3] {
+		if s.Transformed-entered[v] > cacheSize {
+			entered[v] = s.Transformed
+			s.Transformed++
+		}
+	}
+	return s
+}
+
+// vertexCount returns the number of vertices of stride floats in vertices.
+func vertexCount(vertices []float32, stride int) int {
+	if stride <= 0 || len(vertices)%stride != 0 {
+		panic(fmt.Sprintf("meshopt: %d floats are not vertices of %d", len(vertices), stride))
+	}
+	return len(vertices) / stride
+}
+
+// adjacency returns the triangles around every vertex: those of vertex v
+// are triangles[offsets[v]:offsets[v+1]], once for each corner at v.
+func adjacency(indices []uint32, vertexCount int) (offsets, triangles []int) {
+	offsets = make([]int, vertexCount+1)
+	for _, v := range indices {
+		offsets[v+1]++
+	}
+	for v := 0; v < vertexCount; v++ {
+		offsets[v+1] += offsets[v]
+	}
+	triangles = make([]int, len(indices))
+	fill := append([]int(nil), offsets[:vertexCount]...)
+	for k, v := range indices {
+		triangles[fill[v]] = k / 3
+		fill[v]++
+	}
+	return offsets, triangles
+}
@@ -0,0 +1,117 @@
+package meshopt
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// triangleSet returns the triangles of indices as sorted strings, each
+// rotated to start at its smallest index, so reordered triangle lists with
+// the same triangles and windings compare equal.
+func triangleSet(indices []uint32) []string {
+	var set []string
+	for t := 0; t+2 < len(indices); t += 3 {
+		a, b, c := indices[t], indices[t+1], indices[t+2]
+		for a > b || a > c {
+			a, b, c = b, c, a
+		}
+		set = append(set, fmt.Sprint(a, b, c))
+	}
+	sort.Strings(set)
+	return set
+}
+
+func TestWeld(t *testing.T) {
+	// the cube as DrawArrays would draw it, every corner on its own
+	cube := mesh.Cube(1)
+	vertices := cube.Vertices()
+	var unwelded []float32
+	for _, i := range cube.Indices {
+		unwelded = append(unwelded, vertices[int(i)*mesh.VertexSize:int(i+1)*mesh.VertexSize]...)
+	}
+	welded, indices := Weld(unwelded, mesh.VertexSize)
+	if n := len(welded) / mesh.VertexSize; n != 24 || len(indices) != 36 {
+		t.Fatalf("welded into %d vertices and %d indices, want 24 and 36", n, len(indices))
+	}
+	for k, i := range indices {
+		for j := 0; j < mesh.VertexSize; j++ {
+			if welded[int(i)*mesh.VertexSize+j] != unwelded[k*mesh.VertexSize+j] {
+				t.Fatalf("index %d draws a different vertex", k)
+			}
+		}
+	}
+
+	if _, indices := Weld([]float32{0, 1, -0, 1}, 2); indices[1] != 0 {
+		t.Error("0 and -0 weren't welded")
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	// a grid with its triangles shuffled uses the cache badly
+	grid := mesh.Grid(1, 1, 32, 32)
+	vertices := grid.Vertices()
+	indices := append([]uint32(nil), grid.Indices...)
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(indices)/3, func(i, j int) {
+		for k := 0; k < 3; k++ {
+			indices[3*i+k], indices[3*j+k] = indices[3*j+k], indices[3*i+k]
+		}
+	})
+	count := len(grid.Positions)
+	before := Analyze(indices, count, 16)
+	if before.ACMR() < 2 {
+		t.Fatalf("shuffled grid already has ACMR %.3f", before.ACMR())
+	}
+
+	for _, test := range []struct {
+		name     string
+		optimize func([]uint32, int, int) []uint32
+		max      float64
+	}{
+		{"OptimizeCache", OptimizeCache, 0.8},
+		{"Tipsify", Tipsify, 0.8},
+	} {
+		optimized := test.optimize(indices, count, 16)
+		if fmt.Sprint(triangleSet(optimized)) != fmt.Sprint(triangleSet(indices)) {
+			t.Errorf("%s changed the triangles", test.name)
+			continue
+		}
+		after := Analyze(optimized, count, 16)
+		if after.ACMR() > test.max {
+			t.Errorf("%s: ACMR %.3f, want at most %.3f", test.name, after.ACMR(), test.max)
+		}
+
+		v := OptimizeFetch(vertices, mesh.VertexSize, optimized)
+		if len(v) != len(vertices) {
+			t.Errorf("%s: %d floats after OptimizeFetch, want %d", test.name, len(v), len(vertices))
+		}
+		// vertices are numbered in the order they are first used
+		next := uint32(0)
+		for _, i := range optimized {
+			if i > next {
+				t.Fatalf("%s: vertex %d used before %d", test.name, i, next)
+			}
+			if i == next {
+				next++
+			}
+		}
+		if Analyze(optimized, count, 16) != after {
+			t.Errorf("%s: OptimizeFetch changed the cache behaviour", test.name)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// two triangles sharing an edge transform 4 vertices, and 6 with a
+	// cache too small to keep the edge between them
+	quad := []uint32{0, 1, 2, 2, 1, 3}
+	if s := Analyze(quad, 4, 16); s.Transformed != 4 || s.ACMR() != 2 || s.ATVR() != 1 {
+		t.Errorf("got %v", s)
+	}
+	if s := Analyze([]uint32{0, 1, 2, 3, 4, 5, 0, 1, 2}, 6, 3); s.Transformed != 9 {
+		t.Errorf("FIFO of 3 transformed %d vertices, want 9", s.Transformed)
+	}
+}
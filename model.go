@@ -0,0 +1,199 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/camera"
+	"github.com/Happy-Ferret/gl-tutorial/obj"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"image/color"
+	"math"
+)
+
+// modelPath is the OBJ file the model scene draws, set with the --model
+// flag.
+var modelPath = "models/crate.obj"
+
+func init() {
+	registerScene("model", func() Scene { return new(model) })
+}
+
+// modelUniforms are the uniforms of the model shaders.
+type modelUniforms struct {
+	Model        glm.Mat4 `glsl:"model"`
+	View         glm.Mat4 `glsl:"view"`
+	Proj         glm.Mat4 `glsl:"proj"`
+	DiffuseColor glm.Vec3 `glsl:"diffuseColor"`
+	LightDir     glm.Vec3 `glsl:"lightDir"`
+	DiffuseMap   int32    `glsl:"diffuseMap"`
+}
+
+// modelPart is a run of triangles of the model with its material.
+type modelPart struct {
+	first, count int
+	color        glm.Vec3
+	texture      gl.Texture
+}
+
+// model draws a Wavefront OBJ file, scaled to fit the view and turned from
+// Y up to Z up.
+type model struct {
+	mesh     *glMesh
+	parts    []modelPart
+	textures []gl.Texture
+	fit      glm.Mat4
+	program  *shaderProgram
+	uniforms modelUniforms
+	binding  *uniformBinding
+	camera   camera.Controller
+	simTime
+}
+
+func (s *model) Init() {
+	var err error
+
+	gl.Enable(gl.DEPTH_TEST)
+
+	m, err := obj.Load(modelPath)
+	if err != nil {
+		panic(err)
+	}
+
+	// create shader program
+	s.program, err = loadProgram("model", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup vertex data
+	s.mesh, err = uploadMesh(m.Mesh, s.program.Program)
+	if err != nil {
+		panic(err)
+	}
+
+	// setup texture data; parts without a diffuse map sample a white
+	// texture so the shader needn't tell them apart
+	gl.ActiveTexture(gl.TEXTURE0)
+	white := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	white.SetNRGBA(0, 0, color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF})
+	blank, err := createTexture(white, DefaultSampler)
+	if err != nil {
+		panic(err)
+	}
+	s.textures = append(s.textures, blank)
+	maps := make(map[string]gl.Texture)
+	for _, p := range m.Parts {
+		part := modelPart{first: p.First, count: p.Count, color: glm.Vec3{0.8, 0.8, 0.8}, texture: blank}
+		if mat := m.Materials[p.Material]; mat != nil {
+			part.color = mat.Diffuse
+			if mat.DiffuseMap != "" {
+				part.texture, err = s.diffuseMap(maps, mat.DiffuseMap)
+				if err != nil {
+					panic(err)
+				}
+			}
+		}
+		s.parts = append(s.parts, part)
+	}
+
+	// scale the model into a unit cube around the origin
+	s.fit = fitUnitCube(m.Mesh.Positions)
+
+	// look at the scene through a camera the mouse and keyboard move
+	s.camera = newCamera(
+		glm.Vec3{2.2, 3.2, 2.2},
+		glm.Vec3{0.0, 0.0, 0.0},
+		glm.Vec3{0.0, 0.0, 0.5})
+
+	// setup uniforms, and again whenever the shaders are reloaded
+	s.uniforms = modelUniforms{
+		View:       s.camera.View(),
+		LightDir:   glm.Vec3{0.4, 0.6, 1.0}.Normalize(),
+		DiffuseMap: 0,
+	}
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+}
+
+// diffuseMap returns the texture for the image file at path, loading it
+// the first time.
+func (s *model) diffuseMap(maps map[string]gl.Texture, path string) (gl.Texture, error) {
+	if texture, ok := maps[path]; ok {
+		return texture, nil
+	}
+	img, err := texfmt.Open(path)
+	if err != nil {
+		return gl.Texture(0), err
+	}
+	texture, err := createTexture(img, DefaultSampler)
+	if err != nil {
+		return gl.Texture(0), err
+	}
+	maps[path] = texture
+	s.textures = append(s.textures, texture)
+	return texture, nil
+}
+
+// fitUnitCube returns the transform that centers the bounding box of
+// positions on the origin, scales its longest side to 1 and turns Y up
+// into Z up.
+func fitUnitCube(positions []glm.Vec3) glm.Mat4 {
+	if len(positions) == 0 {
+		return glm.Ident4()
+	}
+	lo, hi := positions[0], positions[0]
+	for _, p := range positions {
+		for i := range p {
+			lo[i] = float32(math.Min(float64(lo[i]), float64(p[i])))
+			hi[i] = float32(math.Max(float64(hi[i]), float64(p[i])))
+		}
+	}
+	size := hi.Sub(lo)
+	scale := float32(math.Max(float64(size[0]), math.Max(float64(size[1]), float64(size[2]))))
+	if scale == 0 {
+		scale = 1
+	}
+	center := lo.Add(hi).Mul(0.5)
+	return glm.HomogRotate3DX(math.Pi / 2).
+		Mul4(glm.Scale3D(1/scale, 1/scale, 1/scale)).
+		Mul4(glm.Translate3D(-center[0], -center[1], -center[2]))
+}
+
+// bindUniforms binds s.uniforms to the uniforms of the program and uploads
+// them.
+func (s *model) bindUniforms() {
+	var err error
+	s.binding, err = bindUniformStruct(s.program.Program, &s.uniforms)
+	if err != nil {
+		panic(err)
+	}
+	s.binding.Upload()
+}
+
+func (s *model) Render(alpha float32) {
+	// move the camera and fit the projection to the window
+	s.uniforms.View = s.camera.View()
+	s.uniforms.Proj = perspective(45.0, 1.0, 10.0)
+
+	// clear the screen to black
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	// rotate
+	s.uniforms.Model = glm.HomogRotate3DZ(0.5 * math.Pi * float32(s.seconds(alpha))).Mul4(s.fit)
+
+	// draw every part with its material
+	for _, p := range s.parts {
+		s.uniforms.DiffuseColor = p.color
+		s.binding.Upload()
+		p.texture.Bind(gl.TEXTURE_2D)
+		s.mesh.DrawRange(p.first, p.count)
+	}
+}
+
+func (s *model) Delete() {
+	s.program.Delete()
+	gl.DeleteTextures(s.textures)
+	s.mesh.Delete()
+}
@@ -0,0 +1,116 @@
+package obj
+
+import (
+	"fmt"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Material is a material from an MTL library. Only the parts that matter
+// to a simple Phong or Lambert shader are kept.
+type Material struct {
+	Name      string
+	Ambient   glm.Vec3 // Ka
+	Diffuse   glm.Vec3 // Kd
+	Specular  glm.Vec3 // Ks
+	Emissive  glm.Vec3 // Ke
+	Shininess float32  // Ns
+	Opacity   float32  // d, or 1 - Tr
+
+	// DiffuseMap is the path of the map_Kd texture, with slashes, or
+	// empty if there is none.
+	DiffuseMap string
+}
+
+// DecodeMaterials reads an MTL library from r; name is the file name used
+// in errors.
+func DecodeMaterials(r io.Reader, name string) (map[string]*Material, error) {
+	materials := make(map[string]*Material)
+	var mat *Material
+	err := scanLines(r, name, func(line int, keyword string, args []string) error {
+		errorf := func(format string, args ...interface{}) error {
+			return &Error{File: name, Line: line, Message: fmt.Sprintf(format, args...)}
+		}
+		if keyword == "newmtl" {
+			if len(args) == 0 {
+				return errorf("newmtl needs a material name")
+			}
+			mat = &Material{
+				Name:      strings.Join(args, " "),
+				Diffuse:   glm.Vec3{0.8, 0.8, 0.8},
+				Shininess: 1,
+				Opacity:   1,
+			}
+			materials[mat.Name] = mat
+			return nil
+		}
+		if mat == nil {
+			return errorf("%s before the first newmtl", keyword)
+		}
+
+		number := func() (float32, error) {
+			if len(args) != 1 {
+				return 0, errorf("%s needs 1 number, got %d", keyword, len(args))
+			}
+			x, err := strconv.ParseFloat(args[0], 32)
+			if err != nil {
+				return 0, errorf("bad number %q", args[0])
+			}
+			return float32(x), nil
+		}
+		color := func() (glm.Vec3, error) {
+			// a single value is a gray; spectral and xyz colors aren't
+			// supported
+			if len(args) != 1 && len(args) != 3 {
+				return glm.Vec3{}, errorf("%s needs an r g b color, got %d values", keyword, len(args))
+			}
+			var c glm.Vec3
+			for i := range c {
+				s := args[i%len(args)]
+				x, err := strconv.ParseFloat(s, 32)
+				if err != nil {
+					return glm.Vec3{}, errorf("bad number %q", s)
+				}
+				c[i] = float32(x)
+			}
+			return c, nil
+		}
+
+		var err error
+		switch keyword {
+		case "Ka":
+			mat.Ambient, err = color()
+		case "Kd":
+			mat.Diffuse, err = color()
+		case "Ks":
+			mat.Specular, err = color()
+		case "Ke":
+			mat.Emissive, err = color()
+		case "Ns":
+			mat.Shininess, err = number()
+		case "d":
+			// -halo is rare enough to ignore
+			if len(args) == 2 && args[0] == "-halo" {
+				args = args[1:]
+			}
+			mat.Opacity, err = number()
+		case "Tr":
+			var tr float32
+			tr, err = number()
+			mat.Opacity = 1 - tr
+		case "map_Kd":
+			// options like -s 1 1 1 come first, the file name last
+			if len(args) == 0 {
+				return errorf("map_Kd needs a file name")
+			}
+			mat.DiffuseMap = strings.Replace(args[len(args)-1], `\`, "/", -1)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
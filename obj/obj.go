@@ -0,0 +1,416 @@
+// Package obj reads Wavefront OBJ models and their MTL material libraries
+// into indexed meshes.
+//
+// Faces may have up to 4096 corners and refer to positions, texture
+// coordinates and normals by 1-based or negative, relative indices. Corners
+// with the same indices share a vertex. Faces are split into parts by
+// group, object and material. Normals the file leaves out are averaged
+// from the faces around each position, and tangents are computed from the
+// texture coordinates.
+//
+// Texture coordinates are kept as they are in the file, with v = 0 at the
+// bottom of the image like OpenGL. Free-form geometry, lines, points and
+// smoothing groups are ignored.
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/mesh"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Model is a mesh read from an OBJ file and the materials it uses.
+type Model struct {
+	Mesh  *mesh.Mesh
+	Parts []Part
+
+	// Materials maps the names of all materials in the libraries the
+	// file loads to their definitions.
+	Materials map[string]*Material
+}
+
+// Part is a run of triangles drawn with one material: Count indices of
+// the mesh starting at First. Material is empty if the file didn't set
+// one, and may name a material missing from Model.Materials.
+type Part struct {
+	Object   string
+	Group    string
+	Material string
+	First    int
+	Count    int
+}
+
+// Error is a problem with a line of an OBJ or MTL file.
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Load reads the OBJ file name. Material libraries are looked up relative
+// to the directory of the file, and the texture paths in the materials are
+// joined with it.
+func Load(name string) (*Model, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(name)
+	m, err := Decode(f, name, func(lib string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(lib)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, mat := range m.Materials {
+		if mat.DiffuseMap != "" {
+			mat.DiffuseMap = filepath.Join(dir, filepath.FromSlash(mat.DiffuseMap))
+		}
+	}
+	return m, nil
+}
+
+// Decode reads an OBJ file from r; name is the file name used in errors.
+// open is called with the names of the material libraries, as written in
+// the file, and may be nil to skip them. Texture paths in the materials
+// are relative to the directory of the OBJ file.
+func Decode(r io.Reader, name string, open func(lib string) (io.ReadCloser, error)) (*Model, error) {
+	d := &decoder{
+		name:      name,
+		open:      open,
+		model:     &Model{Mesh: new(mesh.Mesh), Materials: make(map[string]*Material)},
+		vertices:  make(map[corner]uint32),
+		libraries: make(map[string]bool),
+	}
+	err := scanLines(r, name, func(line int, keyword string, args []string) error {
+		d.line = line
+		return d.statement(keyword, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.finish(), nil
+}
+
+// corner is one corner of a face: the 0-based indices of its position,
+// texture coordinates and normal, -1 for the ones left out.
+type corner struct {
+	v, vt, vn int
+}
+
+type decoder struct {
+	name string
+	line int
+	open func(string) (io.ReadCloser, error)
+
+	positions []glm.Vec3
+	texcoords []glm.Vec2
+	normals   []glm.Vec3
+
+	model     *Model
+	vertices  map[corner]uint32
+	corners   []corner // the corner of each vertex of the mesh
+	part      Part
+	libraries map[string]bool
+}
+
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return &Error{File: d.name, Line: d.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (d *decoder) statement(keyword string, args []string) error {
+	switch keyword {
+	case "v":
+		// x y z, then an optional w or r g b we don't use
+		if len(args) < 3 {
+			return d.errorf("v needs 3 coordinates, got %d", len(args))
+		}
+		p, err := d.floats(args[:3])
+		if err != nil {
+			return err
+		}
+		d.positions = append(d.positions, glm.Vec3{p[0], p[1], p[2]})
+	case "vt":
+		if len(args) < 1 || len(args) > 3 {
+			return d.errorf("vt needs 1 to 3 coordinates, got %d", len(args))
+		}
+		t, err := d.floats(args)
+		if err != nil {
+			return err
+		}
+		t = append(t, 0)
+		d.texcoords = append(d.texcoords, glm.Vec2{t[0], t[1]})
+	case "vn":
+		if len(args) != 3 {
+			return d.errorf("vn needs 3 coordinates, got %d", len(args))
+		}
+		n, err := d.floats(args)
+		if err != nil {
+			return err
+		}
+		d.normals = append(d.normals, glm.Vec3{n[0], n[1], n[2]})
+	case "f":
+		return d.face(args)
+	case "g":
+		d.setPart(d.part.Object, strings.Join(args, " "), d.part.Material)
+	case "o":
+		d.setPart(strings.Join(args, " "), d.part.Group, d.part.Material)
+	case "usemtl":
+		if len(args) == 0 {
+			return d.errorf("usemtl needs a material name")
+		}
+		d.setPart(d.part.Object, d.part.Group, strings.Join(args, " "))
+	case "mtllib":
+		if len(args) == 0 {
+			return d.errorf("mtllib needs a file name")
+		}
+		for _, lib := range args {
+			if err := d.library(lib); err != nil {
+				return err
+			}
+		}
+	}
+	// s, l, p and everything else we don't draw
+	return nil
+}
+
+func (d *decoder) floats(args []string) ([]float32, error) {
+	f := make([]float32, len(args))
+	for i, s := range args {
+		x, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return nil, d.errorf("bad number %q", s)
+		}
+		f[i] = float32(x)
+	}
+	return f, nil
+}
+
+// index converts a 1-based or negative OBJ index into a slice of n
+// elements to a 0-based one.
+func (d *decoder) index(s, what string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	switch {
+	case err != nil:
+		return 0, d.errorf("bad %s index %q", what, s)
+	case i > 0 && i <= n:
+		return i - 1, nil
+	case i < 0 && -i <= n:
+		return n + i, nil
+	case i == 0:
+		return 0, d.errorf("%s index 0: indices start at 1", what)
+	}
+	return 0, d.errorf("%s index %d out of range, there are %d so far", what, i, n)
+}
+
+func (d *decoder) corner(s string) (corner, error) {
+	c := corner{-1, -1, -1}
+	refs := strings.Split(s, "/")
+	if len(refs) > 3 || refs[0] == "" {
+		return c, d.errorf("bad face corner %q, want v, v/vt, v//vn or v/vt/vn", s)
+	}
+	var err error
+	if c.v, err = d.index(refs[0], "position", len(d.positions)); err != nil {
+		return c, err
+	}
+	if len(refs) > 1 && refs[1] != "" {
+		if c.vt, err = d.index(refs[1], "texture coordinate", len(d.texcoords)); err != nil {
+			return c, err
+		}
+	}
+	if len(refs) > 2 && refs[2] != "" {
+		if c.vn, err = d.index(refs[2], "normal", len(d.normals)); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// maxCorners limits the corners of a face. Clipping a concave face takes
+// time growing with the square of its corners, and no modelling tool
+// writes faces anywhere near this big.
+const maxCorners = 4096
+
+func (d *decoder) face(args []string) error {
+	if len(args) < 3 {
+		return d.errorf("a face needs at least 3 corners, got %d", len(args))
+	}
+	if len(args) > maxCorners {
+		return d.errorf("a face with %d corners is too big, at most %d are supported", len(args), maxCorners)
+	}
+	corners := make([]corner, len(args))
+	points := make([]glm.Vec3, len(args))
+	for i, s := range args {
+		c, err := d.corner(s)
+		if err != nil {
+			return err
+		}
+		corners[i], points[i] = c, d.positions[c.v]
+	}
+	m := d.model.Mesh
+	for _, t := range triangulate(points) {
+		for _, k := range t {
+			m.Indices = append(m.Indices, d.vertex(corners[k]))
+		}
+	}
+	return nil
+}
+
+// vertex returns the mesh vertex for c, adding it the first time.
+func (d *decoder) vertex(c corner) uint32 {
+	if i, ok := d.vertices[c]; ok {
+		return i
+	}
+	m := d.model.Mesh
+	i := uint32(len(m.Positions))
+	m.Positions = append(m.Positions, d.positions[c.v])
+	var uv glm.Vec2
+	if c.vt >= 0 {
+		uv = d.texcoords[c.vt]
+	}
+	m.Texcoords = append(m.Texcoords, uv)
+	var n glm.Vec3
+	if c.vn >= 0 {
+		n = d.normals[c.vn]
+	}
+	m.Normals = append(m.Normals, n)
+	d.vertices[c] = i
+	d.corners = append(d.corners, c)
+	return i
+}
+
+// setPart ends the current part and starts a new one if anything changed.
+func (d *decoder) setPart(object, group, material string) {
+	if object == d.part.Object && group == d.part.Group && material == d.part.Material {
+		return
+	}
+	d.endPart()
+	d.part = Part{Object: object, Group: group, Material: material, First: len(d.model.Mesh.Indices)}
+}
+
+func (d *decoder) endPart() {
+	d.part.Count = len(d.model.Mesh.Indices) - d.part.First
+	if d.part.Count > 0 {
+		d.model.Parts = append(d.model.Parts, d.part)
+	}
+}
+
+func (d *decoder) library(lib string) error {
+	lib = strings.Replace(lib, `\`, "/", -1)
+	if d.open == nil || d.libraries[lib] {
+		return nil
+	}
+	d.libraries[lib] = true
+	f, err := d.open(lib)
+	if err != nil {
+		return d.errorf("mtllib: %v", err)
+	}
+	defer f.Close()
+
+	materials, err := DecodeMaterials(f, lib)
+	if err != nil {
+		return err
+	}
+	for name, mat := range materials {
+		if mat.DiffuseMap != "" {
+			mat.DiffuseMap = path.Join(path.Dir(lib), mat.DiffuseMap)
+		}
+		d.model.Materials[name] = mat
+	}
+	return nil
+}
+
+// finish ends the last part and fills in the normals the file left out
+// and the tangents.
+func (d *decoder) finish() *Model {
+	d.endPart()
+	m := d.model.Mesh
+
+	// average the normals of the faces around each position, weighted by
+	// their area
+	missing := make(map[int]glm.Vec3)
+	for _, c := range d.corners {
+		if c.vn < 0 {
+			missing[c.v] = glm.Vec3{}
+		}
+	}
+	if len(missing) > 0 {
+		for t := 0; t+2 < len(m.Indices); t += 3 {
+			a, b, c := m.Indices[t], m.Indices[t+1], m.Indices[t+2]
+			face := m.Positions[b].Sub(m.Positions[a]).Cross(m.Positions[c].Sub(m.Positions[a]))
+			for _, i := range []uint32{a, b, c} {
+				if v := d.corners[i].v; d.corners[i].vn < 0 {
+					missing[v] = missing[v].Add(face)
+				}
+			}
+		}
+		for i, c := range d.corners {
+			if c.vn < 0 {
+				m.Normals[i] = missing[c.v]
+			}
+		}
+	}
+	for i, n := range m.Normals {
+		if l := n.Len(); l > 0 {
+			m.Normals[i] = n.Mul(1 / l)
+		} else {
+			m.Normals[i] = glm.Vec3{0, 0, 1}
+		}
+	}
+	m.ComputeTangents()
+	return d.model
+}
+
+// scanLines calls fn with the keyword and arguments of every statement in
+// r, skipping blank lines and comments and joining lines that end in a
+// backslash.
+func scanLines(r io.Reader, name string, fn func(line int, keyword string, args []string) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	var (
+		n, start int
+		text     string
+	)
+	for s.Scan() {
+		n++
+		if text == "" {
+			start = n
+		}
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.HasSuffix(line, `\`) {
+			text += line[:len(line)-1] + " "
+			continue
+		}
+		fields := strings.Fields(text + line)
+		text = ""
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(start, fields[0], fields[1:]); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return &Error{File: name, Line: n + 1, Message: err.Error()}
+	}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		return fn(start, fields[0], fields[1:])
+	}
+	return nil
+}
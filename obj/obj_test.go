@@ -0,0 +1,238 @@
+package obj
+
+import (
+	"errors"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+const cubeOBJ = `# a unit cube with one texture per pair of faces
+mtllib cube.mtl
+o cube
+v -0.5 -0.5 -0.5
+v  0.5 -0.5 -0.5
+v  0.5  0.5 -0.5
+v -0.5  0.5 -0.5
+v -0.5 -0.5  0.5
+v  0.5 -0.5  0.5
+v  0.5  0.5  0.5
+v -0.5  0.5  0.5
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 -1
+vn 0 0 1
+g bottom top
+usemtl red
+f 1/1/1 4/4/1 3/3/1 2/2/1
+f 5/1/2 6/2/2 7/3/2 8/4/2
+g sides
+usemtl wood
+f -8/1 -7/2 -3/3 \
+  -4/4
+f 2/1 3/2 7/3 6/4
+f 3/1 4/2 8/3 7/4
+f 4/1 1/2 5/3 8/4
+`
+
+const cubeMTL = `newmtl red
+Kd 1 0 0
+Ns 10
+newmtl wood
+Kd 1
+d 0.5
+map_Kd -s 2 2 1 textures\wood.png
+`
+
+func openCube(lib string) (io.ReadCloser, error) {
+	if lib != "cube.mtl" {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(strings.NewReader(cubeMTL)), nil
+}
+
+func TestDecode(t *testing.T) {
+	m, err := Decode(strings.NewReader(cubeOBJ), "cube.obj", openCube)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.Mesh.Indices); got != 36 {
+		t.Errorf("%d indices, want 36", got)
+	}
+	// every side corner has different texture coordinates than the
+	// corners of the neighbouring sides at the same position
+	if got := len(m.Mesh.Positions); got != 8+16 {
+		t.Errorf("%d vertices, want 24", got)
+	}
+	want := []Part{
+		{Object: "cube", Group: "bottom top", Material: "red", First: 0, Count: 12},
+		{Object: "cube", Group: "sides", Material: "wood", First: 12, Count: 24},
+	}
+	if len(m.Parts) != len(want) {
+		t.Fatalf("parts %+v, want %+v", m.Parts, want)
+	}
+	for i := range want {
+		if m.Parts[i] != want[i] {
+			t.Errorf("part %d is %+v, want %+v", i, m.Parts[i], want[i])
+		}
+	}
+
+	red, wood := m.Materials["red"], m.Materials["wood"]
+	if red == nil || wood == nil {
+		t.Fatalf("materials %v", m.Materials)
+	}
+	if red.Diffuse != (glm.Vec3{1, 0, 0}) || red.Shininess != 10 || red.Opacity != 1 {
+		t.Errorf("red is %+v", red)
+	}
+	if wood.Diffuse != (glm.Vec3{1, 1, 1}) || wood.Opacity != 0.5 || wood.DiffuseMap != "textures/wood.png" {
+		t.Errorf("wood is %+v", wood)
+	}
+
+	// generated normals point out of the cube
+	for i, n := range m.Mesh.Normals {
+		if p := m.Mesh.Positions[i]; n.Dot(p) <= 0 {
+			t.Errorf("vertex %d at %v has normal %v", i, p, n)
+		}
+	}
+}
+
+// area adds up the signed areas of tris in the XY plane, failing t for
+// triangles that are inside out or flat.
+func area(t *testing.T, points []glm.Vec3, tris [][3]int) float64 {
+	var sum float64
+	for _, tri := range tris {
+		a, b, c := points[tri[0]], points[tri[1]], points[tri[2]]
+		z := b.Sub(a).Cross(c.Sub(a))[2]
+		if z <= 0 {
+			t.Errorf("triangle %v is inside out or flat", tri)
+		}
+		sum += float64(z) / 2
+	}
+	return sum
+}
+
+func TestTriangulate(t *testing.T) {
+	// an L whose only ear at the first corner would cut the notch off
+	l := []glm.Vec3{{1, 1, 0}, {0, 1, 0}, {0, 0, 0}, {2, 0, 0}, {2, 0.5, 0}, {1, 0.5, 0}}
+	tris := triangulate(l)
+	if len(tris) != 4 {
+		t.Fatalf("%d triangles, want 4", len(tris))
+	}
+	if got := area(t, l, tris); got != 1.5 {
+		t.Errorf("triangles cover %g, want 1.5", got)
+	}
+}
+
+func TestTriangulateLarge(t *testing.T) {
+	// a regular polygon is convex and cut into a fan
+	const n = maxCorners
+	circle := make([]glm.Vec3, n)
+	for i := range circle {
+		a := 2 * math.Pi * float64(i) / n
+		circle[i] = glm.Vec3{float32(math.Cos(a)), float32(math.Sin(a)), 0}
+	}
+	tris := triangulate(circle)
+	if len(tris) != n-2 {
+		t.Fatalf("%d triangles for a convex %d-gon, want %d", len(tris), n, n-2)
+	}
+	for i, tri := range tris {
+		if tri != [3]int{0, i + 1, i + 2} {
+			t.Fatalf("triangle %d is %v, not part of a fan", i, tri)
+		}
+	}
+
+	// a comb with half its corners reflex: teeth 1 wide and 4 high with
+	// gaps of 1 between them, on a base 1 high
+	const teeth = n / 4
+	comb := []glm.Vec3{{0, -1, 0}, {2*teeth - 1, -1, 0}}
+	for k := teeth - 1; k >= 0; k-- {
+		x := float32(2 * k)
+		if k < teeth-1 {
+			comb = append(comb, glm.Vec3{x + 1, 0, 0})
+		}
+		comb = append(comb, glm.Vec3{x + 1, 4, 0}, glm.Vec3{x, 4, 0})
+		if k > 0 {
+			comb = append(comb, glm.Vec3{x, 0, 0})
+		}
+	}
+	tris = triangulate(comb)
+	if len(tris) != len(comb)-2 {
+		t.Fatalf("%d triangles for a comb of %d corners, want %d", len(tris), len(comb), len(comb)-2)
+	}
+	if got, want := area(t, comb, tris), float64(6*teeth-1); math.Abs(got-want) > 1e-3*want {
+		t.Errorf("comb triangles cover %g, want %g", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{"v 1 2\n", "test.obj:1: v needs 3 coordinates, got 2"},
+		{"v 1 2 x\n", `test.obj:1: bad number "x"`},
+		{"v 0 0 0\nv 1 0 0\nf 1 2\n", "test.obj:3: a face needs at least 3 corners, got 2"},
+		{"v 0 0 0\nf 1 1 0\n", "test.obj:2: position index 0: indices start at 1"},
+		{"v 0 0 0\n\n# three\nf 1 1 -2\n", "test.obj:4: position index -2 out of range, there are 1 so far"},
+		{"v 0 0 0\nf 1/1 1/1 1/1\n", "test.obj:2: texture coordinate index 1 out of range, there are 0 so far"},
+		{"v 0 0 0\nf 1//a 1 1\n", `test.obj:2: bad normal index "a"`},
+		{"v 0 0 0\nf 1/1/1/1 1 1\n", `test.obj:2: bad face corner "1/1/1/1", want v, v/vt, v//vn or v/vt/vn`},
+		{"v 0 0 0\nf" + strings.Repeat(" 1", maxCorners+1) + "\n", "test.obj:2: a face with 4097 corners is too big, at most 4096 are supported"},
+		{"mtllib missing.mtl\n", "test.obj:1: mtllib: file does not exist"},
+		{"mtllib cube.mtl\nusemtl\n", "test.obj:2: usemtl needs a material name"},
+	}
+	for _, test := range tests {
+		_, err := Decode(strings.NewReader(test.src), "test.obj", openCube)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %s", test.src, err, test.err)
+		}
+	}
+
+	_, err := DecodeMaterials(strings.NewReader("Kd 1 1 1\n"), "test.mtl")
+	var e *Error
+	if !errors.As(err, &e) || e.Line != 1 || e.Message != "Kd before the first newmtl" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(cubeOBJ)
+	f.Add("v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\n")
+	f.Add("v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nv 2 2 2\nf 1 2 3 4 5 1\n")
+	f.Add("newmtl x\nmap_Kd\n")
+	f.Fuzz(func(t *testing.T, src string) {
+		m, err := Decode(strings.NewReader(src), "fuzz.obj", openCube)
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error %v is not an *Error", err)
+			}
+			return
+		}
+		mesh := m.Mesh
+		n := len(mesh.Positions)
+		if len(mesh.Normals) != n || len(mesh.Texcoords) != n || len(mesh.Tangents) != n || len(mesh.Indices)%3 != 0 {
+			t.Fatalf("inconsistent mesh: %d positions, %d normals, %d texcoords, %d tangents, %d indices",
+				n, len(mesh.Normals), len(mesh.Texcoords), len(mesh.Tangents), len(mesh.Indices))
+		}
+		for _, i := range mesh.Indices {
+			if int(i) >= n {
+				t.Fatalf("index %d out of range", i)
+			}
+		}
+		covered := 0
+		for _, p := range m.Parts {
+			if p.First != covered || p.Count <= 0 {
+				t.Fatalf("parts %+v don't follow each other", m.Parts)
+			}
+			covered += p.Count
+		}
+		if covered != len(mesh.Indices) {
+			t.Fatalf("parts cover %d of %d indices", covered, len(mesh.Indices))
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package obj
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+)
+
+// triangulate splits the polygon with the corners points into triangles
+// with the same winding and returns the corner indices of each. Convex
+// polygons are cut into a fan. Concave ones are clipped ear by ear in the
+// plane they roughly lie in; if that fails, as it may for polygons that
+// cross themselves, the rest is cut into a fan.
+func triangulate(points []glm.Vec3) [][3]int {
+	n := len(points)
+	if n == 3 {
+		return [][3]int{{0, 1, 2}}
+	}
+
+	// Newell's method gives the normal of a polygon that isn't quite flat
+	var normal glm.Vec3
+	for i, p := range points {
+		q := points[(i+1)%n]
+		normal[0] += (p[1] - q[1]) * (p[2] + q[2])
+		normal[1] += (p[2] - q[2]) * (p[0] + q[0])
+		normal[2] += (p[0] - q[0]) * (p[1] + q[1])
+	}
+	u := perpendicular(normal)
+	v := normal.Cross(u)
+
+	c := &clipper{
+		flat: make([]glm.Vec2, n),
+		prev: make([]int, n),
+		next: make([]int, n),
+		ear:  make([]bool, n),
+		left: n,
+	}
+	for i, p := range points {
+		c.flat[i] = glm.Vec2{p.Dot(u), p.Dot(v)}
+		c.prev[i], c.next[i] = (i+n-1)%n, (i+1)%n
+	}
+	for i := range points {
+		if !c.convex(i) {
+			c.reflex = append(c.reflex, i)
+		}
+	}
+
+	tris := make([][3]int, 0, n-2)
+	if len(c.reflex) > 0 {
+		tris = c.clip(tris)
+	}
+	// a convex polygon, or what is left of one that can't be clipped
+	first := c.start
+	for i := c.next[first]; c.next[i] != first; i = c.next[i] {
+		tris = append(tris, [3]int{first, i, c.next[i]})
+	}
+	return tris
+}
+
+// clipper clips ears off a polygon flattened into a plane. The corners
+// left form a ring through prev and next. Only reflex corners can lie
+// inside an ear, so they are kept in a list that shrinks as corners next
+// to a clipped ear turn convex, and the ear flags are only updated for
+// those neighbours.
+type clipper struct {
+	flat       []glm.Vec2
+	prev, next []int
+	ear        []bool
+	reflex     []int
+	start      int // a corner that is still left
+	left       int
+}
+
+// clip appends the ears it cuts off to tris until a triangle is left or
+// there are no more ears.
+func (c *clipper) clip(tris [][3]int) [][3]int {
+	for i := range c.ear {
+		c.ear[i] = c.isEar(i)
+	}
+	i, tried, rescanned := c.start, 0, false
+	for c.left > 3 {
+		if !c.ear[i] {
+			i = c.next[i]
+			tried++
+			if tried < c.left {
+				continue
+			}
+			// once around without an ear: the flags only turn true as
+			// the reflex list shrinks, so look at every corner again
+			// before giving up
+			if rescanned {
+				break
+			}
+			for j := i; ; {
+				c.ear[j] = c.isEar(j)
+				if j = c.next[j]; j == i {
+					break
+				}
+			}
+			tried, rescanned = 0, true
+			continue
+		}
+
+		p, q := c.prev[i], c.next[i]
+		tris = append(tris, [3]int{p, i, q})
+		c.next[p], c.prev[q] = q, p
+		c.left--
+		c.start = q
+		for _, k := range [2]int{p, q} {
+			if c.convex(k) {
+				c.removeReflex(k)
+			}
+		}
+		c.ear[p], c.ear[q] = c.isEar(p), c.isEar(q)
+		i, tried, rescanned = p, 0, false
+	}
+	return tris
+}
+
+// convex reports whether corner i turns the way the polygon winds.
+func (c *clipper) convex(i int) bool {
+	a, b, d := c.flat[c.prev[i]], c.flat[i], c.flat[c.next[i]]
+	return cross2(b.Sub(a), d.Sub(b)) > 0
+}
+
+func (c *clipper) removeReflex(i int) {
+	for k, j := range c.reflex {
+		if j == i {
+			last := len(c.reflex) - 1
+			c.reflex[k] = c.reflex[last]
+			c.reflex = c.reflex[:last]
+			return
+		}
+	}
+}
+
+// isEar reports whether corner i is convex and its triangle with the
+// neighbouring corners contains no reflex corner.
+func (c *clipper) isEar(i int) bool {
+	if !c.convex(i) {
+		return false
+	}
+	p, q := c.prev[i], c.next[i]
+	a, b, d := c.flat[p], c.flat[i], c.flat[q]
+	for _, j := range c.reflex {
+		if j == p || j == i || j == q {
+			continue
+		}
+		x := c.flat[j]
+		if cross2(b.Sub(a), x.Sub(a)) >= 0 && cross2(d.Sub(b), x.Sub(b)) >= 0 && cross2(a.Sub(d), x.Sub(d)) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func cross2(a, b glm.Vec2) float32 {
+	return a[0]*b[1] - a[1]*b[0]
+}
+
+// perpendicular returns a unit vector perpendicular to n, or the X axis
+// if n is zero.
+func perpendicular(n glm.Vec3) glm.Vec3 {
+	nn := n.Dot(n)
+	if nn == 0 {
+		return glm.Vec3{1, 0, 0}
+	}
+	axis := glm.Vec3{1, 0, 0}
+	if n[0]*n[0] > 0.8*nn {
+		axis = glm.Vec3{0, 1, 0}
+	}
+	return axis.Sub(n.Mul(n.Dot(axis) / nn)).Normalize()
+}
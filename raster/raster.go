@@ -0,0 +1,460 @@
+// Package raster is a small CPU implementation of the parts of the OpenGL
+// pipeline the exercises use: interleaved vertex buffers, indexed and
+// non-indexed triangles, perspective-correct interpolation of varyings,
+// 2D textures, and the depth and stencil tests.
+//
+// Shaders are plain Go functions. Uniforms are whatever the closures
+// capture.
+package raster
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"math"
+)
+
+// VertexShader reads the attributes of one vertex from in, writes its
+// varyings to out and returns the clip-space position.
+type VertexShader func(in []float32, out []float32) glm.Vec4
+
+// FragmentShader returns the color of a fragment from the interpolated
+// varyings in.
+type FragmentShader func(in []float32) glm.Vec4
+
+// CompareFunc is a depth or stencil comparison, mirroring gl.LESS and
+// friends.
+type CompareFunc int
+
+const (
+	Never CompareFunc = iota
+	Less
+	Equal
+	LEqual
+	Greater
+	NotEqual
+	GEqual
+	Always
+)
+
+func (f CompareFunc) compare(a, b float32) bool {
+	switch f {
+	case Less:
+		return a < b
+	case Equal:
+		return a == b
+	case LEqual:
+		return a <= b
+	case Greater:
+		return a > b
+	case NotEqual:
+		return a != b
+	case GEqual:
+		return a >= b
+	case Always:
+		return true
+	}
+	return false
+}
+
+// StencilOp is what happens to a stencil value, mirroring gl.KEEP and
+// friends.
+type StencilOp int
+
+const (
+	Keep StencilOp = iota
+	Zero
+	Replace
+	Incr
+	IncrWrap
+	Decr
+	DecrWrap
+	Invert
+)
+
+func (op StencilOp) apply(value, ref uint8) uint8 {
+	switch op {
+	case Zero:
+		return 0
+	case Replace:
+		return ref
+	case Incr:
+		if value < 0xFF {
+			return value + 1
+		}
+		return value
+	case IncrWrap:
+		return value + 1
+	case Decr:
+		if value > 0 {
+			return value - 1
+		}
+		return value
+	case DecrWrap:
+		return value - 1
+	case Invert:
+		return ^value
+	}
+	return value
+}
+
+// StencilState corresponds to glStencilFunc, glStencilOp and
+// glStencilMask.
+type StencilState struct {
+	Func      CompareFunc
+	Ref       uint8
+	ValueMask uint8
+
+	Fail  StencilOp // stencil test failed
+	ZFail StencilOp // stencil test passed, depth test failed
+	ZPass StencilOp // both tests passed
+
+	WriteMask uint8
+}
+
+// DefaultStencil is the initial GL stencil state.
+var DefaultStencil = StencilState{
+	Func:      Always,
+	ValueMask: 0xFF,
+	WriteMask: 0xFF,
+}
+
+// Pipeline holds the shaders and fixed-function state for a draw call.
+type Pipeline struct {
+	// Stride is the number of floats per vertex in the vertex buffer.
+	Stride int
+
+	// Varyings is the number of floats passed from the vertex to the
+	// fragment shader.
+	Varyings int
+
+	Vertex   VertexShader
+	Fragment FragmentShader
+
+	DepthTest bool
+	DepthFunc CompareFunc
+	DepthMask bool
+
+	StencilTest bool
+	Stencil     StencilState
+}
+
+// NewPipeline returns a pipeline with the GL default state: depth and
+// stencil tests disabled, depth func LESS and depth writes enabled.
+func NewPipeline(stride, varyings int, vs VertexShader, fs FragmentShader) *Pipeline {
+	return &Pipeline{
+		Stride:    stride,
+		Varyings:  varyings,
+		Vertex:    vs,
+		Fragment:  fs,
+		DepthFunc: Less,
+		DepthMask: true,
+		Stencil:   DefaultStencil,
+	}
+}
+
+// Framebuffer holds color, depth and stencil values. Like in OpenGL the
+// first row is the bottom of the picture.
+type Framebuffer struct {
+	Width, Height int
+	Color         []glm.Vec4
+	Depth         []float32
+	Stencil       []uint8
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	n := width * height
+	fb := &Framebuffer{
+		Width:   width,
+		Height:  height,
+		Color:   make([]glm.Vec4, n),
+		Depth:   make([]float32, n),
+		Stencil: make([]uint8, n),
+	}
+	fb.ClearDepth(1)
+	return fb
+}
+
+func (fb *Framebuffer) ClearColor(c glm.Vec4) {
+	for i := range fb.Color {
+		fb.Color[i] = c
+	}
+}
+
+func (fb *Framebuffer) ClearDepth(d float32) {
+	for i := range fb.Depth {
+		fb.Depth[i] = d
+	}
+}
+
+func (fb *Framebuffer) ClearStencil(s uint8) {
+	for i := range fb.Stencil {
+		fb.Stencil[i] = s
+	}
+}
+
+// Image converts the color buffer to an image with the top row first, the
+// same way the headless GL path reads frames back.
+func (fb *Framebuffer) Image() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, fb.Width, fb.Height))
+	for y := 0; y < fb.Height; y++ {
+		row := img.Pix[(fb.Height-1-y)*img.Stride:]
+		for x := 0; x < fb.Width; x++ {
+			c := fb.Color[y*fb.Width+x]
+			for i := 0; i < 4; i++ {
+				row[x*4+i] = unorm8(c[i])
+			}
+		}
+	}
+	return img
+}
+
+// unorm8 converts a color channel the way GL stores it in an 8-bit
+// buffer.
+func unorm8(v float32) uint8 {
+	if v <= 0 || v != v {
+		return 0
+	}
+	if v >= 1 {
+		return 0xFF
+	}
+	return uint8(math.Floor(float64(v)*255 + 0.5))
+}
+
+// DrawArrays draws count vertices starting at first as a triangle list.
+func (fb *Framebuffer) DrawArrays(p *Pipeline, vertices []float32, first, count int) {
+	indices := make([]uint32, count)
+	for i := range indices {
+		indices[i] = uint32(first + i)
+	}
+	fb.DrawElements(p, vertices, indices)
+}
+
+// DrawElements draws the triangle list described by indices.
+func (fb *Framebuffer) DrawElements(p *Pipeline, vertices []float32, indices []uint32) {
+	// run the vertex shader once per referenced vertex
+	cache := make(map[uint32]clipVertex)
+	shade := func(index uint32) clipVertex {
+		if v, ok := cache[index]; ok {
+			return v
+		}
+		start := int(index) * p.Stride
+		v := clipVertex{varyings: make([]float32, p.Varyings)}
+		v.pos = p.Vertex(vertices[start:start+p.Stride], v.varyings)
+		cache[index] = v
+		return v
+	}
+
+	for i := 0; i+2 < len(indices); i += 3 {
+		tri := [3]clipVertex{shade(indices[i]), shade(indices[i+1]), shade(indices[i+2])}
+		poly := clipNear(tri[:])
+		// fan triangulate the clipped polygon
+		for j := 1; j+1 < len(poly); j++ {
+			fb.rasterize(p, poly[0], poly[j], poly[j+1])
+		}
+	}
+}
+
+type clipVertex struct {
+	pos      glm.Vec4
+	varyings []float32
+}
+
+// clipNear clips a triangle against the near plane z = -w. The other
+// planes are handled by the scissoring in rasterize.
+func clipNear(in []clipVertex) []clipVertex {
+	dist := func(v clipVertex) float32 { return v.pos[2] + v.pos[3] }
+
+	inside := 0
+	for _, v := range in {
+		if dist(v) >= 0 {
+			inside++
+		}
+	}
+	if inside == len(in) {
+		return in
+	}
+	if inside == 0 {
+		return nil
+	}
+
+	var out []clipVertex
+	for i, a := range in {
+		b := in[(i+1)%len(in)]
+		da, db := dist(a), dist(b)
+		if da >= 0 {
+			out = append(out, a)
+		}
+		if (da >= 0) != (db >= 0) {
+			out = append(out, lerpVertex(a, b, da/(da-db)))
+		}
+	}
+	return out
+}
+
+func lerpVertex(a, b clipVertex, t float32) clipVertex {
+	v := clipVertex{
+		pos:      a.pos.Add(b.pos.Sub(a.pos).Mul(t)),
+		varyings: make([]float32, len(a.varyings)),
+	}
+	for i := range v.varyings {
+		v.varyings[i] = a.varyings[i] + (b.varyings[i]-a.varyings[i])*t
+	}
+	return v
+}
+
+// screenVertex is a vertex after the perspective divide and viewport
+// transform. Varyings are premultiplied by invW for perspective-correct
+// interpolation.
+type screenVertex struct {
+	x, y, z  float32
+	invW     float32
+	varyings []float32
+}
+
+func (fb *Framebuffer) toScreen(v clipVertex) screenVertex {
+	invW := 1 / v.pos[3]
+	s := screenVertex{
+		x:        (v.pos[0]*invW + 1) * 0.5 * float32(fb.Width),
+		y:        (v.pos[1]*invW + 1) * 0.5 * float32(fb.Height),
+		z:        (v.pos[2]*invW + 1) * 0.5,
+		invW:     invW,
+		varyings: make([]float32, len(v.varyings)),
+	}
+	for i, f := range v.varyings {
+		s.varyings[i] = f * invW
+	}
+	return s
+}
+
+func edge(ax, ay, bx, by, px, py float32) float32 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// isTopLeft implements the fill convention that decides which triangle
+// owns pixels exactly on a shared edge.
+func isTopLeft(ax, ay, bx, by float32) bool {
+	return (ay == by && bx < ax) || by < ay
+}
+
+func (fb *Framebuffer) rasterize(p *Pipeline, c0, c1, c2 clipVertex) {
+	v0, v1, v2 := fb.toScreen(c0), fb.toScreen(c1), fb.toScreen(c2)
+
+	area := edge(v0.x, v0.y, v1.x, v1.y, v2.x, v2.y)
+	if area == 0 || area != area {
+		return
+	}
+	// there is no face culling, so make the winding counter-clockwise
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+
+	minX := int(math.Floor(float64(min3(v0.x, v1.x, v2.x))))
+	maxX := int(math.Ceil(float64(max3(v0.x, v1.x, v2.x))))
+	minY := int(math.Floor(float64(min3(v0.y, v1.y, v2.y))))
+	maxY := int(math.Ceil(float64(max3(v0.y, v1.y, v2.y))))
+	if minX < 0 {
+		minX = 0
+	}
+	if minY < 0 {
+		minY = 0
+	}
+	if maxX > fb.Width-1 {
+		maxX = fb.Width - 1
+	}
+	if maxY > fb.Height-1 {
+		maxY = fb.Height - 1
+	}
+
+	tl0 := isTopLeft(v1.x, v1.y, v2.x, v2.y)
+	tl1 := isTopLeft(v2.x, v2.y, v0.x, v0.y)
+	tl2 := isTopLeft(v0.x, v0.y, v1.x, v1.y)
+	covered := func(w float32, topLeft bool) bool {
+		return w > 0 || (w == 0 && topLeft)
+	}
+
+	varyings := make([]float32, p.Varyings)
+	for y := minY; y <= maxY; y++ {
+		py := float32(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float32(x) + 0.5
+			w0 := edge(v1.x, v1.y, v2.x, v2.y, px, py)
+			w1 := edge(v2.x, v2.y, v0.x, v0.y, px, py)
+			w2 := edge(v0.x, v0.y, v1.x, v1.y, px, py)
+			if !covered(w0, tl0) || !covered(w1, tl1) || !covered(w2, tl2) {
+				continue
+			}
+			b0, b1, b2 := w0/area, w1/area, w2/area
+
+			z := b0*v0.z + b1*v1.z + b2*v2.z
+			if z < 0 || z > 1 {
+				continue
+			}
+			fb.fragment(p, y*fb.Width+x, z, func() []float32 {
+				invW := b0*v0.invW + b1*v1.invW + b2*v2.invW
+				for i := range varyings {
+					varyings[i] = (b0*v0.varyings[i] + b1*v1.varyings[i] + b2*v2.varyings[i]) / invW
+				}
+				return varyings
+			})
+		}
+	}
+}
+
+// fragment runs the per-fragment tests and, if they pass, the fragment
+// shader for the pixel at index i.
+func (fb *Framebuffer) fragment(p *Pipeline, i int, z float32, varyings func() []float32) {
+	st := &p.Stencil
+	if p.StencilTest {
+		stored := fb.Stencil[i]
+		if !st.Func.compare(float32(st.Ref&st.ValueMask), float32(stored&st.ValueMask)) {
+			fb.writeStencil(st, i, st.Fail)
+			return
+		}
+	}
+
+	if p.DepthTest {
+		if !p.DepthFunc.compare(z, fb.Depth[i]) {
+			if p.StencilTest {
+				fb.writeStencil(st, i, st.ZFail)
+			}
+			return
+		}
+		if p.DepthMask {
+			fb.Depth[i] = z
+		}
+	}
+
+	if p.StencilTest {
+		fb.writeStencil(st, i, st.ZPass)
+	}
+
+	c := p.Fragment(varyings())
+	for j := range c {
+		c[j] = clamp01(c[j])
+	}
+	fb.Color[i] = c
+}
+
+func (fb *Framebuffer) writeStencil(st *StencilState, i int, op StencilOp) {
+	old := fb.Stencil[i]
+	fb.Stencil[i] = (old &^ st.WriteMask) | (op.apply(old, st.Ref) & st.WriteMask)
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 || v != v {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func min3(a, b, c float32) float32 {
+	return float32(math.Min(float64(a), math.Min(float64(b), float64(c))))
+}
+
+func max3(a, b, c float32) float32 {
+	return float32(math.Max(float64(a), math.Max(float64(b), float64(c))))
+}
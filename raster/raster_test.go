@@ -0,0 +1,154 @@
+package raster
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// quad covers the whole viewport with two triangles sharing a diagonal.
+var quad = []float32{
+	-1, -1, 1, -1, 1, 1,
+	1, 1, -1, 1, -1, -1,
+}
+
+func position2D(in, out []float32) glm.Vec4 {
+	return glm.Vec4{in[0], in[1], 0, 1}
+}
+
+func TestSharedEdgeCoveredOnce(t *testing.T) {
+	fb := NewFramebuffer(16, 16)
+	p := NewPipeline(2, 0, position2D, func(in []float32) glm.Vec4 { return glm.Vec4{1, 1, 1, 1} })
+	p.StencilTest = true
+	p.Stencil.ZPass = IncrWrap
+
+	fb.DrawArrays(p, quad, 0, 6)
+	for i, n := range fb.Stencil {
+		if n != 1 {
+			t.Fatalf("pixel (%d, %d) drawn %d times", i%16, i/16, n)
+		}
+	}
+}
+
+func TestColorInterpolation(t *testing.T) {
+	fb := NewFramebuffer(64, 64)
+	vertices := []float32{
+		-1, -1, 1, 0,
+		3, -1, 0, 1,
+		-1, 3, 0, 1,
+	}
+	p := NewPipeline(4, 2, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		return glm.Vec4{in[0], in[1], 0, 1}
+	}, func(in []float32) glm.Vec4 { return glm.Vec4{in[0], in[1], 0, 1} })
+
+	fb.DrawArrays(p, vertices, 0, 3)
+	img := fb.Image()
+
+	// the bottom left pixel is close to the red vertex
+	if c := img.NRGBAAt(0, 63); c.R < 250 || c.G > 5 {
+		t.Errorf("bottom left = %v, want red", c)
+	}
+	// the center is a quarter of the way to the other two vertices
+	if c := img.NRGBAAt(32, 32); c.R < 125 || c.R > 130 || c.G < 125 || c.G > 130 {
+		t.Errorf("center = %v, want half red, half green", c)
+	}
+}
+
+func TestDepthTest(t *testing.T) {
+	fb := NewFramebuffer(4, 4)
+	var color glm.Vec4
+	p := NewPipeline(2, 0, func(in, out []float32) glm.Vec4 {
+		return glm.Vec4{in[0], in[1], color[3], 1}
+	}, func(in []float32) glm.Vec4 { return glm.Vec4{color[0], color[1], color[2], 1} })
+	p.DepthTest = true
+
+	// near red first, then far blue: red must stay
+	color = glm.Vec4{1, 0, 0, -0.5}
+	fb.DrawArrays(p, quad, 0, 6)
+	color = glm.Vec4{0, 0, 1, 0.5}
+	fb.DrawArrays(p, quad, 0, 6)
+
+	if got := fb.Color[0]; got != (glm.Vec4{1, 0, 0, 1}) {
+		t.Errorf("color = %v, want red", got)
+	}
+	if got := fb.Depth[0]; got != 0.25 {
+		t.Errorf("depth = %v, want 0.25", got)
+	}
+}
+
+func TestStencilMask(t *testing.T) {
+	fb := NewFramebuffer(4, 4)
+	left := []float32{
+		-1, -1, 0, -1, 0, 1,
+		0, 1, -1, 1, -1, -1,
+	}
+	p := NewPipeline(2, 0, position2D, func(in []float32) glm.Vec4 { return glm.Vec4{1, 1, 1, 1} })
+	p.StencilTest = true
+
+	// write 1 into the stencil buffer on the left half only
+	p.Stencil.Ref = 1
+	p.Stencil.ZPass = Replace
+	fb.DrawArrays(p, left, 0, 6)
+	fb.ClearColor(glm.Vec4{0, 0, 0, 1})
+
+	p.Stencil.Func = Equal
+	p.Stencil.WriteMask = 0
+	fb.DrawArrays(p, quad, 0, 6)
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := float32(0)
+			if x < 2 {
+				want = 1
+			}
+			if got := fb.Color[y*4+x][0]; got != want {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestNearPlaneClipping(t *testing.T) {
+	fb := NewFramebuffer(8, 8)
+	// the third vertex lies behind the camera
+	vertices := []float32{
+		-1, -1, 0, 1,
+		1, -1, 0, 1,
+		0, 3, -3, -1,
+	}
+	p := NewPipeline(4, 0, func(in, out []float32) glm.Vec4 {
+		return glm.Vec4{in[0], in[1], in[2], in[3]}
+	}, func(in []float32) glm.Vec4 { return glm.Vec4{1, 1, 1, 1} })
+
+	fb.DrawArrays(p, vertices, 0, 3)
+
+	drawn := 0
+	for _, c := range fb.Color {
+		if c[0] == 1 {
+			drawn++
+		}
+	}
+	if drawn == 0 || drawn == len(fb.Color) {
+		t.Errorf("%d of %d pixels drawn, want the part in front of the camera", drawn, len(fb.Color))
+	}
+}
+
+func TestTextureSampling(t *testing.T) {
+	tex := &Texture{
+		Width:  2,
+		Height: 1,
+		Texels: []glm.Vec4{{0, 0, 0, 1}, {1, 1, 1, 1}},
+	}
+
+	// texel centers return the texel, halfway between them blends
+	if got := tex.Sample(glm.Vec2{0.25, 0.5}); got[0] != 0 {
+		t.Errorf("left texel = %v", got)
+	}
+	if got := tex.Sample(glm.Vec2{0.5, 0.5}); got[0] != 0.5 {
+		t.Errorf("middle = %v, want 0.5", got)
+	}
+	// GL_REPEAT wraps around the right edge
+	if got := tex.Sample(glm.Vec2{1.0, 0.5}); got[0] != 0.5 {
+		t.Errorf("right edge = %v, want 0.5", got)
+	}
+}
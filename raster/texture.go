@@ -0,0 +1,72 @@
+package raster
+
+import (
+	glm "github.com/go-gl/mathgl/mgl32"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Texture is a 2D texture sampled with GL_LINEAR filtering and GL_REPEAT
+// wrapping, the state createTexture leaves a texture in.
+type Texture struct {
+	Width, Height int
+	// Texels are stored bottom row first, like after glTexImage2D on a
+	// flipped image.
+	Texels []glm.Vec4
+}
+
+// NewTexture converts img to a texture. The image is flipped so that
+// texture coordinate (0, 0) is its lower left corner.
+func NewTexture(img image.Image) *Texture {
+	b := img.Bounds()
+	t := &Texture{
+		Width:  b.Dx(),
+		Height: b.Dy(),
+		Texels: make([]glm.Vec4, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < t.Height; y++ {
+		for x := 0; x < t.Width; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			t.Texels[(t.Height-1-y)*t.Width+x] = glm.Vec4{
+				float32(c.R) / 255,
+				float32(c.G) / 255,
+				float32(c.B) / 255,
+				float32(c.A) / 255,
+			}
+		}
+	}
+	return t
+}
+
+func (t *Texture) texel(x, y int) glm.Vec4 {
+	x %= t.Width
+	if x < 0 {
+		x += t.Width
+	}
+	y %= t.Height
+	if y < 0 {
+		y += t.Height
+	}
+	return t.Texels[y*t.Width+x]
+}
+
+// Sample returns the bilinearly filtered color at texture coordinate uv.
+func (t *Texture) Sample(uv glm.Vec2) glm.Vec4 {
+	u := float64(uv[0])*float64(t.Width) - 0.5
+	v := float64(uv[1])*float64(t.Height) - 0.5
+	x0, y0 := math.Floor(u), math.Floor(v)
+	fx, fy := float32(u-x0), float32(v-y0)
+	x, y := int(x0), int(y0)
+
+	c00, c10 := t.texel(x, y), t.texel(x+1, y)
+	c01, c11 := t.texel(x, y+1), t.texel(x+1, y+1)
+	bottom := c00.Mul(1 - fx).Add(c10.Mul(fx))
+	top := c01.Mul(1 - fx).Add(c11.Mul(fx))
+	return bottom.Mul(1 - fy).Add(top.Mul(fy))
+}
+
+// Mix is GLSL's mix for vectors.
+func Mix(a, b glm.Vec4, t float32) glm.Vec4 {
+	return a.Mul(1 - t).Add(b.Mul(t))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl"
+	"os"
+	"strings"
+	"time"
+)
+
+// shaderProgram is a program loaded with loadProgram together with what it
+// takes to build it again. While it is live, reloadPrograms rebuilds it
+// when one of the files it was read from changes.
+type shaderProgram struct {
+	gl.Program
+	name    string
+	defines map[string]string
+	files   map[string]time.Time
+
+	// bind is called with the new program current after a reload. Scenes
+	// set it to the function that looked up their uniform locations, so
+	// those are looked up again and constant uniforms set again.
+	bind func()
+}
+
+// livePrograms are the programs reloadPrograms watches.
+var livePrograms []*shaderProgram
+
+// Delete deletes the program and stops watching its files.
+func (p *shaderProgram) Delete() {
+	for i, q := range livePrograms {
+		if q == p {
+			livePrograms = append(livePrograms[:i], livePrograms[i+1:]...)
+			break
+		}
+	}
+	p.Program.Delete()
+}
+
+// changed reports whether any of the files p was built from was modified,
+// removed or replaced since.
+func (p *shaderProgram) changed() bool {
+	for path, modTime := range p.files {
+		var current time.Time
+		if info, err := os.Stat(path); err == nil {
+			current = info.ModTime()
+		}
+		if !current.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload builds p again from its files and swaps the result in. Attributes
+// are bound to the locations they had, so vertex arrays set up for the old
+// program keep working. If building or binding fails, p is left as it was
+// but starts watching the files of the failed attempt.
+func (p *shaderProgram) reload() error {
+	program, files, err := buildProgram(p.name, p.defines, activeAttribs(p.Program))
+	if err != nil {
+		if files == nil {
+			files = make([]string, 0, len(p.files))
+			for path := range p.files {
+				files = append(files, path)
+			}
+		}
+		p.files = modTimes(files)
+		return err
+	}
+
+	old := p.Program
+	p.Program = program
+	p.files = modTimes(files)
+	if err := p.rebind(); err != nil {
+		// the scene can't use the new program; go back to the old one
+		program.Delete()
+		p.Program = old
+		old.Use()
+		p.rebind()
+		return err
+	}
+	old.Delete()
+	checkError(p.name + " reload")
+	return nil
+}
+
+// rebind calls p.bind. Scenes panic when they can't set up their
+// uniforms, e.g. because a uniform struct no longer matches the shaders;
+// after a reload that is turned into an error.
+func (p *shaderProgram) rebind() (err error) {
+	if p.bind == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	p.bind()
+	return nil
+}
+
+// activeAttribs returns the locations of the vertex attributes program
+// uses.
+func activeAttribs(program gl.Program) map[string]gl.AttribLocation {
+	attribs := make(map[string]gl.AttribLocation)
+	for i := 0; i < program.Get(gl.ACTIVE_ATTRIBUTES); i++ {
+		_, _, name := program.GetActiveAttrib(i)
+		if strings.HasPrefix(name, "gl_") {
+			continue
+		}
+		attribs[name] = program.GetAttribLocation(name)
+	}
+	return attribs
+}
+
+// modTimes stats paths. Files that can't be read get the zero time, so
+// they count as changed once they appear.
+func modTimes(paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		times[path] = modTime
+	}
+	return times
+}
+
+// reloadInterval is how often reloadPrograms looks at the shader files.
+const reloadInterval = 500 * time.Millisecond
+
+var lastReloadCheck time.Time
+
+// reloadPrograms rebuilds the live programs whose files changed. A program
+// that fails to compile or link keeps running in its last good version and
+// the log is printed instead; it is tried again on the next change. The
+// current program, vertex array and texture unit are left as they were.
+func reloadPrograms() {
+	if time.Since(lastReloadCheck) < reloadInterval {
+		return
+	}
+	lastReloadCheck = time.Now()
+
+	saved := saveBindings()
+	for _, p := range livePrograms {
+		if !p.changed() {
+			continue
+		}
+		old := p.Program
+		if err := p.reload(); err != nil {
+			fmt.Printf("%s: shader reload failed, keeping the last good program:\n%v\n", p.name, err)
+			continue
+		}
+		fmt.Printf("%s: shaders reloaded\n", p.name)
+		if saved.program == old {
+			saved.program = p.Program
+		}
+	}
+	saved.restore()
+}
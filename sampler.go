@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"github.com/go-gl/gl"
+)
+
+// EXT_texture_filter_anisotropic, which the gl package has no names for
+const (
+	textureMaxAnisotropy    = 0x84FE
+	maxTextureMaxAnisotropy = 0x84FF
+)
+
+// SamplerOptions describes how a texture is sampled.
+type SamplerOptions struct {
+	WrapS, WrapT, WrapR gl.GLenum
+	BorderColor         [4]float32 // used with CLAMP_TO_BORDER
+
+	MinFilter, MagFilter gl.GLenum
+
+	// Mipmaps generates the mipmap chain. It is implied by a mipmapped
+	// MinFilter.
+	Mipmaps bool
+	LODBias float32
+
+	// MaxAnisotropy is clamped to what the driver supports and ignored
+	// without EXT_texture_filter_anisotropic. Values below 1 disable it.
+	MaxAnisotropy float32
+}
+
+// DefaultSampler is what textures were always created with: linear
+// filtering, repeating, no mipmaps.
+var DefaultSampler = SamplerOptions{
+	WrapS:     gl.REPEAT,
+	WrapT:     gl.REPEAT,
+	WrapR:     gl.REPEAT,
+	MinFilter: gl.LINEAR,
+	MagFilter: gl.LINEAR,
+}
+
+// MipmapSampler filters textures drawn smaller than they are, or at an
+// angle, from trilinear mipmaps with 16x anisotropy where the driver has
+// it. The exercises that transform and project their quads use it.
+var MipmapSampler = SamplerOptions{
+	WrapS:         gl.REPEAT,
+	WrapT:         gl.REPEAT,
+	WrapR:         gl.REPEAT,
+	MinFilter:     gl.LINEAR_MIPMAP_LINEAR,
+	MagFilter:     gl.LINEAR,
+	MaxAnisotropy: 16,
+}
+
+func (o SamplerOptions) usesMipmaps() bool {
+	switch o.MinFilter {
+	case gl.NEAREST_MIPMAP_NEAREST, gl.LINEAR_MIPMAP_NEAREST, gl.NEAREST_MIPMAP_LINEAR, gl.LINEAR_MIPMAP_LINEAR:
+		return true
+	}
+	return o.Mipmaps
+}
+
+// samplerPreset is a named SamplerOptions the texture exercises can cycle
+// through.
+type samplerPreset struct {
+	name    string
+	options SamplerOptions
+}
+
+var samplerPresets = []samplerPreset{
+	{"linear", DefaultSampler},
+	{"nearest", SamplerOptions{
+		WrapS: gl.REPEAT, WrapT: gl.REPEAT, WrapR: gl.REPEAT,
+		MinFilter: gl.NEAREST, MagFilter: gl.NEAREST,
+	}},
+	{"trilinear", SamplerOptions{
+		WrapS: gl.REPEAT, WrapT: gl.REPEAT, WrapR: gl.REPEAT,
+		MinFilter: gl.LINEAR_MIPMAP_LINEAR, MagFilter: gl.LINEAR,
+	}},
+	{"anisotropic 16x", MipmapSampler},
+	{"clamp to edge", SamplerOptions{
+		WrapS: gl.CLAMP_TO_EDGE, WrapT: gl.CLAMP_TO_EDGE, WrapR: gl.CLAMP_TO_EDGE,
+		MinFilter: gl.LINEAR, MagFilter: gl.LINEAR,
+	}},
+	{"clamp to border", SamplerOptions{
+		WrapS: gl.CLAMP_TO_BORDER, WrapT: gl.CLAMP_TO_BORDER, WrapR: gl.CLAMP_TO_BORDER,
+		BorderColor: [4]float32{1.0, 0.0, 1.0, 1.0},
+		MinFilter:   gl.LINEAR, MagFilter: gl.LINEAR,
+	}},
+	{"mirrored repeat", SamplerOptions{
+		WrapS: gl.MIRRORED_REPEAT, WrapT: gl.MIRRORED_REPEAT, WrapR: gl.MIRRORED_REPEAT,
+		MinFilter: gl.LINEAR, MagFilter: gl.LINEAR,
+	}},
+	{"blurry (LOD bias +2)", SamplerOptions{
+		WrapS: gl.REPEAT, WrapT: gl.REPEAT, WrapR: gl.REPEAT,
+		MinFilter: gl.LINEAR_MIPMAP_LINEAR, MagFilter: gl.LINEAR,
+		LODBias: 2,
+	}},
+}
+
+// maxAnisotropy returns the largest anisotropy the driver supports, or 0
+// without EXT_texture_filter_anisotropic.
+func maxAnisotropy() float32 {
+	checkError("before anisotropy query")
+	limit := make([]float32, 1)
+	gl.GetFloatv(maxTextureMaxAnisotropy, limit)
+	if gl.GetError() != gl.NO_ERROR {
+		return 0
+	}
+	return limit[0]
+}
+
+// applySampler sets opts on the texture bound to target on the active
+// texture unit, generating mipmaps if they are needed.
+func applySampler(target gl.GLenum, opts SamplerOptions) {
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, int(opts.WrapS))
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, int(opts.WrapT))
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_R, int(opts.WrapR))
+	gl.TexParameterfv(target, gl.TEXTURE_BORDER_COLOR, opts.BorderColor[:])
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, int(opts.MinFilter))
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, int(opts.MagFilter))
+	gl.TexParameterf(target, gl.TEXTURE_LOD_BIAS, opts.LODBias)
+
+	if opts.usesMipmaps() {
+		gl.GenerateMipmap(target)
+	}
+
+	if limit := maxAnisotropy(); limit > 0 {
+		anisotropy := opts.MaxAnisotropy
+		if anisotropy < 1 {
+			anisotropy = 1
+		}
+		if anisotropy > limit {
+			anisotropy = limit
+		}
+		gl.TexParameterf(target, textureMaxAnisotropy, anisotropy)
+	}
+	checkError("sampler")
+}
+
+// samplerCycler switches a scene's textures through samplerPresets when
+// the sampler action, T, is pressed. Texture i is expected on texture unit
+// i, the way the exercises set them up.
+type samplerCycler struct {
+	sampled []gl.Texture
+	preset  int
+}
+
+func (c *samplerCycler) HandleActions(actions *input.Actions) {
+	if !actions.Pressed("sampler") || len(c.sampled) == 0 {
+		return
+	}
+
+	c.preset = (c.preset + 1) % len(samplerPresets)
+	preset := samplerPresets[c.preset]
+	for i, texture := range c.sampled {
+		gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(i))
+		texture.Bind(gl.TEXTURE_2D)
+		applySampler(gl.TEXTURE_2D, preset.options)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	fmt.Printf("sampler: %s\n", preset.name)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"github.com/Happy-Ferret/gl-tutorial/loop"
+	"sort"
+	"time"
+)
+
+// Scene is a single exercise. Init is called once the GL context is
+// current and Delete before the window is destroyed. In between, a scene
+// draws its frames as a Drawer or a Simulation.
+type Scene interface {
+	Init()
+	Delete()
+}
+
+// Drawer is a scene that draws a frame at a time. The animated exercises
+// are Simulations, so the run command refuses --fixed-step for Drawers.
+type Drawer interface {
+	Draw(clock Clock)
+}
+
+// Simulation is a scene that moves its state on in fixed steps of
+// simulationStep, however fast frames are drawn. Before every frame, the
+// launcher calls Update once for every step the clock moved on by, then
+// Render with how far the frame is between the state before the last
+// update, 0, and after it, 1, to interpolate them.
+type Simulation interface {
+	Update(dt time.Duration)
+	Render(alpha float32)
+}
+
+// ActionReceiver is implemented by scenes that react to input actions
+// other than those the launcher handles, like quit or pause. It is called
+// once a frame, before Draw, with the actions of the frame.
+type ActionReceiver interface {
+	HandleActions(actions *input.Actions)
+}
+
+// WindowConfig describes the window a scene wants to be created with. The
+// size is in screen coordinates, which on HiDPI displays are larger than
+// pixels. A fullscreen window without a size takes the current video mode
+// of the monitor.
+type WindowConfig struct {
+	Width      int
+	Height     int
+	Fullscreen bool
+}
+
+// WindowConfigurer is implemented by scenes that need a window other than
+// the default 800x600 one.
+type WindowConfigurer interface {
+	WindowConfig() WindowConfig
+}
+
+var defaultWindowConfig = WindowConfig{Width: 800, Height: 600}
+
+var scenes = make(map[string]func() Scene)
+
+// registerScene makes an exercise available to the launcher under name.
+// It is meant to be called from the init function of each exercise file.
+func registerScene(name string, newScene func() Scene) {
+	if _, ok := scenes[name]; ok {
+		panic(fmt.Sprintf("scene %q registered twice", name))
+	}
+	switch newScene().(type) {
+	case Drawer, Simulation:
+	default:
+		panic(fmt.Sprintf("scene %q is neither a Drawer nor a Simulation", name))
+	}
+	scenes[name] = newScene
+}
+
+// sceneNames returns the names of all registered scenes in sorted order.
+func sceneNames() []string {
+	names := make([]string, 0, len(scenes))
+	for name := range scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupScene creates a fresh instance of the named scene.
+func lookupScene(name string) (Scene, error) {
+	newScene, ok := scenes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exercise %q, see 'gltut list'", name)
+	}
+	return newScene(), nil
+}
+
+// drawFrame draws a frame of scene at the time of clock. A Simulation is
+// updated by sim first.
+func drawFrame(scene Scene, clock Clock, sim *loop.Loop) {
+	switch scene := scene.(type) {
+	case Simulation:
+		sim.Advance(clock.Delta(), scene.Update)
+		scene.Render(sim.Alpha())
+	case Drawer:
+		scene.Draw(clock)
+	}
+}
@@ -0,0 +1,280 @@
+package shader
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/glsl"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goProgram is what the Go code in one file says about a program it loads
+// with loadProgram: the defines it passes and the uniform and attribute
+// names it looks up. tagged holds the names of struct fields tagged glsl,
+// which may be either.
+type goProgram struct {
+	name     string
+	defines  map[string]string
+	uniforms map[string]token.Position
+	attribs  map[string]token.Position
+	tagged   map[string]token.Position
+}
+
+// Lint checks every pair of name.vert.glsl and name.frag.glsl in dir with
+// the glsl package and compares them with the names the Go code in goDir
+// uses, writing what it finds to w. It returns the number of errors;
+// warnings aren't counted. Neither needs a GL context.
+func Lint(dir, goDir string, w io.Writer) (int, error) {
+	programs, err := scanGoPrograms(goDir)
+	if err != nil {
+		return 0, err
+	}
+	vertexFiles, err := filepath.Glob(filepath.Join(dir, "*.vert.glsl"))
+	if err != nil {
+		return 0, err
+	}
+
+	errors := 0
+	for _, file := range vertexFiles {
+		name := strings.TrimSuffix(filepath.Base(file), ".vert.glsl")
+		refs := programs[name]
+		if refs == nil {
+			refs = newGoProgram(name)
+		}
+		n, err := lintProgram(dir, refs, w)
+		if err != nil {
+			return errors, err
+		}
+		errors += n
+	}
+	return errors, nil
+}
+
+// lintProgram checks the shaders of one program, see Lint.
+func lintProgram(dir string, refs *goProgram, w io.Writer) (int, error) {
+	var sources [2]*Source
+	var shaders [2]*glsl.Shader
+	var errs []glsl.Error
+	for i, ext := range []string{".vert.glsl", ".frag.glsl"} {
+		src, err := Load(os.DirFS(dir), refs.name+ext, refs.defines)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", dir, err)
+		}
+		for j, f := range src.Files {
+			src.Files[j] = filepath.Join(dir, f)
+		}
+		sh, shaderErrs := glsl.Check(glsl.Stage(i), src.Code)
+		sources[i], shaders[i] = src, sh
+		errs = append(errs, shaderErrs...)
+	}
+
+	vertex, fragment := shaders[glsl.Vertex], shaders[glsl.Fragment]
+	if len(errs) == 0 {
+		errs = append(errs, glsl.Link(vertex, fragment)...)
+		if fragment.Output("outColor") == nil {
+			errs = append(errs, glsl.Error{Stage: glsl.Fragment, Msg: "no output outColor, which loadProgram binds to the first draw buffer"})
+		}
+	}
+
+	errors := 0
+	for _, e := range errs {
+		src := sources[e.Stage]
+		se := ShaderError{Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg, Warning: e.Warning}
+		if e.Pos.Source < len(src.Files) {
+			se.File = src.Files[e.Pos.Source]
+		}
+		fmt.Fprint(w, src.Annotate([]ShaderError{se}))
+		if !e.Warning {
+			errors++
+		}
+	}
+	if errors > 0 {
+		// the Go names can't be compared with shaders that don't compile
+		return errors, nil
+	}
+
+	report := func(pos token.Position, warning bool, format string, args ...interface{}) {
+		e := ShaderError{File: pos.Filename, Line: pos.Line, Column: pos.Column, Message: fmt.Sprintf(format, args...), Warning: warning}
+		fmt.Fprintln(w, e)
+		if !warning {
+			errors++
+		}
+	}
+	for name, pos := range refs.tagged {
+		if vertex.Input(name) != nil {
+			refs.attribs[name] = pos
+		} else {
+			refs.uniforms[name] = pos
+		}
+	}
+	for _, name := range sortedNames(refs.uniforms) {
+		pos := refs.uniforms[name]
+		v, f := vertex.Uniform(name), fragment.Uniform(name)
+		switch {
+		case v == nil && f == nil:
+			report(pos, false, "uniform %s is not declared by the %s shaders", name, refs.name)
+		case (v == nil || !v.Read) && (f == nil || !f.Read):
+			report(pos, true, "uniform %s is never read by the %s shaders; its location will be -1", name, refs.name)
+		}
+	}
+	for _, name := range sortedNames(refs.attribs) {
+		pos := refs.attribs[name]
+		switch in := vertex.Input(name); {
+		case in == nil:
+			report(pos, false, "attribute %s is not an input of %s.vert.glsl", name, refs.name)
+		case !in.Read:
+			report(pos, true, "attribute %s is never read by %s.vert.glsl; its location will be -1", name, refs.name)
+		}
+	}
+	return errors, nil
+}
+
+func sortedNames(m map[string]token.Position) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// attribConstructors are the functions that name a vertex attribute of a
+// VertexFormat.
+var attribConstructors = map[string]bool{
+	"Float1": true, "Float2": true, "Float3": true, "Float4": true,
+	"Half2": true, "Half3": true, "Half4": true,
+	"UByte4N": true, "Byte4N": true, "UShort2N": true, "Short4N": true,
+}
+
+func newGoProgram(name string) *goProgram {
+	return &goProgram{
+		name:     name,
+		uniforms: make(map[string]token.Position),
+		attribs:  make(map[string]token.Position),
+		tagged:   make(map[string]token.Position),
+	}
+}
+
+// scanGoPrograms finds the loadProgram calls in the Go files of dir. The
+// uniform and attribute names a file looks up are taken to belong to the
+// programs that file loads, and so do the names in glsl struct tags, see
+// bindUniformStruct. Only string literals are understood; the values of
+// defines that aren't literals are taken to be 0.
+func scanGoPrograms(dir string) (map[string]*goProgram, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	programs := make(map[string]*goProgram)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var loaded []*goProgram
+		uniforms := make(map[string]token.Position)
+		attribs := make(map[string]token.Position)
+		tagged := make(map[string]token.Position)
+		ast.Inspect(f, func(n ast.Node) bool {
+			if field, ok := n.(*ast.Field); ok && field.Tag != nil {
+				tag, _ := stringLit(field.Tag)
+				if name := reflect.StructTag(tag).Get("glsl"); name != "" {
+					tagged[name] = fset.Position(field.Tag.Pos())
+				}
+				return true
+			}
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			name, ok := stringLit(call.Args[0])
+			if !ok {
+				return true
+			}
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				if attribConstructors[fun.Name] {
+					attribs[name] = fset.Position(call.Args[0].Pos())
+				}
+				if fun.Name == "loadProgram" && len(call.Args) == 2 {
+					p := programs[name]
+					if p == nil {
+						p = newGoProgram(name)
+						programs[name] = p
+					}
+					p.defines = defineLits(call.Args[1])
+					loaded = append(loaded, p)
+				}
+			case *ast.SelectorExpr:
+				switch fun.Sel.Name {
+				case "GetUniformLocation":
+					uniforms[name] = fset.Position(call.Args[0].Pos())
+				case "GetAttribLocation":
+					attribs[name] = fset.Position(call.Args[0].Pos())
+				}
+			}
+			return true
+		})
+
+		for _, p := range loaded {
+			for name, pos := range uniforms {
+				p.uniforms[name] = pos
+			}
+			for name, pos := range attribs {
+				p.attribs[name] = pos
+			}
+			for name, pos := range tagged {
+				p.tagged[name] = pos
+			}
+		}
+	}
+	return programs, nil
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// defineLits returns the defines of a map[string]string literal passed to
+// loadProgram.
+func defineLits(e ast.Expr) map[string]string {
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	defines := make(map[string]string)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		name, ok := stringLit(kv.Key)
+		if !ok {
+			continue
+		}
+		value, ok := stringLit(kv.Value)
+		if !ok {
+			value = "0"
+		}
+		defines[name] = value
+	}
+	return defines
+}
@@ -0,0 +1,65 @@
+package shader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintShaders(t *testing.T) {
+	var out bytes.Buffer
+	// the shaders of the exercises and the launcher that loads them
+	dir := filepath.Join("..", "shaders")
+	errors, err := Lint(dir, "..", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors > 0 {
+		t.Errorf("%d errors in %s:\n%s", errors, dir, out.String())
+	}
+}
+
+func TestLintGoNames(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"scene.go": `package main
+
+func (s *scene) Init() {
+	s.program, _ = loadProgram("test", nil)
+	format := NewVertexFormat(Float2("position"))
+	s.overrideColorLocation = s.program.GetUniformLocation("overrideColor")
+}
+`,
+		"test.vert.glsl": `#version 150
+in vec2 position;
+void main()
+{
+	gl_Position = vec4(position, 0.0, 1.0);
+}
+`,
+		"test.frag.glsl": `#version 150
+out vec4 outColor;
+void main()
+{
+	outColor = vec4(1.0);
+}
+`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	errors, err := Lint(dir, dir, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "scene.go:6:57: error: uniform overrideColor is not declared by the test shaders"
+	if errors != 1 || !strings.Contains(out.String(), want) {
+		t.Errorf("lint found %d errors:\n%s\nwant %q", errors, out.String(), want)
+	}
+}
@@ -0,0 +1,198 @@
+package shader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ShaderError is one message from a driver's compile log, traced back to
+// the file it is about. Line and Column are 1-based; zero means the driver
+// didn't say.
+type ShaderError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+func (e ShaderError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+			if e.Column > 0 {
+				fmt.Fprintf(&b, ":%d", e.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	if e.Warning {
+		b.WriteString("warning: ")
+	} else {
+		b.WriteString("error: ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// logFormats match a line of a compile log, naming the source string,
+// line, column, severity and message. Not every driver gives a column.
+var logFormats = []*regexp.Regexp{
+	// Mesa: 0:12(5): error: `foo' undeclared
+	regexp.MustCompile(`^(?P<string>\d+):(?P<line>\d+)\((?P<column>\d+)\): (?:preprocessor )?(?P<severity>error|warning): (?P<message>.*)$`),
+	// NVIDIA: 0(12) : error C1008: undefined variable "foo"
+	regexp.MustCompile(`^(?P<string>\d+)\((?P<line>\d+)\)\s*: (?P<severity>error|warning)(?: \w+)?: (?P<message>.*)$`),
+	// AMD, Intel on Windows, Apple: ERROR: 0:12: 'foo' : undeclared identifier
+	regexp.MustCompile(`^(?P<severity>ERROR|WARNING): (?P<string>\d+):(?P<line>\d+): (?P<message>.*)$`),
+}
+
+// logSummary matches the closing line some drivers add.
+var logSummary = regexp.MustCompile(`^(ERROR|WARNING): \d+ compilation (errors|warnings)\.`)
+
+// ParseLog splits a driver's compile log into its messages. The source
+// string numbers in the log are resolved through files, as in
+// Source.Files. Lines in no known format are kept as messages without a
+// location, so nothing the driver said gets lost.
+func ParseLog(log string, files []string) []ShaderError {
+	var errs []ShaderError
+	for _, line := range splitLines(log) {
+		line = strings.TrimSpace(line)
+		if line == "" || logSummary.MatchString(line) {
+			continue
+		}
+		e, ok := parseLogLine(line, files)
+		if !ok {
+			e = ShaderError{Message: line}
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+func parseLogLine(line string, files []string) (ShaderError, bool) {
+	for _, format := range logFormats {
+		m := format.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		group := func(name string) string {
+			if i := format.SubexpIndex(name); i >= 0 {
+				return m[i]
+			}
+			return ""
+		}
+		str, _ := strconv.Atoi(group("string"))
+		e := ShaderError{
+			File:    strconv.Itoa(str),
+			Message: strings.TrimSpace(group("message")),
+			Warning: strings.EqualFold(group("severity"), "warning"),
+		}
+		if str < len(files) {
+			e.File = files[str]
+		}
+		e.Line, _ = strconv.Atoi(group("line"))
+		e.Column, _ = strconv.Atoi(group("column"))
+		return e, true
+	}
+	return ShaderError{}, false
+}
+
+// Errors parses a compile log of s, see ParseLog.
+func (s *Source) Errors(log string) []ShaderError {
+	return ParseLog(log, s.Files)
+}
+
+// contextLines is how many lines Annotate shows around the offending one.
+const contextLines = 2
+
+// Annotate formats errs with the lines of s they point at, a caret under
+// the column and a few lines of context. Drivers that give no column often
+// quote the identifier they complain about; the caret goes under that when
+// it appears on the line.
+func (s *Source) Annotate(errs []ShaderError) string {
+	var b strings.Builder
+	for _, e := range errs {
+		b.WriteString(e.Error())
+		b.WriteString("\n")
+
+		lines := s.lines(e.File)
+		if e.Line < 1 || e.Line > len(lines) {
+			continue
+		}
+		first, last := e.Line-contextLines, e.Line+contextLines
+		if first < 1 {
+			first = 1
+		}
+		if last > len(lines) {
+			last = len(lines)
+		}
+		width := len(strconv.Itoa(last))
+		for n := first; n <= last; n++ {
+			text := lines[n-1]
+			fmt.Fprintf(&b, "%*d | %s\n", width, n, text)
+			if n != e.Line {
+				continue
+			}
+			column := e.Column
+			if column == 0 {
+				column = guessColumn(text, e.Message)
+			}
+			if column > 0 && column <= len(text)+1 {
+				fmt.Fprintf(&b, "%*s | %s^\n", width, "", indent(text[:column-1]))
+			}
+		}
+	}
+	return b.String()
+}
+
+// lines returns the lines of file, or nil if it isn't part of s.
+func (s *Source) lines(file string) []string {
+	for i, f := range s.Files {
+		if f == file && i < len(s.texts) {
+			return splitLines(s.texts[i])
+		}
+	}
+	return nil
+}
+
+var quoted = regexp.MustCompile("[\"'`]([A-Za-z_][A-Za-z0-9_]*)[\"']")
+
+// guessColumn finds the first identifier quoted in message on line and
+// returns its 1-based column, or 0.
+func guessColumn(line, message string) int {
+	m := quoted.FindStringSubmatch(message)
+	if m == nil {
+		return 0
+	}
+	name := m[1]
+	for start := 0; ; {
+		j := strings.Index(line[start:], name)
+		if j < 0 {
+			return 0
+		}
+		i, end := start+j, start+j+len(name)
+		if (i == 0 || !isIdent(line[i-1])) && (end == len(line) || !isIdent(line[end])) {
+			return i + 1
+		}
+		start = i + 1
+	}
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// indent turns prefix into blanks of the same width, keeping tabs so the
+// caret lines up with the source line above it.
+func indent(prefix string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, prefix)
+}
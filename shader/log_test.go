@@ -0,0 +1,87 @@
+package shader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Logs as the drivers print them for an undeclared identifier on line 5 of
+// scene.frag.glsl and a warning in the included common/samplers.glsl.
+var recordedLogs = map[string]string{
+	"mesa": "0:5(25): error: `colour' undeclared\n" +
+		"0:5(16): error: operands to arithmetic operators must be numeric\n" +
+		"1:2(1): warning: extension `GL_foo' unsupported\n",
+	"nvidia": "0(5) : error C1008: undefined variable \"colour\"\n" +
+		"1(2) : warning C7555: extension GL_foo not supported\n",
+	"amd": "ERROR: 0:5: 'colour' : undeclared identifier \n" +
+		"WARNING: 1:2: 'GL_foo' : extension not supported\n" +
+		"ERROR: 1 compilation errors.  No code generated.\n\n",
+}
+
+func TestParseLog(t *testing.T) {
+	files := []string{"scene.frag.glsl", "common/samplers.glsl"}
+	want := map[string][]ShaderError{
+		"mesa": {
+			{File: "scene.frag.glsl", Line: 5, Column: 25, Message: "`colour' undeclared"},
+			{File: "scene.frag.glsl", Line: 5, Column: 16, Message: "operands to arithmetic operators must be numeric"},
+			{File: "common/samplers.glsl", Line: 2, Column: 1, Message: "extension `GL_foo' unsupported", Warning: true},
+		},
+		"nvidia": {
+			{File: "scene.frag.glsl", Line: 5, Message: `undefined variable "colour"`},
+			{File: "common/samplers.glsl", Line: 2, Message: "extension GL_foo not supported", Warning: true},
+		},
+		"amd": {
+			{File: "scene.frag.glsl", Line: 5, Message: "'colour' : undeclared identifier"},
+			{File: "common/samplers.glsl", Line: 2, Message: "'GL_foo' : extension not supported", Warning: true},
+		},
+	}
+	for driver, log := range recordedLogs {
+		if got := ParseLog(log, files); !reflect.DeepEqual(got, want[driver]) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", driver, got, want[driver])
+		}
+	}
+}
+
+func TestParseLogUnknownFormat(t *testing.T) {
+	got := ParseLog("7:3(1): error: huh\nsomething else went wrong\n", nil)
+	want := []ShaderError{
+		{File: "7", Line: 3, Column: 1, Message: "huh"},
+		{Message: "something else went wrong"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if s := got[1].Error(); s != "error: something else went wrong" {
+		t.Errorf("Error() = %q", s)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	src := &Source{
+		Files: []string{"scene.frag.glsl"},
+		texts: []string{"#version 150\n" +
+			"out vec4 outColor;\n" +
+			"\n" +
+			"void main() {\n" +
+			"\toutColor = vec4(1.0) * colour;\n" +
+			"}\n"},
+	}
+
+	for _, driver := range []string{"mesa", "nvidia", "amd"} {
+		errs := src.Errors(recordedLogs[driver])[:1]
+		got := src.Annotate(errs)
+		want := strings.Join([]string{
+			errs[0].Error(),
+			"3 | ",
+			"4 | void main() {",
+			"5 | \toutColor = vec4(1.0) * colour;",
+			"  | \t" + strings.Repeat(" ", 23) + "^",
+			"6 | }",
+			"",
+		}, "\n")
+		if got != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", driver, got, want)
+		}
+	}
+}
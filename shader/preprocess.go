@@ -0,0 +1,193 @@
+// Package shader loads GLSL source files. It expands #include directives,
+// injects #defines from Go and keeps track of where every line came from,
+// so that the line numbers in driver messages can be traced back to the
+// original files. Lint checks the shaders without a GL context.
+package shader
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source is a preprocessed shader, ready to be handed to the driver.
+type Source struct {
+	Code string
+
+	// Files lists the files Code was assembled from, the root file first.
+	// The index of a file is the source string number its #line
+	// directives use.
+	Files []string
+
+	// texts holds the contents of Files, for Annotate.
+	texts []string
+}
+
+// Load reads the shader name from fsys and preprocesses it:
+//
+//   - #include "file" is replaced by the contents of file, which is looked
+//     up next to the including file first and at the root of fsys second.
+//     A file is included at most once per shader, so common blocks may
+//     include what they depend on.
+//   - defines are inserted as #define directives right after #version, or
+//     at the top if there is none. An empty value defines just the name.
+//   - #line directives are emitted around every include, so the driver
+//     reports lines of the original files. Their source string number is
+//     the file's index in Source.Files.
+func Load(fsys fs.FS, name string, defines map[string]string) (*Source, error) {
+	p := &preprocessor{fsys: fsys, included: make(map[string]bool)}
+	if err := p.file(name, nil, defines); err != nil {
+		return nil, err
+	}
+	return &Source{Code: p.out.String(), Files: p.files, texts: p.texts}, nil
+}
+
+type preprocessor struct {
+	fsys     fs.FS
+	out      strings.Builder
+	files    []string
+	texts    []string
+	included map[string]bool
+}
+
+// file appends name to the output. stack holds the files that include it;
+// defines are only used for the root file.
+func (p *preprocessor) file(name string, stack []string, defines map[string]string) error {
+	data, err := fs.ReadFile(p.fsys, name)
+	if err != nil {
+		return err
+	}
+	index := len(p.files)
+	p.files = append(p.files, name)
+	p.texts = append(p.texts, string(data))
+	p.included[name] = true
+	root := len(stack) == 0
+	stack = append(stack, name)
+
+	lines := splitLines(string(data))
+	defined := false
+	switch {
+	case !root:
+		p.line(1, index)
+		defined = true
+	case !hasVersion(lines):
+		if len(defines) > 0 {
+			p.defines(defines)
+			p.line(1, index)
+		}
+		defined = true
+	}
+
+	for i, text := range lines {
+		n := i + 1
+		directive, arg := parseDirective(text)
+		switch {
+		case directive == "version" && !defined:
+			p.out.WriteString(text + "\n")
+			p.defines(defines)
+			p.line(n+1, index)
+			defined = true
+
+		case directive == "include":
+			inc, err := p.resolve(name, arg)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", name, n, err)
+			}
+			for _, s := range stack {
+				if s == inc {
+					return fmt.Errorf("%s:%d: include cycle: %s -> %s", name, n, strings.Join(stack, " -> "), inc)
+				}
+			}
+			if p.included[inc] {
+				// keep the line count of the including file intact
+				p.out.WriteString("\n")
+				continue
+			}
+			if err := p.file(inc, stack, nil); err != nil {
+				return err
+			}
+			p.line(n+1, index)
+
+		default:
+			p.out.WriteString(text + "\n")
+		}
+	}
+	return nil
+}
+
+// resolve finds the file an #include in from refers to.
+func (p *preprocessor) resolve(from, arg string) (string, error) {
+	name, err := strconv.Unquote(arg)
+	if err != nil || !strings.HasPrefix(arg, `"`) {
+		return "", fmt.Errorf("#include expects a quoted file name, got %s", arg)
+	}
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("#include %q: path must be relative", name)
+	}
+
+	candidates := []string{path.Join(path.Dir(from), name), path.Clean(name)}
+	for _, c := range candidates {
+		if _, err := fs.Stat(p.fsys, c); err == nil {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("#include %q: file not found", name)
+}
+
+func (p *preprocessor) defines(defines map[string]string) {
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value := defines[name]; value != "" {
+			fmt.Fprintf(&p.out, "#define %s %s\n", name, value)
+		} else {
+			fmt.Fprintf(&p.out, "#define %s\n", name)
+		}
+	}
+}
+
+// line makes the next line of output count as line n of file index.
+func (p *preprocessor) line(n, index int) {
+	fmt.Fprintf(&p.out, "#line %d %d\n", n, index)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// parseDirective returns the name and argument of a preprocessor
+// directive, or an empty name for any other line.
+func parseDirective(line string) (directive, arg string) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+	line = strings.TrimSpace(line[1:])
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], strings.TrimSpace(line[i:])
+}
+
+func hasVersion(lines []string) bool {
+	for _, line := range lines {
+		if directive, _ := parseDirective(line); directive == "version" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package shader
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testFiles = fstest.MapFS{
+	"scene.frag.glsl": {Data: []byte("#version 150\n" +
+		"#include \"common/samplers.glsl\"\n" +
+		"out vec4 outColor;\n" +
+		"#include \"common/samplers.glsl\"\n" +
+		"void main() { outColor = COLOR; }\n")},
+	"common/samplers.glsl": {Data: []byte("#include \"types.glsl\"\n" +
+		"uniform sampler2D tex;\n")},
+	"common/types.glsl": {Data: []byte("// nothing but a comment\n")},
+	"noversion.glsl":    {Data: []byte("float f;\n")},
+	"cycle/a.glsl":      {Data: []byte("#include \"b.glsl\"\n")},
+	"cycle/b.glsl":      {Data: []byte("\n#include \"a.glsl\"\n")},
+	"missing.glsl":      {Data: []byte("#version 150\n\n#include \"nope.glsl\"\n")},
+}
+
+func TestLoad(t *testing.T) {
+	src, err := Load(testFiles, "scene.frag.glsl", map[string]string{"COLOR": "vec4(1.0)", "DEBUG": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"#version 150",
+		"#define COLOR vec4(1.0)",
+		"#define DEBUG",
+		"#line 2 0",
+		"#line 1 1",
+		"#line 1 2",
+		"// nothing but a comment",
+		"#line 2 1",
+		"uniform sampler2D tex;",
+		"#line 3 0",
+		"out vec4 outColor;",
+		"", // the second include of samplers.glsl
+		"void main() { outColor = COLOR; }",
+		"",
+	}, "\n")
+	if src.Code != want {
+		t.Errorf("code:\n%s\nwant:\n%s", src.Code, want)
+	}
+
+	files := []string{"scene.frag.glsl", "common/samplers.glsl", "common/types.glsl"}
+	if strings.Join(src.Files, ",") != strings.Join(files, ",") {
+		t.Errorf("files = %v, want %v", src.Files, files)
+	}
+}
+
+func TestLoadWithoutVersion(t *testing.T) {
+	src, err := Load(testFiles, "noversion.glsl", map[string]string{"N": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#define N 3\n#line 1 0\nfloat f;\n"; src.Code != want {
+		t.Errorf("code = %q, want %q", src.Code, want)
+	}
+
+	src, err = Load(testFiles, "noversion.glsl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "float f;\n"; src.Code != want {
+		t.Errorf("code without defines = %q, want %q", src.Code, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, err string
+	}{
+		{"cycle/a.glsl", "cycle/b.glsl:2: include cycle: cycle/a.glsl -> cycle/b.glsl -> cycle/a.glsl"},
+		{"missing.glsl", `missing.glsl:3: #include "nope.glsl": file not found`},
+	}
+	for _, test := range tests {
+		_, err := Load(testFiles, test.name, nil)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: err = %v, want %s", test.name, err, test.err)
+		}
+	}
+}
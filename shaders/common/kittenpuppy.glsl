@@ -0,0 +1,3 @@
+// the two sample images most exercises blend
+uniform sampler2D texKitten;
+uniform sampler2D texPuppy;
@@ -0,0 +1,4 @@
+// camera and object transforms of the 3D exercises
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 proj;
@@ -0,0 +1,18 @@
+#version 150
+
+in vec3 position;
+in vec3 color;
+in vec2 texcoord;
+
+out vec3 Color;
+out vec2 Texcoord;
+
+#include "common/transform.glsl"
+uniform vec3 overrideColor;
+
+void main()
+{
+	Texcoord = texcoord;
+	Color = overrideColor * color;
+	gl_Position = proj * view * model * vec4(position, 1.0);
+}
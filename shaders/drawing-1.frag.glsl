@@ -0,0 +1,8 @@
+#version 150
+
+out vec4 outColor;
+
+void main()
+{
+	outColor = vec4(1.0, 1.0, 1.0, 1.0);
+}
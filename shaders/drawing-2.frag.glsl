@@ -0,0 +1,10 @@
+#version 150
+
+out vec4 outColor;
+
+uniform vec3 triangleColor;
+
+void main()
+{
+	outColor = vec4(triangleColor, 1.0);
+}
@@ -0,0 +1,60 @@
+#version 150
+
+in vec3 Position;
+in vec3 Normal;
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+// the metallic-roughness material of glTF
+uniform vec4 baseColorFactor;
+uniform sampler2D baseColorMap;
+uniform float metallicFactor;
+uniform float roughnessFactor;
+uniform sampler2D metallicRoughnessMap;
+uniform vec3 emissiveFactor;
+uniform float alphaCutoff;
+
+uniform vec3 cameraPos;
+uniform vec3 lightDir;
+
+const float PI = 3.14159265;
+const vec3 lightColor = vec3(3.0);
+const vec3 ambient = vec3(0.15);
+
+void main()
+{
+	// the base color map is sRGB
+	vec4 base = texture(baseColorMap, Texcoord);
+	base = baseColorFactor * vec4(pow(base.rgb, vec3(2.2)), base.a);
+	if (base.a < alphaCutoff)
+		discard;
+	vec4 mr = texture(metallicRoughnessMap, Texcoord);
+	float metallic = metallicFactor * mr.b;
+	float roughness = clamp(roughnessFactor * mr.g, 0.04, 1.0);
+
+	vec3 n = normalize(Normal);
+	if (!gl_FrontFacing)
+		n = -n;
+	vec3 v = normalize(cameraPos - Position);
+	vec3 h = normalize(lightDir + v);
+	float nl = max(dot(n, lightDir), 0.0);
+	float nv = max(dot(n, v), 0.001);
+	float nh = max(dot(n, h), 0.0);
+	float vh = max(dot(v, h), 0.0);
+
+	// Cook-Torrance with the GGX distribution, Smith-Schlick geometry
+	// and Schlick's Fresnel approximation
+	float a2 = roughness * roughness * roughness * roughness;
+	float q = nh * nh * (a2 - 1.0) + 1.0;
+	float d = a2 / (PI * q * q);
+	float k = roughness * roughness / 2.0;
+	float g = nl / (nl * (1.0 - k) + k) * nv / (nv * (1.0 - k) + k);
+	vec3 f0 = mix(vec3(0.04), base.rgb, metallic);
+	vec3 f = f0 + (1.0 - f0) * pow(1.0 - vh, 5.0);
+
+	vec3 specular = d * g * f / max(4.0 * nl * nv, 0.001);
+	vec3 diffuse = (1.0 - f) * (1.0 - metallic) * base.rgb / PI;
+	vec3 color = (diffuse + specular) * lightColor * nl + ambient * base.rgb + emissiveFactor;
+	outColor = vec4(pow(color, vec3(1.0 / 2.2)), base.a);
+}
@@ -0,0 +1,21 @@
+#version 150
+
+in vec3 position;
+in vec3 normal;
+in vec2 texcoord;
+
+out vec3 Position;
+out vec3 Normal;
+out vec2 Texcoord;
+
+#include "common/transform.glsl"
+uniform mat3 normalMatrix;
+
+void main()
+{
+	vec4 world = model * vec4(position, 1.0);
+	Position = world.xyz;
+	Normal = normalMatrix * normal;
+	Texcoord = texcoord;
+	gl_Position = proj * view * world;
+}
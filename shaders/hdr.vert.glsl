@@ -0,0 +1,14 @@
+#version 150
+
+in vec2 position;
+in vec2 texcoord;
+
+out vec2 Texcoord;
+
+uniform vec2 scale;
+
+void main()
+{
+	Texcoord = texcoord;
+	gl_Position = vec4(position * scale, 0.0, 1.0);
+}
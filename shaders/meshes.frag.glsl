@@ -0,0 +1,15 @@
+#version 150
+
+in vec3 Normal;
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+uniform sampler2D tex;
+uniform vec3 lightDir;
+
+void main()
+{
+	float diffuse = max(dot(normalize(Normal), lightDir), 0.0);
+	outColor = (0.25 + 0.75 * diffuse) * texture(tex, Texcoord);
+}
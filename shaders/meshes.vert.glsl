@@ -0,0 +1,18 @@
+#version 150
+
+in vec3 position;
+in vec3 normal;
+in vec2 texcoord;
+
+out vec3 Normal;
+out vec2 Texcoord;
+
+#include "common/transform.glsl"
+
+void main()
+{
+	Texcoord = texcoord;
+	// the models are only rotated, so the model matrix can turn normals too
+	Normal = mat3(model) * normal;
+	gl_Position = proj * view * model * vec4(position, 1.0);
+}
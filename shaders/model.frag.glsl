@@ -0,0 +1,18 @@
+#version 150
+
+in vec3 Normal;
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+uniform sampler2D diffuseMap;
+uniform vec3 diffuseColor;
+uniform vec3 lightDir;
+
+void main()
+{
+	// faces may be seen from behind, so light both sides
+	float diffuse = abs(dot(normalize(Normal), lightDir));
+	vec4 color = vec4(diffuseColor, 1.0) * texture(diffuseMap, Texcoord);
+	outColor = vec4((0.25 + 0.75 * diffuse) * color.rgb, color.a);
+}
@@ -0,0 +1,12 @@
+#version 150
+
+in vec3 Direction;
+
+out vec4 outColor;
+
+uniform samplerCube sky;
+
+void main()
+{
+	outColor = texture(sky, Direction);
+}
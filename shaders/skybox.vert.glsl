@@ -0,0 +1,17 @@
+#version 150
+
+in vec3 position;
+
+out vec3 Direction;
+
+uniform mat4 orientation;
+uniform mat4 view;
+uniform mat4 proj;
+
+void main()
+{
+	Direction = (orientation * vec4(position, 1.0)).xyz;
+	vec4 pos = proj * view * vec4(position, 1.0);
+	// z = w puts the sky on the far plane, wherever the near plane is
+	gl_Position = pos.xyww;
+}
@@ -0,0 +1,15 @@
+#version 150
+
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+uniform sampler2D tex;
+
+void main()
+{
+	if (Texcoord.y < 0.5)
+		outColor = texture(tex, vec2(Texcoord.x, 1.0 - Texcoord.y));
+	else
+		outColor = texture(tex, Texcoord);
+}
@@ -0,0 +1,32 @@
+#version 150
+
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+uniform sampler2D scene;
+uniform float exposure;
+uniform int operator; // CLAMP, REINHARD or ACES, defined by the Go code
+
+// Krzysztof Narkowicz's fit of the ACES filmic curve
+vec3 aces(vec3 x)
+{
+	return (x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14);
+}
+
+vec3 linearToSRGB(vec3 c)
+{
+	return mix(c * 12.92, 1.055 * pow(c, vec3(1.0 / 2.4)) - 0.055, step(0.0031308, c));
+}
+
+void main()
+{
+	vec4 hdr = texture(scene, Texcoord);
+	vec3 c = hdr.rgb * exposure;
+	if (operator == REINHARD) {
+		c = c / (1.0 + c);
+	} else if (operator == ACES) {
+		c = aces(c);
+	}
+	outColor = vec4(linearToSRGB(clamp(c, 0.0, 1.0)), hdr.a);
+}
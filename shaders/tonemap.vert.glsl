@@ -0,0 +1,10 @@
+#version 150
+
+out vec2 Texcoord;
+
+void main()
+{
+	// a single triangle covering the screen, without vertex data
+	Texcoord = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+	gl_Position = vec4(Texcoord * 2.0 - 1.0, 0.0, 1.0);
+}
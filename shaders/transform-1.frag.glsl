@@ -0,0 +1,15 @@
+#version 150
+
+in vec3 Color;
+in vec2 Texcoord;
+
+out vec4 outColor;
+
+#include "common/kittenpuppy.glsl"
+
+void main()
+{
+	vec4 colKitten = texture(texKitten, Texcoord);
+	vec4 colPuppy = texture(texPuppy, Texcoord);
+	outColor = mix(colKitten, colPuppy, 0.5);
+}
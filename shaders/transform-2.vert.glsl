@@ -0,0 +1,17 @@
+#version 150
+
+in vec2 position;
+in vec3 color;
+in vec2 texcoord;
+
+out vec3 Color;
+out vec2 Texcoord;
+
+uniform mat4 trans;
+
+void main()
+{
+	Texcoord = texcoord;
+	Color = color;
+	gl_Position = trans * vec4(position, 0.0, 1.0);
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"github.com/go-gl/gl"
+	"github.com/go-gl/glh"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// skyTextureUnit is the texture unit the skybox binds its cube map to, out
+// of the way of the units the exercises use.
+const skyTextureUnit = 8
+
+// skyPath is the cube map the 3D exercises draw behind the scene, set with
+// the --sky flag. Without it they clear to a flat color as before.
+var skyPath string
+
+// skyboxVertices is a cube around the origin drawn as 12 triangles.
+var skyboxVertices = []gl.GLfloat{
+	-1, -1, -1, 1, -1, -1, 1, 1, -1, 1, 1, -1, -1, 1, -1, -1, -1, -1,
+	-1, -1, 1, 1, -1, 1, 1, 1, 1, 1, 1, 1, -1, 1, 1, -1, -1, 1,
+	-1, 1, 1, -1, 1, -1, -1, -1, -1, -1, -1, -1, -1, -1, 1, -1, 1, 1,
+	1, 1, 1, 1, 1, -1, 1, -1, -1, 1, -1, -1, 1, -1, 1, 1, 1, 1,
+	-1, -1, -1, 1, -1, -1, 1, -1, 1, 1, -1, 1, -1, -1, 1, -1, -1, -1,
+	-1, 1, -1, 1, 1, -1, 1, 1, 1, 1, 1, 1, -1, 1, 1, -1, 1, -1,
+}
+
+// skybox draws a cube map behind everything else. It is a pass scenes call
+// from their Draw, so it leaves the scene's program, vertex array and
+// active texture unit as it found them.
+type skybox struct {
+	vbo                 gl.Buffer
+	texture             gl.Texture
+	program             *shaderProgram
+	orientationLocation gl.UniformLocation
+	viewLocation        gl.UniformLocation
+	projLocation        gl.UniformLocation
+	vao                 gl.VertexArray
+}
+
+// loadSky creates the skybox for skyPath, or returns nil if no sky was
+// asked for.
+func loadSky() (*skybox, error) {
+	if skyPath == "" {
+		return nil, nil
+	}
+	return newSkybox(skyPath)
+}
+
+// newSkybox loads the cube map at path, either a directory of faces or an
+// equirectangular panorama. The cube map's Y axis, which is up in
+// panoramas, is turned to the Z axis the exercises use as up.
+func newSkybox(path string) (*skybox, error) {
+	saved := saveBindings()
+	defer saved.restore()
+
+	s := new(skybox)
+	var err error
+
+	gl.ActiveTexture(gl.TEXTURE0 + skyTextureUnit)
+	s.texture, err = loadCubemap(path, cubemapSampler)
+	if err != nil {
+		return nil, err
+	}
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	s.vao = gl.GenVertexArray()
+	s.vao.Bind()
+
+	s.vbo = gl.GenBuffer()
+	s.vbo.Bind(gl.ARRAY_BUFFER)
+	gl.BufferData(gl.ARRAY_BUFFER, int(glh.Sizeof(gl.FLOAT))*len(skyboxVertices), skyboxVertices, gl.STATIC_DRAW)
+	checkError("skybox vertex data")
+
+	s.program, err = loadProgram("skybox", nil)
+	if err != nil {
+		s.texture.Delete()
+		s.vbo.Delete()
+		s.vao.Delete()
+		return nil, err
+	}
+
+	err = NewVertexFormat(Float3("position")).Enable(s.program.Program, skyboxVertices)
+	if err != nil {
+		s.Delete()
+		return nil, err
+	}
+
+	s.bindUniforms()
+	s.program.bind = s.bindUniforms
+
+	return s, nil
+}
+
+func (s *skybox) bindUniforms() {
+	s.program.GetUniformLocation("sky").Uniform1i(skyTextureUnit)
+	s.orientationLocation = s.program.GetUniformLocation("orientation")
+	s.orientationLocation.UniformMatrix4fv(false, glm.HomogRotate3DX(-math.Pi/2))
+	s.viewLocation = s.program.GetUniformLocation("view")
+	s.projLocation = s.program.GetUniformLocation("proj")
+}
+
+// Draw renders the sky as seen with the camera of view and proj. Only the
+// rotation of view is used, so the sky stays infinitely far away. Call it
+// right after clearing: it neither tests nor writes depth and stencil.
+func (s *skybox) Draw(view, proj glm.Mat4) {
+	saved := saveBindings()
+	defer saved.restore()
+
+	depthTest, stencilTest := gl.IsEnabled(gl.DEPTH_TEST), gl.IsEnabled(gl.STENCIL_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.STENCIL_TEST)
+
+	s.program.Use()
+	s.vao.Bind()
+	gl.ActiveTexture(gl.TEXTURE0 + skyTextureUnit)
+	s.texture.Bind(gl.TEXTURE_CUBE_MAP)
+
+	s.viewLocation.UniformMatrix4fv(false, view.Mat3().Mat4())
+	s.projLocation.UniformMatrix4fv(false, proj)
+	gl.DrawArrays(gl.TRIANGLES, 0, len(skyboxVertices)/3)
+	checkError("skybox")
+
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+	if stencilTest {
+		gl.Enable(gl.STENCIL_TEST)
+	}
+}
+
+func (s *skybox) Delete() {
+	s.program.Delete()
+	s.texture.Delete()
+	s.vbo.Delete()
+	s.vao.Delete()
+}
+
+// glBindings is the state a pass has to put back before returning to the
+// scene that called it.
+type glBindings struct {
+	program       gl.Program
+	vao           gl.VertexArray
+	activeTexture gl.GLenum
+}
+
+func saveBindings() glBindings {
+	v := make([]int32, 1)
+	var b glBindings
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, v)
+	b.program = gl.Program(v[0])
+	gl.GetIntegerv(gl.VERTEX_ARRAY_BINDING, v)
+	b.vao = gl.VertexArray(v[0])
+	gl.GetIntegerv(gl.ACTIVE_TEXTURE, v)
+	b.activeTexture = gl.GLenum(v[0])
+	return b
+}
+
+func (b glBindings) restore() {
+	b.program.Use()
+	b.vao.Bind()
+	gl.ActiveTexture(b.activeTexture)
+}
@@ -0,0 +1,155 @@
+package softscenes
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/raster"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+// cubeVertices holds the 36 vertices of the textured cube followed by the
+// 6 vertices of the floor depth-2 reflects it in: position, color,
+// texcoord.
+var cubeVertices = []float32{
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, -0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, -0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 1.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 1.0, 0.0,
+	-0.5, 0.5, 0.5, 1.0, 1.0, 1.0, 0.0, 0.0,
+	-0.5, 0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 1.0,
+
+	-1.0, -1.0, -0.5, 0.0, 0.0, 0.0, 0.0, 0.0,
+	1.0, -1.0, -0.5, 0.0, 0.0, 0.0, 1.0, 0.0,
+	1.0, 1.0, -0.5, 0.0, 0.0, 0.0, 1.0, 1.0,
+	1.0, 1.0, -0.5, 0.0, 0.0, 0.0, 1.0, 1.0,
+	-1.0, 1.0, -0.5, 0.0, 0.0, 0.0, 0.0, 1.0,
+	-1.0, -1.0, -0.5, 0.0, 0.0, 0.0, 0.0, 0.0,
+}
+
+func init() {
+	register("depth-1", func() Scene { return new(depth1) })
+	register("depth-2", func() Scene { return new(depth2) })
+}
+
+type depth1 struct {
+	pipeline *raster.Pipeline
+	camera   camera
+}
+
+func (s *depth1) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(8, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[3:])
+		return s.camera.project(glm.Vec4{in[0], in[1], in[2], 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	s.pipeline.DepthTest = true
+	s.camera = newCamera(glm.Vec3{1.2, 1.2, 1.2}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 1.0})
+	return nil
+}
+
+func (s *depth1) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	s.camera.model = glm.HomogRotate3DZ(math.Pi * float32(now.Seconds()))
+
+	fb.ClearColor(black)
+	fb.ClearDepth(1.0)
+	fb.DrawArrays(s.pipeline, cubeVertices, 0, 36)
+}
+
+type depth2 struct {
+	pipeline      *raster.Pipeline
+	camera        camera
+	overrideColor glm.Vec3
+}
+
+func (s *depth2) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(8, 5, func(in, out []float32) glm.Vec4 {
+		out[0] = s.overrideColor[0] * in[3]
+		out[1] = s.overrideColor[1] * in[4]
+		out[2] = s.overrideColor[2] * in[5]
+		out[3], out[4] = in[6], in[7]
+		return s.camera.project(glm.Vec4{in[0], in[1], in[2], 1.0})
+	}, func(in []float32) glm.Vec4 {
+		uv := glm.Vec2{in[3], in[4]}
+		c := raster.Mix(kitten.Sample(uv), puppy.Sample(uv), 0.5)
+		return glm.Vec4{in[0] * c[0], in[1] * c[1], in[2] * c[2], c[3]}
+	})
+	s.pipeline.DepthTest = true
+	s.overrideColor = glm.Vec3{1.0, 1.0, 1.0}
+	s.camera = newCamera(glm.Vec3{2.2, 3.2, 2.2}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 0.5})
+	return nil
+}
+
+func (s *depth2) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	p := s.pipeline
+
+	fb.ClearColor(glm.Vec4{1.0, 1.0, 1.0, 1.0})
+	fb.ClearDepth(1.0)
+
+	// draw top box
+	s.camera.model = glm.HomogRotate3DZ(math.Pi * float32(now.Seconds()))
+	fb.DrawArrays(p, cubeVertices, 0, 36)
+
+	// draw floor
+	p.StencilTest = true
+	p.Stencil.Func, p.Stencil.Ref, p.Stencil.ValueMask = raster.Always, 1, 0xFF
+	p.Stencil.Fail, p.Stencil.ZFail, p.Stencil.ZPass = raster.Keep, raster.Keep, raster.Replace
+	p.Stencil.WriteMask = 0xFF
+	p.DepthMask = false
+	fb.ClearStencil(0)
+	fb.DrawArrays(p, cubeVertices, 36, 6)
+
+	// draw reflection
+	p.Stencil.Func = raster.Equal
+	p.Stencil.WriteMask = 0x00
+	p.DepthMask = true
+	s.camera.model = s.camera.model.Mul4(glm.Translate3D(0.0, 0.0, -1.0)).Mul4(glm.Scale3D(1.0, 1.0, -1.0))
+	s.overrideColor = glm.Vec3{0.3, 0.3, 0.3}
+	fb.DrawArrays(p, cubeVertices, 0, 36)
+	s.overrideColor = glm.Vec3{1.0, 1.0, 1.0}
+
+	p.StencilTest = false
+}
@@ -0,0 +1,149 @@
+package softscenes
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/raster"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+var black = glm.Vec4{0.0, 0.0, 0.0, 1.0}
+
+// passthrough2D is the vertex shader of the 2D exercises: a vec2 position
+// followed by attributes that are handed to the fragment shader unchanged.
+func passthrough2D(in, out []float32) glm.Vec4 {
+	copy(out, in[2:])
+	return glm.Vec4{in[0], in[1], 0.0, 1.0}
+}
+
+// vertexColor outputs the interpolated vec3 color.
+func vertexColor(in []float32) glm.Vec4 {
+	return glm.Vec4{in[0], in[1], in[2], 1.0}
+}
+
+func init() {
+	register("drawing-1", func() Scene { return new(drawing1) })
+	register("drawing-2", func() Scene { return new(drawing2) })
+	register("drawing-3", func() Scene { return new(drawing3) })
+	register("drawing-4", func() Scene { return new(drawing4) })
+	register("drawing-5", func() Scene { return new(drawing5) })
+}
+
+type drawing1 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+}
+
+func (s *drawing1) Init(dir string) error {
+	s.vertices = []float32{
+		0.0, 0.5,
+		0.5, -0.5,
+		-0.5, -0.5,
+	}
+	s.pipeline = raster.NewPipeline(2, 0, passthrough2D, func(in []float32) glm.Vec4 {
+		return glm.Vec4{1.0, 1.0, 1.0, 1.0}
+	})
+	return nil
+}
+
+func (s *drawing1) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawArrays(s.pipeline, s.vertices, 0, 3)
+}
+
+type drawing2 struct {
+	vertices      []float32
+	pipeline      *raster.Pipeline
+	triangleColor glm.Vec3
+}
+
+func (s *drawing2) Init(dir string) error {
+	s.vertices = []float32{
+		0.0, 0.5,
+		0.5, -0.5,
+		-0.5, -0.5,
+	}
+	s.pipeline = raster.NewPipeline(2, 0, passthrough2D, func(in []float32) glm.Vec4 {
+		return s.triangleColor.Vec4(1.0)
+	})
+	s.triangleColor = glm.Vec3{1.0, 0.0, 0.0}
+	return nil
+}
+
+func (s *drawing2) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawArrays(s.pipeline, s.vertices, 0, 3)
+
+	// like the GL version the new color shows up in the next frame
+	s.triangleColor = glm.Vec3{float32(math.Sin(now.Seconds()*4)) + 1.0, 0.0, 0.0}
+}
+
+type drawing3 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+}
+
+func (s *drawing3) Init(dir string) error {
+	s.vertices = []float32{
+		0.0, 0.5, 1.0, 0.0, 0.0, // vertex 1: red
+		0.5, -0.5, 0.0, 1.0, 0.0, // vertex 2: green
+		-0.5, -0.5, 0.0, 0.0, 1.0, // vertex 3: blue
+	}
+	s.pipeline = raster.NewPipeline(5, 3, passthrough2D, vertexColor)
+	return nil
+}
+
+func (s *drawing3) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawArrays(s.pipeline, s.vertices, 0, 3)
+}
+
+type drawing4 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+}
+
+func (s *drawing4) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 1.0, 0.0, 0.0, // top left
+		0.5, 0.5, 0.0, 1.0, 0.0, // top right
+		0.5, -0.5, 0.0, 0.0, 1.0, // bottom right
+
+		0.5, -0.5, 0.0, 0.0, 1.0, // bottom right
+		-0.5, -0.5, 1.0, 1.0, 1.0, // bottom left
+		-0.5, 0.5, 1.0, 0.0, 0.0, // top left
+	}
+	s.pipeline = raster.NewPipeline(5, 3, passthrough2D, vertexColor)
+	return nil
+}
+
+func (s *drawing4) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawArrays(s.pipeline, s.vertices, 0, 6)
+}
+
+type drawing5 struct {
+	vertices []float32
+	elements []uint32
+	pipeline *raster.Pipeline
+}
+
+func (s *drawing5) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 1.0, 0.0, 0.0, // top left
+		0.5, 0.5, 0.0, 1.0, 0.0, // top right
+		0.5, -0.5, 0.0, 0.0, 1.0, // bottom right
+		-0.5, -0.5, 1.0, 1.0, 1.0, // bottom left
+	}
+	s.elements = []uint32{
+		0, 1, 2,
+		2, 3, 0,
+	}
+	s.pipeline = raster.NewPipeline(5, 3, passthrough2D, vertexColor)
+	return nil
+}
+
+func (s *drawing5) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, s.vertices, s.elements)
+}
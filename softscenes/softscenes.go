@@ -0,0 +1,99 @@
+// Package softscenes reproduces the drawing, texture, transform and depth
+// exercises on top of the raster package, so they can be rendered and
+// tested on machines without an OpenGL driver. Each scene mirrors the GL
+// version call for call, including when uniforms are updated, so frame n
+// of both paths can be compared directly.
+package softscenes
+
+import (
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/raster"
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"image"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Scene is the software counterpart of the launcher's Scene.
+type Scene interface {
+	// Init loads the scene's textures from dir.
+	Init(dir string) error
+	// Draw renders one frame at time now, delta after the previous one.
+	Draw(fb *raster.Framebuffer, now, delta time.Duration)
+}
+
+var scenes = make(map[string]func() Scene)
+
+func register(name string, fn func() Scene) {
+	if _, dup := scenes[name]; dup {
+		panic("softscenes: scene registered twice: " + name)
+	}
+	scenes[name] = fn
+}
+
+// Names returns the names of all scenes that have a software version in
+// sorted order.
+func Names() []string {
+	names := make([]string, 0, len(scenes))
+	for name := range scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a fresh instance of the named scene.
+func Lookup(name string) (Scene, error) {
+	fn, ok := scenes[name]
+	if !ok {
+		return nil, fmt.Errorf("no software version of scene %q", name)
+	}
+	return fn(), nil
+}
+
+// Render draws frames frames of the named scene into a width x height
+// framebuffer and hands every frame to fn. Like the headless GL path the
+// scene runs on a fixed step clock, so frame n is drawn at n times step.
+func Render(name, dir string, width, height, frames int, step time.Duration, fn func(frame int, img *image.NRGBA) error) error {
+	scene, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	if err := scene.Init(dir); err != nil {
+		return err
+	}
+
+	fb := raster.NewFramebuffer(width, height)
+	var now, delta time.Duration
+	for i := 0; i < frames; i++ {
+		scene.Draw(fb, now, delta)
+		if err := fn(i, fb.Image()); err != nil {
+			return err
+		}
+		delta = step
+		now += delta
+	}
+	return nil
+}
+
+func loadTexture(dir, name string) (*raster.Texture, error) {
+	img, err := texfmt.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return raster.NewTexture(img), nil
+}
+
+// loadKittenPuppy loads the two sample images most exercises blend.
+func loadKittenPuppy(dir string) (kitten, puppy *raster.Texture, err error) {
+	kitten, err = loadTexture(dir, "sample.png")
+	if err != nil {
+		return nil, nil, err
+	}
+	puppy, err = loadTexture(dir, "sample2.png")
+	if err != nil {
+		return nil, nil, err
+	}
+	return kitten, puppy, nil
+}
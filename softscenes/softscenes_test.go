@@ -0,0 +1,99 @@
+package softscenes
+
+import (
+	"flag"
+	"github.com/Happy-Ferret/gl-tutorial/golden"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "write the rendered frames as new reference images")
+
+// goldenFrames is the number of frames rendered for the comparison, half a
+// second, so the animated scenes are compared part of the way through.
+const goldenFrames = 30
+
+func TestScenesDraw(t *testing.T) {
+	for _, name := range Names() {
+		var last *image.NRGBA
+		err := Render(name, "..", 160, 120, 30, time.Second/60, func(frame int, img *image.NRGBA) error {
+			last = img
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		// by frame 30 every exercise draws something over the clear color
+		background := last.NRGBAAt(0, 0)
+		if last.NRGBAAt(80, 60) == background {
+			t.Errorf("%s: center pixel has the background color %v", name, background)
+		}
+	}
+}
+
+func TestDrawing1(t *testing.T) {
+	err := Render("drawing-1", "..", 80, 60, 1, time.Second/60, func(frame int, img *image.NRGBA) error {
+		white := color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+		black := color.NRGBA{0x00, 0x00, 0x00, 0xFF}
+		if c := img.NRGBAAt(40, 30); c != white {
+			t.Errorf("center = %v, want %v", c, white)
+		}
+		// the triangle's corners are at half the viewport size
+		if c := img.NRGBAAt(40, 14); c != black {
+			t.Errorf("above the tip = %v, want %v", c, black)
+		}
+		if c := img.NRGBAAt(15, 44); c != black {
+			t.Errorf("outside the left corner = %v, want %v", c, black)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGolden compares a frame of every scene against the reference images
+// in testdata/golden. Unlike the GL frames, these need no driver, so the
+// comparison runs everywhere.
+func TestGolden(t *testing.T) {
+	for _, name := range Names() {
+		var got *image.NRGBA
+		err := Render(name, "..", 160, 120, goldenFrames, time.Second/60, func(frame int, img *image.NRGBA) error {
+			got = img
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		refPath := filepath.Join("testdata", "golden", name+".png")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := golden.WritePNG(refPath, got); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := golden.ReadPNG(refPath)
+		if err != nil {
+			t.Errorf("%s: %v, run go test -update to create it", name, err)
+			continue
+		}
+		result, err := golden.Compare(want, got, golden.DefaultOptions)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !result.Passed(golden.DefaultOptions) {
+			t.Errorf("%s: frame %d does not match %s: %v", name, goldenFrames-1, refPath, result)
+		}
+	}
+}
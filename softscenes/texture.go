@@ -0,0 +1,249 @@
+package softscenes
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/raster"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+// quadVertices is the colored and textured quad of texture-1, texture-2
+// and the transform exercises: position, color, texcoord.
+var quadVertices = []float32{
+	-0.5, 0.5, 1.0, 0.0, 0.0, 0.0, 1.0, // top left
+	0.5, 0.5, 0.0, 1.0, 0.0, 1.0, 1.0, // top right
+	0.5, -0.5, 0.0, 0.0, 1.0, 1.0, 0.0, // bottom right
+	-0.5, -0.5, 1.0, 1.0, 1.0, 0.0, 0.0, // bottom left
+}
+
+// quadElements draws the four vertex quads as two triangles.
+var quadElements = []uint32{
+	0, 1, 2,
+	2, 3, 0,
+}
+
+func init() {
+	register("texture-1", func() Scene { return new(texture1) })
+	register("texture-2", func() Scene { return new(texture2) })
+	register("texture-3", func() Scene { return new(texture3) })
+	register("texture-4", func() Scene { return new(texture4) })
+	register("texture-5", func() Scene { return new(texture5) })
+	register("texture-6", func() Scene { return new(texture6) })
+}
+
+type texture1 struct {
+	tex      *raster.Texture
+	pipeline *raster.Pipeline
+}
+
+func (s *texture1) Init(dir string) error {
+	var err error
+
+	s.tex, err = loadTexture(dir, "sample.png")
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, passthrough2D, func(in []float32) glm.Vec4 {
+		c := s.tex.Sample(glm.Vec2{in[3], in[4]})
+		return glm.Vec4{c[0] * in[0], c[1] * in[1], c[2] * in[2], c[3]}
+	})
+	return nil
+}
+
+func (s *texture1) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+// mixKittenPuppy blends both samples evenly at the texture coordinate
+// stored at in[i:i+2].
+func mixKittenPuppy(kitten, puppy *raster.Texture, i int) raster.FragmentShader {
+	return func(in []float32) glm.Vec4 {
+		uv := glm.Vec2{in[i], in[i+1]}
+		return raster.Mix(kitten.Sample(uv), puppy.Sample(uv), 0.5)
+	}
+}
+
+type texture2 struct {
+	pipeline *raster.Pipeline
+}
+
+func (s *texture2) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, passthrough2D, mixKittenPuppy(kitten, puppy, 3))
+	return nil
+}
+
+func (s *texture2) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+// oscillator is the Oscillator of the GL texture-3 exercise.
+type oscillator struct {
+	value float32
+	step  float32
+	down  bool
+}
+
+func (o *oscillator) tick(dt float32) {
+	if o.down {
+		o.value -= o.step * dt
+		if o.value <= 0 {
+			o.value = 0
+			o.down = false
+		}
+	} else {
+		o.value += o.step * dt
+		if o.value >= 1 {
+			o.value = 1
+			o.down = true
+		}
+	}
+}
+
+type texture3 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+	osc      oscillator
+	time     float32
+}
+
+func (s *texture3) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 0.0, 1.0, // top left
+		0.5, 0.5, 1.0, 1.0, // top right
+		0.5, -0.5, 1.0, 0.0, // bottom right
+		-0.5, -0.5, 0.0, 0.0, // bottom left
+	}
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(4, 2, passthrough2D, func(in []float32) glm.Vec4 {
+		uv := glm.Vec2{in[0], in[1]}
+		return raster.Mix(kitten.Sample(uv), puppy.Sample(uv), s.time)
+	})
+	s.osc = oscillator{step: 0.003}
+	return nil
+}
+
+func (s *texture3) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, s.vertices, quadElements)
+
+	s.osc.tick(float32(delta.Seconds()))
+	s.time = s.osc.value
+}
+
+type texture4 struct {
+	vertices []float32
+	elements []uint32
+	pipeline *raster.Pipeline
+}
+
+func (s *texture4) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 0.0, 1.0, // top left
+		0.5, 0.5, 1.0, 1.0, // top right
+		0.5, 0.0, 1.0, 0.5, // middle right
+		-0.5, 0.0, 0.0, 0.5, // middle left
+		-0.5, -0.5, 0.0, 1.0, // bottom left
+		0.5, -0.5, 1.0, 1.0, // bottom right
+	}
+	s.elements = []uint32{
+		0, 1, 2,
+		2, 3, 0,
+		4, 5, 2,
+		2, 3, 4,
+	}
+	tex, err := loadTexture(dir, "sample.png")
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(4, 2, passthrough2D, func(in []float32) glm.Vec4 {
+		return tex.Sample(glm.Vec2{in[0], in[1]})
+	})
+	return nil
+}
+
+func (s *texture4) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, s.vertices, s.elements)
+}
+
+type texture5 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+}
+
+func (s *texture5) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 0.0, 1.0, // top left
+		0.5, 0.5, 1.0, 1.0, // top right
+		0.5, -0.5, 1.0, 0.0, // bottom right
+		-0.5, -0.5, 0.0, 0.0, // bottom left
+	}
+	tex, err := loadTexture(dir, "sample.png")
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(4, 2, passthrough2D, func(in []float32) glm.Vec4 {
+		if in[1] < 0.5 {
+			return tex.Sample(glm.Vec2{in[0], 1.0 - in[1]})
+		}
+		return tex.Sample(glm.Vec2{in[0], in[1]})
+	})
+	return nil
+}
+
+func (s *texture5) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, s.vertices, quadElements)
+}
+
+type texture6 struct {
+	vertices []float32
+	pipeline *raster.Pipeline
+	time     float32
+}
+
+func (s *texture6) Init(dir string) error {
+	s.vertices = []float32{
+		-0.5, 0.5, 0.0, 1.0, // top left
+		0.5, 0.5, 1.0, 1.0, // top right
+		0.5, -0.5, 1.0, 0.0, // bottom right
+		-0.5, -0.5, 0.0, 0.0, // bottom left
+	}
+	tex, err := loadTexture(dir, "sample.png")
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(4, 2, passthrough2D, func(in []float32) glm.Vec4 {
+		if in[1] < 0.5 {
+			wave := float32(math.Sin(float64(in[1]*60.0+s.time*2.0))) / 30.0
+			c := tex.Sample(glm.Vec2{in[0] + wave, 1.0 - in[1]})
+			return glm.Vec4{c[0] * 0.7, c[1] * 0.7, c[2], c[3]}
+		}
+		return tex.Sample(glm.Vec2{in[0], in[1]})
+	})
+	return nil
+}
+
+func (s *texture6) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	s.time = float32(now.Seconds())
+
+	fb.ClearColor(black)
+	// the GL version asks for 12 elements of a 6 element buffer; only the
+	// defined part is drawn here
+	fb.DrawElements(s.pipeline, s.vertices, quadElements)
+}
@@ -0,0 +1,170 @@
+package softscenes
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/raster"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+// camera holds the model, view and projection uniforms shared by the
+// transform and depth exercises.
+type camera struct {
+	model, view, proj glm.Mat4
+}
+
+func newCamera(eye, center, up glm.Vec3) camera {
+	return camera{
+		model: glm.Ident4(),
+		view:  glm.LookAtV(eye, center, up),
+		proj:  glm.Perspective(45.0, 800.0/600.0, 1.0, 10.0),
+	}
+}
+
+func (c *camera) project(p glm.Vec4) glm.Vec4 {
+	return c.proj.Mul4(c.view).Mul4(c.model).Mul4x1(p)
+}
+
+func init() {
+	register("transform-1", func() Scene { return new(transform1) })
+	register("transform-2", func() Scene { return new(transform2) })
+	register("transform-3", func() Scene { return new(transform3) })
+	register("transform-4", func() Scene { return new(transform4) })
+	register("transform-5", func() Scene { return new(transform5) })
+}
+
+type transform1 struct {
+	pipeline *raster.Pipeline
+	trans    glm.Mat4
+}
+
+func (s *transform1) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		return s.trans.Mul4x1(glm.Vec4{in[0], in[1], 0.0, 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	s.trans = glm.HomogRotate3DZ(math.Pi)
+	return nil
+}
+
+func (s *transform1) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+type transform2 struct {
+	pipeline *raster.Pipeline
+	trans    glm.Mat4
+}
+
+func (s *transform2) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		return s.trans.Mul4x1(glm.Vec4{in[0], in[1], 0.0, 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	return nil
+}
+
+func (s *transform2) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	s.trans = glm.HomogRotate3DZ(math.Pi * float32(now.Seconds()))
+
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+type transform3 struct {
+	pipeline *raster.Pipeline
+	camera   camera
+}
+
+func (s *transform3) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		return s.camera.project(glm.Vec4{in[0], in[1], 0.0, 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	s.camera = newCamera(glm.Vec3{1.2, 1.2, 1.2}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 1.0})
+	return nil
+}
+
+func (s *transform3) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	s.camera.model = glm.HomogRotate3DZ(math.Pi * float32(now.Seconds()))
+
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+type transform4 struct {
+	pipeline *raster.Pipeline
+	camera   camera
+	time     float32
+}
+
+func (s *transform4) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		scale := float32(math.Sin(float64(s.time)))
+		return s.camera.project(glm.Vec4{in[0] * scale, in[1] * scale, 0.0, 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	s.camera = newCamera(glm.Vec3{1.2, 1.2, 1.2}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 1.0})
+	return nil
+}
+
+func (s *transform4) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	s.time = float32(now.Seconds())
+	s.camera.model = glm.HomogRotate3DZ(math.Pi * float32(now.Seconds()))
+
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
+
+// transform5 is the spinning quad without keyboard input, so it stays at
+// rest.
+type transform5 struct {
+	pipeline *raster.Pipeline
+	camera   camera
+	speed    float32
+}
+
+func (s *transform5) Init(dir string) error {
+	kitten, puppy, err := loadKittenPuppy(dir)
+	if err != nil {
+		return err
+	}
+
+	s.pipeline = raster.NewPipeline(7, 5, func(in, out []float32) glm.Vec4 {
+		copy(out, in[2:])
+		return s.camera.project(glm.Vec4{in[0], in[1], 0.0, 1.0})
+	}, mixKittenPuppy(kitten, puppy, 3))
+	s.camera = newCamera(glm.Vec3{1.2, 1.2, 1.2}, glm.Vec3{0.0, 0.0, 0.0}, glm.Vec3{0.0, 0.0, 1.0})
+	return nil
+}
+
+func (s *transform5) Draw(fb *raster.Framebuffer, now, delta time.Duration) {
+	if s.speed > 0 {
+		s.speed *= 0.99
+	}
+	s.camera.model = glm.HomogRotate3DX(s.speed * 2 * math.Pi)
+
+	fb.ClearColor(black)
+	fb.DrawElements(s.pipeline, quadVertices, quadElements)
+}
@@ -0,0 +1,357 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+)
+
+const (
+	ddpfAlphaPixels = 0x1
+	ddpfAlpha       = 0x2
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+	ddpfLuminance   = 0x20000
+)
+
+// DXGI_FORMAT values of the DX10 extended header that are supported.
+const (
+	dxgiR8G8B8A8     = 28
+	dxgiR8G8B8A8SRGB = 29
+	dxgiR8           = 61
+	dxgiBC1          = 71
+	dxgiBC1SRGB      = 72
+	dxgiBC2          = 74
+	dxgiBC2SRGB      = 75
+	dxgiBC3          = 77
+	dxgiBC3SRGB      = 78
+	dxgiBC4          = 80
+	dxgiBC5          = 83
+	dxgiB8G8R8A8     = 87
+	dxgiB8G8R8A8SRGB = 91
+)
+
+type ddsPixelFormat struct {
+	Size        uint32
+	Flags       uint32
+	FourCC      [4]byte
+	RGBBitCount uint32
+	RMask       uint32
+	GMask       uint32
+	BMask       uint32
+	AMask       uint32
+}
+
+type ddsHeader struct {
+	Magic             [4]byte
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+	PixelFormat       ddsPixelFormat
+	Caps              [4]uint32
+	Reserved2         uint32
+}
+
+type ddsHeaderDX10 struct {
+	DXGIFormat        uint32
+	ResourceDimension uint32
+	MiscFlag          uint32
+	ArraySize         uint32
+	MiscFlags2        uint32
+}
+
+// blockFormat decodes 4x4 texel blocks of size bytes into dst, which holds
+// the 16 texels as RGBA.
+type blockFormat struct {
+	size   int
+	decode func(block []byte, dst *[16]color.NRGBA)
+	gray   bool // single channel, returned as *image.Gray
+}
+
+var (
+	bc1 = blockFormat{size: 8, decode: decodeBC1}
+	bc2 = blockFormat{size: 16, decode: decodeBC2}
+	bc3 = blockFormat{size: 16, decode: decodeBC3}
+	bc4 = blockFormat{size: 8, decode: decodeBC4, gray: true}
+	bc5 = blockFormat{size: 16, decode: decodeBC5}
+)
+
+// decodeDDS reads the top mip level of a DirectDraw Surface. Uncompressed
+// RGB, luminance and alpha surfaces are supported, as are BC1 to BC5
+// (DXT1 to DXT5, ATI1 and ATI2), which are decompressed on the CPU.
+func decodeDDS(r io.Reader) (image.Image, error) {
+	var h ddsHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if string(h.Magic[:]) != "DDS " || h.Size != 124 {
+		return nil, errors.New("invalid header")
+	}
+	width, height := int(h.Width), int(h.Height)
+	if err := checkSize(width, height); err != nil {
+		return nil, err
+	}
+	pf := h.PixelFormat
+
+	if pf.Flags&ddpfFourCC == 0 {
+		return decodeDDSMasked(r, width, height, pf)
+	}
+
+	var (
+		format        blockFormat
+		premultiplied bool
+	)
+	switch fourCC := string(pf.FourCC[:]); fourCC {
+	case "DXT1":
+		format = bc1
+	case "DXT2":
+		format, premultiplied = bc2, true
+	case "DXT3":
+		format = bc2
+	case "DXT4":
+		format, premultiplied = bc3, true
+	case "DXT5":
+		format = bc3
+	case "ATI1", "BC4U":
+		format = bc4
+	case "ATI2", "BC5U":
+		format = bc5
+	case "DX10":
+		var dx10 ddsHeaderDX10
+		if err := binary.Read(r, binary.LittleEndian, &dx10); err != nil {
+			return nil, err
+		}
+		switch dx10.DXGIFormat {
+		case dxgiBC1, dxgiBC1SRGB:
+			format = bc1
+		case dxgiBC2, dxgiBC2SRGB:
+			format = bc2
+		case dxgiBC3, dxgiBC3SRGB:
+			format = bc3
+		case dxgiBC4:
+			format = bc4
+		case dxgiBC5:
+			format = bc5
+		case dxgiR8G8B8A8, dxgiR8G8B8A8SRGB:
+			return decodeDDSMasked(r, width, height, ddsPixelFormat{
+				Flags: ddpfRGB | ddpfAlphaPixels, RGBBitCount: 32,
+				RMask: 0xFF, GMask: 0xFF00, BMask: 0xFF0000, AMask: 0xFF000000,
+			})
+		case dxgiB8G8R8A8, dxgiB8G8R8A8SRGB:
+			return decodeDDSMasked(r, width, height, ddsPixelFormat{
+				Flags: ddpfRGB | ddpfAlphaPixels, RGBBitCount: 32,
+				RMask: 0xFF0000, GMask: 0xFF00, BMask: 0xFF, AMask: 0xFF000000,
+			})
+		case dxgiR8:
+			return decodeDDSMasked(r, width, height, ddsPixelFormat{
+				Flags: ddpfLuminance, RGBBitCount: 8, RMask: 0xFF,
+			})
+		default:
+			return nil, fmt.Errorf("unsupported DXGI format %d", dx10.DXGIFormat)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported FourCC %q", fourCC)
+	}
+
+	img, err := decodeBlocks(r, width, height, format)
+	if err != nil {
+		return nil, err
+	}
+	if premultiplied {
+		// DXT2 and DXT4 store premultiplied color
+		return &image.RGBA{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect}, nil
+	}
+	if format.gray {
+		return redToGray(img), nil
+	}
+	return img, nil
+}
+
+func decodeBlocks(r io.Reader, width, height int, format blockFormat) (*image.NRGBA, error) {
+	blocks := ((width + 3) / 4) * ((height + 3) / 4)
+	data, err := readData(r, blocks*format.size)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	var texels [16]color.NRGBA
+	for by := 0; by < height; by += 4 {
+		for bx := 0; bx < width; bx += 4 {
+			format.decode(data[:format.size], &texels)
+			data = data[format.size:]
+			for i, c := range texels {
+				x, y := bx+i%4, by+i/4
+				if x < width && y < height {
+					img.SetNRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// rgb565 expands a packed 5:6:5 color.
+func rgb565(v uint16) color.NRGBA {
+	r, g, b := uint8(v>>11&0x1F), uint8(v>>5&0x3F), uint8(v&0x1F)
+	return color.NRGBA{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 0xFF}
+}
+
+func lerpColor(a, b color.NRGBA, num, den int) color.NRGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8((int(x)*(den-num) + int(y)*num + den/2) / den)
+	}
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xFF}
+}
+
+// decodeColorBlock decodes the 8 byte color part shared by BC1 to BC3.
+// Only BC1 uses the three color mode with transparent black.
+func decodeColorBlock(block []byte, dst *[16]color.NRGBA, allowTransparent bool) {
+	c0 := binary.LittleEndian.Uint16(block[0:])
+	c1 := binary.LittleEndian.Uint16(block[2:])
+	indices := binary.LittleEndian.Uint32(block[4:])
+
+	var palette [4]color.NRGBA
+	palette[0], palette[1] = rgb565(c0), rgb565(c1)
+	if c0 > c1 || !allowTransparent {
+		palette[2] = lerpColor(palette[0], palette[1], 1, 3)
+		palette[3] = lerpColor(palette[0], palette[1], 2, 3)
+	} else {
+		palette[2] = lerpColor(palette[0], palette[1], 1, 2)
+		palette[3] = color.NRGBA{}
+	}
+
+	for i := range dst {
+		dst[i] = palette[indices>>(2*uint(i))&3]
+	}
+}
+
+// decodeAlphaBlock decodes the 8 byte interpolated channel used by BC3 to
+// BC5.
+func decodeAlphaBlock(block []byte) (values [16]uint8) {
+	a0, a1 := int(block[0]), int(block[1])
+	var palette [8]uint8
+	palette[0], palette[1] = uint8(a0), uint8(a1)
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = uint8(((7-i)*a0 + i*a1 + 3) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = uint8(((5-i)*a0 + i*a1 + 2) / 5)
+		}
+		palette[6], palette[7] = 0, 0xFF
+	}
+
+	var indices uint64
+	for i := 0; i < 6; i++ {
+		indices |= uint64(block[2+i]) << (8 * uint(i))
+	}
+	for i := range values {
+		values[i] = palette[indices>>(3*uint(i))&7]
+	}
+	return values
+}
+
+func decodeBC1(block []byte, dst *[16]color.NRGBA) {
+	decodeColorBlock(block, dst, true)
+}
+
+func decodeBC2(block []byte, dst *[16]color.NRGBA) {
+	decodeColorBlock(block[8:], dst, false)
+	alpha := binary.LittleEndian.Uint64(block)
+	for i := range dst {
+		a := uint8(alpha >> (4 * uint(i)) & 0xF)
+		dst[i].A = a<<4 | a
+	}
+}
+
+func decodeBC3(block []byte, dst *[16]color.NRGBA) {
+	decodeColorBlock(block[8:], dst, false)
+	alpha := decodeAlphaBlock(block)
+	for i := range dst {
+		dst[i].A = alpha[i]
+	}
+}
+
+func decodeBC4(block []byte, dst *[16]color.NRGBA) {
+	red := decodeAlphaBlock(block)
+	for i := range dst {
+		dst[i] = color.NRGBA{red[i], red[i], red[i], 0xFF}
+	}
+}
+
+func decodeBC5(block []byte, dst *[16]color.NRGBA) {
+	red := decodeAlphaBlock(block)
+	green := decodeAlphaBlock(block[8:])
+	for i := range dst {
+		dst[i] = color.NRGBA{red[i], green[i], 0, 0xFF}
+	}
+}
+
+// decodeDDSMasked reads an uncompressed surface whose channels are
+// described by bit masks.
+func decodeDDSMasked(r io.Reader, width, height int, pf ddsPixelFormat) (image.Image, error) {
+	size := int(pf.RGBBitCount) / 8
+	if size < 1 || size > 4 || pf.RGBBitCount%8 != 0 {
+		return nil, fmt.Errorf("unsupported bit count %d", pf.RGBBitCount)
+	}
+
+	channel := func(v, mask uint32) uint8 {
+		if mask == 0 {
+			return 0
+		}
+		v = (v & mask) >> uint(bits.TrailingZeros32(mask))
+		max := mask >> uint(bits.TrailingZeros32(mask))
+		return uint8((uint64(v)*0xFF + uint64(max)/2) / uint64(max))
+	}
+
+	luminance := pf.Flags&ddpfLuminance != 0
+	alphaOnly := pf.Flags&(ddpfAlpha|ddpfRGB|ddpfLuminance) == ddpfAlpha
+	hasAlpha := pf.Flags&(ddpfAlphaPixels|ddpfAlpha) != 0
+
+	data, err := readData(r, width*height*size)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := data[y*width*size:]
+		for x := 0; x < width; x++ {
+			var v uint32
+			for i := 0; i < size; i++ {
+				v |= uint32(row[x*size+i]) << (8 * uint(i))
+			}
+
+			c := color.NRGBA{A: 0xFF}
+			switch {
+			case alphaOnly:
+				c.A = channel(v, pf.AMask)
+			case luminance:
+				c.R = channel(v, pf.RMask)
+				c.G, c.B = c.R, c.R
+			default:
+				c.R, c.G, c.B = channel(v, pf.RMask), channel(v, pf.GMask), channel(v, pf.BMask)
+			}
+			if hasAlpha && !alphaOnly {
+				c.A = channel(v, pf.AMask)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	if luminance && !hasAlpha {
+		return redToGray(img), nil
+	}
+	return img, nil
+}
@@ -0,0 +1,104 @@
+package texfmt
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FloatImage is an in-memory image of linear RGBA with straight alpha and
+// a float32 per channel. It holds high dynamic range data, so channels may
+// exceed 1. At clamps them to [0, 1] without tone mapping.
+type FloatImage struct {
+	// Pix holds the pixels as R, G, B, A, top row first.
+	Pix    []float32
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewFloatImage returns a transparent black FloatImage with bounds r.
+func NewFloatImage(r image.Rectangle) *FloatImage {
+	return &FloatImage{
+		Pix:    make([]float32, 4*r.Dx()*r.Dy()),
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+func (p *FloatImage) ColorModel() color.Model { return color.NRGBA64Model }
+
+func (p *FloatImage) Bounds() image.Rectangle { return p.Rect }
+
+func (p *FloatImage) At(x, y int) color.Color {
+	c := p.FloatAt(x, y)
+	unit := func(v float32) uint16 {
+		if v <= 0 {
+			return 0
+		}
+		if v >= 1 {
+			return 0xFFFF
+		}
+		return uint16(v*0xFFFF + 0.5)
+	}
+	return color.NRGBA64{unit(c[0]), unit(c[1]), unit(c[2]), unit(c[3])}
+}
+
+// PixOffset returns the index of the first channel of pixel (x, y) in Pix.
+func (p *FloatImage) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// FloatAt returns the channels of pixel (x, y), zero outside the bounds.
+func (p *FloatImage) FloatAt(x, y int) [4]float32 {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return [4]float32{}
+	}
+	i := p.PixOffset(x, y)
+	return [4]float32{p.Pix[i], p.Pix[i+1], p.Pix[i+2], p.Pix[i+3]}
+}
+
+// SetFloat sets the channels of pixel (x, y).
+func (p *FloatImage) SetFloat(x, y int, c [4]float32) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	copy(p.Pix[i:i+4], c[:])
+}
+
+// ToFloat converts img to a FloatImage with 16 bits of precision per
+// channel. When linearize is set, the color channels are decoded from sRGB,
+// which is how 8 and 16-bit images are usually stored. A FloatImage is
+// returned as is.
+func ToFloat(img image.Image, linearize bool) *FloatImage {
+	if f, ok := img.(*FloatImage); ok {
+		return f
+	}
+	b := img.Bounds()
+	f := NewFloatImage(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+			v := [4]float32{
+				float32(c.R) / 0xFFFF,
+				float32(c.G) / 0xFFFF,
+				float32(c.B) / 0xFFFF,
+				float32(c.A) / 0xFFFF,
+			}
+			if linearize {
+				for i := 0; i < 3; i++ {
+					v[i] = srgbToLinear(v[i])
+				}
+			}
+			f.SetFloat(x, y, v)
+		}
+	}
+	return f
+}
+
+func srgbToLinear(v float32) float32 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return float32(math.Pow((float64(v)+0.055)/1.055, 2.4))
+}
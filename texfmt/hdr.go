@@ -0,0 +1,202 @@
+package texfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decodeHDR reads a Radiance RGBE picture, flat or run-length encoded, into
+// a FloatImage. The EXPOSURE header is undone, so the result is in the
+// units the picture was rendered in.
+func decodeHDR(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	line, err := readHDRLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "#?") {
+		return nil, errors.New("missing #? signature")
+	}
+
+	exposure := 1.0
+	for {
+		line, err = readHDRLine(br)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "FORMAT="):
+			if format := strings.TrimPrefix(line, "FORMAT="); format != "32-bit_rle_rgbe" {
+				return nil, fmt.Errorf("unsupported pixel format %q", format)
+			}
+		case strings.HasPrefix(line, "EXPOSURE="):
+			e, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(line, "EXPOSURE=")), 64)
+			if err != nil || e <= 0 {
+				return nil, fmt.Errorf("invalid exposure %q", line)
+			}
+			exposure *= e
+		}
+	}
+
+	line, err = readHDRLine(br)
+	if err != nil {
+		return nil, err
+	}
+	width, height, bottomUp, err := parseHDRResolution(line)
+	if err != nil {
+		return nil, err
+	}
+
+	img := NewFloatImage(image.Rect(0, 0, width, height))
+	scanline := make([]byte, 4*width)
+	for i := 0; i < height; i++ {
+		if err := readHDRScanline(br, scanline); err != nil {
+			return nil, err
+		}
+		y := i
+		if bottomUp {
+			y = height - 1 - i
+		}
+		for x := 0; x < width; x++ {
+			p := scanline[4*x : 4*x+4]
+			img.SetFloat(x, y, rgbe(p[0], p[1], p[2], p[3], exposure))
+		}
+	}
+	return img, nil
+}
+
+func readHDRLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// parseHDRResolution understands the standard "-Y height +X width" and the
+// bottom-up "+Y height +X width" orientations.
+func parseHDRResolution(line string) (width, height int, bottomUp bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 || fields[2] != "+X" || (fields[0] != "-Y" && fields[0] != "+Y") {
+		return 0, 0, false, fmt.Errorf("unsupported resolution line %q", line)
+	}
+	height, err = strconv.Atoi(fields[1])
+	if err == nil {
+		width, err = strconv.Atoi(fields[3])
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false, fmt.Errorf("invalid resolution line %q", line)
+	}
+	if err := checkSize(width, height); err != nil {
+		return 0, 0, false, err
+	}
+	return width, height, fields[0] == "+Y", nil
+}
+
+// readHDRScanline reads one scanline of RGBE pixels in any of the three
+// encodings: flat, the original run-length scheme and the adaptive one
+// that stores each channel separately.
+func readHDRScanline(r *bufio.Reader, dst []byte) error {
+	width := len(dst) / 4
+	head, err := r.Peek(4)
+	if err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7FFF || head[0] != 2 || head[1] != 2 || head[2]&0x80 != 0 {
+		return readHDRFlat(r, dst)
+	}
+	r.Discard(4)
+	if n := int(head[2])<<8 | int(head[3]); n != width {
+		return fmt.Errorf("scanline width %d, want %d", n, width)
+	}
+
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				n := int(count) - 128
+				if x+n > width {
+					return errors.New("run overflows the scanline")
+				}
+				v, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				for ; n > 0; n-- {
+					dst[4*x+c] = v
+					x++
+				}
+			} else {
+				n := int(count)
+				if n == 0 || x+n > width {
+					return errors.New("invalid run length")
+				}
+				for ; n > 0; n-- {
+					v, err := r.ReadByte()
+					if err != nil {
+						return err
+					}
+					dst[4*x+c] = v
+					x++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readHDRFlat reads flat pixels, where a pixel of 1, 1, 1, n repeats the
+// previous one n times, shifted up by 8 bits for consecutive repeats.
+func readHDRFlat(r io.Reader, dst []byte) error {
+	var p [4]byte
+	shift := uint(0)
+	for x := 0; x < len(dst)/4; {
+		if _, err := io.ReadFull(r, p[:]); err != nil {
+			return err
+		}
+		if p[0] == 1 && p[1] == 1 && p[2] == 1 {
+			if x == 0 {
+				return errors.New("repeat at the start of a scanline")
+			}
+			n := int(p[3]) << shift
+			if x+n > len(dst)/4 {
+				return errors.New("repeat overflows the scanline")
+			}
+			for ; n > 0; n-- {
+				copy(dst[4*x:4*x+4], dst[4*x-4:4*x])
+				x++
+			}
+			shift += 8
+			continue
+		}
+		copy(dst[4*x:4*x+4], p[:])
+		x++
+		shift = 0
+	}
+	return nil
+}
+
+// rgbe expands a pixel with a shared exponent.
+func rgbe(r, g, b, e byte, exposure float64) [4]float32 {
+	if e == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+	f := math.Ldexp(1, int(e)-(128+8)) / exposure
+	return [4]float32{float32(float64(r) * f), float32(float64(g) * f), float32(float64(b) * f), 1}
+}
@@ -0,0 +1,213 @@
+// Package texfmt decodes the image formats textures are commonly stored in.
+//
+// Formats are looked up by their magic bytes first and by file extension
+// second, so formats without a reliable signature, like TGA, still work
+// when the name is known. PNG, JPEG, GIF, BMP, TGA, DDS and Radiance HDR
+// are registered by default. HDR pictures decode to a FloatImage.
+package texfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/image/bmp"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFormat is returned when no registered format matches the data.
+var ErrFormat = errors.New("texfmt: unknown format")
+
+// Format describes how to recognize and decode one image format.
+type Format struct {
+	Name string
+
+	// Magic lists the byte prefixes that identify the format. A '?'
+	// matches any byte.
+	Magic []string
+
+	// Extensions lists lower case file extensions including the dot.
+	Extensions []string
+
+	Decode func(r io.Reader) (image.Image, error)
+}
+
+var formats []Format
+
+// Register adds a format. Formats registered later take precedence when
+// several match.
+func Register(f Format) {
+	formats = append([]Format{f}, formats...)
+}
+
+func init() {
+	Register(Format{
+		Name:       "png",
+		Magic:      []string{"\x89PNG\r\n\x1a\n"},
+		Extensions: []string{".png"},
+		Decode:     png.Decode,
+	})
+	Register(Format{
+		Name:       "jpeg",
+		Magic:      []string{"\xff\xd8"},
+		Extensions: []string{".jpg", ".jpeg"},
+		Decode:     jpeg.Decode,
+	})
+	Register(Format{
+		Name:       "gif",
+		Magic:      []string{"GIF87a", "GIF89a"},
+		Extensions: []string{".gif"},
+		Decode:     gif.Decode,
+	})
+	Register(Format{
+		Name:       "bmp",
+		Magic:      []string{"BM"},
+		Extensions: []string{".bmp"},
+		Decode:     bmp.Decode,
+	})
+	Register(Format{
+		Name:       "tga",
+		Extensions: []string{".tga", ".tpic"},
+		Decode:     decodeTGA,
+	})
+	Register(Format{
+		Name:       "dds",
+		Magic:      []string{"DDS "},
+		Extensions: []string{".dds"},
+		Decode:     decodeDDS,
+	})
+	Register(Format{
+		Name:       "hdr",
+		Magic:      []string{"#?RADIANCE", "#?RGBE"},
+		Extensions: []string{".hdr", ".pic"},
+		Decode:     decodeHDR,
+	})
+}
+
+func matchMagic(magic string, b []byte) bool {
+	if len(magic) > len(b) {
+		return false
+	}
+	for i := 0; i < len(magic); i++ {
+		if magic[i] != '?' && magic[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sniff returns the format for the data in r, falling back to the
+// extension of name.
+func sniff(r *bufio.Reader, name string) (Format, error) {
+	longest := 0
+	for _, f := range formats {
+		for _, m := range f.Magic {
+			if len(m) > longest {
+				longest = len(m)
+			}
+		}
+	}
+
+	// a short file is fine, it just matches fewer signatures
+	head, err := r.Peek(longest)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return Format{}, err
+	}
+	for _, f := range formats {
+		for _, m := range f.Magic {
+			if matchMagic(m, head) {
+				return f, nil
+			}
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, f := range formats {
+		for _, e := range f.Extensions {
+			if e == ext {
+				return f, nil
+			}
+		}
+	}
+	return Format{}, ErrFormat
+}
+
+// Decode decodes an image in any registered format. name is only used for
+// its extension and may be empty. The name of the format is returned with
+// the image.
+func Decode(r io.Reader, name string) (image.Image, string, error) {
+	br := bufio.NewReader(r)
+	f, err := sniff(br, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, err := f.Decode(br)
+	if err != nil {
+		return nil, f.Name, err
+	}
+	return img, f.Name, nil
+}
+
+// Open decodes the image file at path. Errors mention the path.
+func Open(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, format, err := Decode(f, path)
+	if err != nil {
+		if format != "" {
+			return nil, fmt.Errorf("%s: %s: %v", path, format, err)
+		}
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return img, nil
+}
+
+// maxPixels is the largest number of pixels a decoder allocates an image
+// for, that of a 16384x16384 texture, which is as large as GL drivers go.
+// Headers are checked against it so a corrupt one can't make a decoder
+// run out of memory before it notices the data is missing.
+const maxPixels = 1 << 28
+
+// checkSize returns an error for the dimensions of an image too large to
+// decode.
+func checkSize(width, height int) error {
+	if width < 0 || height < 0 || width > maxPixels || height > maxPixels || width*height > maxPixels {
+		return fmt.Errorf("image size %dx%d too large", width, height)
+	}
+	return nil
+}
+
+// readData reads the n bytes of pixel data a header announces. The buffer
+// grows as the data arrives, so a header claiming more than the file holds
+// fails at the end of the file instead of allocating it all first.
+func readData(r io.Reader, n int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// redToGray keeps the red channel of an image that is known to be gray and
+// opaque.
+func redToGray(img *image.NRGBA) *image.Gray {
+	gray := image.NewGray(img.Rect)
+	for i := range gray.Pix {
+		gray.Pix[i] = img.Pix[i*4]
+	}
+	return gray
+}
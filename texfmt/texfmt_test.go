@@ -0,0 +1,283 @@
+package texfmt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"golang.org/x/image/bmp"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{0xFF, 0x00, 0x00, 0xFF})
+	img.SetNRGBA(1, 0, color.NRGBA{0x00, 0xFF, 0x00, 0xFF})
+	img.SetNRGBA(0, 1, color.NRGBA{0x00, 0x00, 0xFF, 0xFF})
+	img.SetNRGBA(1, 1, color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF})
+	return img
+}
+
+func TestDecodeByMagic(t *testing.T) {
+	encoders := map[string]func(*bytes.Buffer, image.Image) error{
+		"png":  func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) },
+		"jpeg": func(b *bytes.Buffer, img image.Image) error { return jpeg.Encode(b, img, nil) },
+		"gif":  func(b *bytes.Buffer, img image.Image) error { return gif.Encode(b, img, nil) },
+		"bmp":  func(b *bytes.Buffer, img image.Image) error { return bmp.Encode(b, img) },
+	}
+	for name, encode := range encoders {
+		var buf bytes.Buffer
+		if err := encode(&buf, testImage()); err != nil {
+			t.Fatal(err)
+		}
+		// a misleading extension must not matter when the magic matches
+		img, format, err := Decode(&buf, "texture.tga")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if format != name {
+			t.Errorf("decoded %s as %s", name, format)
+		}
+		if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+			t.Errorf("%s: bounds = %v", name, img.Bounds())
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, _, err := Decode(bytes.NewReader([]byte("not an image")), "notes.txt")
+	if err != ErrFormat {
+		t.Errorf("err = %v, want ErrFormat", err)
+	}
+}
+
+func tgaFile(imageType, depth, descriptor uint8, width, height uint16, data []byte) []byte {
+	h := tgaHeader{
+		ImageType:  imageType,
+		Width:      width,
+		Height:     height,
+		Depth:      depth,
+		Descriptor: descriptor,
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, h)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func checkPixels(t *testing.T, img image.Image, want []color.NRGBA) {
+	t.Helper()
+	b := img.Bounds()
+	for i, w := range want {
+		x, y := b.Min.X+i%b.Dx(), b.Min.Y+i/b.Dx()
+		if got := color.NRGBAModel.Convert(img.At(x, y)); got != w {
+			t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, w)
+		}
+	}
+}
+
+func TestTGA(t *testing.T) {
+	red := color.NRGBA{0xFF, 0, 0, 0xFF}
+	blue := color.NRGBA{0, 0, 0xFF, 0xFF}
+	clear := color.NRGBA{0, 0xFF, 0, 0x00}
+
+	// uncompressed BGR, stored bottom row first
+	raw := tgaFile(tgaTrueColor, 24, 0, 2, 2, []byte{
+		0xFF, 0, 0, 0xFF, 0, 0,
+		0, 0, 0xFF, 0, 0, 0xFF,
+	})
+	img, format, err := Decode(bytes.NewReader(raw), "a.TGA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "tga" {
+		t.Errorf("format = %s", format)
+	}
+	checkPixels(t, img, []color.NRGBA{red, red, blue, blue})
+
+	// run-length encoded BGRA with 8 alpha bits, stored top row first
+	rle := tgaFile(tgaTrueColor|tgaRLE, 32, tgaTopToBottom|8, 3, 1, []byte{
+		0x81, 0, 0, 0xFF, 0xFF, // run of two red pixels
+		0x00, 0, 0xFF, 0, 0x00, // one raw transparent green pixel
+	})
+	img, _, err = Decode(bytes.NewReader(rle), "b.tga")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPixels(t, img, []color.NRGBA{red, red, clear})
+
+	gray := tgaFile(tgaGrayscale, 8, tgaTopToBottom, 2, 1, []byte{0x10, 0x20})
+	img, _, err = Decode(bytes.NewReader(gray), "c.tga")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Gray); !ok {
+		t.Errorf("grayscale TGA decoded as %T", img)
+	}
+
+	truncated := tgaFile(tgaTrueColor, 24, 0, 2, 2, []byte{0xFF})
+	if _, _, err := Decode(bytes.NewReader(truncated), "d.tga"); err == nil {
+		t.Error("truncated TGA decoded without error")
+	}
+}
+
+func ddsFile(pf ddsPixelFormat, width, height uint32, data []byte) []byte {
+	h := ddsHeader{Size: 124, Width: width, Height: height, PixelFormat: pf}
+	copy(h.Magic[:], "DDS ")
+	h.PixelFormat.Size = 32
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, h)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestDDSUncompressed(t *testing.T) {
+	raw := ddsFile(ddsPixelFormat{
+		Flags:       ddpfRGB | ddpfAlphaPixels,
+		RGBBitCount: 32,
+		RMask:       0x00FF0000,
+		GMask:       0x0000FF00,
+		BMask:       0x000000FF,
+		AMask:       0xFF000000,
+	}, 2, 1, []byte{
+		0x00, 0x00, 0xFF, 0xFF, // BGRA red
+		0xFF, 0x00, 0x00, 0x80, // BGRA half transparent blue
+	})
+	img, format, err := Decode(bytes.NewReader(raw), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "dds" {
+		t.Errorf("format = %s", format)
+	}
+	checkPixels(t, img, []color.NRGBA{{0xFF, 0, 0, 0xFF}, {0, 0, 0xFF, 0x80}})
+}
+
+func TestDDSBlockCompressed(t *testing.T) {
+	dxt1 := ddsPixelFormat{Flags: ddpfFourCC}
+	copy(dxt1.FourCC[:], "DXT1")
+
+	// color0 white, color1 black; the rows use indices 0, 2, 1 and 3
+	block := []byte{0xFF, 0xFF, 0x00, 0x00, 0x00, 0xAA, 0x55, 0xFF}
+	img, _, err := Decode(bytes.NewReader(ddsFile(dxt1, 4, 4, block)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	white := color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	light := color.NRGBA{0xAA, 0xAA, 0xAA, 0xFF}
+	black := color.NRGBA{0x00, 0x00, 0x00, 0xFF}
+	dark := color.NRGBA{0x55, 0x55, 0x55, 0xFF}
+	checkPixels(t, img, []color.NRGBA{
+		white, white, white, white,
+		light, light, light, light,
+		black, black, black, black,
+		dark, dark, dark, dark,
+	})
+
+	// BC4 holds a single channel
+	bc4 := ddsPixelFormat{Flags: ddpfFourCC}
+	copy(bc4.FourCC[:], "ATI1")
+	gray, _, err := Decode(bytes.NewReader(ddsFile(bc4, 4, 4, []byte{0x80, 0x00, 0, 0, 0, 0, 0, 0})), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := gray.(*image.Gray); !ok || g.GrayAt(3, 3).Y != 0x80 {
+		t.Errorf("BC4 decoded to %T %v", gray, gray.At(3, 3))
+	}
+}
+
+// TestMalformedHeaders checks that sizes in headers are bounded before the
+// pixels are allocated, by the size limit and by the data that follows.
+func TestMalformedHeaders(t *testing.T) {
+	rgba := ddsPixelFormat{Flags: ddpfRGB, RGBBitCount: 32, RMask: 0xFF, GMask: 0xFF00, BMask: 0xFF0000}
+	dxt5 := ddsPixelFormat{Flags: ddpfFourCC}
+	copy(dxt5.FourCC[:], "DXT5")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"a.dds", ddsFile(rgba, 0xFFFFFFFF, 0xFFFFFFFF, nil)},
+		{"b.dds", ddsFile(dxt5, 0xFFFFFFFF, 0xFFFFFFFF, nil)},
+		{"c.dds", ddsFile(rgba, 1<<20, 1<<20, nil)},
+		{"d.dds", ddsFile(rgba, 16384, 16384, make([]byte, 16))},
+		{"e.dds", ddsFile(dxt5, 16384, 16384, make([]byte, 16))},
+		{"a.tga", tgaFile(tgaTrueColor, 32, 0, 0xFFFF, 0xFFFF, nil)},
+		{"b.tga", tgaFile(tgaTrueColor|tgaRLE, 32, 0, 0xFFFF, 0xFFFF, []byte{0xFF, 0, 0, 0, 0})},
+		{"c.tga", tgaFile(tgaTrueColor, 32, 0, 16384, 16384, make([]byte, 16))},
+		{"a.hdr", []byte("#?RADIANCE\n\n-Y 2000000000 +X 2000000000\n")},
+		{"b.hdr", []byte("#?RADIANCE\n\n-Y 20000 +X 20000\n")},
+		{"c.hdr", []byte("#?RADIANCE\n\n-Y 9223372036854775807 +X 2\n")},
+	}
+	for _, test := range tests {
+		if img, _, err := Decode(bytes.NewReader(test.data), test.name); err == nil {
+			t.Errorf("%s: decoded a %v image", test.name, img.Bounds().Size())
+		}
+	}
+}
+
+func TestHDR(t *testing.T) {
+	// two flat pixels, the second repeating the first
+	flat := "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y 1 +X 2\n" +
+		"\x80\x40\x20\x81" + "\x01\x01\x01\x01"
+	img, format, err := Decode(strings.NewReader(flat), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "hdr" {
+		t.Errorf("format = %s", format)
+	}
+	f, ok := img.(*FloatImage)
+	if !ok {
+		t.Fatalf("decoded to %T, want *FloatImage", img)
+	}
+	for x := 0; x < 2; x++ {
+		if got, want := f.FloatAt(x, 0), [4]float32{1, 0.5, 0.25, 1}; got != want {
+			t.Errorf("flat pixel %d = %v, want %v", x, got, want)
+		}
+	}
+
+	// adaptive run-length encoding: a run of red, literal green, a run of
+	// exponents; EXPOSURE halves the values
+	rle := "#?RGBE\nEXPOSURE=2\n\n+Y 1 +X 8\n" +
+		"\x02\x02\x00\x08" +
+		"\x88\x80" +
+		"\x08\x00\x10\x20\x30\x40\x50\x60\x70" +
+		"\x88\x00" +
+		"\x88\x81"
+	img, _, err = Decode(strings.NewReader(rle), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = img.(*FloatImage)
+	for x := 0; x < 8; x++ {
+		want := [4]float32{0.5, float32(x) / 16, 0, 1}
+		if got := f.FloatAt(x, 0); got != want {
+			t.Errorf("RLE pixel %d = %v, want %v", x, got, want)
+		}
+	}
+
+	if _, _, err := Decode(strings.NewReader("#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00"), ""); err == nil {
+		t.Error("XYZE picture decoded without an error")
+	}
+}
+
+func TestToFloat(t *testing.T) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA64(0, 0, color.NRGBA64{0xFFFF, 0x8000, 0, 0xFFFF})
+
+	got := ToFloat(img, false).FloatAt(0, 0)
+	if got[0] != 1 || got[1] < 0.5 || got[1] > 0.5001 || got[2] != 0 || got[3] != 1 {
+		t.Errorf("ToFloat = %v", got)
+	}
+	// mid gray in sRGB is about a fifth of the light
+	if lin := ToFloat(img, true).FloatAt(0, 0); lin[1] < 0.21 || lin[1] > 0.22 {
+		t.Errorf("linearized 0.5 = %v, want 0.214", lin[1])
+	}
+}
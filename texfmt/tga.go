@@ -0,0 +1,209 @@
+package texfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	tgaColorMapped = 1
+	tgaTrueColor   = 2
+	tgaGrayscale   = 3
+	tgaRLE         = 8
+
+	tgaRightToLeft = 0x10
+	tgaTopToBottom = 0x20
+)
+
+type tgaHeader struct {
+	IDLength      uint8
+	ColorMapType  uint8
+	ImageType     uint8
+	ColorMapStart uint16
+	ColorMapLen   uint16
+	ColorMapDepth uint8
+	XOrigin       uint16
+	YOrigin       uint16
+	Width         uint16
+	Height        uint16
+	Depth         uint8
+	Descriptor    uint8
+}
+
+// decodeTGA reads uncompressed and run-length encoded Truevision TGA
+// images: color-mapped, true color and grayscale.
+func decodeTGA(r io.Reader) (image.Image, error) {
+	var h tgaHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(h.IDLength)); err != nil {
+		return nil, err
+	}
+
+	kind := h.ImageType &^ tgaRLE
+	rle := h.ImageType&tgaRLE != 0
+	alphaBits := h.Descriptor & 0x0F
+
+	var palette []color.NRGBA
+	if h.ColorMapType == 1 {
+		size := int(h.ColorMapDepth+7) / 8
+		raw := make([]byte, int(h.ColorMapLen)*size)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return nil, err
+		}
+		palette = make([]color.NRGBA, 0, h.ColorMapLen)
+		for i := 0; i < len(raw); i += size {
+			c, err := tgaColor(raw[i:i+size], h.ColorMapDepth, alphaBits, false)
+			if err != nil {
+				return nil, err
+			}
+			palette = append(palette, c)
+		}
+	}
+
+	var convert func(p []byte) (color.NRGBA, error)
+	switch kind {
+	case tgaColorMapped:
+		if palette == nil {
+			return nil, errors.New("color-mapped image without a color map")
+		}
+		if h.Depth != 8 && h.Depth != 16 {
+			return nil, fmt.Errorf("unsupported color map index size %d", h.Depth)
+		}
+		convert = func(p []byte) (color.NRGBA, error) {
+			i := int(p[0])
+			if len(p) == 2 {
+				i |= int(p[1]) << 8
+			}
+			i -= int(h.ColorMapStart)
+			if i < 0 || i >= len(palette) {
+				return color.NRGBA{}, fmt.Errorf("color map index %d out of range", i)
+			}
+			return palette[i], nil
+		}
+	case tgaTrueColor, tgaGrayscale:
+		gray := kind == tgaGrayscale
+		convert = func(p []byte) (color.NRGBA, error) {
+			return tgaColor(p, h.Depth, alphaBits, gray)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image type %d", h.ImageType)
+	}
+
+	width, height := int(h.Width), int(h.Height)
+	if err := checkSize(width, height); err != nil {
+		return nil, err
+	}
+	size := int(h.Depth+7) / 8
+	var pixels []byte
+	if rle {
+		// runs can expand a short file into a large image, which the size
+		// limit bounds
+		pixels = make([]byte, width*height*size)
+		if err := readTGARLE(r, pixels, size); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if pixels, err = readData(r, width*height*size); err != nil {
+			return nil, err
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		dy := height - 1 - y
+		if h.Descriptor&tgaTopToBottom != 0 {
+			dy = y
+		}
+		for x := 0; x < width; x++ {
+			dx := x
+			if h.Descriptor&tgaRightToLeft != 0 {
+				dx = width - 1 - x
+			}
+			i := (y*width + x) * size
+			c, err := convert(pixels[i : i+size])
+			if err != nil {
+				return nil, err
+			}
+			img.SetNRGBA(dx, dy, c)
+		}
+	}
+
+	if kind == tgaGrayscale && h.Depth == 8 {
+		return redToGray(img), nil
+	}
+	return img, nil
+}
+
+// readTGARLE expands run-length packets into pixels.
+func readTGARLE(r io.Reader, pixels []byte, size int) error {
+	var packet [1]byte
+	pixel := make([]byte, size)
+	for i := 0; i < len(pixels); {
+		if _, err := io.ReadFull(r, packet[:]); err != nil {
+			return err
+		}
+		n := int(packet[0]&0x7F) + 1
+		if i+n*size > len(pixels) {
+			return errors.New("run-length packet overflows the image")
+		}
+
+		if packet[0]&0x80 != 0 {
+			if _, err := io.ReadFull(r, pixel); err != nil {
+				return err
+			}
+			for j := 0; j < n; j++ {
+				copy(pixels[i:], pixel)
+				i += size
+			}
+		} else {
+			if _, err := io.ReadFull(r, pixels[i:i+n*size]); err != nil {
+				return err
+			}
+			i += n * size
+		}
+	}
+	return nil
+}
+
+// tgaColor converts one little endian pixel of the given bit depth.
+func tgaColor(p []byte, depth, alphaBits uint8, gray bool) (color.NRGBA, error) {
+	switch {
+	case gray && depth == 8:
+		return color.NRGBA{p[0], p[0], p[0], 0xFF}, nil
+	case gray && depth == 16:
+		return color.NRGBA{p[0], p[0], p[0], p[1]}, nil
+	case depth == 15 || depth == 16:
+		v := uint16(p[0]) | uint16(p[1])<<8
+		c := color.NRGBA{
+			R: expand5(v >> 10),
+			G: expand5(v >> 5),
+			B: expand5(v),
+			A: 0xFF,
+		}
+		if depth == 16 && alphaBits > 0 && v&0x8000 == 0 {
+			c.A = 0
+		}
+		return c, nil
+	case depth == 24:
+		return color.NRGBA{p[2], p[1], p[0], 0xFF}, nil
+	case depth == 32:
+		c := color.NRGBA{p[2], p[1], p[0], p[3]}
+		if alphaBits == 0 {
+			c.A = 0xFF
+		}
+		return c, nil
+	}
+	return color.NRGBA{}, fmt.Errorf("unsupported pixel depth %d", depth)
+}
+
+func expand5(v uint16) uint8 {
+	v &= 0x1F
+	return uint8(v<<3 | v>>2)
+}
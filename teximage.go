@@ -0,0 +1,246 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	"image"
+	"image/color"
+)
+
+// texImage is an image converted to pixel data glTexImage2D understands.
+// Rows are stored bottom first, because OpenGL expects the first pixel to
+// be the lower left corner.
+type texImage struct {
+	width, height  int
+	internalFormat int
+	format, typ    gl.GLenum
+	pixels         interface{} // []byte, []uint16 or []float32
+}
+
+// newTexImage picks the smallest internal format that holds img without
+// losing precision and converts the pixels to it. Grayscale is copied into
+// the red, green and blue channels: texture swizzles, which would sample a
+// single red channel as gray, need OpenGL 3.3.
+func newTexImage(img image.Image) *texImage {
+	b := img.Bounds()
+	t := &texImage{width: b.Dx(), height: b.Dy()}
+
+	switch src := img.(type) {
+	case *image.NRGBA:
+		t.setFormat(gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE)
+		t.pixels = pack8(b, 4, func(x, y int, dst []byte) {
+			i := src.PixOffset(x, y)
+			copy(dst, src.Pix[i:i+4])
+		})
+
+	case *image.RGBA:
+		t.setFormat(gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE)
+		t.pixels = pack8(b, 4, func(x, y int, dst []byte) {
+			i := src.PixOffset(x, y)
+			a := src.Pix[i+3]
+			dst[0] = unpremultiply8(src.Pix[i], a)
+			dst[1] = unpremultiply8(src.Pix[i+1], a)
+			dst[2] = unpremultiply8(src.Pix[i+2], a)
+			dst[3] = a
+		})
+
+	case *image.NRGBA64:
+		t.setFormat(gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT)
+		t.pixels = pack16(b, 4, func(x, y int, dst []uint16) {
+			c := src.NRGBA64At(x, y)
+			dst[0], dst[1], dst[2], dst[3] = c.R, c.G, c.B, c.A
+		})
+
+	case *image.RGBA64:
+		t.setFormat(gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT)
+		t.pixels = pack16(b, 4, func(x, y int, dst []uint16) {
+			c := src.RGBA64At(x, y)
+			dst[0] = unpremultiply16(c.R, c.A)
+			dst[1] = unpremultiply16(c.G, c.A)
+			dst[2] = unpremultiply16(c.B, c.A)
+			dst[3] = c.A
+		})
+
+	case *image.Gray:
+		t.setFormat(gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE)
+		t.pixels = pack8(b, 3, func(x, y int, dst []byte) {
+			v := src.GrayAt(x, y).Y
+			dst[0], dst[1], dst[2] = v, v, v
+		})
+
+	case *image.Gray16:
+		t.setFormat(gl.RGB16, gl.RGB, gl.UNSIGNED_SHORT)
+		t.pixels = pack16(b, 3, func(x, y int, dst []uint16) {
+			v := src.Gray16At(x, y).Y
+			dst[0], dst[1], dst[2] = v, v, v
+		})
+
+	case *image.Paletted:
+		t.setPaletted(src)
+
+	case *texfmt.FloatImage:
+		t.setFormat(gl.RGBA32F, gl.RGBA, gl.FLOAT)
+		t.pixels = packFloat(b, func(x, y int, dst []float32) {
+			i := src.PixOffset(x, y)
+			copy(dst, src.Pix[i:i+4])
+		})
+
+	case *image.YCbCr, *image.CMYK:
+		// always opaque, so premultiplied and straight alpha agree
+		t.setFormat(gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE)
+		t.pixels = pack8(b, 3, func(x, y int, dst []byte) {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			dst[0], dst[1], dst[2] = c.R, c.G, c.B
+		})
+
+	default:
+		switch img.ColorModel() {
+		case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model, color.Alpha16Model:
+			t.setFormat(gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT)
+			t.pixels = pack16(b, 4, func(x, y int, dst []uint16) {
+				c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+				dst[0], dst[1], dst[2], dst[3] = c.R, c.G, c.B, c.A
+			})
+		default:
+			t.setFormat(gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE)
+			t.pixels = pack8(b, 4, func(x, y int, dst []byte) {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				dst[0], dst[1], dst[2], dst[3] = c.R, c.G, c.B, c.A
+			})
+		}
+	}
+
+	return t
+}
+
+func (t *texImage) setFormat(internalFormat int, format, typ gl.GLenum) {
+	t.internalFormat = internalFormat
+	t.format = format
+	t.typ = typ
+}
+
+// topFirst reorders the rows so the top row comes first. Cube map faces
+// are specified that way, unlike 2D textures.
+func (t *texImage) topFirst() {
+	switch p := t.pixels.(type) {
+	case []byte:
+		flipRows(p, len(p)/t.height)
+	case []uint16:
+		stride := len(p) / t.height
+		line := make([]uint16, stride)
+		for top, bottom := 0, len(p)-stride; top < bottom; top, bottom = top+stride, bottom-stride {
+			copy(line, p[top:top+stride])
+			copy(p[top:top+stride], p[bottom:bottom+stride])
+			copy(p[bottom:bottom+stride], line)
+		}
+	case []float32:
+		stride := len(p) / t.height
+		line := make([]float32, stride)
+		for top, bottom := 0, len(p)-stride; top < bottom; top, bottom = top+stride, bottom-stride {
+			copy(line, p[top:top+stride])
+			copy(p[top:top+stride], p[bottom:bottom+stride])
+			copy(p[bottom:bottom+stride], line)
+		}
+	}
+}
+
+// setPaletted looks at the palette to decide whether the image needs an
+// alpha channel.
+func (t *texImage) setPaletted(src *image.Paletted) {
+	palette := make([]color.NRGBA, 256)
+	opaque := true
+	for i, c := range src.Palette {
+		if i == len(palette) {
+			break
+		}
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		palette[i] = n
+		opaque = opaque && n.A == 0xFF
+	}
+	// indices past the end of the palette are transparent black
+	if len(src.Palette) < len(palette) {
+		opaque = false
+	}
+
+	b := src.Bounds()
+	if opaque {
+		t.setFormat(gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE)
+		t.pixels = pack8(b, 3, func(x, y int, dst []byte) {
+			c := palette[src.ColorIndexAt(x, y)]
+			dst[0], dst[1], dst[2] = c.R, c.G, c.B
+		})
+		return
+	}
+	t.setFormat(gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE)
+	t.pixels = pack8(b, 4, func(x, y int, dst []byte) {
+		c := palette[src.ColorIndexAt(x, y)]
+		dst[0], dst[1], dst[2], dst[3] = c.R, c.G, c.B, c.A
+	})
+}
+
+// pack8 calls fn for every pixel of b with the n bytes the pixel occupies
+// in the flipped buffer.
+func pack8(b image.Rectangle, n int, fn func(x, y int, dst []byte)) []byte {
+	w, h := b.Dx(), b.Dy()
+	data := make([]byte, w*h*n)
+	for y := 0; y < h; y++ {
+		row := data[(h-1-y)*w*n:]
+		for x := 0; x < w; x++ {
+			fn(b.Min.X+x, b.Min.Y+y, row[x*n:x*n+n])
+		}
+	}
+	return data
+}
+
+// pack16 is pack8 for 16-bit channels.
+func pack16(b image.Rectangle, n int, fn func(x, y int, dst []uint16)) []uint16 {
+	w, h := b.Dx(), b.Dy()
+	data := make([]uint16, w*h*n)
+	for y := 0; y < h; y++ {
+		row := data[(h-1-y)*w*n:]
+		for x := 0; x < w; x++ {
+			fn(b.Min.X+x, b.Min.Y+y, row[x*n:x*n+n])
+		}
+	}
+	return data
+}
+
+// packFloat is pack8 for four float channels.
+func packFloat(b image.Rectangle, fn func(x, y int, dst []float32)) []float32 {
+	w, h := b.Dx(), b.Dy()
+	data := make([]float32, w*h*4)
+	for y := 0; y < h; y++ {
+		row := data[(h-1-y)*w*4:]
+		for x := 0; x < w; x++ {
+			fn(b.Min.X+x, b.Min.Y+y, row[x*4:x*4+4])
+		}
+	}
+	return data
+}
+
+// unpremultiply8 turns a premultiplied channel back into a straight one,
+// rounding to the nearest value.
+func unpremultiply8(c, a uint8) uint8 {
+	switch {
+	case a == 0:
+		return 0
+	case a == 0xFF:
+		return c
+	case c >= a:
+		// c > a is not valid premultiplied data; clamp it
+		return 0xFF
+	}
+	return uint8((uint32(c)*0xFF + uint32(a)/2) / uint32(a))
+}
+
+func unpremultiply16(c, a uint16) uint16 {
+	switch {
+	case a == 0:
+		return 0
+	case a == 0xFFFF:
+		return c
+	case c >= a:
+		return 0xFFFF
+	}
+	return uint16((uint32(c)*0xFFFF + uint32(a)/2) / uint32(a))
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"github.com/Happy-Ferret/gl-tutorial/texfmt"
+	"github.com/go-gl/gl"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestUnpremultiply(t *testing.T) {
+	tests8 := []struct {
+		c, a, want uint8
+	}{
+		{0, 0, 0},
+		{0x80, 0xFF, 0x80},
+		{0x40, 0x80, 0x80},
+		{0x01, 0x03, 0x55},
+		{0x02, 0x03, 0xAA},
+		{0x90, 0x80, 0xFF}, // not premultiplied, clamped
+	}
+	for _, test := range tests8 {
+		if got := unpremultiply8(test.c, test.a); got != test.want {
+			t.Errorf("unpremultiply8(%#x, %#x) = %#x, want %#x", test.c, test.a, got, test.want)
+		}
+	}
+
+	tests16 := []struct {
+		c, a, want uint16
+	}{
+		{0, 0, 0},
+		{0x1234, 0xFFFF, 0x1234},
+		{0x4000, 0x8000, 0x8000},
+		{0x0001, 0x0003, 0x5555},
+		{0x9000, 0x8000, 0xFFFF},
+	}
+	for _, test := range tests16 {
+		if got := unpremultiply16(test.c, test.a); got != test.want {
+			t.Errorf("unpremultiply16(%#x, %#x) = %#x, want %#x", test.c, test.a, got, test.want)
+		}
+	}
+}
+
+// column returns a 1x2 image with top at the top and bottom below it, set
+// through the color model of img.
+func column(img interface {
+	image.Image
+	Set(x, y int, c color.Color)
+}, top, bottom color.Color) image.Image {
+	img.Set(0, 0, top)
+	img.Set(0, 1, bottom)
+	return img
+}
+
+func TestNewTexImage(t *testing.T) {
+	rect := image.Rect(0, 0, 1, 2)
+	float := texfmt.NewFloatImage(rect)
+	copy(float.Pix, []float32{1, 2, 3, 4, 0.5, 0.25, 0, 1})
+
+	// white above black
+	ycbcr := image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+	copy(ycbcr.Y, []byte{0xFF, 0})
+	copy(ycbcr.Cb, []byte{0x80, 0x80})
+	copy(ycbcr.Cr, []byte{0x80, 0x80})
+
+	// a subimage that doesn't start at the origin
+	wide := image.NewGray(image.Rect(0, 0, 3, 3))
+	wide.SetGray(2, 1, color.Gray{0x11})
+	wide.SetGray(2, 2, color.Gray{0x22})
+
+	tests := []struct {
+		name           string
+		img            image.Image
+		internalFormat int
+		format, typ    gl.GLenum
+		pixels         interface{} // bottom row first
+	}{
+		{
+			"NRGBA", column(image.NewNRGBA(rect), color.NRGBA{1, 2, 3, 4}, color.NRGBA{5, 6, 7, 8}),
+			gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE,
+			[]byte{5, 6, 7, 8, 1, 2, 3, 4},
+		},
+		{
+			"RGBA unpremultiplied", column(image.NewRGBA(rect), color.RGBA{0x40, 0x20, 0, 0x80}, color.RGBA{0, 0, 0, 0}),
+			gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE,
+			[]byte{0, 0, 0, 0, 0x80, 0x40, 0, 0x80},
+		},
+		{
+			"NRGBA64", column(image.NewNRGBA64(rect), color.NRGBA64{1, 2, 3, 4}, color.NRGBA64{5, 6, 7, 0xFFFF}),
+			gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT,
+			[]uint16{5, 6, 7, 0xFFFF, 1, 2, 3, 4},
+		},
+		{
+			"RGBA64 unpremultiplied", column(image.NewRGBA64(rect), color.RGBA64{0x4000, 0, 0x8000, 0x8000}, color.RGBA64{1, 2, 3, 0xFFFF}),
+			gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT,
+			[]uint16{1, 2, 3, 0xFFFF, 0x8000, 0, 0xFFFF, 0x8000},
+		},
+		{
+			"Gray", column(image.NewGray(rect), color.Gray{0x10}, color.Gray{0x20}),
+			gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE,
+			[]byte{0x20, 0x20, 0x20, 0x10, 0x10, 0x10},
+		},
+		{
+			"Gray subimage", wide.SubImage(image.Rect(2, 1, 3, 3)),
+			gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE,
+			[]byte{0x22, 0x22, 0x22, 0x11, 0x11, 0x11},
+		},
+		{
+			"Gray16", column(image.NewGray16(rect), color.Gray16{0x1234}, color.Gray16{0xABCD}),
+			gl.RGB16, gl.RGB, gl.UNSIGNED_SHORT,
+			[]uint16{0xABCD, 0xABCD, 0xABCD, 0x1234, 0x1234, 0x1234},
+		},
+		{
+			"YCbCr", ycbcr,
+			gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE,
+			[]byte{0, 0, 0, 0xFF, 0xFF, 0xFF},
+		},
+		{
+			"CMYK", column(image.NewCMYK(rect), color.CMYK{0, 0xFF, 0xFF, 0}, color.CMYK{0, 0, 0, 0xFF}),
+			gl.RGB8, gl.RGB, gl.UNSIGNED_BYTE,
+			[]byte{0, 0, 0, 0xFF, 0, 0},
+		},
+		{
+			"float", float,
+			gl.RGBA32F, gl.RGBA, gl.FLOAT,
+			[]float32{0.5, 0.25, 0, 1, 1, 2, 3, 4},
+		},
+		{
+			"Alpha", column(image.NewAlpha(rect), color.Alpha{0x80}, color.Alpha{0xFF}),
+			gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE,
+			[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x80},
+		},
+		{
+			"Alpha16", column(image.NewAlpha16(rect), color.Alpha16{0x8000}, color.Alpha16{0}),
+			gl.RGBA16, gl.RGBA, gl.UNSIGNED_SHORT,
+			[]uint16{0, 0, 0, 0, 0xFFFF, 0xFFFF, 0xFFFF, 0x8000},
+		},
+	}
+	for _, test := range tests {
+		got := newTexImage(test.img)
+		if got.width != 1 || got.height != 2 {
+			t.Errorf("%s: size %dx%d, want 1x2", test.name, got.width, got.height)
+		}
+		if got.internalFormat != test.internalFormat || got.format != test.format || got.typ != test.typ {
+			t.Errorf("%s: format %#x, %#x, %#x, want %#x, %#x, %#x", test.name,
+				got.internalFormat, got.format, got.typ, test.internalFormat, test.format, test.typ)
+		}
+		if !reflect.DeepEqual(got.pixels, test.pixels) {
+			t.Errorf("%s: pixels %v, want %v", test.name, got.pixels, test.pixels)
+		}
+	}
+}
+
+func TestNewTexImagePaletted(t *testing.T) {
+	gray := color.Gray{0x40}
+	red := color.NRGBA{0xFF, 0, 0, 0xFF}
+	faint := color.NRGBA{0x80, 0x80, 0x80, 0x40}
+	full := func(colors ...color.Color) color.Palette {
+		p := make(color.Palette, 256)
+		for i := range p {
+			p[i] = color.Black
+		}
+		copy(p, colors)
+		return p
+	}
+
+	tests := []struct {
+		name           string
+		palette        color.Palette
+		internalFormat int
+		format         gl.GLenum
+		pixels         []byte // index 1 below index 0
+	}{
+		{"gray", full(color.White, gray), gl.RGB8, gl.RGB, []byte{0x40, 0x40, 0x40, 0xFF, 0xFF, 0xFF}},
+		{"gray with alpha", full(faint, gray), gl.RGBA8, gl.RGBA, []byte{0x40, 0x40, 0x40, 0xFF, 0x80, 0x80, 0x80, 0x40}},
+		{"color", full(red, gray), gl.RGB8, gl.RGB, []byte{0x40, 0x40, 0x40, 0xFF, 0, 0}},
+		{"color with alpha", full(red, faint), gl.RGBA8, gl.RGBA, []byte{0x80, 0x80, 0x80, 0x40, 0xFF, 0, 0, 0xFF}},
+		// indices past a short palette are transparent
+		{"short gray", color.Palette{color.White, gray}, gl.RGBA8, gl.RGBA, []byte{0x40, 0x40, 0x40, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+	for _, test := range tests {
+		img := image.NewPaletted(image.Rect(0, 0, 1, 2), test.palette)
+		img.SetColorIndex(0, 0, 0)
+		img.SetColorIndex(0, 1, 1)
+
+		got := newTexImage(img)
+		if got.internalFormat != test.internalFormat || got.format != test.format || got.typ != gl.UNSIGNED_BYTE {
+			t.Errorf("%s: format %#x, %#x, %#x, want %#x, %#x", test.name,
+				got.internalFormat, got.format, got.typ, test.internalFormat, test.format)
+		}
+		if !reflect.DeepEqual(got.pixels, test.pixels) {
+			t.Errorf("%s: pixels %v, want %v", test.name, got.pixels, test.pixels)
+		}
+	}
+}
+
+func TestTexImageTopFirst(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 2, 3))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	tex := newTexImage(img)
+	tex.topFirst()
+	var want []uint16
+	for _, v := range []uint16{0x0001, 0x0203, 0x0405, 0x0607, 0x0809, 0x0A0B} {
+		want = append(want, v, v, v)
+	}
+	if !reflect.DeepEqual(tex.pixels, want) {
+		t.Errorf("pixels %04x, want %04x", tex.pixels, want)
+	}
+}
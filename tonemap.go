@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl"
+	"math"
+	"strconv"
+)
+
+// toneOperator selects the curve that maps scene radiance to the display.
+type toneOperator int
+
+const (
+	toneClamp toneOperator = iota
+	toneReinhard
+	toneACES
+)
+
+var toneOperatorNames = []string{"clamp", "Reinhard", "ACES"}
+
+func (o toneOperator) String() string {
+	return toneOperatorNames[o]
+}
+
+// toneMapTextureUnit is the texture unit the tone-mapping pass reads the
+// scene from.
+const toneMapTextureUnit = 9
+
+// toneMapper renders a scene into an RGBA16F framebuffer the size of the
+// viewport and then draws it, exposed and tone mapped, to the framebuffer
+// that was bound before. Scenes wrap their drawing in Begin and End.
+type toneMapper struct {
+	fbo          gl.Framebuffer
+	color        gl.Texture
+	depthStencil gl.Renderbuffer
+	width        int
+	height       int
+
+	program          *shaderProgram
+	vao              gl.VertexArray
+	exposureLocation gl.UniformLocation
+	operatorLocation gl.UniformLocation
+
+	// Exposure is in stops: every step doubles the brightness.
+	Exposure float32
+	Operator toneOperator
+
+	target gl.Framebuffer
+}
+
+func newToneMapper() (*toneMapper, error) {
+	saved := saveBindings()
+	defer saved.restore()
+
+	m := &toneMapper{Operator: toneReinhard}
+	var err error
+	m.program, err = loadProgram("tonemap", map[string]string{
+		"CLAMP":    strconv.Itoa(int(toneClamp)),
+		"REINHARD": strconv.Itoa(int(toneReinhard)),
+		"ACES":     strconv.Itoa(int(toneACES)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.bindUniforms()
+	m.program.bind = m.bindUniforms
+
+	// core profiles draw nothing without a vertex array, even an empty one
+	m.vao = gl.GenVertexArray()
+
+	m.fbo = gl.GenFramebuffer()
+	m.color = gl.GenTexture()
+	m.depthStencil = gl.GenRenderbuffer()
+	return m, nil
+}
+
+func (m *toneMapper) bindUniforms() {
+	m.program.GetUniformLocation("scene").Uniform1i(toneMapTextureUnit)
+	m.exposureLocation = m.program.GetUniformLocation("exposure")
+	m.operatorLocation = m.program.GetUniformLocation("operator")
+}
+
+// resize reallocates the attachments when the viewport changed size.
+func (m *toneMapper) resize(width, height int) error {
+	if width == m.width && height == m.height {
+		return nil
+	}
+	m.width, m.height = width, height
+
+	saved := saveBindings()
+	defer saved.restore()
+
+	gl.ActiveTexture(gl.TEXTURE0 + toneMapTextureUnit)
+	m.color.Bind(gl.TEXTURE_2D)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, width, height, 0, gl.RGBA, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, m.color, 0)
+
+	m.depthStencil.Bind()
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, m.depthStencil)
+	checkError("tone map framebuffer")
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		// reallocate on the next frame rather than draw into it
+		m.width, m.height = 0, 0
+		return fmt.Errorf("tone map framebuffer incomplete: 0x%x", status)
+	}
+	return nil
+}
+
+// Begin redirects drawing into the floating-point framebuffer. While the
+// window is minimized the viewport is empty and there is nothing to draw
+// into: Begin returns false, and the frame is skipped without calling End.
+func (m *toneMapper) Begin() (bool, error) {
+	v := make([]int32, 4)
+	gl.GetIntegerv(gl.VIEWPORT, v)
+	if v[2] <= 0 || v[3] <= 0 {
+		return false, nil
+	}
+	width, height := int(v[2]), int(v[3])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, v)
+	m.target = gl.Framebuffer(v[0])
+
+	m.fbo.Bind()
+	if err := m.resize(width, height); err != nil {
+		m.target.Bind()
+		return false, err
+	}
+	return true, nil
+}
+
+// End draws what was rendered since Begin to the framebuffer that was
+// bound before.
+func (m *toneMapper) End() {
+	saved := saveBindings()
+	defer saved.restore()
+
+	m.target.Bind()
+	depthTest, stencilTest := gl.IsEnabled(gl.DEPTH_TEST), gl.IsEnabled(gl.STENCIL_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.STENCIL_TEST)
+
+	m.program.Use()
+	m.vao.Bind()
+	gl.ActiveTexture(gl.TEXTURE0 + toneMapTextureUnit)
+	m.color.Bind(gl.TEXTURE_2D)
+	m.exposureLocation.Uniform1f(float32(math.Exp2(float64(m.Exposure))))
+	m.operatorLocation.Uniform1i(int(m.Operator))
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	checkError("tone map")
+
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+	if stencilTest {
+		gl.Enable(gl.STENCIL_TEST)
+	}
+}
+
+func (m *toneMapper) Delete() {
+	m.program.Delete()
+	m.vao.Delete()
+	m.color.Delete()
+	m.depthStencil.Delete()
+	m.fbo.Delete()
+}
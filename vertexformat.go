@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl"
+	"math"
+	"reflect"
+)
+
+// VertexAttrib is one attribute of an interleaved vertex: Size components
+// of Type, starting Offset bytes into the vertex. Integer types can be
+// Normalized to [0, 1], or [-1, 1] if they are signed.
+type VertexAttrib struct {
+	Name       string
+	Size       int
+	Type       gl.GLenum
+	Normalized bool
+	Offset     int
+}
+
+// componentSizes are the attribute component types and their sizes in
+// bytes. The packed 10:10:10:2 types need OpenGL 3.3, newer than the 3.2
+// context the exercises create, so they aren't among them.
+var componentSizes = map[gl.GLenum]int{
+	gl.FLOAT:          4,
+	gl.HALF_FLOAT:     2,
+	gl.BYTE:           1,
+	gl.UNSIGNED_BYTE:  1,
+	gl.SHORT:          2,
+	gl.UNSIGNED_SHORT: 2,
+	gl.INT:            4,
+	gl.UNSIGNED_INT:   4,
+}
+
+// Float1 to Float4 are attributes of 1 to 4 floats.
+func Float1(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 1, Type: gl.FLOAT} }
+func Float2(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 2, Type: gl.FLOAT} }
+func Float3(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 3, Type: gl.FLOAT} }
+func Float4(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 4, Type: gl.FLOAT} }
+
+// Half2 to Half4 are attributes of 16-bit floats, see halfFloat.
+func Half2(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 2, Type: gl.HALF_FLOAT} }
+func Half3(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 3, Type: gl.HALF_FLOAT} }
+func Half4(name string) VertexAttrib { return VertexAttrib{Name: name, Size: 4, Type: gl.HALF_FLOAT} }
+
+// UByte4N is four normalized unsigned bytes, e.g. an RGBA color.
+func UByte4N(name string) VertexAttrib {
+	return VertexAttrib{Name: name, Size: 4, Type: gl.UNSIGNED_BYTE, Normalized: true}
+}
+
+// Byte4N is four normalized signed bytes, e.g. a compact normal.
+func Byte4N(name string) VertexAttrib {
+	return VertexAttrib{Name: name, Size: 4, Type: gl.BYTE, Normalized: true}
+}
+
+// UShort2N is two normalized unsigned shorts, e.g. texture coordinates.
+func UShort2N(name string) VertexAttrib {
+	return VertexAttrib{Name: name, Size: 2, Type: gl.UNSIGNED_SHORT, Normalized: true}
+}
+
+// Short4N is four normalized signed shorts.
+func Short4N(name string) VertexAttrib {
+	return VertexAttrib{Name: name, Size: 4, Type: gl.SHORT, Normalized: true}
+}
+
+// VertexFormat is the layout of interleaved vertices. Stride is the size
+// of a vertex in bytes.
+type VertexFormat struct {
+	Attribs []VertexAttrib
+	Stride  int
+}
+
+// NewVertexFormat lays out attribs one after the other in the order given
+// and computes their offsets and the stride. Every attribute starts at a
+// multiple of four bytes, as some drivers require, so a Half3 takes eight
+// bytes and is followed by two bytes of padding.
+func NewVertexFormat(attribs ...VertexAttrib) *VertexFormat {
+	f := &VertexFormat{}
+	for _, a := range attribs {
+		a.Offset = f.Stride
+		f.Attribs = append(f.Attribs, a)
+		f.Stride += align4(a.bytes())
+	}
+	return f
+}
+
+func (a VertexAttrib) bytes() int {
+	return a.Size * componentSizes[a.Type]
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// check reports attributes that can't be used.
+func (f *VertexFormat) check() error {
+	seen := make(map[string]bool)
+	for _, a := range f.Attribs {
+		switch {
+		case componentSizes[a.Type] == 0:
+			return fmt.Errorf("vertex attribute %s: unknown type %#x", a.Name, a.Type)
+		case a.Size < 1 || a.Size > 4:
+			return fmt.Errorf("vertex attribute %s: size %d is not 1 to 4", a.Name, a.Size)
+		case a.Offset < 0 || a.Offset+a.bytes() > f.Stride:
+			return fmt.Errorf("vertex attribute %s: %d bytes at offset %d don't fit a stride of %d", a.Name, a.bytes(), a.Offset, f.Stride)
+		case seen[a.Name]:
+			return fmt.Errorf("vertex attribute %s appears twice", a.Name)
+		}
+		seen[a.Name] = true
+	}
+	return nil
+}
+
+// Count returns the number of vertices in vertices, a slice of the data in
+// the format. It is an error if the slice doesn't hold a whole number of
+// vertices.
+func (f *VertexFormat) Count(vertices interface{}) (int, error) {
+	v := reflect.ValueOf(vertices)
+	if v.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("vertex data must be a slice, not %T", vertices)
+	}
+	if f.Stride == 0 {
+		return 0, fmt.Errorf("vertex format has no attributes")
+	}
+	size := v.Len() * int(v.Type().Elem().Size())
+	if size%f.Stride != 0 {
+		return 0, fmt.Errorf("%d bytes of vertex data are not a multiple of the %d byte stride", size, f.Stride)
+	}
+	return size / f.Stride, nil
+}
+
+// Enable points the attributes of program at interleaved vertices in the
+// buffer bound to ARRAY_BUFFER and enables them on the bound vertex array.
+// vertices is the data in the buffer, checked with Count; nil skips the
+// check. Attributes the program doesn't use are skipped, as linkers drop
+// the ones that don't contribute to the output.
+func (f *VertexFormat) Enable(program gl.Program, vertices interface{}) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+	if vertices != nil {
+		if _, err := f.Count(vertices); err != nil {
+			return err
+		}
+	}
+	for _, a := range f.Attribs {
+		loc := program.GetAttribLocation(a.Name)
+		if loc < 0 {
+			continue
+		}
+		loc.EnableArray()
+		loc.AttribPointer(uint(a.Size), a.Type, a.Normalized, f.Stride, uintptr(a.Offset))
+		checkError(a.Name + " attrib pointer")
+	}
+	return nil
+}
+
+// halfFloat converts f to the nearest 16-bit float, for vertex data in
+// Half attributes. Values too large for a half become infinities.
+func halfFloat(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+
+	switch {
+	case b&0x7fffffff > 0x7f800000: // NaN
+		return sign | 0x7e00
+	case exp >= 31:
+		return sign | 0x7c00
+	case exp <= 0:
+		// subnormal, or zero if too small
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := uint16(mant >> shift)
+		rest, halfway := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rest > halfway || rest == halfway && h&1 != 0 {
+			h++
+		}
+		return sign | h
+	}
+
+	h := uint16(exp)<<10 | uint16(mant>>13)
+	// round to nearest even; a carry into the exponent is still right
+	if rest := mant & 0x1fff; rest > 0x1000 || rest == 0x1000 && h&1 != 0 {
+		h++
+	}
+	return sign | h
+}
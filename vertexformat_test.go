@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/go-gl/gl"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestVertexFormatLayout(t *testing.T) {
+	f := NewVertexFormat(Float3("position"), Float3("color"), Float2("texcoord"))
+	if f.Stride != 32 {
+		t.Errorf("stride %d, want 32", f.Stride)
+	}
+	for i, want := range []int{0, 12, 24} {
+		if got := f.Attribs[i].Offset; got != want {
+			t.Errorf("%s at offset %d, want %d", f.Attribs[i].Name, got, want)
+		}
+	}
+
+	// a Half3 is padded to four byte alignment
+	f = NewVertexFormat(Half3("position"), UByte4N("color"), Byte4N("normal"))
+	if f.Stride != 16 || f.Attribs[1].Offset != 8 || f.Attribs[2].Offset != 12 {
+		t.Errorf("stride %d, offsets %d and %d, want 16, 8 and 12", f.Stride, f.Attribs[1].Offset, f.Attribs[2].Offset)
+	}
+	if err := f.check(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestPackedVertexLayout checks that a format of packed attributes
+// describes the Go struct the vertices are stored in.
+func TestPackedVertexLayout(t *testing.T) {
+	type vertex struct {
+		position [2]float32
+		color    [4]uint8
+		texcoord [2]uint16
+	}
+	f := NewVertexFormat(Float2("position"), UByte4N("color"), Half2("texcoord"))
+
+	var v vertex
+	offsets := []uintptr{unsafe.Offsetof(v.position), unsafe.Offsetof(v.color), unsafe.Offsetof(v.texcoord)}
+	if uintptr(f.Stride) != unsafe.Sizeof(v) {
+		t.Errorf("stride %d, struct size %d", f.Stride, unsafe.Sizeof(v))
+	}
+	for i, a := range f.Attribs {
+		if uintptr(a.Offset) != offsets[i] {
+			t.Errorf("%s at offset %d, field at %d", a.Name, a.Offset, offsets[i])
+		}
+	}
+	if n, err := f.Count(make([]vertex, 4)); err != nil || n != 4 {
+		t.Errorf("Count = %d, %v, want 4 vertices", n, err)
+	}
+}
+
+func TestVertexFormatCount(t *testing.T) {
+	f := NewVertexFormat(Float2("position"), Float3("color"))
+	n, err := f.Count(make([]gl.GLfloat, 15))
+	if err != nil || n != 3 {
+		t.Errorf("Count = %d, %v, want 3 vertices", n, err)
+	}
+	if _, err := f.Count(make([]gl.GLfloat, 16)); err == nil {
+		t.Error("no error for 16 floats with a stride of 5")
+	}
+	if n, err := NewVertexFormat(Half2("texcoord")).Count(make([]uint16, 6)); err != nil || n != 3 {
+		t.Errorf("Count of halves = %d, %v, want 3 vertices", n, err)
+	}
+}
+
+func TestVertexFormatCheck(t *testing.T) {
+	bad := []*VertexFormat{
+		NewVertexFormat(Float2("position"), Float3("position")),
+		NewVertexFormat(VertexAttrib{Name: "weights", Size: 5, Type: gl.FLOAT}),
+		NewVertexFormat(VertexAttrib{Name: "id", Size: 1, Type: gl.DOUBLE}),
+		NewVertexFormat(VertexAttrib{Name: "normal", Size: 4, Type: gl.INT_2_10_10_10_REV, Normalized: true}),
+		{Attribs: []VertexAttrib{Float4("color")}, Stride: 8},
+	}
+	for _, f := range bad {
+		if err := f.check(); err == nil {
+			t.Errorf("no error for %+v", f.Attribs)
+		}
+	}
+}
+
+func TestHalfFloat(t *testing.T) {
+	tests := []struct {
+		f    float32
+		half uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{1e6, 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+		{5.9604645e-8, 0x0001}, // smallest subnormal
+		{1e-9, 0x0000},
+		{1.0009765625, 0x3c01},
+		{1.00048828125, 0x3c00}, // halfway, rounds to even
+	}
+	for _, test := range tests {
+		if got := halfFloat(test.f); got != test.half {
+			t.Errorf("halfFloat(%g) = %#04x, want %#04x", test.f, got, test.half)
+		}
+	}
+	if got := halfFloat(float32(math.NaN())); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+		t.Errorf("halfFloat(NaN) = %#04x, not a NaN", got)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Happy-Ferret/gl-tutorial/input"
+	"github.com/Happy-Ferret/gl-tutorial/loop"
+	"github.com/go-gl/gl"
+	glfw "github.com/go-gl/glfw3"
+	glm "github.com/go-gl/mathgl/mgl32"
+	"os"
+)
+
+func errorCallback(err glfw.ErrorCode, desc string) {
+	fmt.Printf("%v: %v\n", err, desc)
+}
+
+// framebufferAspect is the width over the height of the framebuffer the
+// scene is drawn into. It is updated before every frame, as the window can
+// be resized.
+var framebufferAspect float32 = 800.0 / 600.0
+
+// perspective returns a perspective projection that fits the framebuffer
+// the scene is drawn into. Scenes call it every frame.
+func perspective(fovy, near, far float32) glm.Mat4 {
+	return glm.Perspective(fovy, framebufferAspect, near, far)
+}
+
+// setFramebufferSize points the viewport at a framebuffer of width by
+// height pixels and updates framebufferAspect. The size of a minimized
+// window is 0, which keeps the last aspect.
+func setFramebufferSize(width, height int) {
+	gl.Viewport(0, 0, width, height)
+	if width > 0 && height > 0 {
+		framebufferAspect = float32(width) / float32(height)
+	}
+}
+
+// createWindow opens a resizable window with an OpenGL 3.2 core context as
+// described by config and makes its context current. A fullscreen window
+// takes the video mode videoMode picks on the primary monitor.
+func createWindow(config WindowConfig) (*glfw.Window, error) {
+	var (
+		err     error
+		monitor *glfw.Monitor
+	)
+
+	// set opengl version
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 2)
+	glfw.WindowHint(glfw.OpenglProfile, glfw.OpenglCoreProfile)
+	glfw.WindowHint(glfw.OpenglForwardCompatible, glfw.True)
+
+	glfw.WindowHint(glfw.Resizable, glfw.True)
+
+	if config.Fullscreen {
+		monitor, err = glfw.GetPrimaryMonitor()
+		if err != nil {
+			return nil, err
+		}
+		mode, err := videoMode(monitor, config.Width, config.Height)
+		if err != nil {
+			return nil, err
+		}
+		config.Width, config.Height = mode.Width, mode.Height
+		glfw.WindowHint(glfw.RefreshRate, mode.RefreshRate)
+	} else {
+		config = config.windowed()
+	}
+
+	window, err := glfw.CreateWindow(config.Width, config.Height, "Testing", monitor, nil)
+	if err != nil {
+		return nil, err
+	}
+	window.MakeContextCurrent()
+
+	return window, nil
+}
+
+// windowed returns config for a window on the desktop, of the default size
+// if config doesn't have one.
+func (config WindowConfig) windowed() WindowConfig {
+	config.Fullscreen = false
+	if config.Width <= 0 || config.Height <= 0 {
+		config.Width, config.Height = defaultWindowConfig.Width, defaultWindowConfig.Height
+	}
+	return config
+}
+
+// videoMode returns the video mode of monitor for a fullscreen window of
+// width by height.
+func videoMode(monitor *glfw.Monitor, width, height int) (*glfw.VideoMode, error) {
+	current, err := monitor.GetVideoMode()
+	if err != nil {
+		return nil, err
+	}
+	modes, err := monitor.GetVideoModes()
+	if err != nil {
+		return nil, err
+	}
+	return pickVideoMode(modes, current, width, height), nil
+}
+
+// pickVideoMode returns the mode of width by height with the highest
+// refresh rate, or current if there is none or the size is 0, so a
+// fullscreen window keeps the desktop resolution unless told otherwise.
+func pickVideoMode(modes []*glfw.VideoMode, current *glfw.VideoMode, width, height int) *glfw.VideoMode {
+	if width <= 0 || height <= 0 {
+		return current
+	}
+	var best *glfw.VideoMode
+	for _, m := range modes {
+		if m.Width == width && m.Height == height && (best == nil || m.RefreshRate > best.RefreshRate) {
+			best = m
+		}
+	}
+	if best == nil {
+		fmt.Printf("no %dx%d video mode, using %dx%d\n", width, height, current.Width, current.Height)
+		return current
+	}
+	return best
+}
+
+// runScene opens a window for scene and draws it until the window is
+// closed, advancing clock once per frame. F11 switches between a window
+// and fullscreen. GLFW 3.0 can't move a window onto a monitor, so that
+// opens a new window and sets the scene up again in its context. The
+// input comes from player if it is set, and is recorded if recorder is.
+func runScene(scene Scene, clock Clock, player *input.Player, recorder *input.Recorder) error {
+	actions, err := loadActions()
+	if err != nil {
+		return err
+	}
+	in := &frameInput{actions: actions, player: player, recorder: recorder}
+
+	glfw.SetErrorCallback(errorCallback)
+
+	if !glfw.Init() {
+		return errors.New("can't init glfw")
+	}
+	defer glfw.Terminate()
+
+	config := defaultWindowConfig
+	if c, ok := scene.(WindowConfigurer); ok {
+		config = c.WindowConfig()
+	}
+	windowed := config.windowed()
+
+	for {
+		window, err := createWindow(config)
+		if err != nil {
+			return err
+		}
+
+		gl.Init()
+		gl.GetError() // ignore INVALID_ENUM that GLEW raises when using OpenGL 3.2+
+
+		sceneCamera = nil
+		scene.Init()
+		toggle := drawWindow(window, scene, clock, in)
+		scene.Delete()
+
+		if !config.Fullscreen {
+			windowed.Width, windowed.Height = window.GetSize()
+		}
+		window.Destroy()
+		if !toggle {
+			return nil
+		}
+
+		// the desktop resolution in fullscreen, the last size in a window
+		if config.Fullscreen {
+			config = windowed
+		} else {
+			config = WindowConfig{Fullscreen: true}
+		}
+	}
+}
+
+// frameInput hands the events of a window, or of a recording, to actions
+// frame by frame, recording them if recorder is set.
+type frameInput struct {
+	actions  *input.Actions
+	player   *input.Player
+	recorder *input.Recorder
+}
+
+// attach starts taking events from window, beginning with its size.
+// Replays ignore the window.
+func (in *frameInput) attach(window *glfw.Window) {
+	in.actions.Clear()
+	handle := func(e input.Event) {
+		if in.recorder != nil {
+			in.recorder.Handle(e)
+		}
+		in.actions.Handle(e)
+	}
+	if in.player != nil {
+		handle = func(input.Event) {}
+	}
+	input.Attach(window, handle)
+
+	width, height := window.GetSize()
+	handle(input.Event{Kind: input.SizeEvent, X: float64(width), Y: float64(height)})
+}
+
+// poll handles the events of the frame.
+func (in *frameInput) poll() {
+	glfw.PollEvents()
+	if in.player != nil {
+		in.player.Frame(in.actions.Handle)
+	}
+}
+
+func (in *frameInput) endFrame() {
+	in.actions.EndFrame()
+	if in.recorder != nil {
+		in.recorder.EndFrame()
+	}
+}
+
+// done reports whether a replay has played every frame of its recording.
+func (in *frameInput) done() bool {
+	return in.player != nil && in.player.Done()
+}
+
+// updateScene hands the actions of a frame to the clock, the scene and
+// its camera. The camera moves in real time, even while the scene is
+// paused or in slow motion.
+func updateScene(scene Scene, clock Clock, actions *input.Actions) {
+	handleClockActions(clock, actions)
+	if r, ok := scene.(ActionReceiver); ok {
+		r.HandleActions(actions)
+	}
+	if sceneCamera != nil {
+		sceneCamera.Update(cameraInput(actions), clock.FrameTime())
+	}
+}
+
+// drawWindow draws scene into window until the window is closed, or the
+// fullscreen action is pressed, which it reports.
+func drawWindow(window *glfw.Window, scene Scene, clock Clock, in *frameInput) (toggleFullscreen bool) {
+	in.attach(window)
+	sim := loop.New(simulationStep)
+	if c, ok := clock.(ClockStarter); ok {
+		c.Start()
+	}
+
+	for !window.ShouldClose() && !toggleFullscreen {
+		in.poll()
+		if in.actions.Pressed("quit") {
+			window.SetShouldClose(true)
+		}
+		toggleFullscreen = in.actions.Pressed("fullscreen")
+		reloadPrograms()
+		updateScene(scene, clock, in.actions)
+
+		// the framebuffer is in pixels, which on HiDPI displays are
+		// smaller than the screen coordinates of the window size
+		width, height := window.GetFramebufferSize()
+		setFramebufferSize(width, height)
+		drawFrame(scene, clock, sim)
+
+		checkError("main loop")
+		if in.actions.Pressed("screenshot") {
+			if err := saveScreenshot(width, height); err != nil {
+				fmt.Println(err)
+			}
+		}
+		window.SwapBuffers()
+		in.endFrame()
+		clock.Advance()
+
+		// a replay ends with its recording rather than freezing the input
+		if in.done() {
+			window.SetShouldClose(true)
+		}
+	}
+
+	return toggleFullscreen
+}
+
+func modesCommand(args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: gltut modes")
+		os.Exit(2)
+	}
+
+	glfw.SetErrorCallback(errorCallback)
+	if !glfw.Init() {
+		return errors.New("can't init glfw")
+	}
+	defer glfw.Terminate()
+
+	monitors, err := glfw.GetMonitors()
+	if err != nil {
+		return err
+	}
+	for _, monitor := range monitors {
+		name, err := monitor.GetName()
+		if err != nil {
+			return err
+		}
+		current, err := monitor.GetVideoMode()
+		if err != nil {
+			return err
+		}
+		modes, err := monitor.GetVideoModes()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", name)
+		for _, m := range modes {
+			mark := " "
+			if *m == *current {
+				mark = "*"
+			}
+			fmt.Printf("\t%s %dx%d %d Hz, %d bits\n", mark, m.Width, m.Height, m.RefreshRate, m.RedBits+m.GreenBits+m.BlueBits)
+		}
+	}
+	return nil
+}
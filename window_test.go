@@ -0,0 +1,28 @@
+package main
+
+import (
+	glfw "github.com/go-gl/glfw3"
+	"testing"
+)
+
+func TestPickVideoMode(t *testing.T) {
+	current := &glfw.VideoMode{Width: 2560, Height: 1600, RefreshRate: 60}
+	slow := &glfw.VideoMode{Width: 1280, Height: 800, RefreshRate: 60}
+	fast := &glfw.VideoMode{Width: 1280, Height: 800, RefreshRate: 120}
+	modes := []*glfw.VideoMode{slow, current, fast}
+
+	tests := []struct {
+		width, height int
+		want          *glfw.VideoMode
+	}{
+		{0, 0, current},
+		{1280, 800, fast},
+		{2560, 1600, current},
+		{1024, 768, current},
+	}
+	for _, test := range tests {
+		if got := pickVideoMode(modes, current, test.width, test.height); got != test.want {
+			t.Errorf("%dx%d: got %+v, want %+v", test.width, test.height, *got, *test.want)
+		}
+	}
+}